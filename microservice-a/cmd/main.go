@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"microservice-a/internal/api/grpcclient"
 	httpHandler "microservice-a/internal/api/http"
+	"microservice-a/internal/fleet"
+	"microservice-a/internal/spool"
+	"microservice-a/internal/valuemodel"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -42,11 +47,88 @@ func main() {
 
 	gen := grpcclient.NewGenerator(grpcTarget, 1*time.Second)
 	//gen := grpcclient.NewGenerator("localhost:50051", 1*time.Second)
-	gen.Start(sensorType, ID1, ID2)
+
+	// Value model used to simulate readings, e.g. VALUE_MODEL=sine
+	modelCfg, err := valuemodel.FromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Error reading value model config: %v", err)
+	}
+	model, err := valuemodel.New(modelCfg)
+	if err != nil {
+		log.Fatalf("Error creating value model: %v", err)
+	}
+	gen.SetValueModel(model)
+	gen.SetFirmware(getEnv("FIRMWARE_VERSION", ""))
+
+	// TLS towards microservice-b when GRPC_TLS=true or a CA or client certificate
+	// is configured; GRPC_TLS_CERT/GRPC_TLS_KEY are the client certificate for
+	// servers requiring mutual TLS
+	caFile, certFile, keyFile := getEnv("GRPC_TLS_CA", ""), getEnv("GRPC_TLS_CERT", ""), getEnv("GRPC_TLS_KEY", "")
+	if useTLS, _ := strconv.ParseBool(getEnv("GRPC_TLS", "false")); useTLS || caFile != "" || certFile != "" {
+		creds, err := grpcclient.ClientCredentials(caFile, certFile, keyFile, getEnv("GRPC_TLS_SERVER_NAME", ""))
+		if err != nil {
+			log.Fatalf("Error loading gRPC TLS config: %v", err)
+		}
+		gen.SetTransportCredentials(creds)
+	}
+	// DEVICE_TOKEN authenticates the generator; it must be bound to the hosted sensors
+	gen.SetDeviceToken(getEnv("DEVICE_TOKEN", ""))
+
+	// Durable spool so readings survive microservice-b outages and restarts
+	var sp *spool.Spool
+	if spoolDir := getEnv("SPOOL_DIR", ""); spoolDir != "" {
+		opts := spool.DefaultOptions()
+		opts.SegmentBytes = getEnvInt64("SPOOL_SEGMENT_BYTES", opts.SegmentBytes)
+		opts.MaxBytes = getEnvInt64("SPOOL_MAX_BYTES", opts.MaxBytes)
+		opts.MaxAge = getEnvDuration("SPOOL_MAX_AGE", opts.MaxAge)
+		// a host crash loses at most the readings of the last SPOOL_SYNC_INTERVAL; 0 syncs every reading
+		opts.SyncInterval = getEnvDuration("SPOOL_SYNC_INTERVAL", opts.SyncInterval)
+
+		sp, err = spool.Open(spoolDir, opts)
+		if err != nil {
+			log.Fatalf("Error opening spool: %v", err)
+		}
+		gen.UseSpool(sp)
+		log.Printf("Spooling readings to %s", spoolDir)
+	}
+
+	// Readings are coalesced into batches of up to SEND_BATCH_SIZE readings,
+	// waiting at most BATCH_LATENCY for a batch to fill up
+	gen.SetBatching(int(getEnvInt64("SEND_BATCH_SIZE", 0)), getEnvDuration("BATCH_LATENCY", -1))
+
+	// POST /replay only opens recordings inside REPLAY_DIR
+	gen.SetReplayDir(getEnv("REPLAY_DIR", "recordings"))
+
+	// A fleet file hosts many virtual sensors in this process instead of the single
+	// SENSOR_TYPE/ID1/ID2 sensor
+	if fleetFile := getEnv("FLEET_FILE", ""); fleetFile != "" {
+		sensors, err := fleet.Load(fleetFile)
+		if err != nil {
+			log.Fatalf("Error loading fleet file: %v", err)
+		}
+		for _, s := range sensors {
+			m, err := valuemodel.New(s.ValueModel)
+			if err != nil {
+				log.Fatalf("Error creating value model for %s/%s: %v", s.Config.ID1, s.Config.ID2, err)
+			}
+			if err := gen.AddSensor(s.Config, m); err != nil {
+				log.Fatalf("Error adding sensor: %v", err)
+			}
+		}
+		gen.StartSending()
+		sensorType = fmt.Sprintf("fleet of %d sensors", len(sensors))
+	} else {
+		gen.Start(sensorType, ID1, ID2)
+	}
 
 	e := echo.New()
 	h := httpHandler.NewHandler(gen)
 	e.POST("/frequency", h.UpdateFrequency)
+	e.GET("/sensors", h.ListSensors)
+	e.PUT("/sensors/:id1/:id2/model", h.UpdateValueModel)
+	e.POST("/replay", h.StartReplay)
+	e.GET("/replay", h.GetReplay)
+	e.DELETE("/replay", h.StopReplay)
 
 	//	Swagger UI endpoint
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -68,6 +150,11 @@ func main() {
 
 	// Stop generator
 	gen.Stop()
+	if sp != nil {
+		if err := sp.Close(); err != nil {
+			log.Printf("spool close failed: %v", err)
+		}
+	}
 
 	// Shutdown Echo server gracefully
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -86,3 +173,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt64 gets an integer environment variable with fallback to default value
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", key, err)
+		}
+		return n
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable with fallback to default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", key, err)
+		}
+		return d
+	}
+	return defaultValue
+}
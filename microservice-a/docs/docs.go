@@ -35,6 +35,18 @@ const docTemplate = `{
                         "name": "freq",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the sensor with this ID1 (requires id2); all sensors are updated when omitted",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the sensor with this ID2 (requires id1)",
+                        "name": "id2",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -53,7 +65,314 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "404": {
+                        "description": "Sensor not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/replay": {
+            "get": {
+                "description": "Returns the progress of the current or last replay.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceA"
+                ],
+                "summary": "Get replay status",
+                "responses": {
+                    "200": {
+                        "description": "Replay status",
+                        "schema": {
+                            "$ref": "#/definitions/replay.Status"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Replays a CSV or NDJSON recording (columns/fields ` + "`" + `timestamp` + "`" + `, ` + "`" + `value` + "`" + `, ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, ` + "`" + `sensor_type` + "`" + `) through the gRPC stream instead of the simulated values of the sensors it targets; other hosted sensors keep simulating. ` + "`" + `speed` + "`" + ` rescales the recorded inter-arrival spacing (1 = original, 2 = twice as fast), ` + "`" + `rewrite_timestamps` + "`" + ` stamps readings with the send time, and ` + "`" + `loop` + "`" + ` starts over at EOF instead of stopping. Records without ids inherit those of the sensor given by ` + "`" + `id1` + "`" + `/` + "`" + `id2` + "`" + `, or of the first hosted sensor. Timestamps may be RFC3339 or unix seconds/milliseconds. ` + "`" + `path` + "`" + ` is relative to ` + "`" + `REPLAY_DIR` + "`" + `; paths leading out of it are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceA"
+                ],
+                "summary": "Replay a recorded sensor data file",
+                "parameters": [
+                    {
+                        "description": "Replay options",
+                        "name": "options",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/replay.Options"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Replay started",
+                        "schema": {
+                            "$ref": "#/definitions/replay.Status"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid options or unreadable file",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Path outside REPLAY_DIR",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Target sensor not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "A replay is already running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stops the running replay; its sensors resume simulated values.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceA"
+                ],
+                "summary": "Stop the running replay",
+                "responses": {
+                    "200": {
+                        "description": "Replay stopped",
+                        "schema": {
+                            "$ref": "#/definitions/replay.Status"
+                        }
+                    },
+                    "404": {
+                        "description": "No replay running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sensors": {
+            "get": {
+                "description": "Lists the virtual sensors hosted by this process with their current frequency.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceA"
+                ],
+                "summary": "List hosted sensors",
+                "responses": {
+                    "200": {
+                        "description": "Hosted sensors",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/grpcclient.SensorStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sensors/{id1}/{id2}/model": {
+            "put": {
+                "description": "Replaces the value model of the sensor identified by ` + "`" + `id1` + "`" + `/` + "`" + `id2` + "`" + `. Supported types: constant, uniform, gaussian, random_walk, sine, step, poisson. Parameters are passed as strings, e.g. ` + "`" + `{\"type\":\"sine\",\"params\":{\"mean\":\"22\",\"period\":\"24h\"}}` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceA"
+                ],
+                "summary": "Change the value model of a sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Value model configuration",
+                        "name": "model",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/valuemodel.Config"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Value model updated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid value model",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Sensor not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "grpcclient.SensorStatus": {
+            "type": "object",
+            "properties": {
+                "frequency": {
+                    "type": "string"
+                },
+                "id1": {
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "sensor_type": {
+                    "type": "string"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "replay.Options": {
+            "type": "object",
+            "properties": {
+                "id1": {
+                    "description": "sensor whose identity fills in records without ids",
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "string"
+                },
+                "loop": {
+                    "description": "start over at EOF instead of stopping",
+                    "type": "boolean"
+                },
+                "path": {
+                    "description": "relative to the replay directory, or absolute within it",
+                    "type": "string"
+                },
+                "rewrite_timestamps": {
+                    "description": "stamp readings with the send time instead of the recorded one",
+                    "type": "boolean"
+                },
+                "speed": {
+                    "description": "1 = original spacing, 2 = twice as fast",
+                    "type": "number"
+                }
+            }
+        },
+        "replay.Status": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "loops": {
+                    "type": "integer"
+                },
+                "options": {
+                    "$ref": "#/definitions/replay.Options"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "started": {
+                    "type": "string"
+                }
+            }
+        },
+        "valuemodel.Config": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
                     }
+                },
+                "seed": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,176 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"microservice-a/internal/valuemodel"
+	pb "microservice-a/pb/shared-proto"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	flushTimeout             = 30 * time.Second // bounds how long a FLUSH_SPOOL command waits for the spool to drain
+	defaultHeartbeatInterval = 10 * time.Second // keeps the hosted sensors online in the device registry
+)
+
+// controlLoop keeps a control stream open so the server can push commands to the
+// hosted sensors. Sensors are announced when the stream opens.
+func (g *Generator) controlLoop() {
+	for {
+		select {
+		case <-g.stop:
+			return
+		default:
+		}
+
+		conn, err := grpc.Dial(g.addr, g.dialOptions()...)
+		if err != nil {
+			log.Println("Failed to connect control stream, retrying in 1s:", err)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		if !g.waitReady(conn) {
+			conn.Close()
+			return
+		}
+		client := pb.NewSensorServiceClient(conn)
+		if err := g.register(client); err != nil {
+			log.Println("Failed to register sensors:", err)
+		}
+		err = g.runControl(client)
+		conn.Close()
+		if status.Code(err) == codes.Unimplemented {
+			log.Println("Server does not support control streams, remote commands disabled")
+			return
+		}
+		if g.ctx.Err() != nil {
+			return
+		}
+		log.Println("Control stream closed, reconnecting:", err)
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// sensorRefs describes the hosted sensors for the device registry
+func (g *Generator) sensorRefs() []*pb.SensorRef {
+	var refs []*pb.SensorRef
+	for _, s := range g.Sensors() {
+		refs = append(refs, &pb.SensorRef{Id1: s.ID1, Id2: s.ID2, SensorType: s.SensorType, Unit: s.Unit})
+	}
+	return refs
+}
+
+// register announces the hosted sensors to the device registry of the server
+func (g *Generator) register(client pb.SensorServiceClient) error {
+	_, err := client.Register(g.ctx, &pb.RegisterRequest{ProducerId: g.producerID, Firmware: g.firmware, Sensors: g.sensorRefs()})
+	if status.Code(err) == codes.Unimplemented {
+		return nil // older server without a device registry
+	}
+	return err
+}
+
+// runControl announces the hosted sensors, sends heartbeats and applies commands
+// until the stream ends
+func (g *Generator) runControl(client pb.SensorServiceClient) error {
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+	stream, err := client.Control(ctx)
+	if err != nil {
+		return err
+	}
+	hello := &pb.Hello{ProducerId: g.producerID, Firmware: g.firmware, Sensors: g.sensorRefs()}
+	if err := stream.Send(&pb.ControlMessage{Payload: &pb.ControlMessage_Hello{Hello: hello}}); err != nil {
+		return err
+	}
+
+	// heartbeats and command results share the stream, which allows one sender at a time
+	var sendMu sync.Mutex
+	send := func(msg *pb.ControlMessage) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(msg)
+	}
+	go func() {
+		ticker := time.NewTicker(g.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := send(&pb.ControlMessage{Payload: &pb.ControlMessage_Heartbeat{Heartbeat: &pb.Heartbeat{SentAt: timestamppb.Now()}}}); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	reply := func(cmd *pb.Command, err error) error {
+		res := &pb.CommandResult{CommandId: cmd.Id, Ok: true}
+		if err != nil {
+			res.Ok, res.Error = false, err.Error()
+		}
+		log.Printf("sensor %s/%s: command %s applied=%v %s", cmd.Id1, cmd.Id2, cmd.Type, res.Ok, res.Error)
+		return send(&pb.ControlMessage{Payload: &pb.ControlMessage_Result{Result: res}})
+	}
+
+	for {
+		cmd, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if cmd.Type == pb.CommandType_FLUSH_SPOOL {
+			// draining the spool takes up to flushTimeout; keep applying commands
+			// meanwhile. A failed send surfaces in Recv.
+			go func() { reply(cmd, g.flushSpool(ctx, cmd)) }()
+			continue
+		}
+		if err := reply(cmd, g.applyCommand(cmd)); err != nil {
+			return err
+		}
+	}
+}
+
+// flushSpool waits for the spool to drain, at most flushTimeout or until ctx ends
+func (g *Generator) flushSpool(ctx context.Context, cmd *pb.Command) error {
+	if _, err := g.lookupSensor(cmd.Id1, cmd.Id2); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	return g.Flush(ctx)
+}
+
+// applyCommand executes a server command against the targeted sensor; FLUSH_SPOOL
+// is run by flushSpool instead
+func (g *Generator) applyCommand(cmd *pb.Command) error {
+	switch cmd.Type {
+	case pb.CommandType_SET_FREQUENCY:
+		if cmd.FrequencyMs <= 0 {
+			return fmt.Errorf("frequency must be positive")
+		}
+		return g.UpdateSensorFrequency(cmd.Id1, cmd.Id2, time.Duration(cmd.FrequencyMs)*time.Millisecond)
+	case pb.CommandType_PAUSE:
+		return g.PauseSensor(cmd.Id1, cmd.Id2)
+	case pb.CommandType_RESUME:
+		return g.ResumeSensor(cmd.Id1, cmd.Id2)
+	case pb.CommandType_SET_VALUE_MODEL:
+		if cmd.ValueModel == nil {
+			return fmt.Errorf("value model is required")
+		}
+		m, err := valuemodel.New(valuemodel.Config{Type: cmd.ValueModel.Type, Seed: cmd.ValueModel.Seed, Params: cmd.ValueModel.Params})
+		if err != nil {
+			return err
+		}
+		return g.SetSensorValueModel(cmd.Id1, cmd.Id2, m)
+	default:
+		return fmt.Errorf("unsupported command %s", cmd.Type)
+	}
+}
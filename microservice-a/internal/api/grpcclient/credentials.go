@@ -0,0 +1,61 @@
+package grpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ClientCredentials builds the TLS credentials the generator dials with. caFile
+// verifies the server instead of the system roots; certFile and keyFile are the
+// client certificate for servers requiring mutual TLS. serverName overrides the
+// name checked against the server certificate.
+func ClientCredentials(caFile, certFile, keyFile, serverName string) (credentials.TransportCredentials, error) {
+	cfg := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read server CA: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in server CA %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// deviceToken sends the device token of the generator with every call
+type deviceToken string
+
+func (t deviceToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false so the token also works against plaintext
+// development servers
+func (deviceToken) RequireTransportSecurity() bool { return false }
+
+// dialOptions returns the transport credentials and device token to dial with
+func (g *Generator) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if g.creds != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(g.creds)}
+	}
+	if g.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(deviceToken(g.token)))
+	}
+	return opts
+}
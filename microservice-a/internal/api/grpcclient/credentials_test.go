@@ -0,0 +1,64 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "microservice-a/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClientCredentials_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	_, err := ClientCredentials(filepath.Join(dir, "missing.pem"), "", "", "")
+	assert.Error(t, err)
+	_, err = ClientCredentials(notPEM, "", "", "")
+	assert.ErrorContains(t, err, "no certificates")
+	_, err = ClientCredentials("", notPEM, notPEM, "")
+	assert.ErrorContains(t, err, "client certificate")
+
+	creds, err := ClientCredentials("", "", "", "microservice-b")
+	require.NoError(t, err)
+	assert.Equal(t, "microservice-b", creds.Info().ServerName)
+}
+
+func TestGenerator_SendsDeviceToken(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	tokens := make(chan string, 1)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) > 0 {
+			select {
+			case tokens <- values[0]:
+			default:
+			}
+		}
+		return handler(ctx, req)
+	}))
+	pb.RegisterSensorServiceServer(server, &RegistrySensorServer{Registered: make(chan *pb.RegisterRequest, 1)})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.SetDeviceToken("smd_1a2b3c4d_secret")
+	gen.Start("Pressure", "D", "3")
+	defer gen.Stop()
+
+	select {
+	case token := <-tokens:
+		assert.Equal(t, "Bearer smd_1a2b3c4d_secret", token)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for an authenticated call")
+	}
+}
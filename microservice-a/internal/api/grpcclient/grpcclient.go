@@ -2,119 +2,262 @@ package grpcclient
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
-	"math/rand"
+	mathrand "math/rand"
+	"microservice-a/internal/replay"
+	"microservice-a/internal/spool"
+	"microservice-a/internal/valuemodel"
 	pb "microservice-a/pb/shared-proto"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/grpc/credentials"
 )
 
+// DefaultFirmware is the version reported to the device registry unless overridden
+const DefaultFirmware = "microservice-a/1.0"
+
+const (
+	defaultBatchSize    = 100                    // max readings coalesced into one batch
+	defaultBatchLatency = 200 * time.Millisecond // max time a reading waits for its batch to fill
+)
+
+// Generator hosts one or more virtual sensors and multiplexes their readings
+// over a single gRPC stream
 type Generator struct {
-	addr   string              // gRPC server address
-	freqCh chan time.Duration  // channel to dynamically update frequency
-	dataCh chan *pb.SensorData // internal channel to buffer data
-	stop   chan struct{}       // to stop the generator
-	client pb.SensorServiceClient
+	addr     string              // gRPC server address
+	freq     time.Duration       // default frequency for new sensors
+	dataCh   chan *pb.SensorData // internal channel to buffer data
+	stop     chan struct{}       // to stop the generator
+	ctx      context.Context     // cancelled on Stop, used for gRPC calls
+	cancel   context.CancelFunc
+	client   pb.SensorServiceClient
+	spool    *spool.Spool // optional durable buffer, replaces dataCh when set
+	sendOnce sync.Once
+
+	batchSize    int           // readings coalesced per batch before waiting for the server ack
+	batchLatency time.Duration // how long to wait for a batch to fill up
+	pending      *batch        // in-memory batch awaiting delivery
+	legacy       atomic.Bool   // server lacks SendSensorBatch, send readings one by one
+	flushCh      chan struct{} // makes the send loop skip the batch latency once
+
+	producerID string            // session id that scopes the sequence numbers of this process
+	emitMu     sync.Mutex        // keeps sequence order and buffer order in step
+	seqs       map[string]uint64 // last sequence handed out per sensor key
+	acked      map[string]uint64 // server high-water mark per sensor key, owned by the send loop
+	firmware   string            // version reported to the device registry
+	heartbeat  time.Duration     // interval of heartbeats on the control stream
+
+	creds credentials.TransportCredentials // TLS credentials, plaintext when nil
+	token string                           // device token sent with every call
+
+	mu      sync.Mutex
+	model   valuemodel.ValueModel // value model of the sensor added by Start
+	sensors map[string]*sensor    // hosted sensors keyed by id1/id2
+	order   []string              // sensor keys in the order they were added
+	player  *replay.Player        // replays recorded data instead of simulated values
 }
 
 // NewGenerator creates a new generator
 func NewGenerator(addr string, freq time.Duration) *Generator {
+	if freq <= 0 {
+		freq = 1 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Generator{
 		addr:   addr,
-		freqCh: make(chan time.Duration, 1),
+		freq:   freq,
 		dataCh: make(chan *pb.SensorData, 100),
 		stop:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+
+		batchSize:    defaultBatchSize,
+		batchLatency: defaultBatchLatency,
+		flushCh:      make(chan struct{}, 1),
+		producerID:   newProducerID(),
+		seqs:         make(map[string]uint64),
+		acked:        make(map[string]uint64),
+		firmware:     DefaultFirmware,
+		heartbeat:    defaultHeartbeatInterval,
+		model:        valuemodel.NewUniform(0, 100, mathrand.New(mathrand.NewSource(time.Now().UnixNano()))),
+		sensors:      make(map[string]*sensor),
+		player:       replay.NewPlayer(""),
 	}
 }
 
-// Start the generator: sends data to gRPC server and handles reconnections
-func (g *Generator) Start(sensorType, id1, id2 string) {
-	go g.generateDataLoop(sensorType, id1, id2) // continuously generate data
-	go g.sendDataLoop()                         // continuously send data via gRPC
+// SetValueModel sets the value model of the single sensor added by Start
+func (g *Generator) SetValueModel(m valuemodel.ValueModel) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.model = m
 }
 
-// generateDataLoop produces sensor data at the current frequency
-func (g *Generator) generateDataLoop(sensorType, id1, id2 string) {
-	freq := 1 * time.Second
-	ticker := time.NewTicker(freq)
-	defer ticker.Stop()
+// UseSpool makes the generator buffer readings in a durable on-disk spool
+// instead of the in-memory channel. Must be called before Start.
+func (g *Generator) UseSpool(s *spool.Spool) {
+	g.spool = s
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			data := &pb.SensorData{
-				Value:      rand.Float64() * 100,
-				SensorType: sensorType,
-				Id1:        id1,
-				Id2:        id2,
-				Timestamp:  timestamppb.Now(),
-			}
-			select {
-			case g.dataCh <- data:
-			default:
-				log.Println("dataCh full, dropping data")
-			}
-		case newFreq := <-g.freqCh:
-			ticker.Stop()
-			ticker = time.NewTicker(newFreq)
-			log.Printf("Frequency updated to %v\n", newFreq)
-		case <-g.stop:
-			return
-		}
+// SetReplayDir sets the directory recordings are replayed from; replays are
+// rejected until it is set. Must be called before Start.
+func (g *Generator) SetReplayDir(dir string) {
+	g.player = replay.NewPlayer(dir)
+}
+
+// SetFirmware sets the version reported to the device registry. Must be called before Start.
+func (g *Generator) SetFirmware(version string) {
+	if version != "" {
+		g.firmware = version
 	}
 }
 
-// sendDataLoop handles gRPC connection and reconnection
-func (g *Generator) sendDataLoop() {
-	for {
-		select {
-		case <-g.stop:
-			return
-		default:
-		}
+// SetTransportCredentials makes the generator dial the server over TLS instead
+// of plaintext. Must be called before Start.
+func (g *Generator) SetTransportCredentials(creds credentials.TransportCredentials) {
+	g.creds = creds
+}
 
-		conn, err := grpc.Dial(g.addr, grpc.WithInsecure())
-		if err != nil {
-			log.Println("Failed to connect gRPC, retrying in 1s:", err)
-			time.Sleep(1 * time.Second)
-			continue
+// SetDeviceToken sets the token the generator authenticates with; the server
+// only accepts readings of the sensors the token is bound to. Must be called
+// before Start.
+func (g *Generator) SetDeviceToken(token string) {
+	g.token = token
+}
+
+// SetBatching controls how readings are coalesced: a batch is sent once it holds
+// size readings or its oldest reading waited latency. Must be called before Start.
+func (g *Generator) SetBatching(size int, latency time.Duration) {
+	if size > 0 {
+		g.batchSize = size
+	}
+	if latency >= 0 {
+		g.batchLatency = latency
+	}
+}
+
+// Start the generator with a single sensor: sends data to gRPC server and handles reconnections
+func (g *Generator) Start(sensorType, id1, id2 string) {
+	g.mu.Lock()
+	model := g.model
+	g.mu.Unlock()
+	if err := g.AddSensor(SensorConfig{SensorType: sensorType, ID1: id1, ID2: id2}, model); err != nil {
+		log.Println("failed to add sensor:", err)
+	}
+	g.StartSending()
+}
+
+// StartSending starts the loop that sends readings of all hosted sensors via gRPC
+func (g *Generator) StartSending() {
+	g.sendOnce.Do(func() {
+		go g.sendLoop()    // continuously send data via gRPC
+		go g.controlLoop() // apply commands pushed by the server
+	})
+}
+
+// emit stamps a reading with the next sequence number of its sensor and hands it over
+// to the spool, or to the in-memory channel when no spool is configured
+func (g *Generator) emit(data *pb.SensorData) {
+	g.emitMu.Lock()
+	defer g.emitMu.Unlock()
+
+	key := sensorKey(data.Id1, data.Id2)
+	g.seqs[key]++
+	data.Seq = g.seqs[key]
+	data.ProducerId = g.producerID
+
+	if g.spool != nil {
+		if err := g.spool.Append(data); err != nil {
+			log.Println("spool append failed, dropping data:", err)
 		}
+		return
+	}
+	select {
+	case g.dataCh <- data:
+	default:
+		log.Println("dataCh full, dropping data")
+	}
+}
 
-		client := pb.NewSensorServiceClient(conn)
-		stream, err := client.SendSensorData(context.Background())
+// StartReplay replays a recorded CSV/NDJSON file through the send path instead of
+// simulated values. Records without ids or sensor type inherit those of the sensor
+// targeted by opts.ID1/opts.ID2, or of the first hosted sensor. Only the sensors
+// the recording is replayed for stop simulating; the others carry on.
+func (g *Generator) StartReplay(opts replay.Options) error {
+	var defaults replay.Record
+	if opts.ID1 != "" || opts.ID2 != "" {
+		s, err := g.lookupSensor(opts.ID1, opts.ID2)
 		if err != nil {
-			log.Println("Failed to start gRPC stream, retrying:", err)
-			conn.Close()
-			time.Sleep(1 * time.Second)
-			continue
+			return err
 		}
+		defaults = replay.Record{SensorType: s.cfg.SensorType, ID1: s.cfg.ID1, ID2: s.cfg.ID2}
+	} else if sensors := g.allSensors(); len(sensors) > 0 {
+		c := sensors[0].cfg
+		defaults = replay.Record{SensorType: c.SensorType, ID1: c.ID1, ID2: c.ID2}
+	}
+	return g.player.Start(opts, defaults, g.emit)
+}
 
-		// send buffered data
-		for data := range g.dataCh {
-			err := stream.Send(data)
-			if err != nil {
-				log.Println("gRPC send failed, reconnecting:", err)
-				conn.Close()
-				break // break inner loop for reconnect
-			}
-		}
+// StopReplay stops a running replay and resumes the simulated values of its sensors
+func (g *Generator) StopReplay() error {
+	return g.player.Stop()
+}
 
-		conn.Close()
-	}
+// ReplayStatus reports progress of the current or last replay
+func (g *Generator) ReplayStatus() replay.Status {
+	return g.player.Status()
 }
 
-// UpdateFrequency dynamically updates data generation frequency
+// UpdateFrequency dynamically updates data generation frequency of every hosted sensor
 func (g *Generator) UpdateFrequency(freq time.Duration) {
+	for _, s := range g.allSensors() {
+		s.updateFrequency(freq)
+	}
+}
+
+// Flush sends buffered readings without waiting for batches to fill up and,
+// with a spool, waits until everything spooled was acknowledged
+func (g *Generator) Flush(ctx context.Context) error {
 	select {
-	case g.freqCh <- freq:
+	case g.flushCh <- struct{}{}:
 	default:
-		log.Println("freqCh full, skipping update")
 	}
+	if g.spool == nil {
+		return nil
+	}
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for g.spool.Pending() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ProducerID returns the session id that scopes the sequence numbers of this generator
+func (g *Generator) ProducerID() string {
+	return g.producerID
+}
+
+// newProducerID returns a random session id; every process start begins a new
+// sequence, spooled readings of earlier sessions keep their own id
+func newProducerID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
 }
 
 // Stop the generator
 func (g *Generator) Stop() {
 	close(g.stop)
+	g.cancel()
 }
@@ -1,13 +1,25 @@
 package grpcclient
 
 import (
+	"context"
+	"io"
+	"microservice-a/internal/replay"
+	"microservice-a/internal/spool"
 	pb "microservice-a/pb/shared-proto"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewGenerator(t *testing.T) {
@@ -18,7 +30,8 @@ func TestNewGenerator(t *testing.T) {
 
 	assert.NotNil(t, gen)
 	assert.Equal(t, addr, gen.addr)
-	assert.NotNil(t, gen.freqCh)
+	assert.Equal(t, freq, gen.freq)
+	assert.NotNil(t, gen.sensors)
 	assert.NotNil(t, gen.dataCh)
 	assert.NotNil(t, gen.stop)
 }
@@ -26,11 +39,15 @@ func TestNewGenerator(t *testing.T) {
 func TestGenerator_UpdateFrequency(t *testing.T) {
 	gen := NewGenerator("localhost:50051", 1*time.Second)
 	newFreq := 2 * time.Second
+	// register the sensor without starting its loop so the update stays in the channel
+	s := newSensor(SensorConfig{SensorType: "Temperature", ID1: "A", ID2: "1", Frequency: time.Second}, nil)
+	gen.sensors[sensorKey("A", "1")] = s
+	gen.order = append(gen.order, sensorKey("A", "1"))
 
 	gen.UpdateFrequency(newFreq)
 
 	select {
-	case receivedFreq := <-gen.freqCh:
+	case receivedFreq := <-s.freqCh:
 		assert.Equal(t, newFreq, receivedFreq)
 	case <-time.After(100 * time.Millisecond):
 		t.Error("Expected frequency update to be sent to channel")
@@ -53,17 +70,27 @@ func TestGenerator_Stop(t *testing.T) {
 // Server that notifies when it receives data
 type FakeSensorServer struct {
 	pb.UnimplementedSensorServiceServer
+	mu       sync.Mutex
 	Received []*pb.SensorData
 	Done     chan struct{}
 }
 
+// received returns a snapshot, readings may still arrive on later batches
+func (s *FakeSensorServer) received() []*pb.SensorData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.SensorData(nil), s.Received...)
+}
+
 func (s *FakeSensorServer) SendSensorData(stream pb.SensorService_SendSensorDataServer) error {
 	for {
 		data, err := stream.Recv()
 		if err != nil {
 			return stream.SendAndClose(&pb.Ack{Ok: true, Message: "All data received"})
 		}
+		s.mu.Lock()
 		s.Received = append(s.Received, data)
+		s.mu.Unlock()
 		select {
 		case s.Done <- struct{}{}:
 		default:
@@ -93,8 +120,8 @@ func TestGenerator_Start_DataGeneration_WithRealServer(t *testing.T) {
 
 	gen.Stop()
 
-	assert.Greater(t, len(fakeServer.Received), 0)
-	for _, d := range fakeServer.Received {
+	assert.Greater(t, len(fakeServer.received()), 0)
+	for _, d := range fakeServer.received() {
 		assert.Equal(t, "Temperature", d.SensorType)
 		assert.Equal(t, "A", d.Id1)
 		assert.Equal(t, "1", d.Id2)
@@ -131,8 +158,8 @@ func TestGenerator_UpdateFrequency_DuringGeneration_WithServer(t *testing.T) {
 
 	gen.Stop()
 
-	assert.Greater(t, len(fakeServer.Received), 0)
-	for _, d := range fakeServer.Received {
+	assert.Greater(t, len(fakeServer.received()), 0)
+	for _, d := range fakeServer.received() {
 		assert.Equal(t, "Temperature", d.SensorType)
 		assert.Equal(t, "A", d.Id1)
 		assert.Equal(t, "1", d.Id2)
@@ -194,12 +221,514 @@ func TestGenerator_MultipleDataGeneration_WithServer(t *testing.T) {
 	gen.Stop()
 
 	// Assert multiple data items
-	count := len(fakeServer.Received)
+	count := len(fakeServer.received())
 	assert.Greater(t, count, 0, "Expected multiple data items generated")
-	for _, d := range fakeServer.Received {
+	for _, d := range fakeServer.received() {
 		assert.Equal(t, "Pressure", d.SensorType)
 		assert.Equal(t, "B", d.Id1)
 		assert.Equal(t, "2", d.Id2)
 		assert.NotNil(t, d.Timestamp)
 	}
 }
+
+// FlakySensorServer rejects the first stream and acknowledges the rest
+type FlakySensorServer struct {
+	pb.UnimplementedSensorServiceServer
+	mu       sync.Mutex
+	calls    int
+	Received []*pb.SensorData
+}
+
+func (s *FlakySensorServer) SendSensorData(stream pb.SensorService_SendSensorDataServer) error {
+	s.mu.Lock()
+	s.calls++
+	first := s.calls == 1
+	s.mu.Unlock()
+
+	var batch []*pb.SensorData
+	for {
+		data, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, data)
+	}
+	if first {
+		return status.Error(codes.Unavailable, "database down")
+	}
+	s.mu.Lock()
+	s.Received = append(s.Received, batch...)
+	s.mu.Unlock()
+	return stream.SendAndClose(&pb.Ack{Ok: true, Message: "All data received"})
+}
+
+func (s *FlakySensorServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Received)
+}
+
+func TestGenerator_Spool_ReplaysUntilAcknowledged(t *testing.T) {
+	sp, err := spool.Open(t.TempDir(), spool.DefaultOptions())
+	require.NoError(t, err)
+	defer sp.Close()
+
+	// readings spooled while microservice-b was unreachable
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sp.Append(&pb.SensorData{Value: float64(i), SensorType: "Temperature", Id1: "A", Id2: "1"}))
+	}
+
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &FlakySensorServer{}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.UseSpool(sp)
+	gen.SetBatching(10, 0)
+	gen.Start("Temperature", "A", "1")
+	defer gen.Stop()
+
+	require.Eventually(t, func() bool { return !sp.Pending() }, 5*time.Second, 20*time.Millisecond)
+
+	fakeServer.mu.Lock()
+	defer fakeServer.mu.Unlock()
+	require.GreaterOrEqual(t, len(fakeServer.Received), 5)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, float64(i), fakeServer.Received[i].Value, "readings must be delivered in order")
+	}
+}
+
+func TestGenerator_MultipleSensors_SingleStream(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &FlakySensorServer{calls: 1} // accept every stream
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	sp, err := spool.Open(t.TempDir(), spool.DefaultOptions())
+	require.NoError(t, err)
+	defer sp.Close()
+
+	gen := NewGenerator(lis.Addr().String(), 20*time.Millisecond)
+	gen.UseSpool(sp)
+	gen.SetBatching(10, 0)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, gen.AddSensor(SensorConfig{SensorType: "Light", ID1: "B", ID2: strconv.Itoa(i)}, nil))
+	}
+	assert.ErrorIs(t, gen.AddSensor(SensorConfig{SensorType: "Light", ID1: "B", ID2: "1"}, nil), ErrSensorExists)
+	gen.StartSending()
+	defer gen.Stop()
+
+	require.Eventually(t, func() bool {
+		fakeServer.mu.Lock()
+		defer fakeServer.mu.Unlock()
+		seen := map[string]bool{}
+		for _, d := range fakeServer.Received {
+			seen[d.Id2] = true
+		}
+		return len(seen) == 3
+	}, 3*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, gen.UpdateSensorFrequency("B", "2", time.Hour))
+	assert.ErrorIs(t, gen.UpdateSensorFrequency("B", "9", time.Hour), ErrSensorNotFound)
+	require.Eventually(t, func() bool {
+		for _, s := range gen.Sensors() {
+			if s.ID2 == "2" {
+				return s.Frequency == "1h0m0s"
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+// BatchSensorServer records every batch it acknowledges
+type BatchSensorServer struct {
+	pb.UnimplementedSensorServiceServer
+	mu      sync.Mutex
+	Batches [][]*pb.SensorData
+}
+
+func (s *BatchSensorServer) SendSensorBatch(stream pb.SensorService_SendSensorBatchServer) error {
+	var received int64
+	for {
+		b, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.Batches = append(s.Batches, b.Readings)
+		s.mu.Unlock()
+		received += int64(len(b.Readings))
+	}
+	return stream.SendAndClose(&pb.Ack{Ok: true, Message: "Batch received", Received: received})
+}
+
+func (s *BatchSensorServer) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.Batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestGenerator_Batching_CoalescesBySize(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &BatchSensorServer{}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	sp, err := spool.Open(t.TempDir(), spool.DefaultOptions())
+	require.NoError(t, err)
+	defer sp.Close()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, sp.Append(&pb.SensorData{Value: float64(i), SensorType: "Temperature", Id1: "A", Id2: "1"}))
+	}
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.UseSpool(sp)
+	gen.SetBatching(10, 0)
+	gen.StartSending()
+	defer gen.Stop()
+
+	require.Eventually(t, func() bool { return !sp.Pending() }, 3*time.Second, 20*time.Millisecond)
+
+	fakeServer.mu.Lock()
+	defer fakeServer.mu.Unlock()
+	require.Len(t, fakeServer.Batches, 3)
+	assert.Len(t, fakeServer.Batches[0], 10)
+	assert.Len(t, fakeServer.Batches[1], 10)
+	assert.Len(t, fakeServer.Batches[2], 5)
+	assert.Equal(t, 24.0, fakeServer.Batches[2][4].Value)
+}
+
+func TestGenerator_Batching_FlushesAfterLatency(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &BatchSensorServer{}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.SetBatching(100, 50*time.Millisecond)
+	gen.StartSending()
+	defer gen.Stop()
+
+	// a partial batch must not wait for the batch to fill up
+	gen.emit(&pb.SensorData{Value: 1, SensorType: "Temperature", Id1: "A", Id2: "1"})
+	require.Eventually(t, func() bool { return fakeServer.total() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestGenerator_Batching_FallsBackToLegacyStream(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &FlakySensorServer{calls: 1} // only implements SendSensorData
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.SetBatching(3, 0)
+	gen.StartSending()
+	defer gen.Stop()
+
+	for i := 0; i < 3; i++ {
+		gen.emit(&pb.SensorData{Value: float64(i), SensorType: "Temperature", Id1: "A", Id2: "1"})
+	}
+	require.Eventually(t, func() bool { return fakeServer.count() == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, gen.legacy.Load())
+}
+
+func TestGenerator_Emit_AssignsSequencePerSensor(t *testing.T) {
+	gen := NewGenerator("localhost:50051", time.Second)
+	gen.emit(&pb.SensorData{Id1: "A", Id2: "1"})
+	gen.emit(&pb.SensorData{Id1: "A", Id2: "2"})
+	gen.emit(&pb.SensorData{Id1: "A", Id2: "1"})
+
+	var got []uint64
+	for i := 0; i < 3; i++ {
+		data := <-gen.dataCh
+		assert.Equal(t, gen.ProducerID(), data.ProducerId)
+		got = append(got, data.Seq)
+	}
+	assert.Equal(t, []uint64{1, 1, 2}, got)
+	assert.NotEqual(t, gen.ProducerID(), NewGenerator("localhost:50051", time.Second).ProducerID())
+}
+
+func TestGenerator_Replay_PausesOnlyReplayedSensor(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rec.csv"),
+		[]byte("timestamp,value\n2025-09-06T10:00:00Z,1000\n2025-09-06T10:00:00.010Z,1000\n"), 0o644))
+
+	gen := NewGenerator("localhost:50051", 10*time.Millisecond)
+	gen.SetReplayDir(dir)
+	for _, id2 := range []string{"1", "2"} {
+		require.NoError(t, gen.AddSensor(SensorConfig{SensorType: "Light", ID1: "B", ID2: id2}, nil))
+	}
+	defer gen.Stop()
+	require.NoError(t, gen.StartReplay(replay.Options{Path: "rec.csv", Loop: true, ID1: "B", ID2: "1"}))
+	defer gen.StopReplay()
+
+	// skip readings simulated before the replay started
+	time.Sleep(30 * time.Millisecond)
+	for len(gen.dataCh) > 0 {
+		<-gen.dataCh
+	}
+
+	perSensor := map[string]int{}
+	for perSensor["1"] < 5 || perSensor["2"] < 5 {
+		select {
+		case data := <-gen.dataCh:
+			perSensor[data.Id2]++
+			if data.Id2 == "1" {
+				assert.Equal(t, float64(1000), data.Value, "replayed sensor must not simulate")
+			} else {
+				assert.Less(t, data.Value, float64(1000), "other sensor keeps simulating")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out, got %v", perSensor)
+		}
+	}
+}
+
+// HighWaterSensorServer has already stored the first readings of a sensor
+type HighWaterSensorServer struct {
+	BatchSensorServer
+	marks []*pb.HighWaterMark
+}
+
+func (s *HighWaterSensorServer) GetHighWaterMarks(_ context.Context, req *pb.HighWaterRequest) (*pb.HighWaterReply, error) {
+	return &pb.HighWaterReply{Marks: s.marks}, nil
+}
+
+func TestGenerator_ResumesFromHighWaterMark(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &HighWaterSensorServer{marks: []*pb.HighWaterMark{{Id1: "A", Id2: "1", Seq: 3}}}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.SetBatching(10, 0)
+	for i := 1; i <= 5; i++ {
+		gen.emit(&pb.SensorData{Value: float64(i), SensorType: "Temperature", Id1: "A", Id2: "1"})
+	}
+	gen.StartSending()
+	defer gen.Stop()
+
+	require.Eventually(t, func() bool { return fakeServer.total() == 2 }, 2*time.Second, 10*time.Millisecond)
+	fakeServer.mu.Lock()
+	defer fakeServer.mu.Unlock()
+	var seqs []uint64
+	for _, b := range fakeServer.Batches {
+		for _, d := range b {
+			seqs = append(seqs, d.Seq)
+		}
+	}
+	assert.Equal(t, []uint64{4, 5}, seqs)
+}
+
+// ControlSensorServer pushes commands to the first control stream and records the replies
+type ControlSensorServer struct {
+	BatchSensorServer
+	commands []*pb.Command
+	Hello    chan *pb.Hello
+	Results  chan *pb.CommandResult
+}
+
+func (s *ControlSensorServer) Control(stream pb.SensorService_ControlServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.Hello <- first.GetHello()
+	for _, cmd := range s.commands {
+		if err := stream.Send(cmd); err != nil {
+			return err
+		}
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.Results <- msg.GetResult()
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestGenerator_ControlStream_AppliesCommands(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &ControlSensorServer{
+		commands: []*pb.Command{
+			{Id: "1", Id1: "A", Id2: "1", Type: pb.CommandType_PAUSE},
+			{Id: "2", Id1: "A", Id2: "1", Type: pb.CommandType_SET_FREQUENCY, FrequencyMs: 2000},
+			{Id: "3", Id1: "A", Id2: "1", Type: pb.CommandType_SET_VALUE_MODEL, ValueModel: &pb.ValueModelConfig{Type: "nope"}},
+			{Id: "4", Id1: "Z", Id2: "9", Type: pb.CommandType_RESUME},
+		},
+		Hello:   make(chan *pb.Hello, 1),
+		Results: make(chan *pb.CommandResult, 4),
+	}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.Start("Temperature", "A", "1")
+	defer gen.Stop()
+
+	select {
+	case hello := <-fakeServer.Hello:
+		assert.Equal(t, gen.ProducerID(), hello.ProducerId)
+		require.Len(t, hello.Sensors, 1)
+		assert.Equal(t, "Temperature", hello.Sensors[0].SensorType)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for hello")
+	}
+
+	results := map[string]*pb.CommandResult{}
+	for i := 0; i < 4; i++ {
+		select {
+		case res := <-fakeServer.Results:
+			results[res.CommandId] = res
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for command results")
+		}
+	}
+	assert.True(t, results["1"].Ok)
+	assert.True(t, results["2"].Ok)
+	assert.False(t, results["3"].Ok)
+	assert.Contains(t, results["3"].Error, "unknown value model")
+	assert.False(t, results["4"].Ok)
+	assert.Contains(t, results["4"].Error, ErrSensorNotFound.Error())
+
+	require.Len(t, gen.Sensors(), 1)
+	assert.True(t, gen.Sensors()[0].Paused)
+}
+
+// PipelinedControlServer pushes all its commands at once and accepts no readings,
+// so the spool never drains
+type PipelinedControlServer struct {
+	pb.UnimplementedSensorServiceServer
+	commands []*pb.Command
+	Results  chan *pb.CommandResult
+}
+
+func (s *PipelinedControlServer) Control(stream pb.SensorService_ControlServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	for _, cmd := range s.commands {
+		if err := stream.Send(cmd); err != nil {
+			return err
+		}
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if res := msg.GetResult(); res != nil {
+			s.Results <- res
+		}
+	}
+}
+
+func TestGenerator_ControlStream_FlushDoesNotBlockCommands(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &PipelinedControlServer{
+		commands: []*pb.Command{
+			{Id: "1", Id1: "A", Id2: "1", Type: pb.CommandType_FLUSH_SPOOL},
+			{Id: "2", Id1: "A", Id2: "1", Type: pb.CommandType_PAUSE},
+		},
+		Results: make(chan *pb.CommandResult, 2),
+	}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	sp, err := spool.Open(t.TempDir(), spool.DefaultOptions())
+	require.NoError(t, err)
+	defer sp.Close()
+	require.NoError(t, sp.Append(&pb.SensorData{Id1: "A", Id2: "1", Seq: 1}))
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.UseSpool(sp)
+	gen.Start("Temperature", "A", "1")
+	defer gen.Stop()
+
+	select {
+	case res := <-fakeServer.Results:
+		assert.Equal(t, "2", res.CommandId, "the pause is applied while the flush is still waiting")
+		assert.True(t, res.Ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the pause result")
+	}
+	assert.True(t, gen.Sensors()[0].Paused)
+}
+
+// RegistrySensorServer records registrations and counts control stream heartbeats
+type RegistrySensorServer struct {
+	BatchSensorServer
+	Registered chan *pb.RegisterRequest
+	heartbeats atomic.Int32
+}
+
+func (s *RegistrySensorServer) Register(_ context.Context, req *pb.RegisterRequest) (*pb.RegisterReply, error) {
+	s.Registered <- req
+	return &pb.RegisterReply{Registered: int32(len(req.Sensors))}, nil
+}
+
+func (s *RegistrySensorServer) Control(stream pb.SensorService_ControlServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if msg.GetHeartbeat() != nil {
+			s.heartbeats.Add(1)
+		}
+	}
+}
+
+func TestGenerator_RegistersSensorsAndSendsHeartbeats(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := grpc.NewServer()
+	fakeServer := &RegistrySensorServer{Registered: make(chan *pb.RegisterRequest, 1)}
+	pb.RegisterSensorServiceServer(server, fakeServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	gen := NewGenerator(lis.Addr().String(), time.Hour)
+	gen.SetFirmware("test/2.0")
+	gen.heartbeat = 20 * time.Millisecond
+	gen.Start("Pressure", "D", "3")
+	defer gen.Stop()
+
+	select {
+	case req := <-fakeServer.Registered:
+		assert.Equal(t, gen.ProducerID(), req.ProducerId)
+		assert.Equal(t, "test/2.0", req.Firmware)
+		require.Len(t, req.Sensors, 1)
+		assert.Equal(t, "hPa", req.Sensors[0].Unit)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for registration")
+	}
+	require.Eventually(t, func() bool { return fakeServer.heartbeats.Load() >= 2 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,263 @@
+package grpcclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	pb "microservice-a/pb/shared-proto"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+var errStopped = errors.New("generator stopped")
+
+// batch is a set of coalesced readings and how to acknowledge their delivery
+type batch struct {
+	readings []*pb.SensorData
+	commit   func() error
+}
+
+// sendLoop handles gRPC connection and reconnection. Readings are coalesced into
+// batches; a batch is only committed (and thus truncated from the spool) once the
+// server acknowledged it, otherwise it is resent after reconnecting.
+func (g *Generator) sendLoop() {
+	for {
+		select {
+		case <-g.stop:
+			return
+		default:
+		}
+
+		conn, err := grpc.Dial(g.addr, g.dialOptions()...)
+		if err != nil {
+			log.Println("Failed to connect gRPC, retrying in 1s:", err)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		// keep readings buffered until the server is reachable
+		if !g.waitReady(conn) {
+			conn.Close()
+			return
+		}
+
+		client := pb.NewSensorServiceClient(conn)
+		if err := g.syncHighWater(client); err != nil {
+			log.Println("Failed to fetch high-water marks, resending unacknowledged readings:", err)
+		}
+		for {
+			b, err := g.nextBatch()
+			if err == errStopped {
+				conn.Close()
+				return
+			}
+			if err != nil {
+				log.Println("Failed to read batch:", err)
+				break
+			}
+			if b.readings = g.unacknowledged(b.readings); len(b.readings) == 0 {
+				// everything was stored before the connection dropped
+				g.pending = nil
+				if err := b.commit(); err != nil {
+					log.Println("Failed to commit batch:", err)
+				}
+				continue
+			}
+			if err := g.deliver(client, b.readings); err != nil {
+				log.Println("gRPC send failed, reconnecting:", err)
+				time.Sleep(1 * time.Second)
+				break
+			}
+			g.pending = nil
+			if err := b.commit(); err != nil {
+				log.Println("Failed to commit batch:", err)
+			}
+		}
+		conn.Close()
+	}
+}
+
+// waitReady blocks until the connection is ready; false means the generator stopped
+func (g *Generator) waitReady(conn *grpc.ClientConn) bool {
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return true
+		}
+		if !conn.WaitForStateChange(g.ctx, state) {
+			return false
+		}
+	}
+}
+
+// syncHighWater asks the server which sequences of this session it already stored,
+// so readings whose ack was lost with the connection are not sent twice
+func (g *Generator) syncHighWater(client pb.SensorServiceClient) error {
+	reply, err := client.GetHighWaterMarks(g.ctx, &pb.HighWaterRequest{ProducerId: g.producerID})
+	if status.Code(err) == codes.Unimplemented {
+		return nil // older server, it stores whatever it receives
+	}
+	if err != nil {
+		return err
+	}
+	for _, m := range reply.Marks {
+		g.acked[sensorKey(m.Id1, m.Id2)] = m.Seq
+	}
+	return nil
+}
+
+// unacknowledged drops readings of this session at or below the server high-water mark
+func (g *Generator) unacknowledged(readings []*pb.SensorData) []*pb.SensorData {
+	kept := readings[:0:0]
+	for _, data := range readings {
+		if data.ProducerId == g.producerID && data.Seq > 0 && data.Seq <= g.acked[sensorKey(data.Id1, data.Id2)] {
+			continue
+		}
+		kept = append(kept, data)
+	}
+	return kept
+}
+
+// nextBatch blocks until readings are available and coalesces them by count and latency
+func (g *Generator) nextBatch() (*batch, error) {
+	if g.spool != nil {
+		return g.nextSpoolBatch()
+	}
+	if g.pending != nil {
+		// resend the batch that failed before reconnecting
+		return g.pending, nil
+	}
+
+	var readings []*pb.SensorData
+	select {
+	case data := <-g.dataCh:
+		readings = append(readings, data)
+	case <-g.stop:
+		return nil, errStopped
+	}
+
+	timer := time.NewTimer(g.batchLatency)
+	defer timer.Stop()
+	for len(readings) < g.batchSize {
+		select {
+		case data := <-g.dataCh:
+			readings = append(readings, data)
+		case <-timer.C:
+			g.pending = &batch{readings: readings, commit: func() error { return nil }}
+			return g.pending, nil
+		case <-g.flushCh:
+			g.pending = &batch{readings: readings, commit: func() error { return nil }}
+			return g.pending, nil
+		case <-g.stop:
+			return nil, errStopped
+		}
+	}
+	g.pending = &batch{readings: readings, commit: func() error { return nil }}
+	return g.pending, nil
+}
+
+// nextSpoolBatch peeks the oldest uncommitted readings from the spool
+func (g *Generator) nextSpoolBatch() (*batch, error) {
+	records, err := g.spool.Peek(g.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	for len(records) == 0 {
+		select {
+		case <-g.spool.Ready():
+		case <-g.stop:
+			return nil, errStopped
+		}
+		if records, err = g.spool.Peek(g.batchSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// give a partial batch the chance to fill up
+	timer := time.NewTimer(g.batchLatency)
+	defer timer.Stop()
+fill:
+	for len(records) < g.batchSize {
+		select {
+		case <-g.spool.Ready():
+			if records, err = g.spool.Peek(g.batchSize); err != nil {
+				return nil, err
+			}
+		case <-timer.C:
+			break fill
+		case <-g.flushCh:
+			break fill
+		case <-g.stop:
+			return nil, errStopped
+		}
+	}
+
+	readings := make([]*pb.SensorData, len(records))
+	for i, r := range records {
+		readings[i] = r.Data
+	}
+	last := records[len(records)-1].Next
+	return &batch{readings: readings, commit: func() error { return g.spool.Commit(last) }}, nil
+}
+
+// deliver sends a batch and waits for the server ack. Servers without
+// SendSensorBatch get the readings one by one over SendSensorData.
+func (g *Generator) deliver(client pb.SensorServiceClient, readings []*pb.SensorData) error {
+	if !g.legacy.Load() {
+		err := g.sendBatch(client, readings)
+		if status.Code(err) != codes.Unimplemented {
+			return err
+		}
+		log.Println("Server does not support SendSensorBatch, falling back to SendSensorData")
+		g.legacy.Store(true)
+	}
+	return g.sendReadings(client, readings)
+}
+
+func (g *Generator) sendBatch(client pb.SensorServiceClient, readings []*pb.SensorData) error {
+	stream, err := client.SendSensorBatch(g.ctx)
+	if err != nil {
+		return err
+	}
+	// io.EOF means the server closed the stream; the reason comes from CloseAndRecv
+	if err := stream.Send(&pb.SensorBatch{Readings: readings}); err != nil && err != io.EOF {
+		return err
+	}
+	ack, err := stream.CloseAndRecv()
+	return checkAck(ack, err)
+}
+
+func (g *Generator) sendReadings(client pb.SensorServiceClient, readings []*pb.SensorData) error {
+	stream, err := client.SendSensorData(g.ctx)
+	if err != nil {
+		return err
+	}
+	for _, data := range readings {
+		if err := stream.Send(data); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+	ack, err := stream.CloseAndRecv()
+	return checkAck(ack, err)
+}
+
+func checkAck(ack *pb.Ack, err error) error {
+	if err != nil {
+		return err
+	}
+	if !ack.Ok {
+		return fmt.Errorf("server rejected batch: %s", ack.Message)
+	}
+	if ack.Duplicates > 0 || ack.Gaps > 0 {
+		log.Printf("Server dropped %d duplicate readings and reported %d missing", ack.Duplicates, ack.Gaps)
+	}
+	return nil
+}
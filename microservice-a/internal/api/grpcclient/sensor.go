@@ -0,0 +1,252 @@
+package grpcclient
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"microservice-a/internal/valuemodel"
+	pb "microservice-a/pb/shared-proto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ErrSensorNotFound = errors.New("sensor not found")
+	ErrSensorExists   = errors.New("sensor already exists")
+)
+
+// SensorConfig describes one virtual sensor hosted by the generator
+type SensorConfig struct {
+	SensorType string
+	Unit       string // defaults by sensor type, see DefaultUnit
+	ID1        string
+	ID2        string
+	Frequency  time.Duration
+}
+
+// SensorStatus is a snapshot of a hosted sensor
+type SensorStatus struct {
+	SensorType string `json:"sensor_type"`
+	Unit       string `json:"unit,omitempty"`
+	ID1        string `json:"id1"`
+	ID2        string `json:"id2"`
+	Frequency  string `json:"frequency"`
+	Paused     bool   `json:"paused"`
+}
+
+// defaultUnits are the units reported for the built-in sensor types
+var defaultUnits = map[string]string{
+	"temperature": "°C",
+	"humidity":    "%",
+	"pressure":    "hPa",
+	"light":       "lux",
+	"motion":      "bool",
+}
+
+// DefaultUnit returns the unit of a built-in sensor type, or "" for custom types
+func DefaultUnit(sensorType string) string {
+	return defaultUnits[strings.ToLower(sensorType)]
+}
+
+// sensor is a virtual sensor producing readings on its own ticker
+type sensor struct {
+	cfg    SensorConfig
+	freqCh chan time.Duration // channel to dynamically update frequency
+
+	mu     sync.Mutex
+	freq   time.Duration
+	model  valuemodel.ValueModel
+	paused atomic.Bool // no readings are produced while set
+}
+
+func sensorKey(id1, id2 string) string {
+	return id1 + "/" + id2
+}
+
+func newSensor(cfg SensorConfig, model valuemodel.ValueModel) *sensor {
+	return &sensor{
+		cfg:    cfg,
+		freqCh: make(chan time.Duration, 1),
+		freq:   cfg.Frequency,
+		model:  model,
+	}
+}
+
+func (s *sensor) status() SensorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SensorStatus{
+		SensorType: s.cfg.SensorType,
+		Unit:       s.cfg.Unit,
+		ID1:        s.cfg.ID1,
+		ID2:        s.cfg.ID2,
+		Frequency:  s.freq.String(),
+		Paused:     s.paused.Load(),
+	}
+}
+
+func (s *sensor) setModel(m valuemodel.ValueModel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.model = m
+}
+
+// nextValue asks the sensor's value model for the reading at t
+func (s *sensor) nextValue(t time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.model.Next(t)
+}
+
+func (s *sensor) updateFrequency(freq time.Duration) {
+	select {
+	case s.freqCh <- freq:
+	default:
+		log.Printf("sensor %s/%s: freqCh full, skipping update", s.cfg.ID1, s.cfg.ID2)
+	}
+}
+
+// AddSensor registers a virtual sensor and starts producing its readings.
+// A zero frequency falls back to the generator default; a nil model to uniform 0-100.
+func (g *Generator) AddSensor(cfg SensorConfig, model valuemodel.ValueModel) error {
+	if cfg.ID1 == "" || cfg.ID2 == "" || cfg.SensorType == "" {
+		return fmt.Errorf("sensor_type, id1 and id2 are required")
+	}
+	if cfg.Frequency <= 0 {
+		cfg.Frequency = g.freq
+	}
+	if cfg.Unit == "" {
+		cfg.Unit = DefaultUnit(cfg.SensorType)
+	}
+
+	if model == nil {
+		// every sensor needs its own model since models are stateful
+		model = valuemodel.NewUniform(0, 100, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := sensorKey(cfg.ID1, cfg.ID2)
+	if _, ok := g.sensors[key]; ok {
+		return fmt.Errorf("%w: %s", ErrSensorExists, key)
+	}
+	s := newSensor(cfg, model)
+	g.sensors[key] = s
+	g.order = append(g.order, key)
+
+	go g.generateDataLoop(s)
+	return nil
+}
+
+// lookupSensor finds a hosted sensor by its ids
+func (g *Generator) lookupSensor(id1, id2 string) (*sensor, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.sensors[sensorKey(id1, id2)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSensorNotFound, sensorKey(id1, id2))
+	}
+	return s, nil
+}
+
+// allSensors returns the hosted sensors in the order they were added
+func (g *Generator) allSensors() []*sensor {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]*sensor, 0, len(g.order))
+	for _, key := range g.order {
+		out = append(out, g.sensors[key])
+	}
+	return out
+}
+
+// Sensors lists the hosted sensors
+func (g *Generator) Sensors() []SensorStatus {
+	sensors := g.allSensors()
+	out := make([]SensorStatus, 0, len(sensors))
+	for _, s := range sensors {
+		out = append(out, s.status())
+	}
+	return out
+}
+
+// UpdateSensorFrequency changes the frequency of a single sensor
+func (g *Generator) UpdateSensorFrequency(id1, id2 string, freq time.Duration) error {
+	s, err := g.lookupSensor(id1, id2)
+	if err != nil {
+		return err
+	}
+	s.updateFrequency(freq)
+	return nil
+}
+
+// SetSensorValueModel replaces the value model of a single sensor
+func (g *Generator) SetSensorValueModel(id1, id2 string, m valuemodel.ValueModel) error {
+	s, err := g.lookupSensor(id1, id2)
+	if err != nil {
+		return err
+	}
+	s.setModel(m)
+	return nil
+}
+
+// PauseSensor stops a sensor from producing readings until ResumeSensor
+func (g *Generator) PauseSensor(id1, id2 string) error {
+	s, err := g.lookupSensor(id1, id2)
+	if err != nil {
+		return err
+	}
+	s.paused.Store(true)
+	return nil
+}
+
+// ResumeSensor lets a paused sensor produce readings again
+func (g *Generator) ResumeSensor(id1, id2 string) error {
+	s, err := g.lookupSensor(id1, id2)
+	if err != nil {
+		return err
+	}
+	s.paused.Store(false)
+	return nil
+}
+
+// generateDataLoop produces readings for one sensor at its current frequency
+func (g *Generator) generateDataLoop(s *sensor) {
+	ticker := time.NewTicker(s.cfg.Frequency)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			// recorded data replaces the simulated values of the replayed sensors
+			if g.player.Targets(s.cfg.ID1, s.cfg.ID2) || s.paused.Load() {
+				continue
+			}
+			data := &pb.SensorData{
+				Value:      s.nextValue(now),
+				SensorType: s.cfg.SensorType,
+				Id1:        s.cfg.ID1,
+				Id2:        s.cfg.ID2,
+				Timestamp:  timestamppb.New(now),
+			}
+			g.emit(data)
+		case newFreq := <-s.freqCh:
+			if newFreq <= 0 {
+				log.Printf("sensor %s/%s: ignoring non-positive frequency %v", s.cfg.ID1, s.cfg.ID2, newFreq)
+				continue
+			}
+			ticker.Reset(newFreq)
+			s.mu.Lock()
+			s.freq = newFreq
+			s.mu.Unlock()
+			log.Printf("sensor %s/%s: frequency updated to %v\n", s.cfg.ID1, s.cfg.ID2, newFreq)
+		case <-g.stop:
+			return
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"microservice-a/internal/api/grpcclient"
+	"microservice-a/internal/replay"
+	"microservice-a/internal/valuemodel"
 
 	"github.com/labstack/echo/v4"
 )
@@ -25,8 +28,11 @@ func NewHandler(gen *grpcclient.Generator) *Handler {
 // @Accept json
 // @Produce json
 // @Param freq query string true "New frequency for sensor data generation (milliseconds or duration string)" example:"1000"
+// @Param id1 query string false "Only update the sensor with this ID1 (requires id2); all sensors are updated when omitted"
+// @Param id2 query string false "Only update the sensor with this ID2 (requires id1)"
 // @Success 200 {object} map[string]interface{} "Frequency successfully updated"
 // @Failure 400 {object} map[string]string "Invalid or missing frequency"
+// @Failure 404 {object} map[string]string "Sensor not found"
 // @Router /frequency [post]
 func (h *Handler) UpdateFrequency(c echo.Context) error {
 	freqStr := c.QueryParam("freq")
@@ -47,9 +53,135 @@ func (h *Handler) UpdateFrequency(c echo.Context) error {
 		duration = d
 	}
 
-	h.generator.UpdateFrequency(duration)
+	id1, id2 := c.QueryParam("id1"), c.QueryParam("id2")
+	if id1 == "" && id2 == "" {
+		h.generator.UpdateFrequency(duration)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":   "Frequency updated",
+			"frequency": duration.String(),
+		})
+	}
+	if id1 == "" || id2 == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "id1 and id2 must be given together"})
+	}
+	if err := h.generator.UpdateSensorFrequency(id1, id2, duration); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":   "Frequency updated",
 		"frequency": duration.String(),
+		"id1":       id1,
+		"id2":       id2,
+	})
+}
+
+// ListSensors godoc
+// @Summary List hosted sensors
+// @Description Lists the virtual sensors hosted by this process with their current frequency.
+// @Tags MicroserviceA
+// @Produce json
+// @Success 200 {array} grpcclient.SensorStatus "Hosted sensors"
+// @Router /sensors [get]
+func (h *Handler) ListSensors(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.generator.Sensors())
+}
+
+// UpdateValueModel godoc
+// @Summary Change the value model of a sensor
+// @Description Replaces the value model of the sensor identified by `id1`/`id2`. Supported types: constant, uniform, gaussian, random_walk, sine, step, poisson. Parameters are passed as strings, e.g. `{"type":"sine","params":{"mean":"22","period":"24h"}}`.
+// @Tags MicroserviceA
+// @Accept json
+// @Produce json
+// @Param id1 path string true "Sensor ID1"
+// @Param id2 path string true "Sensor ID2"
+// @Param model body valuemodel.Config true "Value model configuration"
+// @Success 200 {object} map[string]interface{} "Value model updated"
+// @Failure 400 {object} map[string]string "Invalid value model"
+// @Failure 404 {object} map[string]string "Sensor not found"
+// @Router /sensors/{id1}/{id2}/model [put]
+func (h *Handler) UpdateValueModel(c echo.Context) error {
+	cfg := valuemodel.Config{}
+	if err := c.Bind(&cfg); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	}
+	m, err := valuemodel.New(cfg)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	id1, id2 := c.Param("id1"), c.Param("id2")
+	if err := h.generator.SetSensorValueModel(id1, id2, m); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Value model updated",
+		"id1":     id1,
+		"id2":     id2,
+		"model":   cfg.Type,
 	})
 }
+
+// StartReplay godoc
+// @Summary Replay a recorded sensor data file
+// @Description Replays a CSV or NDJSON recording (columns/fields `timestamp`, `value`, `id1`, `id2`, `sensor_type`) through the gRPC stream instead of the simulated values of the sensors it targets; other hosted sensors keep simulating. `speed` rescales the recorded inter-arrival spacing (1 = original, 2 = twice as fast), `rewrite_timestamps` stamps readings with the send time, and `loop` starts over at EOF instead of stopping. Records without ids inherit those of the sensor given by `id1`/`id2`, or of the first hosted sensor. Timestamps may be RFC3339 or unix seconds/milliseconds. `path` is relative to `REPLAY_DIR`; paths leading out of it are rejected.
+// @Tags MicroserviceA
+// @Accept json
+// @Produce json
+// @Param options body replay.Options true "Replay options"
+// @Success 202 {object} replay.Status "Replay started"
+// @Failure 400 {object} map[string]string "Invalid options or unreadable file"
+// @Failure 403 {object} map[string]string "Path outside REPLAY_DIR"
+// @Failure 404 {object} map[string]string "Target sensor not found"
+// @Failure 409 {object} map[string]string "A replay is already running"
+// @Router /replay [post]
+func (h *Handler) StartReplay(c echo.Context) error {
+	opts := replay.Options{}
+	if err := c.Bind(&opts); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	}
+	if opts.Path == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "path is required"})
+	}
+	if opts.Speed < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "speed must be positive"})
+	}
+
+	if err := h.generator.StartReplay(opts); err != nil {
+		if errors.Is(err, replay.ErrAlreadyRunning) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, grpcclient.ErrSensorNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, replay.ErrOutsideDir) || errors.Is(err, replay.ErrNoReplayDir) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, h.generator.ReplayStatus())
+}
+
+// GetReplay godoc
+// @Summary Get replay status
+// @Description Returns the progress of the current or last replay.
+// @Tags MicroserviceA
+// @Produce json
+// @Success 200 {object} replay.Status "Replay status"
+// @Router /replay [get]
+func (h *Handler) GetReplay(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.generator.ReplayStatus())
+}
+
+// StopReplay godoc
+// @Summary Stop the running replay
+// @Description Stops the running replay; its sensors resume simulated values.
+// @Tags MicroserviceA
+// @Produce json
+// @Success 200 {object} replay.Status "Replay stopped"
+// @Failure 404 {object} map[string]string "No replay running"
+// @Router /replay [delete]
+func (h *Handler) StopReplay(c echo.Context) error {
+	if err := h.generator.StopReplay(); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, h.generator.ReplayStatus())
+}
@@ -1,9 +1,12 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandler_UpdateFrequency_Milliseconds(t *testing.T) {
@@ -184,3 +188,140 @@ func TestHandler_UpdateFrequency_NegativeFreq(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "invalid freq", response["error"])
 }
+
+func TestHandler_Replay(t *testing.T) {
+	e := echo.New()
+	gen := grpcclient.NewGenerator("localhost:50051", 1*time.Second)
+	dir := t.TempDir()
+	gen.SetReplayDir(dir)
+	handler := NewHandler(gen)
+
+	path := filepath.Join(dir, "rec.csv")
+	require.NoError(t, os.WriteFile(path, []byte("timestamp,value\n2025-09-06T10:00:00Z,1\n2025-09-06T11:00:00Z,2\n"), 0o644))
+
+	tests := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{name: "invalid body", payload: "{invalid json", expectedStatus: http.StatusBadRequest},
+		{name: "missing path", payload: `{"speed":2}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative speed", payload: `{"path":"` + path + `","speed":-1}`, expectedStatus: http.StatusBadRequest},
+		{name: "missing file", payload: `{"path":"missing.csv"}`, expectedStatus: http.StatusBadRequest},
+		{name: "outside replay dir", payload: `{"path":"/etc/passwd.csv"}`, expectedStatus: http.StatusForbidden},
+		{name: "escaping replay dir", payload: `{"path":"../rec.csv"}`, expectedStatus: http.StatusForbidden},
+		{name: "start", payload: `{"path":"rec.csv","loop":true}`, expectedStatus: http.StatusAccepted},
+		{name: "already running", payload: `{"path":"` + path + `"}`, expectedStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/replay", bytes.NewBufferString(tt.payload))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, handler.StartReplay(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+
+	// status reports the running replay
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.GetReplay(e.NewContext(httptest.NewRequest(http.MethodGet, "/replay", nil), rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, true, status["running"])
+
+	// stop, then stopping again is a 404
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler.StopReplay(e.NewContext(httptest.NewRequest(http.MethodDelete, "/replay", nil), rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler.StopReplay(e.NewContext(httptest.NewRequest(http.MethodDelete, "/replay", nil), rec)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_UpdateFrequency_TargetSensor(t *testing.T) {
+	e := echo.New()
+	gen := grpcclient.NewGenerator("localhost:50051", 1*time.Second)
+	require.NoError(t, gen.AddSensor(grpcclient.SensorConfig{SensorType: "Light", ID1: "B", ID2: "2"}, nil))
+	defer gen.Stop()
+	handler := NewHandler(gen)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{name: "target sensor", query: "freq=250ms&id1=B&id2=2", expectedStatus: http.StatusOK},
+		{name: "unknown sensor", query: "freq=250ms&id1=Z&id2=9", expectedStatus: http.StatusNotFound},
+		{name: "id1 without id2", query: "freq=250ms&id1=B", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/frequency?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			require.NoError(t, handler.UpdateFrequency(e.NewContext(req, rec)))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+
+	require.Eventually(t, func() bool {
+		return gen.Sensors()[0].Frequency == "250ms"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandler_ListSensors(t *testing.T) {
+	e := echo.New()
+	gen := grpcclient.NewGenerator("localhost:50051", 1*time.Second)
+	require.NoError(t, gen.AddSensor(grpcclient.SensorConfig{SensorType: "Light", ID1: "B", ID2: "1"}, nil))
+	require.NoError(t, gen.AddSensor(grpcclient.SensorConfig{SensorType: "Light", ID1: "B", ID2: "2", Frequency: 2 * time.Second}, nil))
+	defer gen.Stop()
+	handler := NewHandler(gen)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ListSensors(e.NewContext(httptest.NewRequest(http.MethodGet, "/sensors", nil), rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var sensors []map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sensors))
+	require.Len(t, sensors, 2)
+	assert.Equal(t, "1", sensors[0]["id2"])
+	assert.Equal(t, "1s", sensors[0]["frequency"])
+	assert.Equal(t, "2s", sensors[1]["frequency"])
+}
+
+func TestHandler_UpdateValueModel(t *testing.T) {
+	e := echo.New()
+	gen := grpcclient.NewGenerator("localhost:50051", 1*time.Second)
+	require.NoError(t, gen.AddSensor(grpcclient.SensorConfig{SensorType: "Motion", ID1: "C", ID2: "3"}, nil))
+	defer gen.Stop()
+	handler := NewHandler(gen)
+
+	tests := []struct {
+		name           string
+		id1, id2       string
+		payload        string
+		expectedStatus int
+	}{
+		{name: "invalid body", id1: "C", id2: "3", payload: "{invalid json", expectedStatus: http.StatusBadRequest},
+		{name: "unknown model", id1: "C", id2: "3", payload: `{"type":"fractal"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown sensor", id1: "Z", id2: "9", payload: `{"type":"step"}`, expectedStatus: http.StatusNotFound},
+		{name: "success", id1: "C", id2: "3", payload: `{"type":"step","params":{"probability":"0.2"}}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/sensors/"+tt.id1+"/"+tt.id2+"/model", bytes.NewBufferString(tt.payload))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id1", "id2")
+			c.SetParamValues(tt.id1, tt.id2)
+
+			require.NoError(t, handler.UpdateValueModel(c))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
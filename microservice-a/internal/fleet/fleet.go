@@ -0,0 +1,144 @@
+package fleet
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"microservice-a/internal/api/grpcclient"
+	"microservice-a/internal/valuemodel"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of a fleet file. JSON is accepted as well since it is valid YAML.
+//
+//	defaults:
+//	  frequency: 1s
+//	sensors:
+//	  - sensor_type: Temperature
+//	    unit: °C            # defaults by sensor type
+//	    id1: E
+//	    id2: 1
+//	    count: 100          # expands to id2 1..100
+//	    frequency: 500ms
+//	    value_model: {type: sine, params: {mean: 22, amplitude: 4}}
+type File struct {
+	Defaults SensorSpec   `yaml:"defaults"`
+	Sensors  []SensorSpec `yaml:"sensors"`
+}
+
+// SensorSpec describes one sensor, or a range of sensors when Count > 1
+type SensorSpec struct {
+	SensorType string             `yaml:"sensor_type"`
+	Unit       string             `yaml:"unit"`
+	ID1        string             `yaml:"id1"`
+	ID2        string             `yaml:"id2"`
+	Count      int                `yaml:"count"`
+	Frequency  string             `yaml:"frequency"`
+	ValueModel *valuemodel.Config `yaml:"value_model"`
+}
+
+// Sensor is a fully resolved fleet entry
+type Sensor struct {
+	Config     grpcclient.SensorConfig
+	ValueModel valuemodel.Config
+}
+
+// Load reads and expands a fleet file
+func Load(path string) ([]Sensor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fleet file: %w", err)
+	}
+	return Parse(b)
+}
+
+// Parse expands fleet file contents into individual sensors
+func Parse(b []byte) ([]Sensor, error) {
+	var f File
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse fleet file: %w", err)
+	}
+	if len(f.Sensors) == 0 {
+		return nil, fmt.Errorf("fleet file defines no sensors")
+	}
+
+	seen := map[string]bool{}
+	var sensors []Sensor
+	for i, spec := range f.Sensors {
+		expanded, err := expand(spec, f.Defaults)
+		if err != nil {
+			return nil, fmt.Errorf("sensors[%d]: %w", i, err)
+		}
+		for _, s := range expanded {
+			key := s.Config.ID1 + "/" + s.Config.ID2
+			if seen[key] {
+				return nil, fmt.Errorf("sensors[%d]: duplicate sensor %s", i, key)
+			}
+			seen[key] = true
+			sensors = append(sensors, s)
+		}
+	}
+	return sensors, nil
+}
+
+// expand applies defaults and turns a counted spec into consecutive id2 values
+func expand(spec, defaults SensorSpec) ([]Sensor, error) {
+	if spec.SensorType == "" {
+		spec.SensorType = defaults.SensorType
+	}
+	if spec.Unit == "" {
+		spec.Unit = defaults.Unit
+	}
+	if spec.Frequency == "" {
+		spec.Frequency = defaults.Frequency
+	}
+	if spec.ValueModel == nil {
+		spec.ValueModel = defaults.ValueModel
+	}
+	if spec.SensorType == "" || spec.ID1 == "" || spec.ID2 == "" {
+		return nil, fmt.Errorf("sensor_type, id1 and id2 are required")
+	}
+
+	var freq time.Duration
+	if spec.Frequency != "" {
+		d, err := time.ParseDuration(spec.Frequency)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid frequency %q", spec.Frequency)
+		}
+		freq = d
+	}
+	model := valuemodel.Config{}
+	if spec.ValueModel != nil {
+		model = *spec.ValueModel
+	}
+
+	count := spec.Count
+	if count <= 1 {
+		return []Sensor{{
+			Config:     grpcclient.SensorConfig{SensorType: spec.SensorType, Unit: spec.Unit, ID1: spec.ID1, ID2: spec.ID2, Frequency: freq},
+			ValueModel: model,
+		}}, nil
+	}
+
+	first, err := strconv.Atoi(spec.ID2)
+	if err != nil {
+		return nil, fmt.Errorf("id2 must be an integer when count is set")
+	}
+	sensors := make([]Sensor, 0, count)
+	for n := 0; n < count; n++ {
+		m := model
+		// give every replica its own deterministic stream
+		if model.Seed != nil {
+			seed := *model.Seed + int64(n)
+			m.Seed = &seed
+		}
+		sensors = append(sensors, Sensor{
+			Config:     grpcclient.SensorConfig{SensorType: spec.SensorType, Unit: spec.Unit, ID1: spec.ID1, ID2: strconv.Itoa(first + n), Frequency: freq},
+			ValueModel: m,
+		})
+	}
+	return sensors, nil
+}
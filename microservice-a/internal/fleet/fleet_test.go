@@ -0,0 +1,83 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_YAML(t *testing.T) {
+	sensors, err := Parse([]byte(`
+defaults:
+  frequency: 2s
+  value_model: {type: gaussian, params: {mean: 50}}
+sensors:
+  - sensor_type: Temperature
+    unit: K
+    id1: E
+    id2: 5
+    frequency: 500ms
+    value_model:
+      type: sine
+      seed: 10
+      params: {mean: 22, amplitude: 4, period: 24h}
+  - sensor_type: Motion
+    id1: C
+    id2: 1
+    count: 3
+`))
+	require.NoError(t, err)
+	require.Len(t, sensors, 4)
+
+	assert.Equal(t, "Temperature", sensors[0].Config.SensorType)
+	assert.Equal(t, "5", sensors[0].Config.ID2)
+	assert.Equal(t, "K", sensors[0].Config.Unit)
+	assert.Equal(t, 500*time.Millisecond, sensors[0].Config.Frequency)
+	assert.Equal(t, "sine", sensors[0].ValueModel.Type)
+	assert.Equal(t, "22", sensors[0].ValueModel.Params["mean"])
+
+	for i, s := range sensors[1:] {
+		assert.Equal(t, "C", s.Config.ID1)
+		assert.Equal(t, []string{"1", "2", "3"}[i], s.Config.ID2)
+		assert.Equal(t, 2*time.Second, s.Config.Frequency, "defaults apply")
+		assert.Equal(t, "gaussian", s.ValueModel.Type)
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "sensors": [
+    {"sensor_type": "Light", "id1": "B", "id2": 1, "count": 2, "value_model": {"type": "uniform", "seed": 7}}
+  ]
+}`), 0o644))
+
+	sensors, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, time.Duration(0), sensors[0].Config.Frequency, "generator default applies")
+	require.NotNil(t, sensors[1].ValueModel.Seed)
+	assert.Equal(t, int64(7), *sensors[0].ValueModel.Seed)
+	assert.Equal(t, int64(8), *sensors[1].ValueModel.Seed, "replicas get distinct seeds")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no sensors":        `sensors: []`,
+		"missing id":        `sensors: [{sensor_type: Light, id1: B}]`,
+		"bad frequency":     `sensors: [{sensor_type: Light, id1: B, id2: 1, frequency: fast}]`,
+		"non numeric count": `sensors: [{sensor_type: Light, id1: B, id2: x, count: 2}]`,
+		"duplicate":         `sensors: [{sensor_type: Light, id1: B, id2: 1}, {sensor_type: Light, id1: B, id2: 1}]`,
+		"malformed":         `sensors: [`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(content))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "microservice-a/pb/shared-proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("replay already running")
+	ErrNotRunning     = errors.New("no replay running")
+	ErrNoReplayDir    = errors.New("replay is disabled: no replay directory configured")
+	ErrOutsideDir     = errors.New("recording is outside the replay directory")
+)
+
+// Options controls how a recording is replayed
+type Options struct {
+	Path              string  `json:"path"`               // relative to the replay directory, or absolute within it
+	Speed             float64 `json:"speed"`              // 1 = original spacing, 2 = twice as fast
+	RewriteTimestamps bool    `json:"rewrite_timestamps"` // stamp readings with the send time instead of the recorded one
+	Loop              bool    `json:"loop"`               // start over at EOF instead of stopping
+	ID1               string  `json:"id1,omitempty"`      // sensor whose identity fills in records without ids
+	ID2               string  `json:"id2,omitempty"`
+}
+
+// Status describes the current or last replay
+type Status struct {
+	Running bool       `json:"running"`
+	Options Options    `json:"options"`
+	Sent    int64      `json:"sent"`
+	Loops   int64      `json:"loops"`
+	Started *time.Time `json:"started,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// Record is one reading of a recording
+type Record struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Value      float64   `json:"value"`
+	ID1        string    `json:"id1"`
+	ID2        string    `json:"id2"`
+	SensorType string    `json:"sensor_type"`
+}
+
+// Reader yields the records of a recording in file order
+type Reader interface {
+	Next() (Record, error)
+	Close() error
+}
+
+// Open opens a CSV or NDJSON recording, detected by file extension
+func Open(path string) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		r, err := newCSVReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return r, nil
+	case ".ndjson", ".jsonl", ".json":
+		return &ndjsonReader{f: f, scanner: bufio.NewScanner(f)}, nil
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unsupported recording format %q (want .csv or .ndjson)", filepath.Ext(path))
+	}
+}
+
+type csvReader struct {
+	f       *os.File
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(f *os.File) (*csvReader, error) {
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "value"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing %q column", required)
+		}
+	}
+	return &csvReader{f: f, r: r, columns: columns}, nil
+}
+
+func (c *csvReader) field(row []string, name string) string {
+	if i, ok := c.columns[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func (c *csvReader) Next() (Record, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+	ts, err := parseTimestamp(c.field(row, "timestamp"))
+	if err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", line, err)
+	}
+	value, err := strconv.ParseFloat(c.field(row, "value"), 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("line %d: invalid value", line)
+	}
+	return Record{
+		Timestamp:  ts,
+		Value:      value,
+		ID1:        c.field(row, "id1"),
+		ID2:        c.field(row, "id2"),
+		SensorType: c.field(row, "sensor_type"),
+	}, nil
+}
+
+func (c *csvReader) Close() error { return c.f.Close() }
+
+type ndjsonReader struct {
+	f       *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonReader) Next() (Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+		text := strings.TrimSpace(n.scanner.Text())
+		if text == "" {
+			continue
+		}
+		var raw struct {
+			Timestamp  json.RawMessage `json:"timestamp"`
+			Value      float64         `json:"value"`
+			ID1        string          `json:"id1"`
+			ID2        json.RawMessage `json:"id2"`
+			SensorType string          `json:"sensor_type"`
+		}
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return Record{}, fmt.Errorf("line %d: invalid record", n.line)
+		}
+		ts, err := parseTimestamp(strings.Trim(string(raw.Timestamp), `"`))
+		if err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", n.line, err)
+		}
+		return Record{
+			Timestamp:  ts,
+			Value:      raw.Value,
+			ID1:        raw.ID1,
+			ID2:        strings.Trim(string(raw.ID2), `"`),
+			SensorType: raw.SensorType,
+		}, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func (n *ndjsonReader) Close() error { return n.f.Close() }
+
+// parseTimestamp accepts RFC3339 strings or unix epochs in seconds or milliseconds
+func parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// the field is not quoted, errors are reported over the REST API
+		return time.Time{}, errors.New("invalid timestamp")
+	}
+	if f > 1e12 {
+		return time.UnixMilli(int64(f)), nil
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+}
+
+// Player replays a recording through an emit callback, one replay at a time.
+// Only recordings inside its directory can be replayed.
+type Player struct {
+	dir     string
+	mu      sync.Mutex
+	status  Status
+	targets map[[2]string]bool // sensors the running replay emits for
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewPlayer returns a player of the recordings in dir; with an empty dir
+// every replay is rejected
+func NewPlayer(dir string) *Player {
+	return &Player{dir: dir}
+}
+
+// resolve returns the file path names inside the player directory, following
+// symlinks so that none leads out of it
+func (p *Player) resolve(path string) (string, error) {
+	if p.dir == "" {
+		return "", ErrNoReplayDir
+	}
+	dir, err := filepath.Abs(p.dir)
+	if err != nil {
+		return "", err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", fmt.Errorf("open replay directory: %w", err)
+	}
+	full := filepath.Clean(path)
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(dir, full)
+	}
+	if !within(dir, full) {
+		return "", ErrOutsideDir
+	}
+	if full, err = filepath.EvalSymlinks(full); err != nil {
+		return "", fmt.Errorf("open recording: %w", err)
+	}
+	if !within(dir, full) {
+		return "", ErrOutsideDir
+	}
+	return full, nil
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Start begins replaying opts.Path; defaults fills in missing id1/id2/sensor_type
+func (p *Player) Start(opts Options, defaults Record, emit func(*pb.SensorData)) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	path, err := p.resolve(opts.Path)
+	if err != nil {
+		return err
+	}
+	// fail fast on a missing or malformed file
+	r, err := Open(path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status.Running {
+		r.Close()
+		return ErrAlreadyRunning
+	}
+	now := time.Now()
+	p.status = Status{Running: true, Options: opts, Started: &now}
+	p.targets = map[[2]string]bool{{defaults.ID1, defaults.ID2}: true}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go p.run(r, path, opts, defaults, emit, p.stop, p.done)
+	return nil
+}
+
+// Stop ends the running replay and waits for it to finish
+func (p *Player) Stop() error {
+	p.mu.Lock()
+	if !p.status.Running || p.stop == nil {
+		p.mu.Unlock()
+		return ErrNotRunning
+	}
+	close(p.stop)
+	p.stop = nil
+	done := p.done
+	p.mu.Unlock()
+	<-done
+	return nil
+}
+
+// Running reports whether a replay is in progress
+func (p *Player) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status.Running
+}
+
+// Targets reports whether the running replay emits readings for sensor
+// id1/id2: the sensor filling in records without ids, or one a record named
+func (p *Player) Targets(id1, id2 string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status.Running && p.targets[[2]string{id1, id2}]
+}
+
+// Status returns a snapshot of the current or last replay
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *Player) run(r Reader, path string, opts Options, defaults Record, emit func(*pb.SensorData), stop, done chan struct{}) {
+	var runErr error
+	defer func() {
+		if r != nil {
+			r.Close()
+		}
+		p.mu.Lock()
+		p.status.Running = false
+		if runErr != nil {
+			p.status.Error = runErr.Error()
+		}
+		p.mu.Unlock()
+		close(done)
+	}()
+
+	var prev time.Time
+	var inPass int64
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			if !opts.Loop {
+				return
+			}
+			if inPass == 0 {
+				runErr = errors.New("recording is empty")
+				return
+			}
+			inPass = 0
+			r.Close()
+			next, err := Open(path)
+			if err != nil {
+				r, runErr = nil, err
+				return
+			}
+			r = next
+			prev = time.Time{}
+			p.mu.Lock()
+			p.status.Loops++
+			p.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			runErr = err
+			return
+		}
+
+		// preserve the recorded inter-arrival spacing, scaled by speed
+		if !prev.IsZero() {
+			if gap := rec.Timestamp.Sub(prev); gap > 0 {
+				if !sleepOrStop(time.Duration(float64(gap)/opts.Speed), stop) {
+					return
+				}
+			}
+		}
+		prev = rec.Timestamp
+		inPass++
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		ts := rec.Timestamp
+		if opts.RewriteTimestamps {
+			ts = time.Now()
+		}
+		id1, id2 := firstNonEmpty(rec.ID1, defaults.ID1), firstNonEmpty(rec.ID2, defaults.ID2)
+		p.mu.Lock()
+		p.targets[[2]string{id1, id2}] = true
+		p.mu.Unlock()
+		emit(&pb.SensorData{
+			Value:      rec.Value,
+			SensorType: firstNonEmpty(rec.SensorType, defaults.SensorType),
+			Id1:        id1,
+			Id2:        id2,
+			Timestamp:  timestamppb.New(ts),
+		})
+		p.mu.Lock()
+		p.status.Sent++
+		p.mu.Unlock()
+	}
+}
+
+// sleepOrStop waits for d and reports false if stopped first
+func sleepOrStop(d time.Duration, stop <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package replay
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "microservice-a/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// collector gathers emitted readings
+type collector struct {
+	mu   sync.Mutex
+	data []*pb.SensorData
+}
+
+func (c *collector) emit(d *pb.SensorData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = append(c.data, d)
+}
+
+func (c *collector) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.data)
+}
+
+func TestOpen_CSV(t *testing.T) {
+	path := writeFile(t, "rec.csv", "timestamp,value,id1,id2,sensor_type\n"+
+		"2025-09-06T10:00:00Z,21.5,A,1,Temperature\n"+
+		"1757152801,22,A,1,Temperature\n")
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	rec, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 21.5, rec.Value)
+	assert.Equal(t, "A", rec.ID1)
+	assert.Equal(t, "1", rec.ID2)
+	assert.Equal(t, "Temperature", rec.SensorType)
+	assert.True(t, rec.Timestamp.Equal(time.Date(2025, 9, 6, 10, 0, 0, 0, time.UTC)))
+
+	rec, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1757152801), rec.Timestamp.Unix())
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOpen_NDJSON(t *testing.T) {
+	path := writeFile(t, "rec.ndjson",
+		`{"timestamp":"2025-09-06T10:00:00Z","value":1.5,"id1":"B","id2":2,"sensor_type":"Light"}`+"\n\n"+
+			`{"timestamp":1757152800000,"value":2,"id1":"B","id2":"2"}`+"\n")
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	rec, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "B", rec.ID1)
+	assert.Equal(t, "2", rec.ID2)
+	assert.Equal(t, "Light", rec.SensorType)
+
+	rec, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "2", rec.ID2)
+	assert.Equal(t, int64(1757152800), rec.Timestamp.Unix())
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	_, err := Open(writeFile(t, "rec.txt", "x"))
+	assert.Error(t, err)
+
+	_, err = Open(writeFile(t, "rec.csv", "ts,reading\n"))
+	assert.Error(t, err)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
+
+func TestPlayer_PreservesSpacingScaledBySpeed(t *testing.T) {
+	path := writeFile(t, "rec.csv", "timestamp,value\n"+
+		"2025-09-06T10:00:00Z,1\n"+
+		"2025-09-06T10:00:01Z,2\n"+
+		"2025-09-06T10:00:02Z,3\n")
+
+	c := &collector{}
+	p := NewPlayer(filepath.Dir(path))
+	start := time.Now()
+	require.NoError(t, p.Start(Options{Path: path, Speed: 10}, Record{ID1: "A", ID2: "1", SensorType: "Temperature"}, c.emit))
+	require.Eventually(t, func() bool { return !p.Running() }, 2*time.Second, 5*time.Millisecond)
+	elapsed := time.Since(start)
+
+	// two gaps of 1s replayed at 10x speed
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 1*time.Second)
+
+	require.Equal(t, 3, c.len())
+	assert.Equal(t, "A", c.data[0].Id1)
+	assert.Equal(t, "Temperature", c.data[0].SensorType)
+	assert.Equal(t, int64(1757152800), c.data[0].Timestamp.AsTime().Unix(), "recorded timestamps are kept by default")
+	assert.Equal(t, int64(3), p.Status().Sent)
+}
+
+func TestPlayer_RewriteTimestampsAndLoop(t *testing.T) {
+	path := writeFile(t, "rec.csv", "timestamp,value\n2025-09-06T10:00:00Z,1\n2025-09-06T10:00:00.01Z,2\n")
+
+	c := &collector{}
+	p := NewPlayer(filepath.Dir(path))
+	require.NoError(t, p.Start(Options{Path: path, Loop: true, RewriteTimestamps: true}, Record{}, c.emit))
+	require.Eventually(t, func() bool { return c.len() >= 6 }, 2*time.Second, 5*time.Millisecond)
+
+	assert.ErrorIs(t, p.Start(Options{Path: path}, Record{}, c.emit), ErrAlreadyRunning)
+	require.NoError(t, p.Stop())
+	assert.False(t, p.Running())
+	assert.GreaterOrEqual(t, p.Status().Loops, int64(2))
+	assert.WithinDuration(t, time.Now(), c.data[0].Timestamp.AsTime(), 5*time.Second)
+
+	assert.ErrorIs(t, p.Stop(), ErrNotRunning)
+}
+
+func TestPlayer_ReportsParseErrors(t *testing.T) {
+	path := writeFile(t, "rec.csv", "timestamp,value\nnot-a-time,1\n")
+
+	p := NewPlayer(filepath.Dir(path))
+	require.NoError(t, p.Start(Options{Path: "rec.csv"}, Record{}, func(*pb.SensorData) {}))
+	require.Eventually(t, func() bool { return !p.Running() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "line 2: invalid timestamp", p.Status().Error, "file contents are not echoed")
+}
+
+func TestPlayer_RejectsPathsOutsideDir(t *testing.T) {
+	outside := writeFile(t, "secret.csv", "timestamp,value\n2025-09-06T10:00:00Z,1\n")
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.csv")))
+	p := NewPlayer(dir)
+
+	for _, path := range []string{outside, "../secret.csv", filepath.Join(dir, "..", "secret.csv"), "link.csv"} {
+		assert.ErrorIs(t, p.Start(Options{Path: path}, Record{}, func(*pb.SensorData) {}), ErrOutsideDir, path)
+	}
+	assert.ErrorIs(t, NewPlayer("").Start(Options{Path: outside}, Record{}, func(*pb.SensorData) {}), ErrNoReplayDir)
+}
@@ -0,0 +1,545 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "microservice-a/pb/shared-proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+	headerSize = 8 // 4 bytes length + 4 bytes crc32
+)
+
+var ErrCorruptRecord = errors.New("spool: corrupt record")
+
+// Options configures the on-disk spool
+type Options struct {
+	SegmentBytes int64         // roll over to a new segment after this many bytes
+	MaxBytes     int64         // drop oldest segments once the spool grows past this size (0 = unlimited)
+	MaxAge       time.Duration // drop closed segments older than this (0 = unlimited)
+	SyncInterval time.Duration // longest time appended records stay unsynced (0 = sync every append)
+}
+
+// DefaultOptions returns sane defaults for a single sensor generator
+func DefaultOptions() Options {
+	return Options{
+		SegmentBytes: 4 << 20,
+		MaxBytes:     256 << 20,
+		MaxAge:       7 * 24 * time.Hour,
+		SyncInterval: time.Second,
+	}
+}
+
+// Position points at a record inside the spool
+type Position struct {
+	Segment uint64 // base name of the segment file
+	Offset  int64  // byte offset inside the segment
+}
+
+// Record is a single spooled reading together with the position right after it
+type Record struct {
+	Data *pb.SensorData
+	Next Position
+}
+
+type segment struct {
+	base    uint64
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Spool is a segment based write-ahead log of sensor readings.
+// Readings are appended by the generator and drained in order by the sender;
+// segments are only removed once everything in them has been committed.
+// Appends reach the disk within SyncInterval, so a crash of the host loses at
+// most the readings of the last SyncInterval; a crash of the process loses none.
+type Spool struct {
+	mu       sync.Mutex
+	dir      string
+	opts     Options
+	segments []*segment
+	active   *os.File
+	cursor   Position // first uncommitted record
+	ready    chan struct{}
+	nextBase uint64
+	dirty    bool          // the active segment has unsynced appends
+	stop     chan struct{} // stops the sync loop
+}
+
+// Open opens (or creates) the spool stored in dir and recovers its state
+func Open(dir string, opts Options) (*Spool, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = DefaultOptions().SegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+
+	s := &Spool{
+		dir:   dir,
+		opts:  opts,
+		ready: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.openActive(); err != nil {
+		return nil, err
+	}
+	if s.pendingLocked() {
+		s.notify()
+	}
+	if opts.SyncInterval > 0 {
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+// syncLoop syncs the appends of the active segment every SyncInterval
+func (s *Spool) syncLoop() {
+	ticker := time.NewTicker(s.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		if s.active != nil && s.dirty {
+			if err := s.active.Sync(); err != nil {
+				log.Printf("spool: failed to sync segment: %v", err)
+			} else {
+				s.dirty = false
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// load scans existing segments and restores the committed cursor
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read spool dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{base: base, path: filepath.Join(s.dir, e.Name())}
+		if err := seg.recover(); err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].base < s.segments[j].base })
+	if n := len(s.segments); n > 0 {
+		s.nextBase = s.segments[n-1].base + 1
+	}
+
+	s.cursor = s.readCursor()
+	// the committed segment may have been removed already
+	if len(s.segments) > 0 && s.cursor.Segment < s.segments[0].base {
+		s.cursor = Position{Segment: s.segments[0].base}
+	}
+	return nil
+}
+
+// recover truncates a torn record left at the end of a segment by a crash
+func (seg *segment) recover() error {
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	var valid int64
+	for {
+		n, _, err := readRecord(r, info.Size()-valid)
+		if err != nil {
+			break
+		}
+		valid += n
+	}
+	if info.Size() != valid {
+		log.Printf("spool: truncating torn segment %s from %d to %d bytes", seg.path, info.Size(), valid)
+		if err := f.Truncate(valid); err != nil {
+			return fmt.Errorf("truncate segment: %w", err)
+		}
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("sync segment: %w", err)
+		}
+	}
+	seg.size = valid
+	seg.modTime = info.ModTime()
+	return nil
+}
+
+func (s *Spool) readCursor() Position {
+	b, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if err != nil || len(b) != 16 {
+		return Position{}
+	}
+	return Position{
+		Segment: binary.BigEndian.Uint64(b[:8]),
+		Offset:  int64(binary.BigEndian.Uint64(b[8:])),
+	}
+}
+
+func (s *Spool) writeCursor() error {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], s.cursor.Segment)
+	binary.BigEndian.PutUint64(b[8:], uint64(s.cursor.Offset))
+	tmp := filepath.Join(s.dir, cursorFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	// the cursor must be on disk before it replaces the old one, or a crash
+	// could leave an empty cursor and redeliver the whole spool
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, cursorFile)); err != nil {
+		return err
+	}
+	return s.syncDir()
+}
+
+// syncDir makes created, renamed and removed files in the spool dir durable
+func (s *Spool) syncDir() error {
+	d, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// openActive opens the last segment for appending, creating one if needed
+func (s *Spool) openActive() error {
+	if len(s.segments) == 0 {
+		return s.roll()
+	}
+	last := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open active segment: %w", err)
+	}
+	s.active = f
+	return nil
+}
+
+// roll syncs and closes the active segment and starts a new one
+func (s *Spool) roll() error {
+	if s.active != nil {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("sync segment: %w", err)
+		}
+		if err := s.active.Close(); err != nil {
+			return err
+		}
+		s.dirty = false
+	}
+	seg := &segment{
+		base:    s.nextBase,
+		path:    filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.nextBase, segmentExt)),
+		modTime: time.Now(),
+	}
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+	if err := s.syncDir(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync spool dir: %w", err)
+	}
+	if len(s.segments) == 0 {
+		s.cursor = Position{Segment: seg.base}
+	}
+	s.active = f
+	s.segments = append(s.segments, seg)
+	s.nextBase++
+	return nil
+}
+
+// Append writes a reading to the end of the spool
+func (s *Spool) Append(data *pb.SensorData) error {
+	payload, err := proto.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal reading: %w", err)
+	}
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return errors.New("spool: closed")
+	}
+	active := s.segments[len(s.segments)-1]
+	if active.size > 0 && active.size+int64(len(buf)) > s.opts.SegmentBytes {
+		if err := s.roll(); err != nil {
+			return err
+		}
+		active = s.segments[len(s.segments)-1]
+	}
+	if _, err := s.active.Write(buf); err != nil {
+		return fmt.Errorf("append record: %w", err)
+	}
+	active.size += int64(len(buf))
+	active.modTime = time.Now()
+	s.dirty = true
+	if s.opts.SyncInterval <= 0 {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("sync record: %w", err)
+		}
+		s.dirty = false
+	}
+
+	s.enforceLimitsLocked()
+	s.notify()
+	return nil
+}
+
+// enforceLimitsLocked drops the oldest closed segments that exceed MaxBytes or MaxAge
+func (s *Spool) enforceLimitsLocked() {
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		overSize := s.opts.MaxBytes > 0 && s.sizeLocked() > s.opts.MaxBytes
+		overAge := s.opts.MaxAge > 0 && time.Since(oldest.modTime) > s.opts.MaxAge
+		if !overSize && !overAge {
+			return
+		}
+		log.Printf("spool: dropping segment %s (%d bytes) to honour limits", oldest.path, oldest.size)
+		s.removeOldestLocked()
+	}
+}
+
+func (s *Spool) removeOldestLocked() {
+	oldest := s.segments[0]
+	if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("spool: failed to remove segment %s: %v", oldest.path, err)
+	}
+	s.segments = s.segments[1:]
+	if s.cursor.Segment <= oldest.base {
+		s.cursor = Position{Segment: s.segments[0].base}
+		if err := s.writeCursor(); err != nil {
+			log.Printf("spool: failed to persist cursor: %v", err)
+		}
+	}
+}
+
+func (s *Spool) sizeLocked() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total
+}
+
+// Size returns the number of bytes currently held on disk
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sizeLocked()
+}
+
+// Peek returns up to max uncommitted records in order without removing them
+func (s *Spool) Peek(max int) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	pos := s.cursor
+	for _, seg := range s.segments {
+		if len(records) >= max {
+			break
+		}
+		if seg.base < pos.Segment {
+			continue
+		}
+		offset := int64(0)
+		if seg.base == pos.Segment {
+			offset = pos.Offset
+		}
+		if offset >= seg.size {
+			continue
+		}
+		recs, err := readSegment(seg, offset, max-len(records))
+		if err != nil {
+			return records, err
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+func readSegment(seg *segment, offset int64, max int) ([]Record, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	r := bufio.NewReader(io.LimitReader(f, seg.size-offset))
+	for len(records) < max {
+		n, payload, err := readRecord(r, seg.size-offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return records, err
+		}
+		offset += n
+		data := &pb.SensorData{}
+		if err := proto.Unmarshal(payload, data); err != nil {
+			return records, fmt.Errorf("%w: %v", ErrCorruptRecord, err)
+		}
+		records = append(records, Record{Data: data, Next: Position{Segment: seg.base, Offset: offset}})
+	}
+	return records, nil
+}
+
+// readRecord reads one framed record of at most max bytes on disk and returns
+// its size on disk and payload. A length running past max is corrupt, so a
+// torn header is never trusted for an allocation.
+func readRecord(r io.Reader, max int64) (int64, []byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, ErrCorruptRecord
+		}
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if int64(headerSize)+int64(length) > max {
+		return 0, nil, ErrCorruptRecord
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, ErrCorruptRecord
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, nil, ErrCorruptRecord
+	}
+	return int64(headerSize) + int64(length), payload, nil
+}
+
+// Commit marks every record up to pos as delivered and removes fully delivered segments
+func (s *Spool) Commit(pos Position) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pos.Segment < s.cursor.Segment || (pos.Segment == s.cursor.Segment && pos.Offset <= s.cursor.Offset) {
+		return nil
+	}
+	s.cursor = pos
+
+	// remove closed segments that are fully delivered
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		if oldest.base > s.cursor.Segment || (oldest.base == s.cursor.Segment && s.cursor.Offset < oldest.size) {
+			break
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+		s.segments = s.segments[1:]
+		if s.cursor.Segment <= oldest.base {
+			s.cursor = Position{Segment: s.segments[0].base}
+		}
+	}
+	return s.writeCursor()
+}
+
+// Pending reports whether there are uncommitted records
+func (s *Spool) Pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pendingLocked()
+}
+
+func (s *Spool) pendingLocked() bool {
+	for _, seg := range s.segments {
+		if seg.base > s.cursor.Segment && seg.size > 0 {
+			return true
+		}
+		if seg.base == s.cursor.Segment && s.cursor.Offset < seg.size {
+			return true
+		}
+	}
+	return false
+}
+
+// Ready is signalled whenever new records are appended
+func (s *Spool) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func (s *Spool) notify() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Close syncs and closes the active segment
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	close(s.stop)
+	err := s.active.Sync()
+	if cerr := s.active.Close(); err == nil {
+		err = cerr
+	}
+	s.active = nil
+	return err
+}
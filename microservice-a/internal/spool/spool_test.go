@@ -0,0 +1,227 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "microservice-a/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func reading(v float64) *pb.SensorData {
+	return &pb.SensorData{
+		Value:      v,
+		SensorType: "Temperature",
+		Id1:        "A",
+		Id2:        "1",
+		Timestamp:  timestamppb.Now(),
+	}
+}
+
+func TestSpool_AppendPeekCommit(t *testing.T) {
+	s, err := Open(t.TempDir(), DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Append(reading(float64(i))))
+	}
+
+	records, err := s.Peek(3)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, 0.0, records[0].Data.Value)
+	assert.Equal(t, 2.0, records[2].Data.Value)
+
+	// peeking again without commit replays the same records
+	again, err := s.Peek(3)
+	require.NoError(t, err)
+	assert.Equal(t, records[0].Data.Value, again[0].Data.Value)
+
+	require.NoError(t, s.Commit(records[2].Next))
+	records, err = s.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 3.0, records[0].Data.Value)
+
+	require.NoError(t, s.Commit(records[1].Next))
+	assert.False(t, s.Pending())
+}
+
+func TestSpool_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{SegmentBytes: 64})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Append(reading(float64(i))))
+	}
+	records, err := s.Peek(4)
+	require.NoError(t, err)
+	require.NoError(t, s.Commit(records[3].Next))
+	require.NoError(t, s.Close())
+
+	s, err = Open(dir, Options{SegmentBytes: 64})
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.True(t, s.Pending())
+	records, err = s.Peek(100)
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	for i, r := range records {
+		assert.Equal(t, float64(i+4), r.Data.Value)
+	}
+}
+
+func TestSpool_CommitRemovesDeliveredSegments(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{SegmentBytes: 64})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Append(reading(float64(i))))
+	}
+	before := countSegments(t, dir)
+	require.Greater(t, before, 1)
+
+	records, err := s.Peek(100)
+	require.NoError(t, err)
+	require.NoError(t, s.Commit(records[len(records)-1].Next))
+
+	assert.Equal(t, 1, countSegments(t, dir))
+	assert.False(t, s.Pending())
+}
+
+func TestSpool_TruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, s.Append(reading(1)))
+	require.NoError(t, s.Append(reading(2)))
+	require.NoError(t, s.Close())
+
+	// simulate a crash in the middle of a write
+	matches, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.Len(t, matches, 1)
+	f, err := os.OpenFile(matches[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	records, err := s.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.NoError(t, s.Append(reading(3)))
+	records, err = s.Peek(10)
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestSpool_TruncatesCorruptLength(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, s.Append(reading(1)))
+	require.NoError(t, s.Close())
+
+	// a garbage header claiming a 4 GiB record must not be allocated
+	matches, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.Len(t, matches, 1)
+	f, err := os.OpenFile(matches[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 0, 0, 0, 0, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open(dir, DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	records, err := s.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1.0, records[0].Data.Value)
+}
+
+func TestSpool_SyncsWithinInterval(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{SyncInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Append(reading(1)))
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return !s.dirty
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSpool_MaxBytesDropsOldest(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{SegmentBytes: 64, MaxBytes: 200})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, s.Append(reading(float64(i))))
+	}
+	assert.LessOrEqual(t, s.Size(), int64(200+64))
+
+	records, err := s.Peek(100)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Greater(t, records[0].Data.Value, 0.0, "oldest readings should have been dropped")
+	assert.Equal(t, 49.0, records[len(records)-1].Data.Value)
+}
+
+func TestSpool_MaxAgeDropsExpiredSegments(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{SegmentBytes: 64, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, s.Append(reading(float64(i))))
+	}
+	// age every closed segment
+	s.mu.Lock()
+	for _, seg := range s.segments {
+		seg.modTime = time.Now().Add(-2 * time.Hour)
+	}
+	s.mu.Unlock()
+
+	require.NoError(t, s.Append(reading(99)))
+	records, err := s.Peek(100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 99.0, records[0].Data.Value)
+}
+
+func TestSpool_ReadySignalledOnAppend(t *testing.T) {
+	s, err := Open(t.TempDir(), DefaultOptions())
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Append(reading(1)))
+	select {
+	case <-s.Ready():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("expected ready signal after append")
+	}
+}
+
+func countSegments(t *testing.T, dir string) int {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	return len(matches)
+}
@@ -0,0 +1,264 @@
+package valuemodel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueModel produces the next simulated reading for a sensor
+type ValueModel interface {
+	Next(t time.Time) float64
+}
+
+// Model type names accepted in configuration
+const (
+	TypeConstant   = "constant"
+	TypeUniform    = "uniform"
+	TypeGaussian   = "gaussian"
+	TypeRandomWalk = "random_walk"
+	TypeSine       = "sine"
+	TypeStep       = "step"
+	TypePoisson    = "poisson"
+)
+
+// Config selects and parameterizes a value model
+type Config struct {
+	Type   string            `json:"type" yaml:"type"`
+	Seed   *int64            `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Constant always returns the same value
+type Constant struct {
+	Value float64
+}
+
+func (m *Constant) Next(time.Time) float64 { return m.Value }
+
+// Uniform returns values uniformly distributed in [Min, Max)
+type Uniform struct {
+	Min, Max float64
+	rng      *rand.Rand
+}
+
+func NewUniform(min, max float64, rng *rand.Rand) *Uniform {
+	return &Uniform{Min: min, Max: max, rng: rng}
+}
+
+func (m *Uniform) Next(time.Time) float64 {
+	return m.Min + m.rng.Float64()*(m.Max-m.Min)
+}
+
+// Gaussian returns Mean plus normally distributed noise
+type Gaussian struct {
+	Mean, StdDev float64
+	rng          *rand.Rand
+}
+
+func (m *Gaussian) Next(time.Time) float64 {
+	return m.Mean + m.rng.NormFloat64()*m.StdDev
+}
+
+// RandomWalk moves by a normally distributed step each reading and stays within [Min, Max]
+type RandomWalk struct {
+	Step, Min, Max float64
+	current        float64
+	rng            *rand.Rand
+}
+
+func (m *RandomWalk) Next(time.Time) float64 {
+	m.current += m.rng.NormFloat64() * m.Step
+	// reflect at the bounds so the walk does not stick to them
+	if m.current > m.Max {
+		m.current = 2*m.Max - m.current
+	}
+	if m.current < m.Min {
+		m.current = 2*m.Min - m.current
+	}
+	m.current = math.Max(m.Min, math.Min(m.Max, m.current))
+	return m.current
+}
+
+// Sine follows a periodic cycle (e.g. a diurnal temperature curve) with optional noise
+type Sine struct {
+	Mean, Amplitude, Noise float64
+	Period, Phase          time.Duration
+	rng                    *rand.Rand
+}
+
+func (m *Sine) Next(t time.Time) float64 {
+	elapsed := t.Add(-m.Phase).UnixNano() % int64(m.Period)
+	angle := 2 * math.Pi * float64(elapsed) / float64(m.Period)
+	v := m.Mean + m.Amplitude*math.Sin(angle)
+	if m.Noise > 0 {
+		v += m.rng.NormFloat64() * m.Noise
+	}
+	return v
+}
+
+// Step flips between Low and High with the given probability per reading,
+// which makes it suitable for boolean sensors like Motion
+type Step struct {
+	Low, High   float64
+	Probability float64
+	high        bool
+	rng         *rand.Rand
+}
+
+func (m *Step) Next(time.Time) float64 {
+	if m.rng.Float64() < m.Probability {
+		m.high = !m.high
+	}
+	if m.high {
+		return m.High
+	}
+	return m.Low
+}
+
+// Poisson returns a noisy baseline with spikes arriving as a Poisson process
+type Poisson struct {
+	Base, Noise, Spike float64
+	Rate               float64 // expected spikes per second
+	last               time.Time
+	rng                *rand.Rand
+}
+
+func (m *Poisson) Next(t time.Time) float64 {
+	v := m.Base
+	if m.Noise > 0 {
+		v += m.rng.NormFloat64() * m.Noise
+	}
+	if !m.last.IsZero() {
+		dt := t.Sub(m.last).Seconds()
+		if dt > 0 && m.rng.Float64() < 1-math.Exp(-m.Rate*dt) {
+			v += m.Spike
+		}
+	}
+	m.last = t
+	return v
+}
+
+// New builds a value model from its configuration
+func New(cfg Config) (ValueModel, error) {
+	seed := time.Now().UnixNano()
+	if cfg.Seed != nil {
+		seed = *cfg.Seed
+	}
+	rng := rand.New(rand.NewSource(seed))
+	p := params(cfg.Params)
+
+	var m ValueModel
+	var err error
+	switch strings.ToLower(cfg.Type) {
+	case TypeConstant:
+		m = &Constant{Value: p.float("value", 0)}
+	case "", TypeUniform:
+		u := NewUniform(p.float("min", 0), p.float("max", 100), rng)
+		if u.Max < u.Min {
+			err = fmt.Errorf("uniform: max must be >= min")
+		}
+		m = u
+	case TypeGaussian:
+		m = &Gaussian{Mean: p.float("mean", 50), StdDev: p.float("stddev", 1), rng: rng}
+	case TypeRandomWalk:
+		w := &RandomWalk{Step: p.float("step", 1), Min: p.float("min", 0), Max: p.float("max", 100), rng: rng}
+		w.current = p.float("start", (w.Min+w.Max)/2)
+		if w.Max < w.Min {
+			err = fmt.Errorf("random_walk: max must be >= min")
+		}
+		m = w
+	case TypeSine:
+		sn := &Sine{
+			Mean:      p.float("mean", 50),
+			Amplitude: p.float("amplitude", 10),
+			Noise:     p.float("noise", 0),
+			Period:    p.duration("period", 24*time.Hour),
+			Phase:     p.duration("phase", 0),
+			rng:       rng,
+		}
+		if sn.Period <= 0 {
+			err = fmt.Errorf("sine: period must be positive")
+		}
+		m = sn
+	case TypeStep:
+		st := &Step{Low: p.float("low", 0), High: p.float("high", 1), Probability: p.float("probability", 0.1), rng: rng}
+		if st.Probability < 0 || st.Probability > 1 {
+			err = fmt.Errorf("step: probability must be between 0 and 1")
+		}
+		m = st
+	case TypePoisson:
+		ps := &Poisson{Base: p.float("base", 0), Noise: p.float("noise", 0), Spike: p.float("spike", 100), Rate: p.float("rate", 0.01), rng: rng}
+		if ps.Rate < 0 {
+			err = fmt.Errorf("poisson: rate must not be negative")
+		}
+		m = ps
+	default:
+		return nil, fmt.Errorf("unknown value model %q", cfg.Type)
+	}
+
+	// parse errors take precedence over range checks on defaulted values
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FromEnv reads the VALUE_MODEL, VALUE_SEED and VALUE_<PARAM> variables,
+// e.g. VALUE_MODEL=sine VALUE_MEAN=21 VALUE_AMPLITUDE=4 VALUE_PERIOD=24h
+func FromEnv(getenv func(string) string) (Config, error) {
+	cfg := Config{Type: getenv("VALUE_MODEL"), Params: map[string]string{}}
+	if s := getenv("VALUE_SEED"); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid VALUE_SEED: %w", err)
+		}
+		cfg.Seed = &seed
+	}
+	for _, key := range []string{"value", "min", "max", "mean", "stddev", "step", "start", "amplitude", "noise", "period", "phase", "low", "high", "probability", "base", "spike", "rate"} {
+		if v := getenv("VALUE_" + strings.ToUpper(key)); v != "" {
+			cfg.Params[key] = v
+		}
+	}
+	return cfg, nil
+}
+
+// paramParser parses model parameters and remembers the first error
+type paramParser struct {
+	values map[string]string
+	err    error
+}
+
+func params(values map[string]string) *paramParser {
+	return &paramParser{values: values}
+}
+
+func (p *paramParser) float(key string, def float64) float64 {
+	s, ok := p.values[key]
+	if !ok || s == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f
+}
+
+func (p *paramParser) duration(key string, def time.Duration) time.Duration {
+	s, ok := p.values[key]
+	if !ok || s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d
+}
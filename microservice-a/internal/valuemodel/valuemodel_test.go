@@ -0,0 +1,130 @@
+package valuemodel
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seeded(typ string, params map[string]string) Config {
+	seed := int64(42)
+	return Config{Type: typ, Seed: &seed, Params: params}
+}
+
+func TestNew_DefaultsToUniform(t *testing.T) {
+	m, err := New(Config{})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		v := m.Next(time.Now())
+		assert.GreaterOrEqual(t, v, 0.0)
+		assert.Less(t, v, 100.0)
+	}
+}
+
+func TestNew_SeedIsDeterministic(t *testing.T) {
+	cfg := seeded(TypeGaussian, map[string]string{"mean": "20", "stddev": "2"})
+	a, err := New(cfg)
+	require.NoError(t, err)
+	b, err := New(cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, a.Next(now), b.Next(now))
+	}
+}
+
+func TestConstant(t *testing.T) {
+	m, err := New(seeded(TypeConstant, map[string]string{"value": "7.5"}))
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, m.Next(time.Now()))
+}
+
+func TestRandomWalk_StaysWithinBounds(t *testing.T) {
+	m, err := New(seeded(TypeRandomWalk, map[string]string{"min": "10", "max": "20", "step": "5"}))
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		v := m.Next(time.Now())
+		assert.GreaterOrEqual(t, v, 10.0)
+		assert.LessOrEqual(t, v, 20.0)
+	}
+}
+
+func TestSine_FollowsPeriod(t *testing.T) {
+	m, err := New(seeded(TypeSine, map[string]string{"mean": "20", "amplitude": "5", "period": "24h"}))
+	require.NoError(t, err)
+
+	base := time.Unix(0, 0)
+	assert.InDelta(t, 20.0, m.Next(base), 1e-9)
+	assert.InDelta(t, 25.0, m.Next(base.Add(6*time.Hour)), 1e-9)
+	assert.InDelta(t, 15.0, m.Next(base.Add(18*time.Hour)), 1e-9)
+}
+
+func TestStep_ProducesOnlyLowOrHigh(t *testing.T) {
+	m, err := New(seeded(TypeStep, map[string]string{"probability": "0.5"}))
+	require.NoError(t, err)
+	seen := map[float64]bool{}
+	for i := 0; i < 100; i++ {
+		v := m.Next(time.Now())
+		assert.True(t, v == 0 || v == 1)
+		seen[v] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestPoisson_EmitsSpikes(t *testing.T) {
+	m, err := New(seeded(TypePoisson, map[string]string{"base": "10", "spike": "100", "rate": "0.5"}))
+	require.NoError(t, err)
+
+	ts := time.Unix(0, 0)
+	spikes := 0
+	for i := 0; i < 1000; i++ {
+		ts = ts.Add(time.Second)
+		if v := m.Next(ts); math.Abs(v-110) < 1e-9 {
+			spikes++
+		}
+	}
+	// expect roughly 1000 * (1 - e^-0.5) ≈ 393 spikes
+	assert.InDelta(t, 393, spikes, 80)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []Config{
+		{Type: "fractal"},
+		{Type: TypeUniform, Params: map[string]string{"min": "abc"}},
+		{Type: TypeUniform, Params: map[string]string{"min": "10", "max": "1"}},
+		{Type: TypeSine, Params: map[string]string{"period": "0s"}},
+		{Type: TypeStep, Params: map[string]string{"probability": "2"}},
+	}
+	for _, cfg := range tests {
+		_, err := New(cfg)
+		assert.Error(t, err, "config %+v", cfg)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{
+		"VALUE_MODEL":     "sine",
+		"VALUE_SEED":      "7",
+		"VALUE_MEAN":      "21",
+		"VALUE_AMPLITUDE": "4",
+		"VALUE_PERIOD":    "24h",
+	}
+	cfg, err := FromEnv(func(k string) string { return env[k] })
+	require.NoError(t, err)
+	assert.Equal(t, "sine", cfg.Type)
+	require.NotNil(t, cfg.Seed)
+	assert.Equal(t, int64(7), *cfg.Seed)
+	assert.Equal(t, map[string]string{"mean": "21", "amplitude": "4", "period": "24h"}, cfg.Params)
+
+	_, err = FromEnv(func(k string) string {
+		if k == "VALUE_SEED" {
+			return "seven"
+		}
+		return ""
+	})
+	assert.Error(t, err)
+}
@@ -22,6 +22,110 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type CommandType int32
+
+const (
+	CommandType_COMMAND_UNSPECIFIED CommandType = 0
+	CommandType_SET_FREQUENCY       CommandType = 1
+	CommandType_PAUSE               CommandType = 2
+	CommandType_RESUME              CommandType = 3
+	CommandType_SET_VALUE_MODEL     CommandType = 4
+	CommandType_FLUSH_SPOOL         CommandType = 5
+)
+
+// Enum value maps for CommandType.
+var (
+	CommandType_name = map[int32]string{
+		0: "COMMAND_UNSPECIFIED",
+		1: "SET_FREQUENCY",
+		2: "PAUSE",
+		3: "RESUME",
+		4: "SET_VALUE_MODEL",
+		5: "FLUSH_SPOOL",
+	}
+	CommandType_value = map[string]int32{
+		"COMMAND_UNSPECIFIED": 0,
+		"SET_FREQUENCY":       1,
+		"PAUSE":               2,
+		"RESUME":              3,
+		"SET_VALUE_MODEL":     4,
+		"FLUSH_SPOOL":         5,
+	}
+)
+
+func (x CommandType) Enum() *CommandType {
+	p := new(CommandType)
+	*p = x
+	return p
+}
+
+func (x CommandType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CommandType) Descriptor() protoreflect.EnumDescriptor {
+	return file_sensor_proto_enumTypes[0].Descriptor()
+}
+
+func (CommandType) Type() protoreflect.EnumType {
+	return &file_sensor_proto_enumTypes[0]
+}
+
+func (x CommandType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CommandType.Descriptor instead.
+func (CommandType) EnumDescriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{0}
+}
+
+type SlowConsumerPolicy int32
+
+const (
+	SlowConsumerPolicy_DROP       SlowConsumerPolicy = 0 // readings that do not fit into the buffer are dropped and counted
+	SlowConsumerPolicy_DISCONNECT SlowConsumerPolicy = 1 // the stream ends with RESOURCE_EXHAUSTED
+)
+
+// Enum value maps for SlowConsumerPolicy.
+var (
+	SlowConsumerPolicy_name = map[int32]string{
+		0: "DROP",
+		1: "DISCONNECT",
+	}
+	SlowConsumerPolicy_value = map[string]int32{
+		"DROP":       0,
+		"DISCONNECT": 1,
+	}
+)
+
+func (x SlowConsumerPolicy) Enum() *SlowConsumerPolicy {
+	p := new(SlowConsumerPolicy)
+	*p = x
+	return p
+}
+
+func (x SlowConsumerPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SlowConsumerPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_sensor_proto_enumTypes[1].Descriptor()
+}
+
+func (SlowConsumerPolicy) Type() protoreflect.EnumType {
+	return &file_sensor_proto_enumTypes[1]
+}
+
+func (x SlowConsumerPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SlowConsumerPolicy.Descriptor instead.
+func (SlowConsumerPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{1}
+}
+
 type SensorData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -29,6 +133,8 @@ type SensorData struct {
 	Id1           string                 `protobuf:"bytes,3,opt,name=id1,proto3" json:"id1,omitempty"` // e.g., "A", "SENSORX"
 	Id2           string                 `protobuf:"bytes,4,opt,name=id2,proto3" json:"id2,omitempty"`
 	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Seq           uint64                 `protobuf:"varint,6,opt,name=seq,proto3" json:"seq,omitempty"`                                // per-sensor sequence number, starts at 1; 0 means unsequenced
+	ProducerId    string                 `protobuf:"bytes,7,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"` // generator session the sequence belongs to
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -98,17 +204,227 @@ func (x *SensorData) GetTimestamp() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *SensorData) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *SensorData) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+// SensorBatch carries several readings in one stream message
+type SensorBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Readings      []*SensorData          `protobuf:"bytes,1,rep,name=readings,proto3" json:"readings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SensorBatch) Reset() {
+	*x = SensorBatch{}
+	mi := &file_sensor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SensorBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SensorBatch) ProtoMessage() {}
+
+func (x *SensorBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SensorBatch.ProtoReflect.Descriptor instead.
+func (*SensorBatch) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SensorBatch) GetReadings() []*SensorData {
+	if x != nil {
+		return x.Readings
+	}
+	return nil
+}
+
+type HighWaterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProducerId    string                 `protobuf:"bytes,1,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HighWaterRequest) Reset() {
+	*x = HighWaterRequest{}
+	mi := &file_sensor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HighWaterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HighWaterRequest) ProtoMessage() {}
+
+func (x *HighWaterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HighWaterRequest.ProtoReflect.Descriptor instead.
+func (*HighWaterRequest) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HighWaterRequest) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+type HighWaterMark struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id1           string                 `protobuf:"bytes,1,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,2,opt,name=id2,proto3" json:"id2,omitempty"`
+	Seq           uint64                 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HighWaterMark) Reset() {
+	*x = HighWaterMark{}
+	mi := &file_sensor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HighWaterMark) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HighWaterMark) ProtoMessage() {}
+
+func (x *HighWaterMark) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HighWaterMark.ProtoReflect.Descriptor instead.
+func (*HighWaterMark) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HighWaterMark) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *HighWaterMark) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *HighWaterMark) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type HighWaterReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Marks         []*HighWaterMark       `protobuf:"bytes,1,rep,name=marks,proto3" json:"marks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HighWaterReply) Reset() {
+	*x = HighWaterReply{}
+	mi := &file_sensor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HighWaterReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HighWaterReply) ProtoMessage() {}
+
+func (x *HighWaterReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HighWaterReply.ProtoReflect.Descriptor instead.
+func (*HighWaterReply) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HighWaterReply) GetMarks() []*HighWaterMark {
+	if x != nil {
+		return x.Marks
+	}
+	return nil
+}
+
 type Ack struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Received      int64                  `protobuf:"varint,3,opt,name=received,proto3" json:"received,omitempty"`     // number of readings persisted on this stream
+	Duplicates    int64                  `protobuf:"varint,4,opt,name=duplicates,proto3" json:"duplicates,omitempty"` // readings dropped because their sequence was already stored
+	Gaps          int64                  `protobuf:"varint,5,opt,name=gaps,proto3" json:"gaps,omitempty"`             // readings missing between accepted sequences
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Ack) Reset() {
 	*x = Ack{}
-	mi := &file_sensor_proto_msgTypes[1]
+	mi := &file_sensor_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -120,7 +436,7 @@ func (x *Ack) String() string {
 func (*Ack) ProtoMessage() {}
 
 func (x *Ack) ProtoReflect() protoreflect.Message {
-	mi := &file_sensor_proto_msgTypes[1]
+	mi := &file_sensor_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -133,7 +449,7 @@ func (x *Ack) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Ack.ProtoReflect.Descriptor instead.
 func (*Ack) Descriptor() ([]byte, []int) {
-	return file_sensor_proto_rawDescGZIP(), []int{1}
+	return file_sensor_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Ack) GetOk() bool {
@@ -150,71 +466,1681 @@ func (x *Ack) GetMessage() string {
 	return ""
 }
 
-var File_sensor_proto protoreflect.FileDescriptor
+func (x *Ack) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
 
-const file_sensor_proto_rawDesc = "" +
-	"\n" +
-	"\fsensor.proto\x12\x06sensor\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa1\x01\n" +
-	"\n" +
-	"SensorData\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\x01R\x05value\x12\x1f\n" +
-	"\vsensor_type\x18\x02 \x01(\tR\n" +
-	"sensorType\x12\x10\n" +
-	"\x03id1\x18\x03 \x01(\tR\x03id1\x12\x10\n" +
-	"\x03id2\x18\x04 \x01(\tR\x03id2\x128\n" +
-	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"/\n" +
-	"\x03Ack\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2D\n" +
-	"\rSensorService\x123\n" +
-	"\x0eSendSensorData\x12\x12.sensor.SensorData\x1a\v.sensor.Ack(\x01B\x13Z\x11./shared-proto;pbb\x06proto3"
+func (x *Ack) GetDuplicates() int64 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
 
-var (
-	file_sensor_proto_rawDescOnce sync.Once
-	file_sensor_proto_rawDescData []byte
-)
+func (x *Ack) GetGaps() int64 {
+	if x != nil {
+		return x.Gaps
+	}
+	return 0
+}
 
-func file_sensor_proto_rawDescGZIP() []byte {
-	file_sensor_proto_rawDescOnce.Do(func() {
-		file_sensor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sensor_proto_rawDesc), len(file_sensor_proto_rawDesc)))
-	})
-	return file_sensor_proto_rawDescData
+// SensorRef identifies a sensor hosted by a generator
+type SensorRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id1           string                 `protobuf:"bytes,1,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,2,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType    string                 `protobuf:"bytes,3,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	Unit          string                 `protobuf:"bytes,4,opt,name=unit,proto3" json:"unit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-var file_sensor_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_sensor_proto_goTypes = []any{
-	(*SensorData)(nil),            // 0: sensor.SensorData
-	(*Ack)(nil),                   // 1: sensor.Ack
-	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+func (x *SensorRef) Reset() {
+	*x = SensorRef{}
+	mi := &file_sensor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
-var file_sensor_proto_depIdxs = []int32{
-	2, // 0: sensor.SensorData.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 1: sensor.SensorService.SendSensorData:input_type -> sensor.SensorData
-	1, // 2: sensor.SensorService.SendSensorData:output_type -> sensor.Ack
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+
+func (x *SensorRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_sensor_proto_init() }
-func file_sensor_proto_init() {
-	if File_sensor_proto != nil {
-		return
+func (*SensorRef) ProtoMessage() {}
+
+func (x *SensorRef) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SensorRef.ProtoReflect.Descriptor instead.
+func (*SensorRef) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SensorRef) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *SensorRef) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *SensorRef) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+func (x *SensorRef) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+// Hello is the first message on a control stream and announces the hosted sensors
+type Hello struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProducerId    string                 `protobuf:"bytes,1,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`
+	Sensors       []*SensorRef           `protobuf:"bytes,2,rep,name=sensors,proto3" json:"sensors,omitempty"`
+	Firmware      string                 `protobuf:"bytes,3,opt,name=firmware,proto3" json:"firmware,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_sensor_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Hello) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+func (x *Hello) GetSensors() []*SensorRef {
+	if x != nil {
+		return x.Sensors
+	}
+	return nil
+}
+
+func (x *Hello) GetFirmware() string {
+	if x != nil {
+		return x.Firmware
+	}
+	return ""
+}
+
+// Heartbeat keeps the sensors of a generator marked online
+type Heartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SentAt        *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_sensor_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Heartbeat) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+type RegisterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProducerId    string                 `protobuf:"bytes,1,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`
+	Firmware      string                 `protobuf:"bytes,2,opt,name=firmware,proto3" json:"firmware,omitempty"`
+	Sensors       []*SensorRef           `protobuf:"bytes,3,rep,name=sensors,proto3" json:"sensors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_sensor_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RegisterRequest) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetFirmware() string {
+	if x != nil {
+		return x.Firmware
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetSensors() []*SensorRef {
+	if x != nil {
+		return x.Sensors
+	}
+	return nil
+}
+
+type RegisterReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Registered    int32                  `protobuf:"varint,1,opt,name=registered,proto3" json:"registered,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterReply) Reset() {
+	*x = RegisterReply{}
+	mi := &file_sensor_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterReply) ProtoMessage() {}
+
+func (x *RegisterReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterReply.ProtoReflect.Descriptor instead.
+func (*RegisterReply) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RegisterReply) GetRegistered() int32 {
+	if x != nil {
+		return x.Registered
+	}
+	return 0
+}
+
+// CommandResult reports whether a command was applied
+type CommandResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Ok            bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResult) Reset() {
+	*x = CommandResult{}
+	mi := &file_sensor_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResult) ProtoMessage() {}
+
+func (x *CommandResult) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
+func (*CommandResult) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CommandResult) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *CommandResult) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CommandResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ControlMessage is sent by generators on the control stream
+type ControlMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ControlMessage_Hello
+	//	*ControlMessage_Result
+	//	*ControlMessage_Heartbeat
+	Payload       isControlMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlMessage) Reset() {
+	*x = ControlMessage{}
+	mi := &file_sensor_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlMessage) ProtoMessage() {}
+
+func (x *ControlMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlMessage.ProtoReflect.Descriptor instead.
+func (*ControlMessage) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ControlMessage) GetPayload() isControlMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ControlMessage) GetHello() *Hello {
+	if x != nil {
+		if x, ok := x.Payload.(*ControlMessage_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *ControlMessage) GetResult() *CommandResult {
+	if x != nil {
+		if x, ok := x.Payload.(*ControlMessage_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *ControlMessage) GetHeartbeat() *Heartbeat {
+	if x != nil {
+		if x, ok := x.Payload.(*ControlMessage_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return nil
+}
+
+type isControlMessage_Payload interface {
+	isControlMessage_Payload()
+}
+
+type ControlMessage_Hello struct {
+	Hello *Hello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type ControlMessage_Result struct {
+	Result *CommandResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+type ControlMessage_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,3,opt,name=heartbeat,proto3,oneof"`
+}
+
+func (*ControlMessage_Hello) isControlMessage_Payload() {}
+
+func (*ControlMessage_Result) isControlMessage_Payload() {}
+
+func (*ControlMessage_Heartbeat) isControlMessage_Payload() {}
+
+// ValueModelConfig selects and parameterizes a value model, see microservice-a/internal/valuemodel
+type ValueModelConfig struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Seed          *int64                 `protobuf:"varint,2,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValueModelConfig) Reset() {
+	*x = ValueModelConfig{}
+	mi := &file_sensor_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValueModelConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValueModelConfig) ProtoMessage() {}
+
+func (x *ValueModelConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValueModelConfig.ProtoReflect.Descriptor instead.
+func (*ValueModelConfig) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ValueModelConfig) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ValueModelConfig) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+func (x *ValueModelConfig) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+// Command is pushed by microservice-b to the generator hosting id1/id2
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Id1           string                 `protobuf:"bytes,2,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,3,opt,name=id2,proto3" json:"id2,omitempty"`
+	Type          CommandType            `protobuf:"varint,4,opt,name=type,proto3,enum=sensor.CommandType" json:"type,omitempty"`
+	FrequencyMs   int64                  `protobuf:"varint,5,opt,name=frequency_ms,json=frequencyMs,proto3" json:"frequency_ms,omitempty"` // SET_FREQUENCY
+	ValueModel    *ValueModelConfig      `protobuf:"bytes,6,opt,name=value_model,json=valueModel,proto3" json:"value_model,omitempty"`     // SET_VALUE_MODEL
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_sensor_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Command) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Command) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *Command) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *Command) GetType() CommandType {
+	if x != nil {
+		return x.Type
+	}
+	return CommandType_COMMAND_UNSPECIFIED
+}
+
+func (x *Command) GetFrequencyMs() int64 {
+	if x != nil {
+		return x.FrequencyMs
+	}
+	return 0
+}
+
+func (x *Command) GetValueModel() *ValueModelConfig {
+	if x != nil {
+		return x.ValueModel
+	}
+	return nil
+}
+
+// ReadingFilter selects stored readings; empty fields match everything
+type ReadingFilter struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id1             string                 `protobuf:"bytes,1,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2             string                 `protobuf:"bytes,2,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType      string                 `protobuf:"bytes,3,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	From            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	To              *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=to,proto3" json:"to,omitempty"`
+	IncludeArchived bool                   `protobuf:"varint,6,opt,name=include_archived,json=includeArchived,proto3" json:"include_archived,omitempty"` // include archived readings, never trashed ones
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReadingFilter) Reset() {
+	*x = ReadingFilter{}
+	mi := &file_sensor_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadingFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadingFilter) ProtoMessage() {}
+
+func (x *ReadingFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadingFilter.ProtoReflect.Descriptor instead.
+func (*ReadingFilter) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ReadingFilter) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *ReadingFilter) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *ReadingFilter) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+func (x *ReadingFilter) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ReadingFilter) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ReadingFilter) GetIncludeArchived() bool {
+	if x != nil {
+		return x.IncludeArchived
+	}
+	return false
+}
+
+// Reading is a stored reading; live readings have no id or created_at
+type Reading struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Id1           string                 `protobuf:"bytes,2,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,3,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType    string                 `protobuf:"bytes,4,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	Value         float64                `protobuf:"fixed64,5,opt,name=value,proto3" json:"value,omitempty"`
+	Ts            *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=ts,proto3" json:"ts,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reading) Reset() {
+	*x = Reading{}
+	mi := &file_sensor_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reading) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reading) ProtoMessage() {}
+
+func (x *Reading) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reading.ProtoReflect.Descriptor instead.
+func (*Reading) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Reading) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Reading) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *Reading) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *Reading) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+func (x *Reading) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Reading) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Ts
+	}
+	return nil
+}
+
+func (x *Reading) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// Aggregation buckets readings by time instead of returning them
+type Aggregation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bucket        string                 `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`                  // 1m, 5m, 1h or 1d; default 1h
+	Functions     []string               `protobuf:"bytes,2,rep,name=functions,proto3" json:"functions,omitempty"`            // avg, min, max, sum, count, first, last, stddev or a percentile like p95; default avg
+	GroupBy       []string               `protobuf:"bytes,3,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"` // any of id1, id2, sensor_type
+	Fill          string                 `protobuf:"bytes,4,opt,name=fill,proto3" json:"fill,omitempty"`                      // empty buckets between from and to: null, previous or linear
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Aggregation) Reset() {
+	*x = Aggregation{}
+	mi := &file_sensor_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Aggregation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Aggregation) ProtoMessage() {}
+
+func (x *Aggregation) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Aggregation.ProtoReflect.Descriptor instead.
+func (*Aggregation) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Aggregation) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *Aggregation) GetFunctions() []string {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+func (x *Aggregation) GetGroupBy() []string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return nil
+}
+
+func (x *Aggregation) GetFill() string {
+	if x != nil {
+		return x.Fill
+	}
+	return ""
+}
+
+type QueryReadingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        *ReadingFilter         `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`   // default 1
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // default 10; ignored for aggregations
+	Aggregation   *Aggregation           `protobuf:"bytes,4,opt,name=aggregation,proto3" json:"aggregation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryReadingsRequest) Reset() {
+	*x = QueryReadingsRequest{}
+	mi := &file_sensor_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryReadingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryReadingsRequest) ProtoMessage() {}
+
+func (x *QueryReadingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryReadingsRequest.ProtoReflect.Descriptor instead.
+func (*QueryReadingsRequest) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *QueryReadingsRequest) GetFilter() *ReadingFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *QueryReadingsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *QueryReadingsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryReadingsRequest) GetAggregation() *Aggregation {
+	if x != nil {
+		return x.Aggregation
+	}
+	return nil
+}
+
+// AggregateBucket is one bucket of one group; functions without a value, e.g.
+// in an empty bucket filled with null, are left out of values
+type AggregateBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bucket        *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Id1           string                 `protobuf:"bytes,2,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,3,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType    string                 `protobuf:"bytes,4,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	Values        map[string]float64     `protobuf:"bytes,5,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateBucket) Reset() {
+	*x = AggregateBucket{}
+	mi := &file_sensor_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateBucket) ProtoMessage() {}
+
+func (x *AggregateBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateBucket.ProtoReflect.Descriptor instead.
+func (*AggregateBucket) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AggregateBucket) GetBucket() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Bucket
+	}
+	return nil
+}
+
+func (x *AggregateBucket) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *AggregateBucket) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *AggregateBucket) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+func (x *AggregateBucket) GetValues() map[string]float64 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type QueryReadingsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Readings      []*Reading             `protobuf:"bytes,1,rep,name=readings,proto3" json:"readings,omitempty"`
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Total         int64                  `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	TotalPages    int64                  `protobuf:"varint,5,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	Buckets       []*AggregateBucket     `protobuf:"bytes,6,rep,name=buckets,proto3" json:"buckets,omitempty"` // set instead of readings for aggregations
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryReadingsReply) Reset() {
+	*x = QueryReadingsReply{}
+	mi := &file_sensor_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryReadingsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryReadingsReply) ProtoMessage() {}
+
+func (x *QueryReadingsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryReadingsReply.ProtoReflect.Descriptor instead.
+func (*QueryReadingsReply) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *QueryReadingsReply) GetReadings() []*Reading {
+	if x != nil {
+		return x.Readings
+	}
+	return nil
+}
+
+func (x *QueryReadingsReply) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *QueryReadingsReply) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryReadingsReply) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *QueryReadingsReply) GetTotalPages() int64 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+func (x *QueryReadingsReply) GetBuckets() []*AggregateBucket {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+type GetLatestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id1           string                 `protobuf:"bytes,1,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,2,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType    string                 `protobuf:"bytes,3,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLatestRequest) Reset() {
+	*x = GetLatestRequest{}
+	mi := &file_sensor_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLatestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestRequest) ProtoMessage() {}
+
+func (x *GetLatestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestRequest.ProtoReflect.Descriptor instead.
+func (*GetLatestRequest) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetLatestRequest) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *GetLatestRequest) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *GetLatestRequest) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+type GetLatestReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Readings      []*Reading             `protobuf:"bytes,1,rep,name=readings,proto3" json:"readings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLatestReply) Reset() {
+	*x = GetLatestReply{}
+	mi := &file_sensor_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLatestReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestReply) ProtoMessage() {}
+
+func (x *GetLatestReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestReply.ProtoReflect.Descriptor instead.
+func (*GetLatestReply) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetLatestReply) GetReadings() []*Reading {
+	if x != nil {
+		return x.Readings
+	}
+	return nil
+}
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id1           string                 `protobuf:"bytes,1,opt,name=id1,proto3" json:"id1,omitempty"`
+	Id2           string                 `protobuf:"bytes,2,opt,name=id2,proto3" json:"id2,omitempty"`
+	SensorType    string                 `protobuf:"bytes,3,opt,name=sensor_type,json=sensorType,proto3" json:"sensor_type,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"` // replay the readings taken after it first, at most 10000
+	OnSlow        SlowConsumerPolicy     `protobuf:"varint,5,opt,name=on_slow,json=onSlow,proto3,enum=sensor.SlowConsumerPolicy" json:"on_slow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_sensor_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SubscribeRequest) GetId1() string {
+	if x != nil {
+		return x.Id1
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetId2() string {
+	if x != nil {
+		return x.Id2
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetSensorType() string {
+	if x != nil {
+		return x.SensorType
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetOnSlow() SlowConsumerPolicy {
+	if x != nil {
+		return x.OnSlow
+	}
+	return SlowConsumerPolicy_DROP
+}
+
+// SubscribeEvent is one message of a Subscribe stream
+type SubscribeEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*SubscribeEvent_Reading
+	//	*SubscribeEvent_Dropped
+	//	*SubscribeEvent_Truncated
+	//	*SubscribeEvent_Heartbeat
+	Payload       isSubscribeEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeEvent) Reset() {
+	*x = SubscribeEvent{}
+	mi := &file_sensor_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeEvent) ProtoMessage() {}
+
+func (x *SubscribeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_sensor_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeEvent.ProtoReflect.Descriptor instead.
+func (*SubscribeEvent) Descriptor() ([]byte, []int) {
+	return file_sensor_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *SubscribeEvent) GetPayload() isSubscribeEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SubscribeEvent) GetReading() *Reading {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeEvent_Reading); ok {
+			return x.Reading
+		}
+	}
+	return nil
+}
+
+func (x *SubscribeEvent) GetDropped() uint64 {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeEvent_Dropped); ok {
+			return x.Dropped
+		}
+	}
+	return 0
+}
+
+func (x *SubscribeEvent) GetTruncated() bool {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeEvent_Truncated); ok {
+			return x.Truncated
+		}
+	}
+	return false
+}
+
+func (x *SubscribeEvent) GetHeartbeat() bool {
+	if x != nil {
+		if x, ok := x.Payload.(*SubscribeEvent_Heartbeat); ok {
+			return x.Heartbeat
+		}
+	}
+	return false
+}
+
+type isSubscribeEvent_Payload interface {
+	isSubscribeEvent_Payload()
+}
+
+type SubscribeEvent_Reading struct {
+	Reading *Reading `protobuf:"bytes,1,opt,name=reading,proto3,oneof"`
+}
+
+type SubscribeEvent_Dropped struct {
+	Dropped uint64 `protobuf:"varint,2,opt,name=dropped,proto3,oneof"` // readings lost because the client was too slow
+}
+
+type SubscribeEvent_Truncated struct {
+	Truncated bool `protobuf:"varint,3,opt,name=truncated,proto3,oneof"` // the replay hit its limit, older readings were skipped
+}
+
+type SubscribeEvent_Heartbeat struct {
+	Heartbeat bool `protobuf:"varint,4,opt,name=heartbeat,proto3,oneof"` // sent every 15s on idle streams
+}
+
+func (*SubscribeEvent_Reading) isSubscribeEvent_Payload() {}
+
+func (*SubscribeEvent_Dropped) isSubscribeEvent_Payload() {}
+
+func (*SubscribeEvent_Truncated) isSubscribeEvent_Payload() {}
+
+func (*SubscribeEvent_Heartbeat) isSubscribeEvent_Payload() {}
+
+var File_sensor_proto protoreflect.FileDescriptor
+
+const file_sensor_proto_rawDesc = "" +
+	"\n" +
+	"\fsensor.proto\x12\x06sensor\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd4\x01\n" +
+	"\n" +
+	"SensorData\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x01R\x05value\x12\x1f\n" +
+	"\vsensor_type\x18\x02 \x01(\tR\n" +
+	"sensorType\x12\x10\n" +
+	"\x03id1\x18\x03 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x04 \x01(\tR\x03id2\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x10\n" +
+	"\x03seq\x18\x06 \x01(\x04R\x03seq\x12\x1f\n" +
+	"\vproducer_id\x18\a \x01(\tR\n" +
+	"producerId\"=\n" +
+	"\vSensorBatch\x12.\n" +
+	"\breadings\x18\x01 \x03(\v2\x12.sensor.SensorDataR\breadings\"3\n" +
+	"\x10HighWaterRequest\x12\x1f\n" +
+	"\vproducer_id\x18\x01 \x01(\tR\n" +
+	"producerId\"E\n" +
+	"\rHighWaterMark\x12\x10\n" +
+	"\x03id1\x18\x01 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x02 \x01(\tR\x03id2\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x04R\x03seq\"=\n" +
+	"\x0eHighWaterReply\x12+\n" +
+	"\x05marks\x18\x01 \x03(\v2\x15.sensor.HighWaterMarkR\x05marks\"\x7f\n" +
+	"\x03Ack\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\breceived\x18\x03 \x01(\x03R\breceived\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x04 \x01(\x03R\n" +
+	"duplicates\x12\x12\n" +
+	"\x04gaps\x18\x05 \x01(\x03R\x04gaps\"d\n" +
+	"\tSensorRef\x12\x10\n" +
+	"\x03id1\x18\x01 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x02 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x03 \x01(\tR\n" +
+	"sensorType\x12\x12\n" +
+	"\x04unit\x18\x04 \x01(\tR\x04unit\"q\n" +
+	"\x05Hello\x12\x1f\n" +
+	"\vproducer_id\x18\x01 \x01(\tR\n" +
+	"producerId\x12+\n" +
+	"\asensors\x18\x02 \x03(\v2\x11.sensor.SensorRefR\asensors\x12\x1a\n" +
+	"\bfirmware\x18\x03 \x01(\tR\bfirmware\"@\n" +
+	"\tHeartbeat\x123\n" +
+	"\asent_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\"{\n" +
+	"\x0fRegisterRequest\x12\x1f\n" +
+	"\vproducer_id\x18\x01 \x01(\tR\n" +
+	"producerId\x12\x1a\n" +
+	"\bfirmware\x18\x02 \x01(\tR\bfirmware\x12+\n" +
+	"\asensors\x18\x03 \x03(\v2\x11.sensor.SensorRefR\asensors\"/\n" +
+	"\rRegisterReply\x12\x1e\n" +
+	"\n" +
+	"registered\x18\x01 \x01(\x05R\n" +
+	"registered\"T\n" +
+	"\rCommandResult\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x0e\n" +
+	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xa6\x01\n" +
+	"\x0eControlMessage\x12%\n" +
+	"\x05hello\x18\x01 \x01(\v2\r.sensor.HelloH\x00R\x05hello\x12/\n" +
+	"\x06result\x18\x02 \x01(\v2\x15.sensor.CommandResultH\x00R\x06result\x121\n" +
+	"\theartbeat\x18\x03 \x01(\v2\x11.sensor.HeartbeatH\x00R\theartbeatB\t\n" +
+	"\apayload\"\xc1\x01\n" +
+	"\x10ValueModelConfig\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x17\n" +
+	"\x04seed\x18\x02 \x01(\x03H\x00R\x04seed\x88\x01\x01\x12<\n" +
+	"\x06params\x18\x03 \x03(\v2$.sensor.ValueModelConfig.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\a\n" +
+	"\x05_seed\"\xc4\x01\n" +
+	"\aCommand\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03id1\x18\x02 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x03 \x01(\tR\x03id2\x12'\n" +
+	"\x04type\x18\x04 \x01(\x0e2\x13.sensor.CommandTypeR\x04type\x12!\n" +
+	"\ffrequency_ms\x18\x05 \x01(\x03R\vfrequencyMs\x129\n" +
+	"\vvalue_model\x18\x06 \x01(\v2\x18.sensor.ValueModelConfigR\n" +
+	"valueModel\"\xdb\x01\n" +
+	"\rReadingFilter\x12\x10\n" +
+	"\x03id1\x18\x01 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x02 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x03 \x01(\tR\n" +
+	"sensorType\x12.\n" +
+	"\x04from\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12)\n" +
+	"\x10include_archived\x18\x06 \x01(\bR\x0fincludeArchived\"\xdb\x01\n" +
+	"\aReading\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x10\n" +
+	"\x03id1\x18\x02 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x03 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x04 \x01(\tR\n" +
+	"sensorType\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\x01R\x05value\x12*\n" +
+	"\x02ts\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"r\n" +
+	"\vAggregation\x12\x16\n" +
+	"\x06bucket\x18\x01 \x01(\tR\x06bucket\x12\x1c\n" +
+	"\tfunctions\x18\x02 \x03(\tR\tfunctions\x12\x19\n" +
+	"\bgroup_by\x18\x03 \x03(\tR\agroupBy\x12\x12\n" +
+	"\x04fill\x18\x04 \x01(\tR\x04fill\"\xa6\x01\n" +
+	"\x14QueryReadingsRequest\x12-\n" +
+	"\x06filter\x18\x01 \x01(\v2\x15.sensor.ReadingFilterR\x06filter\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x125\n" +
+	"\vaggregation\x18\x04 \x01(\v2\x13.sensor.AggregationR\vaggregation\"\x82\x02\n" +
+	"\x0fAggregateBucket\x122\n" +
+	"\x06bucket\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x06bucket\x12\x10\n" +
+	"\x03id1\x18\x02 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x03 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x04 \x01(\tR\n" +
+	"sensorType\x12;\n" +
+	"\x06values\x18\x05 \x03(\v2#.sensor.AggregateBucket.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\xd5\x01\n" +
+	"\x12QueryReadingsReply\x12+\n" +
+	"\breadings\x18\x01 \x03(\v2\x0f.sensor.ReadingR\breadings\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x14\n" +
+	"\x05total\x18\x04 \x01(\x03R\x05total\x12\x1f\n" +
+	"\vtotal_pages\x18\x05 \x01(\x03R\n" +
+	"totalPages\x121\n" +
+	"\abuckets\x18\x06 \x03(\v2\x17.sensor.AggregateBucketR\abuckets\"W\n" +
+	"\x10GetLatestRequest\x12\x10\n" +
+	"\x03id1\x18\x01 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x02 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x03 \x01(\tR\n" +
+	"sensorType\"=\n" +
+	"\x0eGetLatestReply\x12+\n" +
+	"\breadings\x18\x01 \x03(\v2\x0f.sensor.ReadingR\breadings\"\xbe\x01\n" +
+	"\x10SubscribeRequest\x12\x10\n" +
+	"\x03id1\x18\x01 \x01(\tR\x03id1\x12\x10\n" +
+	"\x03id2\x18\x02 \x01(\tR\x03id2\x12\x1f\n" +
+	"\vsensor_type\x18\x03 \x01(\tR\n" +
+	"sensorType\x120\n" +
+	"\x05since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x123\n" +
+	"\aon_slow\x18\x05 \x01(\x0e2\x1a.sensor.SlowConsumerPolicyR\x06onSlow\"\xa4\x01\n" +
+	"\x0eSubscribeEvent\x12+\n" +
+	"\areading\x18\x01 \x01(\v2\x0f.sensor.ReadingH\x00R\areading\x12\x1a\n" +
+	"\adropped\x18\x02 \x01(\x04H\x00R\adropped\x12\x1e\n" +
+	"\ttruncated\x18\x03 \x01(\bH\x00R\ttruncated\x12\x1e\n" +
+	"\theartbeat\x18\x04 \x01(\bH\x00R\theartbeatB\t\n" +
+	"\apayload*v\n" +
+	"\vCommandType\x12\x17\n" +
+	"\x13COMMAND_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rSET_FREQUENCY\x10\x01\x12\t\n" +
+	"\x05PAUSE\x10\x02\x12\n" +
+	"\n" +
+	"\x06RESUME\x10\x03\x12\x13\n" +
+	"\x0fSET_VALUE_MODEL\x10\x04\x12\x0f\n" +
+	"\vFLUSH_SPOOL\x10\x05*.\n" +
+	"\x12SlowConsumerPolicy\x12\b\n" +
+	"\x04DROP\x10\x00\x12\x0e\n" +
+	"\n" +
+	"DISCONNECT\x10\x012\x81\x04\n" +
+	"\rSensorService\x123\n" +
+	"\x0eSendSensorData\x12\x12.sensor.SensorData\x1a\v.sensor.Ack(\x01\x125\n" +
+	"\x0fSendSensorBatch\x12\x13.sensor.SensorBatch\x1a\v.sensor.Ack(\x01\x12E\n" +
+	"\x11GetHighWaterMarks\x12\x18.sensor.HighWaterRequest\x1a\x16.sensor.HighWaterReply\x126\n" +
+	"\aControl\x12\x16.sensor.ControlMessage\x1a\x0f.sensor.Command(\x010\x01\x12:\n" +
+	"\bRegister\x12\x17.sensor.RegisterRequest\x1a\x15.sensor.RegisterReply\x12I\n" +
+	"\rQueryReadings\x12\x1c.sensor.QueryReadingsRequest\x1a\x1a.sensor.QueryReadingsReply\x12=\n" +
+	"\tGetLatest\x12\x18.sensor.GetLatestRequest\x1a\x16.sensor.GetLatestReply\x12?\n" +
+	"\tSubscribe\x12\x18.sensor.SubscribeRequest\x1a\x16.sensor.SubscribeEvent0\x01B\x13Z\x11./shared-proto;pbb\x06proto3"
+
+var (
+	file_sensor_proto_rawDescOnce sync.Once
+	file_sensor_proto_rawDescData []byte
+)
+
+func file_sensor_proto_rawDescGZIP() []byte {
+	file_sensor_proto_rawDescOnce.Do(func() {
+		file_sensor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sensor_proto_rawDesc), len(file_sensor_proto_rawDesc)))
+	})
+	return file_sensor_proto_rawDescData
+}
+
+var file_sensor_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_sensor_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_sensor_proto_goTypes = []any{
+	(CommandType)(0),              // 0: sensor.CommandType
+	(SlowConsumerPolicy)(0),       // 1: sensor.SlowConsumerPolicy
+	(*SensorData)(nil),            // 2: sensor.SensorData
+	(*SensorBatch)(nil),           // 3: sensor.SensorBatch
+	(*HighWaterRequest)(nil),      // 4: sensor.HighWaterRequest
+	(*HighWaterMark)(nil),         // 5: sensor.HighWaterMark
+	(*HighWaterReply)(nil),        // 6: sensor.HighWaterReply
+	(*Ack)(nil),                   // 7: sensor.Ack
+	(*SensorRef)(nil),             // 8: sensor.SensorRef
+	(*Hello)(nil),                 // 9: sensor.Hello
+	(*Heartbeat)(nil),             // 10: sensor.Heartbeat
+	(*RegisterRequest)(nil),       // 11: sensor.RegisterRequest
+	(*RegisterReply)(nil),         // 12: sensor.RegisterReply
+	(*CommandResult)(nil),         // 13: sensor.CommandResult
+	(*ControlMessage)(nil),        // 14: sensor.ControlMessage
+	(*ValueModelConfig)(nil),      // 15: sensor.ValueModelConfig
+	(*Command)(nil),               // 16: sensor.Command
+	(*ReadingFilter)(nil),         // 17: sensor.ReadingFilter
+	(*Reading)(nil),               // 18: sensor.Reading
+	(*Aggregation)(nil),           // 19: sensor.Aggregation
+	(*QueryReadingsRequest)(nil),  // 20: sensor.QueryReadingsRequest
+	(*AggregateBucket)(nil),       // 21: sensor.AggregateBucket
+	(*QueryReadingsReply)(nil),    // 22: sensor.QueryReadingsReply
+	(*GetLatestRequest)(nil),      // 23: sensor.GetLatestRequest
+	(*GetLatestReply)(nil),        // 24: sensor.GetLatestReply
+	(*SubscribeRequest)(nil),      // 25: sensor.SubscribeRequest
+	(*SubscribeEvent)(nil),        // 26: sensor.SubscribeEvent
+	nil,                           // 27: sensor.ValueModelConfig.ParamsEntry
+	nil,                           // 28: sensor.AggregateBucket.ValuesEntry
+	(*timestamppb.Timestamp)(nil), // 29: google.protobuf.Timestamp
+}
+var file_sensor_proto_depIdxs = []int32{
+	29, // 0: sensor.SensorData.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 1: sensor.SensorBatch.readings:type_name -> sensor.SensorData
+	5,  // 2: sensor.HighWaterReply.marks:type_name -> sensor.HighWaterMark
+	8,  // 3: sensor.Hello.sensors:type_name -> sensor.SensorRef
+	29, // 4: sensor.Heartbeat.sent_at:type_name -> google.protobuf.Timestamp
+	8,  // 5: sensor.RegisterRequest.sensors:type_name -> sensor.SensorRef
+	9,  // 6: sensor.ControlMessage.hello:type_name -> sensor.Hello
+	13, // 7: sensor.ControlMessage.result:type_name -> sensor.CommandResult
+	10, // 8: sensor.ControlMessage.heartbeat:type_name -> sensor.Heartbeat
+	27, // 9: sensor.ValueModelConfig.params:type_name -> sensor.ValueModelConfig.ParamsEntry
+	0,  // 10: sensor.Command.type:type_name -> sensor.CommandType
+	15, // 11: sensor.Command.value_model:type_name -> sensor.ValueModelConfig
+	29, // 12: sensor.ReadingFilter.from:type_name -> google.protobuf.Timestamp
+	29, // 13: sensor.ReadingFilter.to:type_name -> google.protobuf.Timestamp
+	29, // 14: sensor.Reading.ts:type_name -> google.protobuf.Timestamp
+	29, // 15: sensor.Reading.created_at:type_name -> google.protobuf.Timestamp
+	17, // 16: sensor.QueryReadingsRequest.filter:type_name -> sensor.ReadingFilter
+	19, // 17: sensor.QueryReadingsRequest.aggregation:type_name -> sensor.Aggregation
+	29, // 18: sensor.AggregateBucket.bucket:type_name -> google.protobuf.Timestamp
+	28, // 19: sensor.AggregateBucket.values:type_name -> sensor.AggregateBucket.ValuesEntry
+	18, // 20: sensor.QueryReadingsReply.readings:type_name -> sensor.Reading
+	21, // 21: sensor.QueryReadingsReply.buckets:type_name -> sensor.AggregateBucket
+	18, // 22: sensor.GetLatestReply.readings:type_name -> sensor.Reading
+	29, // 23: sensor.SubscribeRequest.since:type_name -> google.protobuf.Timestamp
+	1,  // 24: sensor.SubscribeRequest.on_slow:type_name -> sensor.SlowConsumerPolicy
+	18, // 25: sensor.SubscribeEvent.reading:type_name -> sensor.Reading
+	2,  // 26: sensor.SensorService.SendSensorData:input_type -> sensor.SensorData
+	3,  // 27: sensor.SensorService.SendSensorBatch:input_type -> sensor.SensorBatch
+	4,  // 28: sensor.SensorService.GetHighWaterMarks:input_type -> sensor.HighWaterRequest
+	14, // 29: sensor.SensorService.Control:input_type -> sensor.ControlMessage
+	11, // 30: sensor.SensorService.Register:input_type -> sensor.RegisterRequest
+	20, // 31: sensor.SensorService.QueryReadings:input_type -> sensor.QueryReadingsRequest
+	23, // 32: sensor.SensorService.GetLatest:input_type -> sensor.GetLatestRequest
+	25, // 33: sensor.SensorService.Subscribe:input_type -> sensor.SubscribeRequest
+	7,  // 34: sensor.SensorService.SendSensorData:output_type -> sensor.Ack
+	7,  // 35: sensor.SensorService.SendSensorBatch:output_type -> sensor.Ack
+	6,  // 36: sensor.SensorService.GetHighWaterMarks:output_type -> sensor.HighWaterReply
+	16, // 37: sensor.SensorService.Control:output_type -> sensor.Command
+	12, // 38: sensor.SensorService.Register:output_type -> sensor.RegisterReply
+	22, // 39: sensor.SensorService.QueryReadings:output_type -> sensor.QueryReadingsReply
+	24, // 40: sensor.SensorService.GetLatest:output_type -> sensor.GetLatestReply
+	26, // 41: sensor.SensorService.Subscribe:output_type -> sensor.SubscribeEvent
+	34, // [34:42] is the sub-list for method output_type
+	26, // [26:34] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
+}
+
+func init() { file_sensor_proto_init() }
+func file_sensor_proto_init() {
+	if File_sensor_proto != nil {
+		return
+	}
+	file_sensor_proto_msgTypes[12].OneofWrappers = []any{
+		(*ControlMessage_Hello)(nil),
+		(*ControlMessage_Result)(nil),
+		(*ControlMessage_Heartbeat)(nil),
+	}
+	file_sensor_proto_msgTypes[13].OneofWrappers = []any{}
+	file_sensor_proto_msgTypes[24].OneofWrappers = []any{
+		(*SubscribeEvent_Reading)(nil),
+		(*SubscribeEvent_Dropped)(nil),
+		(*SubscribeEvent_Truncated)(nil),
+		(*SubscribeEvent_Heartbeat)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sensor_proto_rawDesc), len(file_sensor_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_sensor_proto_goTypes,
 		DependencyIndexes: file_sensor_proto_depIdxs,
+		EnumInfos:         file_sensor_proto_enumTypes,
 		MessageInfos:      file_sensor_proto_msgTypes,
 	}.Build()
 	File_sensor_proto = out.File
@@ -2,15 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
 	"microservice-b/database"
+	"microservice-b/internal/alerting"
+	"microservice-b/internal/anomaly"
 	"microservice-b/internal/api/grpc"
 	httpHandler "microservice-b/internal/api/http"
+	"microservice-b/internal/control"
+	"microservice-b/internal/live"
+	"microservice-b/internal/registry"
 	"microservice-b/internal/repository"
+	"microservice-b/internal/retention"
+	"microservice-b/internal/rollup"
 	"microservice-b/internal/usecase"
+	"microservice-b/internal/webhook"
 	myMiddleware "microservice-b/middleware"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -20,6 +30,7 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"google.golang.org/grpc/credentials"
 )
 
 // @title sensor-microservice-b
@@ -29,8 +40,16 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 // @BasePath /
 func main() {
+	// The first admin of an empty users table; flags override the environment
+	bootstrapEmail := flag.String("bootstrap-admin-email", os.Getenv("BOOTSTRAP_ADMIN_EMAIL"), "email of the admin created when there are no users")
+	bootstrapPassword := flag.String("bootstrap-admin-password", os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"), "password of the admin created when there are no users")
+	flag.Parse()
+
 	// Structured logger
 	log := logrus.New()
 	log.SetFormatter(&logrus.JSONFormatter{})
@@ -45,19 +64,209 @@ func main() {
 	// Repositories
 	sensorRepository := repository.NewSensorRepository(db)
 	userRepo := repository.NewUserRepository(db)
+	deviceRepo := repository.NewDeviceRepository(db)
+	rollupRepo := repository.NewRollupRepository(db)
+	retentionRepo := repository.NewRetentionRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	anomalyRepo := repository.NewAnomalyRepository(db)
 
 	jwtSecret := os.Getenv("AUTH_SECRET")
 	if jwtSecret == "" {
 		log.Fatal("AUTH_SECRET environment variable not set")
 	}
 	// Usecases
+	// Access tokens are valid for ACCESS_TOKEN_TTL, refresh tokens for REFRESH_TOKEN_TTL
+	accessTTL, refreshTTL := usecase.DefaultAccessTTL, usecase.DefaultRefreshTTL
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		if accessTTL, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid ACCESS_TOKEN_TTL")
+		}
+	}
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		if refreshTTL, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid REFRESH_TOKEN_TTL")
+		}
+	}
+	// SIGNUP_MODE is open, invite (default) or disabled; invitations expire after INVITATION_TTL
+	signupMode := os.Getenv("SIGNUP_MODE")
+	switch signupMode {
+	case "":
+		signupMode = usecase.SignupInvite
+	case usecase.SignupOpen, usecase.SignupInvite, usecase.SignupDisabled:
+	default:
+		log.Fatalf("invalid SIGNUP_MODE %q", signupMode)
+	}
+	invitationTTL := usecase.DefaultInvitationTTL
+	if v := os.Getenv("INVITATION_TTL"); v != "" {
+		if invitationTTL, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid INVITATION_TTL")
+		}
+	}
 	userUseCase := &usecase.UserRepository{
-		Repo:      userRepo,
-		JWTSecret: jwtSecret,
+		Repo:          userRepo,
+		JWTSecret:     jwtSecret,
+		AccessTTL:     accessTTL,
+		RefreshTTL:    refreshTTL,
+		SignupMode:    signupMode,
+		InvitationTTL: invitationTTL,
+	}
+	if *bootstrapEmail != "" {
+		created, err := userUseCase.BootstrapAdmin(*bootstrapEmail, *bootstrapPassword)
+		if err != nil {
+			log.WithError(err).Fatal("bootstrapping the first admin failed")
+		}
+		if created {
+			log.WithField("email", *bootstrapEmail).Info("created the first admin")
+		}
+	} else if n, err := userRepo.Count(map[string]interface{}{"status": "all"}); err == nil && n == 0 && signupMode != usecase.SignupOpen {
+		log.Warn("there are no users and signup is not open; set BOOTSTRAP_ADMIN_EMAIL and BOOTSTRAP_ADMIN_PASSWORD to create the first admin")
+	}
+
+	// Routes admin commands to the control streams of connected generators
+	hub := control.NewHub()
+
+	// Device registry; devices silent for DEVICE_OFFLINE_AFTER are reported offline
+	offlineAfter := registry.DefaultOfflineAfter
+	if v := os.Getenv("DEVICE_OFFLINE_AFTER"); v != "" {
+		if offlineAfter, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid DEVICE_OFFLINE_AFTER")
+		}
 	}
+	deviceRegistry := registry.New(deviceRepo, offlineAfter)
+	deviceTokenUseCase := &usecase.DeviceTokenRepository{Repo: deviceRepo}
+
+	// Fans stored readings out to live stream clients, each buffering at most
+	// LIVE_BUFFER readings
+	liveBuffer := live.DefaultBuffer
+	if v := os.Getenv("LIVE_BUFFER"); v != "" {
+		if liveBuffer, err = strconv.Atoi(v); err != nil || liveBuffer <= 0 {
+			log.Fatalf("invalid LIVE_BUFFER %q", v)
+		}
+	}
+	liveHub := live.NewHub(liveBuffer)
+
+	// gRPC transport security: TLS with GRPC_TLS_CERT/GRPC_TLS_KEY, mutual TLS
+	// when GRPC_TLS_CLIENT_CA is set as well
+	var grpcCreds credentials.TransportCredentials
+	if certFile, keyFile := os.Getenv("GRPC_TLS_CERT"), os.Getenv("GRPC_TLS_KEY"); certFile != "" || keyFile != "" {
+		if grpcCreds, err = grpc.ServerCredentials(certFile, keyFile, os.Getenv("GRPC_TLS_CLIENT_CA")); err != nil {
+			log.WithError(err).Fatal("invalid gRPC TLS configuration")
+		}
+	} else {
+		log.Warn("GRPC_TLS_CERT is not set; the gRPC ingest port runs without TLS")
+	}
+	// GRPC_DEVICE_AUTH is required (default) or disabled; when required
+	// generators must present a device token bound to the sensors they write
+	var deviceAuth grpc.DeviceAuthenticator
+	switch v := os.Getenv("GRPC_DEVICE_AUTH"); v {
+	case "", "required":
+		deviceAuth = deviceTokenUseCase
+	case "disabled":
+		log.Warn("GRPC_DEVICE_AUTH is disabled; any client reaching the gRPC port can write readings of every sensor")
+	default:
+		log.Fatalf("invalid GRPC_DEVICE_AUTH %q", v)
+	}
+
+	// Background downsampling into the rollup tables every ROLLUP_INTERVAL; minutes
+	// are rolled up once ROLLUP_GRACE has passed after their end
+	rollupInterval, rollupGrace := rollup.DefaultInterval, rollup.DefaultGrace
+	if v := os.Getenv("ROLLUP_INTERVAL"); v != "" {
+		if rollupInterval, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid ROLLUP_INTERVAL")
+		}
+	}
+	if v := os.Getenv("ROLLUP_GRACE"); v != "" {
+		if rollupGrace, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid ROLLUP_GRACE")
+		}
+	}
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go rollup.New(rollupRepo, rollupInterval, rollupGrace).Start(workerCtx)
+
+	// Retention policies are enforced every RETENTION_INTERVAL
+	retentionInterval := retention.DefaultInterval
+	if v := os.Getenv("RETENTION_INTERVAL"); v != "" {
+		if retentionInterval, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid RETENTION_INTERVAL")
+		}
+	}
+	retentionEnforcer := retention.New(retentionRepo, retentionInterval)
+	go retentionEnforcer.Start(workerCtx)
+
+	// Deleted readings stay restorable from the trash for TRASH_GRACE
+	trashGrace := retention.DefaultTrashGrace
+	if v := os.Getenv("TRASH_GRACE"); v != "" {
+		if trashGrace, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid TRASH_GRACE")
+		}
+	}
+	go retention.NewTrashPurger(retentionRepo, retentionInterval, trashGrace).Start(workerCtx)
+
+	// Sequence marks of producer sessions idle for SEQUENCE_TTL are purged
+	sequenceTTL := retention.DefaultSequenceTTL
+	if v := os.Getenv("SEQUENCE_TTL"); v != "" {
+		if sequenceTTL, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid SEQUENCE_TTL")
+		}
+	}
+	go retention.NewSequencePurger(retentionRepo, retentionInterval, sequenceTTL).Start(workerCtx)
+
+	// Alert rules are evaluated on every stored reading; absence rules every
+	// ALERT_INTERVAL, when the rules are reloaded as well
+	alertInterval := alerting.DefaultInterval
+	if v := os.Getenv("ALERT_INTERVAL"); v != "" {
+		if alertInterval, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid ALERT_INTERVAL")
+		}
+	}
+	// Webhook deliveries are attempted as events are published; failed ones are
+	// looked for every WEBHOOK_INTERVAL and dead after WEBHOOK_MAX_ATTEMPTS
+	webhookInterval := webhook.DefaultInterval
+	if v := os.Getenv("WEBHOOK_INTERVAL"); v != "" {
+		if webhookInterval, err = time.ParseDuration(v); err != nil {
+			log.WithError(err).Fatal("invalid WEBHOOK_INTERVAL")
+		}
+	}
+	webhookMaxAttempts := webhook.DefaultMaxAttempts
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		if webhookMaxAttempts, err = strconv.Atoi(v); err != nil || webhookMaxAttempts <= 0 {
+			log.WithError(err).Fatal("invalid WEBHOOK_MAX_ATTEMPTS")
+		}
+	}
+	dispatcher := webhook.New(webhookRepo, webhookInterval, webhookMaxAttempts)
+	go dispatcher.Start(workerCtx)
+	go registry.NewMonitor(deviceRegistry, dispatcher, registry.DefaultCheckInterval).Start(workerCtx)
+
+	alertEngine := alerting.New(alertRepo, sensorRepository, alertInterval)
+	alertEngine.SetPublisher(dispatcher)
+	// load the firing alerts before readings arrive, so they are not fired twice
+	if err := alertEngine.Load(); err != nil {
+		log.WithError(err).Fatal("loading alert rules failed")
+	}
+	go alertEngine.Start(workerCtx)
+
+	// Readings are scored against rolling, EWMA and hour of day baselines of
+	// ANOMALY_WINDOW readings; from ANOMALY_THRESHOLD stddevs they are stored as
+	// anomalies. Alert rules of type anomaly fire on the scores.
+	anomalyWindow := anomaly.DefaultWindow
+	if v := os.Getenv("ANOMALY_WINDOW"); v != "" {
+		if anomalyWindow, err = strconv.Atoi(v); err != nil || anomalyWindow <= 0 {
+			log.WithError(err).Fatal("invalid ANOMALY_WINDOW")
+		}
+	}
+	anomalyThreshold := anomaly.DefaultThreshold
+	if v := os.Getenv("ANOMALY_THRESHOLD"); v != "" {
+		if anomalyThreshold, err = strconv.ParseFloat(v, 64); err != nil || anomalyThreshold <= 0 {
+			log.WithError(err).Fatal("invalid ANOMALY_THRESHOLD")
+		}
+	}
+	detector := anomaly.New(anomalyRepo, anomalyWindow, anomalyThreshold)
 
 	// Start gRPC server in goroutine
-	go grpc.StartGRPCServer(sensorRepository, ":50051")
+	go grpc.StartGRPCServer(sensorRepository, hub, deviceRegistry, liveHub, alertEngine, detector, jwtSecret, userRepo, userUseCase, deviceAuth, grpcCreds, ":50051")
 	log.Println("Microservice B started. gRPC server listening on :50051")
 
 	// Start Echo REST server
@@ -71,18 +280,123 @@ func main() {
 	// Handlers
 	sensorHandler := httpHandler.NewSensorHandler(sensorRepository)
 	userHandler := httpHandler.NewUserHandler(userUseCase)
+	deviceHandler := httpHandler.NewDeviceHandler(hub, deviceRegistry)
+	retentionHandler := httpHandler.NewRetentionHandler(retentionRepo, retentionEnforcer)
+	auditHandler := httpHandler.NewAuditHandler(auditRepo)
+	usersHandler := httpHandler.NewUsersHandler(userUseCase)
+	invitationHandler := httpHandler.NewInvitationHandler(userUseCase)
+	apiKeyHandler := httpHandler.NewAPIKeyHandler(userUseCase)
+	deviceTokenHandler := httpHandler.NewDeviceTokenHandler(deviceTokenUseCase)
+	alertHandler := httpHandler.NewAlertHandler(alertRepo, alertEngine)
+	webhookHandler := httpHandler.NewWebhookHandler(webhookRepo, dispatcher)
+	anomalyHandler := httpHandler.NewAnomalyHandler(anomalyRepo)
+	streamHandler := httpHandler.NewStreamHandler(liveHub, sensorRepository)
 
 	// Public routes
 	e.POST("/signup", userHandler.Signup)
-	e.POST("/login", userHandler.Login)
+	e.POST("/login", userHandler.Login, myMiddleware.Audit(auditRepo))
+	e.POST("/refresh", userHandler.Refresh)
+	e.POST("/logout", userHandler.Logout, myMiddleware.JWTMiddleware(userUseCase.JWTSecret, userRepo, nil), myMiddleware.Audit(auditRepo))
 
-	// Protected routes
+	// Protected routes; the permission each route requires is declared in permissions.go
 	apiGroup := e.Group("/api")
-	apiGroup.Use(myMiddleware.JWTMiddleware(userUseCase.JWTSecret))
+	// Machine clients may authenticate with an API key instead of a JWT
+	apiGroup.Use(myMiddleware.JWTMiddleware(userUseCase.JWTSecret, userRepo, userUseCase))
+	apiGroup.Use(myMiddleware.Audit(auditRepo))
+	apiGroup.Use(myMiddleware.Authorize(routePermissions))
+	apiGroup.Use(myMiddleware.RestrictID1(id1Routes))
+
+	// Live streams of incoming readings; EventSource and WebSocket clients in
+	// browsers cannot set headers and may pass the token as access_token instead
+	stream := e.Group("/api/stream",
+		myMiddleware.QueryToken,
+		myMiddleware.JWTMiddleware(userUseCase.JWTSecret, userRepo, userUseCase),
+		myMiddleware.Audit(auditRepo),
+		myMiddleware.Authorize(routePermissions),
+		myMiddleware.RestrictID1(id1Routes),
+	)
+	stream.GET("", streamHandler.StreamSSE)
+	stream.GET("/ws", streamHandler.StreamWebSocket)
 
 	apiGroup.GET("/sensors", sensorHandler.GetSensors)
+	apiGroup.GET("/sensors/aggregate", sensorHandler.AggregateSensors)
 	apiGroup.DELETE("/sensors", sensorHandler.DeleteSensors)
+	apiGroup.GET("/sensors/trash", sensorHandler.GetTrash)
+	apiGroup.POST("/sensors/restore", sensorHandler.RestoreSensors)
 	apiGroup.PATCH("/sensors", sensorHandler.EditSensors)
+	apiGroup.GET("/sensors/:id/history", sensorHandler.GetHistory)
+	apiGroup.POST("/sensors/changes/:change_set/revert", sensorHandler.RevertChangeSet)
+
+	apiGroup.GET("/devices", deviceHandler.ListDevices)
+
+	// Generator commands
+	devices := apiGroup.Group("/devices/:id1/:id2")
+	devices.POST("/frequency", deviceHandler.SetFrequency)
+	devices.POST("/pause", deviceHandler.Pause)
+	devices.POST("/resume", deviceHandler.Resume)
+	devices.PUT("/model", deviceHandler.SetValueModel)
+	devices.POST("/flush", deviceHandler.FlushSpool)
+
+	// Device tokens of generators
+	deviceTokens := apiGroup.Group("/device-tokens")
+	deviceTokens.POST("", deviceTokenHandler.CreateDeviceToken)
+	deviceTokens.GET("", deviceTokenHandler.ListDeviceTokens)
+	deviceTokens.DELETE("/:id", deviceTokenHandler.RevokeDeviceToken)
+
+	// Alerting
+	alerts := apiGroup.Group("/alerts")
+	alerts.GET("", alertHandler.ListAlerts)
+	alerts.GET("/rules", alertHandler.ListRules)
+	alerts.POST("/rules", alertHandler.CreateRule)
+	alerts.PATCH("/rules/:id", alertHandler.UpdateRule)
+	alerts.DELETE("/rules/:id", alertHandler.DeleteRule)
+	apiGroup.GET("/anomalies", anomalyHandler.ListAnomalies)
+
+	// Webhooks
+	webhooks := apiGroup.Group("/webhooks")
+	webhooks.GET("", webhookHandler.ListWebhooks)
+	webhooks.POST("", webhookHandler.CreateWebhook)
+	webhooks.PATCH("/:id", webhookHandler.UpdateWebhook)
+	webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+	webhooks.POST("/:id/test", webhookHandler.TestWebhook)
+	webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+	webhooks.POST("/deliveries/:id/retry", webhookHandler.RetryDelivery)
+
+	// Retention policies
+	policies := apiGroup.Group("/retention/policies")
+	policies.GET("", retentionHandler.ListPolicies)
+	policies.POST("", retentionHandler.CreatePolicy)
+	policies.POST("/dry-run", retentionHandler.DryRunPolicies)
+	policies.DELETE("/:id", retentionHandler.DeletePolicy)
+
+	// Audit log
+	apiGroup.GET("/audit", auditHandler.ListAuditEvents)
+
+	// User management
+	users := apiGroup.Group("/users")
+	users.GET("", usersHandler.ListUsers)
+	users.GET("/:id", usersHandler.GetUser)
+	users.PATCH("/:id", usersHandler.UpdateUser)
+	users.POST("/:id/archive", usersHandler.ArchiveUser)
+	users.POST("/:id/unarchive", usersHandler.UnarchiveUser)
+	users.POST("/:id/password-reset", usersHandler.ResetPassword)
+
+	// Invitations
+	invitations := apiGroup.Group("/invitations")
+	invitations.POST("", invitationHandler.CreateInvitation)
+	invitations.GET("", invitationHandler.ListInvitations)
+	invitations.DELETE("/:id", invitationHandler.RevokeInvitation)
+
+	// API keys
+	keys := apiGroup.Group("/keys")
+	keys.POST("", apiKeyHandler.CreateAPIKey)
+	keys.GET("", apiKeyHandler.ListAPIKeys)
+	keys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+
+	// Own account
+	apiGroup.GET("/me", usersHandler.GetMe)
+	apiGroup.PATCH("/me", usersHandler.UpdateMe)
+	apiGroup.POST("/me/password", usersHandler.ChangeMyPassword)
 
 	// Swagger UI endpoint
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -104,6 +418,7 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down servers...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
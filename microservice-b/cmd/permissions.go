@@ -0,0 +1,74 @@
+package main
+
+import myMiddleware "microservice-b/middleware"
+
+// routePermissions is the permission each /api route requires; routes missing
+// here are admin only
+var routePermissions = myMiddleware.RoutePermissions{
+	"GET /api/sensors":                             myMiddleware.PermRead,
+	"GET /api/sensors/aggregate":                   myMiddleware.PermRead,
+	"GET /api/sensors/trash":                       myMiddleware.PermRead,
+	"GET /api/sensors/:id/history":                 myMiddleware.PermRead,
+	"PATCH /api/sensors":                           myMiddleware.PermWrite,
+	"POST /api/sensors/restore":                    myMiddleware.PermWrite,
+	"POST /api/sensors/changes/:change_set/revert": myMiddleware.PermWrite,
+	"DELETE /api/sensors":                          myMiddleware.PermDelete,
+	"GET /api/stream":                              myMiddleware.PermRead,
+	"GET /api/stream/ws":                           myMiddleware.PermRead,
+	"GET /api/devices":                             myMiddleware.PermRead,
+	"POST /api/devices/:id1/:id2/frequency":        myMiddleware.PermAdmin,
+	"POST /api/devices/:id1/:id2/pause":            myMiddleware.PermAdmin,
+	"POST /api/devices/:id1/:id2/resume":           myMiddleware.PermAdmin,
+	"PUT /api/devices/:id1/:id2/model":             myMiddleware.PermAdmin,
+	"POST /api/devices/:id1/:id2/flush":            myMiddleware.PermAdmin,
+	"POST /api/device-tokens":                      myMiddleware.PermAdmin,
+	"GET /api/device-tokens":                       myMiddleware.PermAdmin,
+	"DELETE /api/device-tokens/:id":                myMiddleware.PermAdmin,
+	"GET /api/alerts":                              myMiddleware.PermRead,
+	"GET /api/alerts/rules":                        myMiddleware.PermRead,
+	"POST /api/alerts/rules":                       myMiddleware.PermAdmin,
+	"PATCH /api/alerts/rules/:id":                  myMiddleware.PermAdmin,
+	"DELETE /api/alerts/rules/:id":                 myMiddleware.PermAdmin,
+	"GET /api/anomalies":                           myMiddleware.PermRead,
+	"GET /api/webhooks":                            myMiddleware.PermAdmin,
+	"POST /api/webhooks":                           myMiddleware.PermAdmin,
+	"PATCH /api/webhooks/:id":                      myMiddleware.PermAdmin,
+	"DELETE /api/webhooks/:id":                     myMiddleware.PermAdmin,
+	"POST /api/webhooks/:id/test":                  myMiddleware.PermAdmin,
+	"GET /api/webhooks/:id/deliveries":             myMiddleware.PermAdmin,
+	"POST /api/webhooks/deliveries/:id/retry":      myMiddleware.PermAdmin,
+	"GET /api/retention/policies":                  myMiddleware.PermAdmin,
+	"POST /api/retention/policies":                 myMiddleware.PermAdmin,
+	"POST /api/retention/policies/dry-run":         myMiddleware.PermAdmin,
+	"DELETE /api/retention/policies/:id":           myMiddleware.PermAdmin,
+	"GET /api/audit":                               myMiddleware.PermAdmin,
+	"GET /api/users":                               myMiddleware.PermAdmin,
+	"GET /api/users/:id":                           myMiddleware.PermAdmin,
+	"PATCH /api/users/:id":                         myMiddleware.PermAdmin,
+	"POST /api/users/:id/archive":                  myMiddleware.PermAdmin,
+	"POST /api/users/:id/unarchive":                myMiddleware.PermAdmin,
+	"POST /api/users/:id/password-reset":           myMiddleware.PermAdmin,
+	"POST /api/invitations":                        myMiddleware.PermAdmin,
+	"GET /api/invitations":                         myMiddleware.PermAdmin,
+	"DELETE /api/invitations/:id":                  myMiddleware.PermAdmin,
+	"POST /api/keys":                               myMiddleware.PermSelf,
+	"GET /api/keys":                                myMiddleware.PermSelf,
+	"DELETE /api/keys/:id":                         myMiddleware.PermSelf,
+	"GET /api/me":                                  myMiddleware.PermSelf,
+	"PATCH /api/me":                                myMiddleware.PermSelf,
+	"POST /api/me/password":                        myMiddleware.PermSelf,
+}
+
+// id1Routes are the routes an API key with an id1 allowlist may call, as they
+// select readings by the id1 query parameter
+var id1Routes = myMiddleware.ID1Routes{
+	"GET /api/sensors":           true,
+	"GET /api/sensors/aggregate": true,
+	"GET /api/sensors/trash":     true,
+	"PATCH /api/sensors":         true,
+	"DELETE /api/sensors":        true,
+	"GET /api/stream":            true,
+	"GET /api/stream/ws":         true,
+	"GET /api/alerts":            true,
+	"GET /api/anomalies":         true,
+}
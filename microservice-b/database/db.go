@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -66,7 +67,13 @@ func findMigrationsFolderRoot() string {
 
 // migrateUp applies pending migrations
 func migrateUp(db *sqlx.DB, dsn string) error {
-	sqlDB := db.DB
+	// migration files may hold several statements; only the migration connection allows that
+	sqlDB, err := sql.Open("mysql", dsn+"&multiStatements=true")
+	if err != nil {
+		return fmt.Errorf("open migration connection failed: %w", err)
+	}
+	defer sqlDB.Close()
+
 	driver, err := mysql.WithInstance(sqlDB, &mysql.Config{})
 	if err != nil {
 		return fmt.Errorf("create DB driver failed: %w", err)
@@ -0,0 +1,9 @@
+CREATE TABLE sensor_sequences (
+                                  id1 VARCHAR(16) NOT NULL,
+                                  id2 INT NOT NULL,
+                                  producer_id VARCHAR(64) NOT NULL,
+                                  last_seq BIGINT UNSIGNED NOT NULL,
+                                  updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+                                  PRIMARY KEY (id1, id2, producer_id),
+                                  INDEX IX_producer (producer_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
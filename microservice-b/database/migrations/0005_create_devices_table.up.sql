@@ -0,0 +1,15 @@
+CREATE TABLE devices (
+                         id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                         id1 VARCHAR(16) NOT NULL,
+                         id2 INT NOT NULL,
+                         sensor_type VARCHAR(32) NOT NULL,
+                         unit VARCHAR(16) NOT NULL DEFAULT '',
+                         firmware VARCHAR(64) NOT NULL DEFAULT '',
+                         producer_id VARCHAR(64) NOT NULL DEFAULT '',
+                         remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+                         connected BOOLEAN NOT NULL DEFAULT FALSE,
+                         first_seen DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                         last_seen DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                         UNIQUE INDEX UX_id_combo (id1, id2),
+                         INDEX IX_producer (producer_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,6 @@
+ALTER TABLE sensor_readings DROP INDEX IX_created_at;
+DROP TABLE IF EXISTS rollup_dirty;
+DROP TABLE IF EXISTS rollup_state;
+DROP TABLE IF EXISTS sensor_rollups_1d;
+DROP TABLE IF EXISTS sensor_rollups_1h;
+DROP TABLE IF EXISTS sensor_rollups_1m;
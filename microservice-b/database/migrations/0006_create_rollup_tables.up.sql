@@ -0,0 +1,34 @@
+CREATE TABLE sensor_rollups_1m (
+                                   id1 VARCHAR(16) NOT NULL,
+                                   id2 INT NOT NULL,
+                                   sensor_type VARCHAR(32) NOT NULL,
+                                   bucket DATETIME(6) NOT NULL,
+                                   min_value DOUBLE NOT NULL,
+                                   max_value DOUBLE NOT NULL,
+                                   sum_value DOUBLE NOT NULL,
+                                   cnt BIGINT UNSIGNED NOT NULL,
+                                   PRIMARY KEY (id1, id2, bucket),
+                                   INDEX IX_bucket (bucket)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE sensor_rollups_1h LIKE sensor_rollups_1m;
+
+CREATE TABLE sensor_rollups_1d LIKE sensor_rollups_1m;
+
+-- watermark: every bucket before it is rolled up; late_scan: last scan for late readings
+CREATE TABLE rollup_state (
+                              name VARCHAR(16) NOT NULL PRIMARY KEY,
+                              watermark DATETIME(6) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- ranges whose rollups must be recomputed after edits and deletes; NULL means unbounded
+CREATE TABLE rollup_dirty (
+                              id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                              id1 VARCHAR(16) NULL DEFAULT NULL,
+                              id2 INT NULL DEFAULT NULL,
+                              from_ts DATETIME(6) NULL DEFAULT NULL,
+                              to_ts DATETIME(6) NULL DEFAULT NULL,
+                              created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE sensor_readings ADD INDEX IX_created_at (created_at);
@@ -0,0 +1,12 @@
+CREATE TABLE retention_policies (
+                                    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                    name VARCHAR(255) NOT NULL,
+                                    sensor_type VARCHAR(32) NULL DEFAULT NULL,
+                                    id1 VARCHAR(16) NULL DEFAULT NULL,
+                                    id2 INT NULL DEFAULT NULL,
+                                    archive_after_days INT NULL DEFAULT NULL,
+                                    purge_after_days INT NULL DEFAULT NULL,
+                                    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+                                    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                    updated_at DATETIME(6) NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE sensor_readings
+    DROP INDEX IX_delete_batch,
+    DROP COLUMN delete_batch;
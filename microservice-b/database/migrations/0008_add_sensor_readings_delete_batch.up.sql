@@ -0,0 +1,3 @@
+ALTER TABLE sensor_readings
+    ADD COLUMN delete_batch VARCHAR(32) NULL DEFAULT NULL AFTER archived_at,
+    ADD INDEX IX_delete_batch (delete_batch);
@@ -0,0 +1,13 @@
+CREATE TABLE sensor_reading_revisions (
+                                          id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                          change_set VARCHAR(32) NOT NULL,
+                                          reading_id BIGINT UNSIGNED NOT NULL,
+                                          action VARCHAR(16) NOT NULL,
+                                          old_value DOUBLE NULL DEFAULT NULL,
+                                          new_value DOUBLE NULL DEFAULT NULL,
+                                          user_id BIGINT UNSIGNED NULL DEFAULT NULL,
+                                          reason VARCHAR(512) NOT NULL DEFAULT '',
+                                          created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                          INDEX IX_reading (reading_id),
+                                          INDEX IX_change_set (change_set)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,24 @@
+CREATE TABLE audit_events (
+                              id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                              user_id BIGINT UNSIGNED NULL DEFAULT NULL,
+                              email VARCHAR(255) NOT NULL DEFAULT '',
+                              role VARCHAR(32) NOT NULL DEFAULT '',
+                              action VARCHAR(255) NOT NULL,
+                              path VARCHAR(1024) NOT NULL,
+                              params TEXT NULL,
+                              status INT NOT NULL,
+                              affected BIGINT NULL DEFAULT NULL,
+                              remote_ip VARCHAR(64) NOT NULL DEFAULT '',
+                              created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                              INDEX IX_created_at (created_at),
+                              INDEX IX_user (user_id, created_at),
+                              INDEX IX_action (action, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- with binary logging on, creating triggers without the SUPER privilege needs
+-- log_bin_trust_function_creators=1 on the server (set in docker-compose.yml)
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
@@ -0,0 +1,19 @@
+CREATE TABLE refresh_tokens (
+                                id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                user_id BIGINT UNSIGNED NOT NULL,
+                                token_hash CHAR(64) NOT NULL,
+                                access_jti VARCHAR(32) NOT NULL,
+                                access_expires_at DATETIME(6) NOT NULL,
+                                expires_at DATETIME(6) NOT NULL,
+                                revoked_at DATETIME(6) NULL DEFAULT NULL,
+                                created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                UNIQUE INDEX UX_token_hash (token_hash),
+                                INDEX IX_user (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE revoked_tokens (
+                                jti VARCHAR(32) PRIMARY KEY,
+                                expires_at DATETIME(6) NOT NULL,
+                                created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                INDEX IX_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
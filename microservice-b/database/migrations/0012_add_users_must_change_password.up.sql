@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER role;
@@ -0,0 +1,14 @@
+CREATE TABLE invitations (
+                             id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                             token_hash CHAR(64) NOT NULL,
+                             email VARCHAR(255) NOT NULL,
+                             role ENUM('admin','analyst') NOT NULL DEFAULT 'analyst',
+                             created_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                             expires_at DATETIME(6) NOT NULL,
+                             accepted_at DATETIME(6) NULL DEFAULT NULL,
+                             accepted_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                             revoked_at DATETIME(6) NULL DEFAULT NULL,
+                             created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                             UNIQUE INDEX UX_token_hash (token_hash),
+                             INDEX IX_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,16 @@
+CREATE TABLE api_keys (
+                          id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                          name VARCHAR(100) NOT NULL,
+                          prefix VARCHAR(16) NOT NULL,
+                          key_hash CHAR(64) NOT NULL,
+                          user_id BIGINT UNSIGNED NULL DEFAULT NULL,
+                          scopes VARCHAR(255) NOT NULL,
+                          id1_allowlist VARCHAR(1024) NULL DEFAULT NULL,
+                          created_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                          expires_at DATETIME(6) NULL DEFAULT NULL,
+                          last_used_at DATETIME(6) NULL DEFAULT NULL,
+                          revoked_at DATETIME(6) NULL DEFAULT NULL,
+                          created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                          UNIQUE INDEX UX_key_hash (key_hash),
+                          INDEX IX_user (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,12 @@
+CREATE TABLE device_tokens (
+                               id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                               name VARCHAR(100) NOT NULL,
+                               prefix VARCHAR(16) NOT NULL,
+                               token_hash CHAR(64) NOT NULL,
+                               sensors TEXT NOT NULL,
+                               created_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                               last_used_at DATETIME(6) NULL DEFAULT NULL,
+                               revoked_at DATETIME(6) NULL DEFAULT NULL,
+                               created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                               UNIQUE INDEX UX_token_hash (token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,38 @@
+CREATE TABLE alert_rules (
+                             id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                             name VARCHAR(255) NOT NULL,
+                             type VARCHAR(16) NOT NULL,
+                             sensor_type VARCHAR(32) NULL DEFAULT NULL,
+                             id1 VARCHAR(16) NULL DEFAULT NULL,
+                             id2 INT NULL DEFAULT NULL,
+                             operator VARCHAR(2) NULL DEFAULT NULL,
+                             threshold DOUBLE NULL DEFAULT NULL,
+                             min_value DOUBLE NULL DEFAULT NULL,
+                             max_value DOUBLE NULL DEFAULT NULL,
+                             hysteresis DOUBLE NOT NULL DEFAULT 0,
+                             for_seconds INT NOT NULL DEFAULT 0,
+                             severity VARCHAR(16) NOT NULL DEFAULT 'warning',
+                             enabled BOOLEAN NOT NULL DEFAULT TRUE,
+                             created_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                             created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                             updated_at DATETIME(6) NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE alerts (
+                        id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                        rule_id BIGINT UNSIGNED NOT NULL,
+                        rule_name VARCHAR(255) NOT NULL,
+                        severity VARCHAR(16) NOT NULL,
+                        id1 VARCHAR(16) NOT NULL,
+                        id2 INT NOT NULL,
+                        sensor_type VARCHAR(32) NOT NULL DEFAULT '',
+                        state VARCHAR(16) NOT NULL,
+                        value DOUBLE NULL DEFAULT NULL,
+                        message VARCHAR(255) NOT NULL,
+                        fired_at DATETIME(6) NOT NULL,
+                        resolved_at DATETIME(6) NULL DEFAULT NULL,
+                        INDEX IX_state (state),
+                        INDEX IX_rule (rule_id, state),
+                        INDEX IX_sensor (id1, id2),
+                        INDEX IX_fired_at (fired_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
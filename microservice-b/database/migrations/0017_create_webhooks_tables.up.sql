@@ -0,0 +1,28 @@
+CREATE TABLE webhooks (
+                          id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                          name VARCHAR(255) NOT NULL,
+                          url VARCHAR(2048) NOT NULL,
+                          events VARCHAR(255) NOT NULL,
+                          secret VARCHAR(255) NOT NULL,
+                          enabled BOOLEAN NOT NULL DEFAULT TRUE,
+                          created_by BIGINT UNSIGNED NULL DEFAULT NULL,
+                          created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                          updated_at DATETIME(6) NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE webhook_deliveries (
+                                    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                    webhook_id BIGINT UNSIGNED NOT NULL,
+                                    event_id CHAR(32) NOT NULL,
+                                    event_type VARCHAR(32) NOT NULL,
+                                    payload MEDIUMTEXT NOT NULL,
+                                    status VARCHAR(16) NOT NULL,
+                                    attempts INT NOT NULL DEFAULT 0,
+                                    next_attempt_at DATETIME(6) NULL DEFAULT NULL,
+                                    last_status_code INT NULL DEFAULT NULL,
+                                    last_error VARCHAR(1024) NULL DEFAULT NULL,
+                                    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                    delivered_at DATETIME(6) NULL DEFAULT NULL,
+                                    INDEX IX_due (status, next_attempt_at),
+                                    INDEX IX_webhook (webhook_id, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
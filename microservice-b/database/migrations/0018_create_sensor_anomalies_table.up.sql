@@ -0,0 +1,17 @@
+CREATE TABLE sensor_anomalies (
+                                  id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                  id1 VARCHAR(16) NOT NULL,
+                                  id2 INT NOT NULL,
+                                  sensor_type VARCHAR(32) NOT NULL DEFAULT '',
+                                  value DOUBLE NOT NULL,
+                                  ts DATETIME(6) NOT NULL,
+                                  method VARCHAR(16) NOT NULL,
+                                  score DOUBLE NOT NULL,
+                                  expected DOUBLE NOT NULL,
+                                  z_rolling DOUBLE NULL DEFAULT NULL,
+                                  z_ewma DOUBLE NULL DEFAULT NULL,
+                                  z_seasonal DOUBLE NULL DEFAULT NULL,
+                                  created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+                                  INDEX IX_combo_ts (id1, id2, ts),
+                                  INDEX IX_ts (ts)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
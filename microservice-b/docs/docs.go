@@ -15,51 +15,60 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/sensors": {
+        "/api/alerts": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
                     }
                 ],
-                "description": "This endpoint retrieves sensor readings from the database.You can filter results by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, or by a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).You can also combine filters (e.g., ID1 + time range).Pagination is supported via ` + "`" + `page` + "`" + ` and ` + "`" + `limit` + "`" + ` query parameters.- ` + "`" + `page` + "`" + `: Page number starting from 1- ` + "`" + `limit` + "`" + `: Number of records per page (default: 10) Time parameters must be in RFC3339 format (UTC). Example: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the alerts fired by the rules, most recently fired first. An alert is ` + "`" + `firing` + "`" + ` until its sensor is back within the limit of the rule, or until the rule is changed, disabled or deleted; then it is ` + "`" + `resolved` + "`" + `.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "MicroserviceB"
+                    "Alerts"
                 ],
-                "summary": "Retrieve sensor readings with filters",
+                "summary": "List alerts",
                 "parameters": [
                     {
+                        "enum": [
+                            "firing",
+                            "resolved"
+                        ],
                         "type": "string",
-                        "example": "\"A\"",
-                        "description": "Filter by ID1 (string identifier)",
-                        "name": "id1",
+                        "description": "Filter by state",
+                        "name": "state",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "example": 1,
-                        "description": "Filter by ID2 (integer identifier)",
-                        "name": "id2",
+                        "description": "Filter by rule",
+                        "name": "rule_id",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "example": "\"2025-09-06T10:00:00Z\"",
-                        "description": "Filter from timestamp (RFC3339 format)",
-                        "name": "from",
+                        "example": "\"critical\"",
+                        "description": "Filter by severity",
+                        "name": "severity",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "example": "\"2025-09-06T12:00:00Z\"",
-                        "description": "Filter to timestamp (RFC3339 format)",
-                        "name": "to",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2",
+                        "name": "id2",
                         "in": "query"
                     },
                     {
@@ -81,96 +90,80 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Paginated sensor readings with metadata",
+                        "description": "Paginated alerts with metadata",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/alerts/rules": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
                     }
                 ],
-                "description": "This endpoint deletes sensor readings from the database.You can filter records by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, or by a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).You can also combine filters (e.g., ID1 + time range).Time parameters must be in RFC3339 format (UTC).Example: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `If no filters are provided, **no rows will be deleted**.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the alert rules, oldest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "MicroserviceB"
-                ],
-                "summary": "Delete sensor readings with filters",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "example": "\"A\"",
-                        "description": "Filter by ID1 (string identifier)",
-                        "name": "id1",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "example": 1,
-                        "description": "Filter by ID2 (integer identifier)",
-                        "name": "id2",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "example": "\"2025-09-06T10:00:00Z\"",
-                        "description": "Filter from timestamp (RFC3339 format)",
-                        "name": "from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "example": "\"2025-09-06T12:00:00Z\"",
-                        "description": "Filter to timestamp (RFC3339 format)",
-                        "name": "to",
-                        "in": "query"
-                    }
+                    "Alerts"
                 ],
+                "summary": "List alert rules",
                 "responses": {
                     "200": {
-                        "description": "Number of deleted rows, e.g. {\\\"deleted\\\": 3}",
+                        "description": "Rules, e.g. {\\\"data\\\": [...], \\\"total\\\": 2}",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Internal server error, e.g. {\\\"error\\\": \\\"database failure\\\"}",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             },
-            "patch": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "This endpoint allows updating sensor values based on optional filters such as ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, and a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).If no filters are provided, no rows will be updated.Time parameters should be provided in RFC3339 format (UTC).Example time format: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `",
+                "description": "Creates a rule for a sensor type, an id1 or an id1/id2 pair, or for all sensors when none is given. Types: ` + "`" + `threshold` + "`" + ` fires when the value compares to ` + "`" + `threshold` + "`" + ` by ` + "`" + `operator` + "`" + ` (` + "`" + `\u003e` + "`" + `, ` + "`" + `\u003e=` + "`" + `, ` + "`" + `\u003c` + "`" + `, ` + "`" + `\u003c=` + "`" + `); ` + "`" + `range` + "`" + ` fires when the value leaves [` + "`" + `min_value` + "`" + `, ` + "`" + `max_value` + "`" + `]; ` + "`" + `rate` + "`" + ` fires when the change per minute between consecutive readings of a sensor compares to ` + "`" + `threshold` + "`" + ` by ` + "`" + `operator` + "`" + ` (e.g. ` + "`" + `\u003c` + "`" + ` -5 for a drop faster than 5 per minute); ` + "`" + `absence` + "`" + ` fires when a sensor that reported before sends no reading for ` + "`" + `for_seconds` + "`" + `; ` + "`" + `anomaly` + "`" + ` fires when the anomaly score of a reading, its distance from the baselines of its sensor in stddevs (see ` + "`" + `GET /api/anomalies` + "`" + `), reaches ` + "`" + `threshold` + "`" + `. Other rules fire once the condition held for ` + "`" + `for_seconds` + "`" + ` (default 0) and resolve once the value is back within the limit by ` + "`" + `hysteresis` + "`" + `. Rules are evaluated on every stored reading; absence rules every ` + "`" + `ALERT_INTERVAL` + "`" + `.",
                 "consumes": [
                     "application/json"
                 ],
@@ -178,130 +171,113 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "MicroserviceB"
+                    "Alerts"
                 ],
-                "summary": "Update sensor readings values with filters",
+                "summary": "Create an alert rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "example": "\"A\"",
-                        "description": "Filter by ID1 (string identifier)",
-                        "name": "id1",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "example": "1",
-                        "description": "Filter by ID2 (integer identifier)",
-                        "name": "id2",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Start timestamp in RFC3339 format (e.g., 2025-09-06T10:00:00Z)",
-                        "name": "from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "End timestamp in RFC3339 format (e.g., 2025-09-06T12:00:00Z)",
-                        "name": "to",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Sensor update request payload",
+                        "description": "Alert rule",
                         "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.EditSensorsRequest"
+                            "$ref": "#/definitions/model.AlertRuleRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Number of updated rows, e.g. {\\\"updated\\\": 5}",
+                    "201": {
+                        "description": "Created rule",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.AlertRule"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body, e.g. {\\\"error\\\": \\\"invalid body\\\"}",
+                        "description": "Invalid rule",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error, e.g. {\\\"error\\\": \\\"database failure\\\"}",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Authenticates a user by validating the provided email and password. Returns a JWT token upon successful login.",
-                "consumes": [
-                    "application/json"
+        "/api/alerts/rules/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Deletes a rule and resolves its firing alerts; its alerts stay listed",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Alerts"
                 ],
-                "summary": "Authenticate a user and return a JWT token",
+                "summary": "Delete an alert rule",
                 "parameters": [
                     {
-                        "description": "User login credentials payload",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Login"
-                        }
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "JWT token response",
+                        "description": "e.g. {\\\"deleted\\\": 1}",
                         "schema": {
-                            "$ref": "#/definitions/model.LoginResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "invalid request payload\"}",
+                        "description": "Invalid rule id",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "invalid credentials\"}",
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "something went wrong\"}",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/signup": {
-            "post": {
-                "description": "Register a new user with email, password, optional name fields, and role. The email must be unique. Passwords must be between 6 and 60 characters. Role defaults to \"analyst\" if not provided.",
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the given fields of a rule. Changing its condition or disabling it resolves its firing alerts; the rule is then evaluated from scratch.",
                 "consumes": [
                     "application/json"
                 ],
@@ -309,35 +285,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Alerts"
                 ],
-                "summary": "Create a new user account",
+                "summary": "Update an alert rule",
                 "parameters": [
                     {
-                        "description": "User signup payload",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.SignupRequest"
+                            "$ref": "#/definitions/model.AlertRuleRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User created successfully",
+                    "200": {
+                        "description": "Updated rule",
                         "schema": {
-                            "$ref": "#/definitions/model.SignupResponse"
+                            "$ref": "#/definitions/model.AlertRule"
                         }
                     },
                     "400": {
-                        "description": "Invalid request or validation failed",
+                        "description": "Invalid rule",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "User with email already exists",
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -350,49 +339,4189 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.EditSensorsRequest": {
-            "type": "object",
-            "properties": {
-                "value": {
-                    "type": "number"
-                }
-            }
-        },
-        "model.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
-                },
-                "details": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "model.Login": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                }
-            }
-        },
-        "model.LoginResponse": {
-            "type": "object",
-            "properties": {
-                "token": {
-                    "type": "string"
-                }
-            }
+        },
+        "/api/anomalies": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the readings flagged as anomalous when they arrived, latest first. Every reading is scored against three baselines of its sensor built from the readings before it: ` + "`" + `rolling` + "`" + ` (mean and stddev of the last ` + "`" + `ANOMALY_WINDOW` + "`" + ` readings), ` + "`" + `ewma` + "`" + ` (exponentially weighted mean and variance of the same span) and ` + "`" + `seasonal` + "`" + ` (exponentially weighted mean and variance of the readings in the same UTC hour of day, with the same span). ` + "`" + `z_rolling` + "`" + `, ` + "`" + `z_ewma` + "`" + ` and ` + "`" + `z_seasonal` + "`" + ` are the distances from each baseline in stddevs; ` + "`" + `method` + "`" + `, ` + "`" + `score` + "`" + ` and ` + "`" + `expected` + "`" + ` are those of the largest one. A reading is anomalous from an absolute score of ` + "`" + `ANOMALY_THRESHOLD` + "`" + ` (default 4). Baselines score after 30 readings and are kept in memory, so they warm up again after a restart.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Anomalies"
+                ],
+                "summary": "List anomalous readings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Temperature\"",
+                        "description": "Filter by sensor type",
+                        "name": "sensor_type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "rolling",
+                            "ewma",
+                            "seasonal"
+                        ],
+                        "type": "string",
+                        "description": "Filter by the method with the largest deviation",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "example": 6,
+                        "description": "Only anomalies with at least this absolute score",
+                        "name": "min_score",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-08T00:00:00Z\"",
+                        "description": "Reading time from (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-09T00:00:00Z\"",
+                        "description": "Reading time to (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated anomalies with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the recorded API actions, newest first. Every authenticated request and every login attempt is recorded with the caller, the action (method and route), its path and query parameters, the response status and, for changes to readings, the number of affected rows.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"DELETE /api/sensors\"",
+                        "description": "Filter by action (method and route)",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated audit events with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/device-tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists device tokens, newest first, with their sensors and last use",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Tokens"
+                ],
+                "summary": "List device tokens",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include revoked tokens",
+                        "name": "include_revoked",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated device tokens with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a token a generator presents on the gRPC ingest port as ` + "`" + `authorization: Bearer \u003ctoken\u003e` + "`" + ` metadata. The token may only write readings of the listed ` + "`" + `id1/id2` + "`" + ` sensors; ` + "`" + `id1/*` + "`" + ` allows every sensor of an id1. Readings, registrations and control streams naming other sensors are rejected with PermissionDenied. The token is returned only in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Tokens"
+                ],
+                "summary": "Create a device token",
+                "parameters": [
+                    {
+                        "description": "Token settings",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateDeviceTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Device token and the token itself",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateDeviceTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/device-tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes a device token. Streams already open keep running; the generator is refused once it reconnects.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Tokens"
+                ],
+                "summary": "Revoke a device token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Device token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"revoked\\\": 3}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid device token id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No active token with this id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists every sensor known to the device registry with its sensor type, unit, firmware, remote address, first/last seen time and whether its generator has a control stream attached. A device is ` + "`" + `online` + "`" + ` while it sent readings or heartbeats recently, otherwise ` + "`" + `offline` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List registered devices",
+                "parameters": [
+                    {
+                        "enum": [
+                            "online",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Devices, e.g. {\\\"data\\\": [...], \\\"total\\\": 5}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id1}/{id2}/flush": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Makes the generator hosting the sensor deliver its spooled readings right away instead of waiting for batches to fill up.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Flush the spool of a generator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"1\"",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command applied",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No generator connected for the sensor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Generator rejected the command",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "504": {
+                        "description": "Generator did not reply in time",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id1}/{id2}/frequency": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pushes a frequency change over the control stream to the generator hosting the sensor and reports whether it was applied. The frequency is a Go duration, e.g. ` + "`" + `500ms` + "`" + ` or ` + "`" + `2s` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Change the frequency of a sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"1\"",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New frequency",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SetFrequencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command applied",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid frequency",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No generator connected for the sensor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Generator rejected the command",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "504": {
+                        "description": "Generator did not reply in time",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id1}/{id2}/model": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the value model (constant, uniform, gaussian, random_walk, sine, step, poisson) the generator uses for the sensor. Params are model specific, e.g. ` + "`" + `{\"mean\": \"21\", \"amplitude\": \"4\", \"period\": \"24h\"}` + "`" + ` for sine.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Change the value model of a sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"1\"",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Value model",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ValueModelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command applied",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No generator connected for the sensor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Generator rejected the command",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "504": {
+                        "description": "Generator did not reply in time",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id1}/{id2}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stops the generator from producing readings for the sensor until it is resumed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Pause a sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"1\"",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command applied",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No generator connected for the sensor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Generator rejected the command",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "504": {
+                        "description": "Generator did not reply in time",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id1}/{id2}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Resume a paused sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Sensor ID1",
+                        "name": "id1",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"1\"",
+                        "description": "Sensor ID2",
+                        "name": "id2",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command applied",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No generator connected for the sensor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Generator rejected the command",
+                        "schema": {
+                            "$ref": "#/definitions/control.Result"
+                        }
+                    },
+                    "504": {
+                        "description": "Generator did not reply in time",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists invitations, newest first, optionally filtered by email and status.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitations"
+                ],
+                "summary": "List invitations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invited email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "accepted",
+                            "revoked",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Invitation status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated invitations with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a single-use invitation for an email and role that expires after ` + "`" + `INVITATION_TTL` + "`" + ` (default 7 days). The token is returned only in this response; the invitee passes it as ` + "`" + `invite_token` + "`" + ` to ` + "`" + `POST /signup` + "`" + ` with the invited email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitations"
+                ],
+                "summary": "Invite a user",
+                "parameters": [
+                    {
+                        "description": "Invitee",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateInvitationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Invitation and its token",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateInvitationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User with email already exists",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes a pending invitation so its token can no longer be used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitations"
+                ],
+                "summary": "Revoke an invitation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"revoked\\\": 3}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid invitation id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Invitation already accepted or revoked",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the caller's API keys, newest first, with their last use. Admins list every key, service account keys included, with ` + "`" + `all=true` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API Keys"
+                ],
+                "summary": "List API keys",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "All users' keys (admins only)",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include revoked keys",
+                        "name": "include_revoked",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated API keys with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates an API key for the caller. Machine clients send it in the ` + "`" + `X-API-Key` + "`" + ` header or as a Bearer token instead of a JWT. Scopes (` + "`" + `sensors:read` + "`" + `, ` + "`" + `sensors:write` + "`" + `, ` + "`" + `sensors:delete` + "`" + `) cannot exceed the caller's role, and the key acts with its owner's current role. With ` + "`" + `id1_allowlist` + "`" + ` the key can only call the sensor routes filtered by ` + "`" + `id1` + "`" + ` with one of the listed values. Admins can create ` + "`" + `service_account` + "`" + ` keys owned by no user. The key is returned only in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API Keys"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "description": "Key settings",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key and the key itself",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Scope or service account not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes one of the caller's API keys; admins can revoke any key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API Keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"revoked\\\": 3}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid API key id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No active key with this id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Me"
+                ],
+                "summary": "Get own account",
+                "responses": {
+                    "200": {
+                        "description": "Own account",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the caller's first and last name; omitted fields are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Me"
+                ],
+                "summary": "Update own profile",
+                "parameters": [
+                    {
+                        "description": "Fields to change",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated account",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/password": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the caller's password after checking the current one. All sessions of the caller, including the current one, are revoked; log in again with the new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Me"
+                ],
+                "summary": "Change own password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "password changed",
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Current password is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/retention/policies": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the retention policies. A policy archives the readings it covers after ` + "`" + `archive_after_days` + "`" + ` and purges them after ` + "`" + `purge_after_days` + "`" + `; when several policies cover a reading, the most specific one (id1, then id2, then sensor_type) applies and ties go to the older policy.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "List retention policies",
+                "responses": {
+                    "200": {
+                        "description": "Policies, e.g. {\\\"data\\\": [...], \\\"total\\\": 2}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a retention policy for a sensor type, an id1 or an id1/id2 pair, or for all readings when none is given. At least one of ` + "`" + `archive_after_days` + "`" + ` and ` + "`" + `purge_after_days` + "`" + ` is required; purging must come after archiving.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Create a retention policy",
+                "parameters": [
+                    {
+                        "description": "Retention policy",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RetentionPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created policy",
+                        "schema": {
+                            "$ref": "#/definitions/model.RetentionPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid policy",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/retention/policies/dry-run": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reports per policy how many readings the retention job would archive and purge if it ran now, without changing anything. With a body, the given policy is evaluated as if it had been created, alongside the stored ones.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Dry-run retention policies",
+                "parameters": [
+                    {
+                        "description": "Candidate policy",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.RetentionPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Results, e.g. {\\\"data\\\": [{\\\"policy_id\\\": 1, \\\"archived\\\": 120, \\\"purged\\\": 0}]}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid candidate policy",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/retention/policies/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Delete a retention policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Policy ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"deleted\\\": 1}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Policy not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "This endpoint retrieves sensor readings from the database.You can filter results by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, or by a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).You can also combine filters (e.g., ID1 + time range).Pagination is supported via ` + "`" + `page` + "`" + ` and ` + "`" + `limit` + "`" + ` query parameters.- ` + "`" + `page` + "`" + `: Page number starting from 1- ` + "`" + `limit` + "`" + `: Number of records per page (default: 10) Time parameters must be in RFC3339 format (UTC). Example: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Retrieve sensor readings with filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Include readings archived by retention policies",
+                        "name": "include_archived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated sensor readings with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "This endpoint moves sensor readings to the trash under a new delete batch; they can be restored with ` + "`" + `POST /api/sensors/restore` + "`" + ` until the trash is purged.You can filter records by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, or by a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).You can also combine filters (e.g., ID1 + time range).Time parameters must be in RFC3339 format (UTC).Example: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `If no filters are provided, **no rows will be deleted**.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Delete sensor readings with filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason recorded in the revision history",
+                        "name": "reason",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of deleted rows and their delete batch, e.g. {\\\"deleted\\\": 3, \\\"batch_id\\\": \\\"9f86d081884c7d659a2feaa0c55ad015\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error, e.g. {\\\"error\\\": \\\"database failure\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "This endpoint allows updating sensor values based on optional filters such as ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + `, and a time range (` + "`" + `from` + "`" + `, ` + "`" + `to` + "`" + `).If no filters are provided, no rows will be updated.Time parameters should be provided in RFC3339 format (UTC).Example time format: ` + "`" + `2025-09-06T15:04:05Z` + "`" + `",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Update sensor readings values with filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "1",
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp in RFC3339 format (e.g., 2025-09-06T10:00:00Z)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp in RFC3339 format (e.g., 2025-09-06T12:00:00Z)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Sensor update request payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.EditSensorsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of updated rows and the change set recording them, e.g. {\\\"updated\\\": 5, \\\"change_set\\\": \\\"...\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, e.g. {\\\"error\\\": \\\"invalid body\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error, e.g. {\\\"error\\\": \\\"database failure\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors/aggregate": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Buckets sensor readings by time and computes aggregation functions per bucket in the database. Functions: ` + "`" + `avg` + "`" + `, ` + "`" + `min` + "`" + `, ` + "`" + `max` + "`" + `, ` + "`" + `sum` + "`" + `, ` + "`" + `count` + "`" + `, ` + "`" + `first` + "`" + `, ` + "`" + `last` + "`" + `, ` + "`" + `stddev` + "`" + ` and percentiles like ` + "`" + `p50` + "`" + `, ` + "`" + `p95` + "`" + `, ` + "`" + `p99.9` + "`" + `. Results can be grouped by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + ` and ` + "`" + `sensor_type` + "`" + ` and filtered like ` + "`" + `GET /api/sensors` + "`" + `. With ` + "`" + `fill` + "`" + `, empty buckets between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` are returned with ` + "`" + `null` + "`" + ` values, the ` + "`" + `previous` + "`" + ` bucket's values or a ` + "`" + `linear` + "`" + ` interpolation; ` + "`" + `count` + "`" + ` is 0 for empty buckets. Queries using only ` + "`" + `avg` + "`" + `, ` + "`" + `min` + "`" + `, ` + "`" + `max` + "`" + `, ` + "`" + `sum` + "`" + ` and ` + "`" + `count` + "`" + ` are served from rollups, which keep the aggregates of readings archived or purged by retention policies; they always include archived readings, the other functions only with ` + "`" + `include_archived` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Aggregate sensor readings into time buckets",
+                "parameters": [
+                    {
+                        "enum": [
+                            "1m",
+                            "5m",
+                            "1h",
+                            "1d"
+                        ],
+                        "type": "string",
+                        "default": "1h",
+                        "description": "Bucket size",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "avg",
+                        "example": "\"avg,max,p95\"",
+                        "description": "Comma separated aggregation functions",
+                        "name": "agg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"id1,id2\"",
+                        "description": "Comma separated group columns (id1, id2, sensor_type)",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "null",
+                            "previous",
+                            "linear"
+                        ],
+                        "type": "string",
+                        "description": "Gap filling mode",
+                        "name": "fill",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Include readings archived by retention policies",
+                        "name": "include_archived",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Aggregated buckets, e.g. {\\\"bucket\\\": \\\"5m\\\", \\\"data\\\": [{\\\"bucket\\\": \\\"2025-09-06T10:00:00Z\\\", \\\"id1\\\": \\\"A\\\", \\\"values\\\": {\\\"avg\\\": 21.5}}]}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors/changes/{change_set}/revert": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Undoes the changes recorded under a change set, as a new change set. Edits and reverts are undone by restoring the previous value of every reading that still holds the value the change set gave it; deletes by restoring the delete batch from the trash. Readings changed again, restored or purged since are reported as conflicts and left alone.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Revert a change set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Change set",
+                        "name": "change_set",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the revert",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.RevertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of the revert",
+                        "schema": {
+                            "$ref": "#/definitions/model.RevertResult"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Change set not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Change set cannot be reverted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves readings out of the trash, either a whole delete batch (` + "`" + `batch_id` + "`" + ` in the body) or the trashed readings matching the query filters. At least one of them is required.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Restore deleted sensor readings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Delete batch to restore",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.RestoreSensorsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of restored rows and the change set recording them, e.g. {\\\"restored\\\": 3, \\\"change_set\\\": \\\"...\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Neither batch id nor filters given",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors/trash": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the readings in the trash, most recently deleted first. ` + "`" + `archived_at` + "`" + ` is the deletion time and ` + "`" + `delete_batch` + "`" + ` the batch id returned by ` + "`" + `DELETE /api/sensors` + "`" + `. Trashed readings are purged once the trash grace period has passed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "List deleted sensor readings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by delete batch",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1 (string identifier)",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2 (integer identifier)",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Filter from timestamp (RFC3339 format)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T12:00:00Z\"",
+                        "description": "Filter to timestamp (RFC3339 format)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated trashed readings with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid time format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sensors/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists every change made to a reading, oldest first: edits, deletes, restores and reverts, with the old and new value, the user who made it and the reason given. Revisions sharing a ` + "`" + `change_set` + "`" + ` were made by the same request and can be reverted together.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MicroserviceB"
+                ],
+                "summary": "Revision history of a sensor reading",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reading ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Revisions, e.g. {\\\"data\\\": [...], \\\"total\\\": 2}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reading id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams readings as they are stored, as Server-Sent Events whose event name is the ` + "`" + `type` + "`" + ` of the JSON data: ` + "`" + `reading` + "`" + `, ` + "`" + `dropped` + "`" + ` (readings lost because the client was too slow, with their count), ` + "`" + `truncated` + "`" + `, ` + "`" + `heartbeat` + "`" + ` (every 15s) or ` + "`" + `error` + "`" + ` (the stream ends). Readings can be filtered by ` + "`" + `id1` + "`" + `, ` + "`" + `id2` + "`" + ` and ` + "`" + `sensor_type` + "`" + `; live readings carry no ` + "`" + `id` + "`" + `. With ` + "`" + `since` + "`" + ` the stored readings taken after it are replayed first, oldest first and at most 10000 (then ` + "`" + `truncated` + "`" + ` is sent). Every client has a bounded buffer: with ` + "`" + `on_slow=drop` + "`" + ` (default) readings that do not fit are dropped and counted, with ` + "`" + `on_slow=disconnect` + "`" + ` the stream ends. Browsers, which cannot set headers on EventSource requests, may pass the access token as ` + "`" + `access_token` + "`" + `.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Live"
+                ],
+                "summary": "Live stream of incoming readings (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Humidity\"",
+                        "description": "Filter by sensor type",
+                        "name": "sensor_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Replay readings taken after this time first (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "drop",
+                            "disconnect"
+                        ],
+                        "type": "string",
+                        "default": "drop",
+                        "description": "Slow consumer policy",
+                        "name": "on_slow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Access token, for clients that cannot set headers",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of events",
+                        "schema": {
+                            "$ref": "#/definitions/model.LiveEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stream/ws": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Same stream as ` + "`" + `GET /api/stream` + "`" + `, over a WebSocket: every event is a JSON text message with a ` + "`" + `type` + "`" + ` of ` + "`" + `reading` + "`" + `, ` + "`" + `dropped` + "`" + `, ` + "`" + `truncated` + "`" + `, ` + "`" + `heartbeat` + "`" + ` or ` + "`" + `error` + "`" + `. Messages sent by the client are ignored.",
+                "tags": [
+                    "Live"
+                ],
+                "summary": "Live stream of incoming readings (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"A\"",
+                        "description": "Filter by ID1",
+                        "name": "id1",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Filter by ID2",
+                        "name": "id2",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Humidity\"",
+                        "description": "Filter by sensor type",
+                        "name": "sensor_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"2025-09-06T10:00:00Z\"",
+                        "description": "Replay readings taken after this time first (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "drop",
+                            "disconnect"
+                        ],
+                        "type": "string",
+                        "default": "drop",
+                        "description": "Slow consumer policy",
+                        "name": "on_slow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Access token, for clients that cannot set headers",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching protocols, then a stream of events",
+                        "schema": {
+                            "$ref": "#/definitions/model.LiveEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists users ordered by id. ` + "`" + `q` + "`" + ` matches a substring of the email or the names; ` + "`" + `status` + "`" + ` selects active (default), archived or all users.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search email, first and last name",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "admin",
+                            "analyst"
+                        ],
+                        "type": "string",
+                        "description": "Filter by role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "active",
+                        "description": "active, archived or all",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated users with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the names and role of an active user; omitted fields are left unchanged. Changing the role logs the user out everywhere. Admins cannot change their own role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Update a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated user",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/archive": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disables an account: the user can no longer log in and all their sessions are revoked. Admins cannot archive themselves.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Archive a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"archived\\\": 3}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot archive own account",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No active user with this id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/password-reset": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the user's password with a temporary one, returned once, and revokes all their sessions. After logging in with it the user can only use ` + "`" + `/api/me` + "`" + ` until they set a new password with ` + "`" + `POST /api/me/password` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Force a password reset",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Temporary password",
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordResetResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No active user with this id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/unarchive": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Re-enables an archived account; the user logs in with their previous password.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Unarchive a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"unarchived\\\": 3}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No archived user with this id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the webhooks, oldest first. Their secrets are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "Webhooks, e.g. {\\\"data\\\": [...], \\\"total\\\": 2}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Subscribes an http(s) URL to event types: ` + "`" + `alert.firing` + "`" + `, ` + "`" + `alert.resolved` + "`" + `, ` + "`" + `device.offline` + "`" + `, ` + "`" + `device.online` + "`" + `. Every event is POSTed as JSON ` + "`" + `{\"id\", \"type\", \"created_at\", \"data\"}` + "`" + ` with the headers ` + "`" + `X-Webhook-Event` + "`" + `, ` + "`" + `X-Webhook-Delivery` + "`" + `, ` + "`" + `X-Webhook-Timestamp` + "`" + ` (Unix seconds) and ` + "`" + `X-Webhook-Signature` + "`" + `: ` + "`" + `sha256=` + "`" + ` followed by the hex HMAC-SHA256 of ` + "`" + `\u003ctimestamp\u003e.\u003cbody\u003e` + "`" + ` keyed with the secret. A secret is generated when none is given; it is only returned here. Non-2xx answers are retried with exponential backoff from 30s up to 1h, ` + "`" + `WEBHOOK_MAX_ATTEMPTS` + "`" + ` times (default 8), after which the delivery is dead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created webhook with its secret",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateWebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/deliveries/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Queues a dead delivery again with a fresh set of attempts; the same payload is sent, signed anew",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Retry a dead webhook delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "e.g. {\\\"queued\\\": 12}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid delivery id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is not dead",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a webhook with its delivery history; pending deliveries are dropped",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "e.g. {\\\"deleted\\\": 1}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the given fields of a webhook; a new ` + "`" + `secret` + "`" + ` rotates it. Pending deliveries of a disabled webhook are dead and can be retried once it is enabled again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated webhook",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the deliveries of a webhook, newest first, with their attempts and the status code or error of the last one. A delivery is ` + "`" + `pending` + "`" + ` until it is ` + "`" + `delivered` + "`" + ` or, after its last attempt failed, ` + "`" + `dead` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "example": 1,
+                        "description": "Page number (starting from 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "example": 10,
+                        "description": "Page size (number of records per page)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paginated deliveries with metadata",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/test": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sends a signed ` + "`" + `webhook.test` + "`" + ` event to the webhook right away, enabled or not, and returns the delivery: ` + "`" + `delivered` + "`" + `, or ` + "`" + `dead` + "`" + ` with the status code or error of the single attempt. The test is listed in the delivery history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Test a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of the test delivery",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook id",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permission",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Authenticates a user by validating the provided email and password. Returns a short-lived JWT access token and a refresh token that ` + "`" + `POST /refresh` + "`" + ` exchanges for new ones.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Authenticate a user and return a JWT token",
+                "parameters": [
+                    {
+                        "description": "User login credentials payload",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Login"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Access and refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request payload\"}",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid credentials\"}",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong\"}",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the access token of the request and, when given, the refresh token of the session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Log out",
+                "parameters": [
+                    {
+                        "description": "Refresh token of the session",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "logged out",
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "Returns a new access token and a new refresh token; the refresh token presented is revoked. Reusing a revoked refresh token revokes every session of its user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Exchange a refresh token for new tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Access and refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/signup": {
+            "post": {
+                "description": "Register a new user with email, password, optional name fields, and role. The email must be unique. Passwords must be between 6 and 60 characters. Role defaults to \"analyst\" if not provided.\nDepends on ` + "`" + `SIGNUP_MODE` + "`" + `: ` + "`" + `invite` + "`" + ` (default) requires the ` + "`" + `invite_token` + "`" + ` of a pending invitation for the email, ` + "`" + `open` + "`" + ` lets anyone sign up as an analyst, ` + "`" + `disabled` + "`" + ` rejects every signup. With an invitation the user gets the invited role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Create a new user account",
+                "parameters": [
+                    {
+                        "description": "User signup payload",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.SignupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Signup disabled, invitation required or invalid, or admin role without invitation",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User with email already exists",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "control.Result": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean"
+                },
+                "command_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AlertRule": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "for_seconds": {
+                    "description": "for absence rules the silence that fires the alert",
+                    "type": "integer"
+                },
+                "hysteresis": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "id1": {
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "integer"
+                },
+                "max_value": {
+                    "type": "number"
+                },
+                "min_value": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "operator": {
+                    "description": "\u003e, \u003e=, \u003c or \u003c= for threshold and rate rules",
+                    "type": "string"
+                },
+                "sensor_type": {
+                    "type": "string"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "for rate rules a change per minute, for anomaly rules a z-score",
+                    "type": "number"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AlertRuleRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "defaults to true",
+                    "type": "boolean"
+                },
+                "for_seconds": {
+                    "type": "integer",
+                    "example": 60
+                },
+                "hysteresis": {
+                    "type": "number",
+                    "example": 2
+                },
+                "id1": {
+                    "type": "string",
+                    "example": "A"
+                },
+                "id2": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "max_value": {
+                    "type": "number"
+                },
+                "min_value": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "hot boiler"
+                },
+                "operator": {
+                    "type": "string",
+                    "enum": [
+                        "\u003e",
+                        "\u003e=",
+                        "\u003c",
+                        "\u003c="
+                    ],
+                    "example": "\u003e"
+                },
+                "sensor_type": {
+                    "type": "string",
+                    "example": "Temperature"
+                },
+                "severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ],
+                    "example": "critical"
+                },
+                "threshold": {
+                    "type": "number",
+                    "example": 80
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "threshold",
+                        "range",
+                        "rate",
+                        "absence",
+                        "anomaly"
+                    ],
+                    "example": "threshold"
+                }
+            }
+        },
+        "model.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "ExpiresAt is optional; keys without it never expire",
+                    "type": "string"
+                },
+                "id1_allowlist": {
+                    "description": "ID1Allowlist restricts the key to readings of these id1s",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "grafana"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "sensors:read"
+                    ]
+                },
+                "service_account": {
+                    "description": "ServiceAccount creates a key owned by no user (admins only)",
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "id1_allowlist": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key": {
+                    "type": "string",
+                    "example": "smk_1a2b3c4d_..."
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "smk_1a2b3c4d"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "sensors:read"
+                    ]
+                },
+                "user_id": {
+                    "description": "UserID is the owner whose role the key acts with; nil for service account keys",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.CreateDeviceTokenRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "humidity-generator"
+                },
+                "sensors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "A/1"
+                    ]
+                }
+            }
+        },
+        "model.CreateDeviceTokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "smd_1a2b3c4d"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "sensors": {
+                    "description": "Sensors are the id1/id2 pairs the token may write; id1/* allows every id2 of id1",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "A/1"
+                    ]
+                },
+                "token": {
+                    "type": "string",
+                    "example": "smd_1a2b3c4d_..."
+                }
+            }
+        },
+        "model.CreateInvitationRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "new.user@example.com"
+                },
+                "role": {
+                    "description": "defaults to analyst",
+                    "type": "string",
+                    "example": "analyst"
+                }
+            }
+        },
+        "model.CreateInvitationResponse": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
+                    "type": "string"
+                },
+                "accepted_by": {
+                    "description": "id of the user created",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "alert.firing"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "whsec_6f1c0e..."
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.EditSensorsRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "sensor recalibrated"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.LiveEvent": {
+            "type": "object",
+            "properties": {
+                "dropped": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "reading": {
+                    "$ref": "#/definitions/model.SensorReading"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "reading"
+                }
+            }
+        },
+        "model.Login": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "seconds until the access token expires",
+                    "type": "integer",
+                    "example": 900
+                },
+                "password_change_required": {
+                    "description": "PasswordChangeRequired is set after an admin password reset; until the\npassword is changed via POST /api/me/password only /api/me is accessible",
+                    "type": "boolean"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "access token",
+                    "type": "string"
+                }
+            }
+        },
+        "model.PasswordResetResponse": {
+            "type": "object",
+            "properties": {
+                "temporary_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RestoreSensorsRequest": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RetentionPolicy": {
+            "type": "object",
+            "properties": {
+                "archive_after_days": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "id1": {
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "purge_after_days": {
+                    "type": "integer"
+                },
+                "sensor_type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.RetentionPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "archive_after_days": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "enabled": {
+                    "description": "defaults to true",
+                    "type": "boolean"
+                },
+                "id1": {
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "raw temperature"
+                },
+                "purge_after_days": {
+                    "type": "integer",
+                    "example": 365
+                },
+                "sensor_type": {
+                    "type": "string",
+                    "example": "Temperature"
+                }
+            }
+        },
+        "model.RevertRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "wrong calibration offset"
+                }
+            }
+        },
+        "model.RevertResult": {
+            "type": "object",
+            "properties": {
+                "change_set": {
+                    "description": "change set recording the revert itself",
+                    "type": "string"
+                },
+                "conflicts": {
+                    "description": "readings changed again, purged or restored since",
+                    "type": "integer"
+                },
+                "reverted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SensorReading": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delete_batch": {
+                    "description": "set while in the trash; ArchivedAt is the deletion time",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "id1": {
+                    "type": "string"
+                },
+                "id2": {
+                    "type": "integer"
+                },
+                "sensor_type": {
+                    "type": "string"
+                },
+                "ts": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.SetFrequencyRequest": {
+            "type": "object",
+            "properties": {
+                "frequency": {
+                    "description": "Go duration",
+                    "type": "string",
+                    "example": "2s"
+                }
+            }
         },
         "model.SignupRequest": {
             "type": "object",
@@ -403,6 +4532,10 @@ const docTemplate = `{
                 "first_name": {
                     "type": "string"
                 },
+                "invite_token": {
+                    "description": "InviteToken is required when signup is invite-only; the invitation decides the role",
+                    "type": "string"
+                },
                 "last_name": {
                     "type": "string"
                 },
@@ -421,9 +4554,202 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UpdateUserRequest": {
+            "type": "object",
+            "properties": {
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "analyst"
+                }
+            }
+        },
+        "model.User": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_login": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "must_change_password": {
+                    "description": "set by an admin password reset",
+                    "type": "boolean"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ValueModelRequest": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "seed": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "sine"
+                }
+            }
+        },
+        "model.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "alert.firing"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "description": "set while pending",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "delivered"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "defaults to true",
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "alert.firing",
+                        "device.offline"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "on-call"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://oncall.example.com/hooks/sensors"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "BearerAuth": {
             "type": "apiKey",
             "name": "Authorization",
@@ -435,7 +4761,7 @@ const docTemplate = `{
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
-	Host:             "localhost:8081",
+	Host:             "localhost:8000",
 	BasePath:         "",
 	Schemes:          []string{},
 	Title:            "sensor-microservice-b",
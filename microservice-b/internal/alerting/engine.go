@@ -0,0 +1,466 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+)
+
+// Publisher is told about alerts that fire and resolve, e.g. to notify webhooks
+type Publisher interface {
+	Publish(eventType string, data interface{})
+}
+
+// DefaultInterval is how often the rules are reloaded and absence rules are evaluated
+const DefaultInterval = 30 * time.Second
+
+// Engine evaluates the enabled alert rules against incoming readings and fires
+// and resolves alerts for every sensor a rule covers. Threshold, range and rate
+// rules are evaluated on the ingest path; absence rules periodically against the
+// latest stored reading of each sensor.
+type Engine struct {
+	repo      *repository.AlertRepository
+	readings  *repository.SensorRepository
+	interval  time.Duration
+	now       func() time.Time
+	publisher Publisher
+
+	mu     sync.Mutex
+	rules  []model.AlertRule
+	states map[stateKey]*state
+	queue  []transition // decided under mu, stored and published by flush
+
+	// storeMu is held while the queued transitions are stored, so they are
+	// stored in order without holding up the evaluation of other readings
+	storeMu sync.Mutex
+}
+
+type stateKey struct {
+	rule   uint64
+	sensor string // id1/id2
+}
+
+// state is what the engine tracks per rule and sensor
+type state struct {
+	since     time.Time    // when the condition started to hold; zero while it does not
+	alert     *model.Alert // the firing alert, if any
+	lastValue float64      // the previous reading, for rate rules
+	lastTS    time.Time
+}
+
+// transition is an alert that fires or resolves
+type transition struct {
+	alert    *model.Alert // shared with the state while firing; its ID is set once stored
+	resolved *time.Time   // nil when the alert fires
+	st       *state       // to put the state back when storing a firing alert fails
+	since    time.Time
+}
+
+func New(repo *repository.AlertRepository, readings *repository.SensorRepository, interval time.Duration) *Engine {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Engine{repo: repo, readings: readings, interval: interval, now: time.Now, states: make(map[stateKey]*state)}
+}
+
+// SetPublisher makes the engine publish alert.firing and alert.resolved events to p
+func (e *Engine) SetPublisher(p Publisher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.publisher = p
+}
+
+// Start reloads the rules and evaluates the absence rules every interval until
+// ctx is cancelled
+func (e *Engine) Start(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Load(); err != nil {
+			log.Printf("alerting: %v", err)
+		} else if err := e.CheckAbsence(); err != nil {
+			log.Printf("alerting: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Load reads the enabled rules and the firing alerts. Rules whose condition
+// changed since the last load are evaluated from scratch.
+func (e *Engine) Load() error {
+	e.storeMu.Lock()
+	defer e.storeMu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the queued transitions must be stored before the firing alerts are read
+	for _, t := range e.queue {
+		e.store(t, e.publisher)
+	}
+	e.queue = nil
+	rules, err := e.repo.ListRules()
+	if err != nil {
+		return err
+	}
+	firing, err := e.repo.ListFiring()
+	if err != nil {
+		return err
+	}
+
+	conditions := make(map[uint64]string, len(e.rules))
+	for i := range e.rules {
+		conditions[e.rules[i].ID] = e.rules[i].Condition()
+	}
+	var enabled []model.AlertRule
+	unchanged := make(map[uint64]bool)
+	for i := range rules {
+		if !rules[i].Enabled {
+			continue
+		}
+		enabled = append(enabled, rules[i])
+		if c, ok := conditions[rules[i].ID]; ok && c == rules[i].Condition() {
+			unchanged[rules[i].ID] = true
+		}
+	}
+	e.rules = enabled
+	for k, st := range e.states {
+		if !unchanged[k.rule] {
+			delete(e.states, k)
+			continue
+		}
+		st.alert = nil
+	}
+	// the stored alerts are authoritative, they may have been resolved by an API call
+	for i := range firing {
+		a := firing[i]
+		e.state(stateKey{a.RuleID, sensorKey(a.ID1, a.ID2)}).alert = &a
+	}
+	return nil
+}
+
+// ResolveRule resolves the firing alerts of a rule at at, e.g. when the rule is
+// changed or deleted
+func (e *Engine) ResolveRule(ruleID uint64, at time.Time) error {
+	e.mu.Lock()
+	for k, st := range e.states {
+		if k.rule == ruleID && st.alert != nil {
+			e.resolve(st, at)
+		}
+	}
+	e.mu.Unlock()
+	// alerts firing before the rule was resolved are stored first
+	e.flush(true)
+	_, err := e.repo.ResolveRule(ruleID, at)
+	return err
+}
+
+// Observe evaluates the rules covering the sensors of stored readings. Failures
+// are logged only, they must not fail ingestion.
+func (e *Engine) Observe(readings []*pb.SensorData) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.flush(false)
+	defer e.mu.Unlock()
+	for _, data := range readings {
+		id2, err := strconv.Atoi(data.Id2)
+		if err != nil {
+			continue
+		}
+		ts := e.now()
+		if data.Timestamp != nil {
+			ts = data.Timestamp.AsTime()
+		}
+		for i := range e.rules {
+			r := &e.rules[i]
+			if !r.Covers(data.SensorType, data.Id1, id2) {
+				continue
+			}
+			st := e.state(stateKey{r.ID, sensorKey(data.Id1, id2)})
+			a := model.Alert{ID1: data.Id1, ID2: id2, SensorType: data.SensorType}
+			switch r.Type {
+			case model.AlertAnomaly:
+				// evaluated on the scores of ObserveAnomalies
+			case model.AlertAbsence:
+				// the sensor reports again
+				if st.alert != nil {
+					e.resolve(st, ts)
+				}
+			case model.AlertRate:
+				if !st.lastTS.IsZero() && !ts.After(st.lastTS) {
+					continue // late reading
+				}
+				prev, prevTS := st.lastValue, st.lastTS
+				st.lastValue, st.lastTS = data.Value, ts
+				if prevTS.IsZero() {
+					continue
+				}
+				e.evaluate(r, st, a, (data.Value-prev)/ts.Sub(prevTS).Minutes(), ts)
+			default:
+				e.evaluate(r, st, a, data.Value, ts)
+			}
+		}
+	}
+}
+
+// ObserveAnomalies evaluates the anomaly rules covering the sensors of scored
+// readings against their absolute anomaly score
+func (e *Engine) ObserveAnomalies(scores []model.AnomalyScore) {
+	if e == nil || len(scores) == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.flush(false)
+	defer e.mu.Unlock()
+	for _, s := range scores {
+		for i := range e.rules {
+			r := &e.rules[i]
+			if r.Type != model.AlertAnomaly || !r.Covers(s.SensorType, s.ID1, s.ID2) {
+				continue
+			}
+			st := e.state(stateKey{r.ID, sensorKey(s.ID1, s.ID2)})
+			e.evaluate(r, st, model.Alert{ID1: s.ID1, ID2: s.ID2, SensorType: s.SensorType}, math.Abs(s.Score), s.TS)
+		}
+	}
+}
+
+// CheckAbsence fires absence alerts for the sensors whose latest reading is
+// older than their rules allow. Sensors that never sent a reading are not known.
+func (e *Engine) CheckAbsence() error {
+	e.mu.Lock()
+	var rules []model.AlertRule
+	for _, r := range e.rules {
+		if r.Type == model.AlertAbsence {
+			rules = append(rules, r)
+		}
+	}
+	e.mu.Unlock()
+
+	now := e.now()
+	for i := range rules {
+		r := &rules[i]
+		filters := make(map[string]interface{})
+		if r.SensorType != nil {
+			filters["sensor_type"] = *r.SensorType
+		}
+		if r.ID1 != nil {
+			filters["id1"] = *r.ID1
+		}
+		if r.ID2 != nil {
+			filters["id2"] = *r.ID2
+		}
+		latest, err := e.readings.GetLatest(filters)
+		if err != nil {
+			return err
+		}
+		allowed := time.Duration(r.ForSeconds) * time.Second
+		e.mu.Lock()
+		for _, reading := range latest {
+			st := e.state(stateKey{r.ID, sensorKey(reading.ID1, reading.ID2)})
+			silent := now.Sub(reading.TS)
+			if silent < allowed {
+				if st.alert != nil {
+					e.resolve(st, now)
+				}
+				continue
+			}
+			if st.alert == nil {
+				e.fire(r, st, model.Alert{
+					ID1:        reading.ID1,
+					ID2:        reading.ID2,
+					SensorType: reading.SensorType,
+					Message:    fmt.Sprintf("no reading for %s", silent.Truncate(time.Second)),
+					FiredAt:    now,
+				})
+			}
+		}
+		e.mu.Unlock()
+		e.flush(false)
+	}
+	return nil
+}
+
+// evaluate moves the state of a value rule on with the value v taken at ts
+func (e *Engine) evaluate(r *model.AlertRule, st *state, a model.Alert, v float64, ts time.Time) {
+	breached, cleared := check(r, v)
+	if st.alert != nil {
+		if cleared {
+			e.resolve(st, ts)
+		}
+		return
+	}
+	if !breached {
+		st.since = time.Time{}
+		return
+	}
+	if st.since.IsZero() {
+		st.since = ts
+	}
+	if ts.Sub(st.since) < time.Duration(r.ForSeconds)*time.Second {
+		return
+	}
+	a.Value = &v
+	a.Message = message(r, v)
+	a.FiredAt = ts
+	e.fire(r, st, a)
+}
+
+// check reports whether v breaches the rule and whether it is back within the
+// limit by the hysteresis, which resolves a firing alert
+func check(r *model.AlertRule, v float64) (breached, cleared bool) {
+	h := r.Hysteresis
+	if r.Type == model.AlertRange {
+		if r.MinValue == nil || r.MaxValue == nil {
+			return false, true
+		}
+		return v < *r.MinValue || v > *r.MaxValue, v >= *r.MinValue+h && v <= *r.MaxValue-h
+	}
+	if r.Type == model.AlertAnomaly {
+		if r.Threshold == nil {
+			return false, true
+		}
+		return v >= *r.Threshold, v < *r.Threshold-h
+	}
+	if r.Operator == nil || r.Threshold == nil {
+		return false, true
+	}
+	t := *r.Threshold
+	switch *r.Operator {
+	case ">":
+		return v > t, v <= t-h
+	case ">=":
+		return v >= t, v < t-h
+	case "<":
+		return v < t, v >= t+h
+	case "<=":
+		return v <= t, v > t+h
+	}
+	return false, true
+}
+
+func message(r *model.AlertRule, v float64) string {
+	switch r.Type {
+	case model.AlertRange:
+		return fmt.Sprintf("value %.4g outside [%.4g, %.4g]", v, *r.MinValue, *r.MaxValue)
+	case model.AlertRate:
+		return fmt.Sprintf("rate %.4g/min %s %.4g", v, *r.Operator, *r.Threshold)
+	case model.AlertAnomaly:
+		return fmt.Sprintf("anomaly score %.3g >= %.4g", v, *r.Threshold)
+	}
+	return fmt.Sprintf("value %.4g %s %.4g", v, *r.Operator, *r.Threshold)
+}
+
+// fire moves st to firing a and queues a to be stored; mu must be held
+func (e *Engine) fire(r *model.AlertRule, st *state, a model.Alert) {
+	a.RuleID, a.RuleName, a.Severity, a.State = r.ID, r.Name, r.Severity, model.AlertFiring
+	e.queue = append(e.queue, transition{alert: &a, st: st, since: st.since})
+	st.alert = &a
+	st.since = time.Time{}
+}
+
+// resolve clears the firing alert of st and queues it to be stored as resolved
+// at at; mu must be held
+func (e *Engine) resolve(st *state, at time.Time) {
+	e.queue = append(e.queue, transition{alert: st.alert, resolved: &at})
+	st.alert = nil
+	st.since = time.Time{}
+}
+
+// flush stores and publishes the queued transitions in order. Unless wait is
+// set it leaves them to a flush already in progress, which stores them too.
+func (e *Engine) flush(wait bool) {
+	for {
+		if wait {
+			e.storeMu.Lock()
+		} else if !e.storeMu.TryLock() {
+			return
+		}
+		for {
+			e.mu.Lock()
+			queue, publisher := e.queue, e.publisher
+			e.queue = nil
+			e.mu.Unlock()
+			if len(queue) == 0 {
+				break
+			}
+			for _, t := range queue {
+				if !e.store(t, publisher) {
+					e.mu.Lock()
+					// the state is pending again, the next evaluation tries again
+					if t.st.alert == t.alert {
+						t.st.alert, t.st.since = nil, t.since
+					}
+					e.mu.Unlock()
+				}
+			}
+		}
+		e.storeMu.Unlock()
+
+		// transitions queued after the queue was found empty but before
+		// storeMu was released were left to this flush
+		e.mu.Lock()
+		pending := len(e.queue) > 0
+		e.mu.Unlock()
+		if !pending {
+			return
+		}
+	}
+}
+
+// store writes a transition and publishes it to p; it reports false when a
+// firing alert could not be stored. storeMu must be held.
+func (e *Engine) store(t transition, p Publisher) bool {
+	a := t.alert
+	if t.resolved == nil {
+		id, err := e.repo.Fire(a)
+		if err != nil {
+			log.Printf("alerting: failed to fire rule %d for %s: %v", a.RuleID, sensorKey(a.ID1, a.ID2), err)
+			return false
+		}
+		a.ID = id
+		log.Printf("alerting: %s alert %d of rule %d (%s) fired for %s: %s", a.Severity, id, a.RuleID, a.RuleName, sensorKey(a.ID1, a.ID2), a.Message)
+		if p != nil {
+			p.Publish(model.EventAlertFiring, *a)
+		}
+		return true
+	}
+	if a.ID == 0 {
+		return true // it failed to fire
+	}
+	if err := e.repo.Resolve(a.ID, *t.resolved); err != nil {
+		// still firing when stored, Load restores it and it resolves again
+		log.Printf("alerting: failed to resolve alert %d: %v", a.ID, err)
+		return true
+	}
+	resolved := *a
+	resolved.State, resolved.ResolvedAt = model.AlertResolved, t.resolved
+	if p != nil {
+		p.Publish(model.EventAlertResolved, resolved)
+	}
+	return true
+}
+
+func (e *Engine) state(k stateKey) *state {
+	st, ok := e.states[k]
+	if !ok {
+		st = &state{}
+		e.states[k] = st
+	}
+	return st
+}
+
+func sensorKey(id1 string, id2 int) string {
+	return id1 + "/" + strconv.Itoa(id2)
+}
@@ -0,0 +1,214 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var t0 = time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+
+func newTestEngine(t *testing.T, rules ...model.AlertRule) (*Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sqlxDB := sqlx.NewDb(db, "mysql")
+	e := New(repository.NewAlertRepository(sqlxDB), repository.NewSensorRepository(sqlxDB), time.Minute)
+	e.rules = rules
+	e.now = func() time.Time { return t0 }
+	return e, mock
+}
+
+func reading(value float64, at time.Duration) []*pb.SensorData {
+	return []*pb.SensorData{{Id1: "A", Id2: "1", SensorType: "Temperature", Value: value, Timestamp: timestamppb.New(t0.Add(at))}}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestEngine_ThresholdForDurationAndHysteresis(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{
+		ID: 1, Name: "hot", Type: model.AlertThreshold, SensorType: ptr("Temperature"),
+		Operator: ptr(">"), Threshold: ptr(80.0), Hysteresis: 2, ForSeconds: 60, Severity: "critical",
+	})
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(1), "hot", "critical", "A", 1, "Temperature", model.AlertFiring, 87.0, "value 87 > 80", t0.Add(90*time.Second)).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE id = \\? AND state = \\?").
+		WithArgs(model.AlertResolved, t0.Add(150*time.Second), uint64(5), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	e.Observe(reading(85, 0))
+	e.Observe(reading(79, 10*time.Second)) // the condition has to hold without a break
+	e.Observe(reading(85, 30*time.Second))
+	e.Observe(reading(86, 60*time.Second))
+	e.Observe(reading(87, 90*time.Second))  // held for 60s: fires
+	e.Observe(reading(88, 100*time.Second)) // already firing
+	e.Observe(reading(79, 120*time.Second)) // within the hysteresis
+	e.Observe(reading(78, 150*time.Second)) // resolves
+	e.Observe(reading(23, 150*time.Second))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_RetriesFailedFire(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 1, Name: "hot", Type: model.AlertThreshold, Operator: ptr(">"), Threshold: ptr(80.0), Severity: "critical"})
+	var published events
+	e.SetPublisher(&published)
+	mock.ExpectExec("INSERT INTO alerts").WillReturnError(assert.AnError)
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(1), "hot", "critical", "A", 1, "Temperature", model.AlertFiring, 86.0, "value 86 > 80", t0.Add(time.Second)).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+
+	e.Observe(reading(85, 0))
+	// the alert was not stored, so the state is pending again
+	e.Observe(reading(86, time.Second))
+	e.Observe(reading(87, 2*time.Second))
+
+	assert.Equal(t, events{"alert.firing firing A/1"}, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_RangeRule(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 2, Name: "pressure", Type: model.AlertRange, ID1: ptr("A"), MinValue: ptr(10.0), MaxValue: ptr(20.0), Severity: "warning"})
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(2), "pressure", "warning", "A", 1, "Temperature", model.AlertFiring, 9.5, "value 9.5 outside [10, 20]", t0.Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+
+	e.Observe(reading(15, 0))
+	e.Observe(reading(9.5, time.Minute))
+	// other sensors are not covered
+	e.Observe([]*pb.SensorData{{Id1: "B", Id2: "1", Value: 50, Timestamp: timestamppb.New(t0)}})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_RateRule(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 3, Name: "pressure drop", Type: model.AlertRate, Operator: ptr("<"), Threshold: ptr(-5.0), Severity: "warning"})
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(3), "pressure drop", "warning", "A", 1, "Temperature", model.AlertFiring, -7.0, "rate -7/min < -5", t0.Add(2*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE alerts SET state").
+		WithArgs(model.AlertResolved, t0.Add(150*time.Second), uint64(7), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	e.Observe(reading(100, 0))
+	e.Observe(reading(97, time.Minute))     // -3 per minute
+	e.Observe(reading(50, 30*time.Second))  // late readings are skipped
+	e.Observe(reading(90, 2*time.Minute))   // -7 per minute
+	e.Observe(reading(89, 150*time.Second)) // -2 per minute
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_AbsenceRule(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 4, Name: "silent", Type: model.AlertAbsence, ID1: ptr("A"), ForSeconds: 300, Severity: "warning"})
+	mock.ExpectQuery("FROM sensor_readings WHERE archived_at IS NULL AND id1 = \\?\\) r WHERE rn = 1").
+		WithArgs("A").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "value", "ts"}).
+			AddRow(1, "A", 1, "Temperature", 20.5, t0.Add(-10*time.Minute)).
+			AddRow(2, "A", 2, "Temperature", 21.5, t0.Add(-time.Minute)))
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(4), "silent", "warning", "A", 1, "Temperature", model.AlertFiring, nil, "no reading for 10m0s", t0).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec("UPDATE alerts SET state").
+		WithArgs(model.AlertResolved, t0.Add(time.Second), uint64(8), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, e.CheckAbsence())
+	// the sensor reports again
+	e.Observe(reading(20, time.Second))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_LoadRestoresFiringAlerts(t *testing.T) {
+	e, mock := newTestEngine(t)
+	mock.ExpectQuery("SELECT \\* FROM alert_rules ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "operator", "threshold", "severity", "enabled"}).
+			AddRow(1, "hot", model.AlertThreshold, ">", 80.0, "critical", true).
+			AddRow(2, "cold", model.AlertThreshold, "<", 0.0, "critical", false))
+	mock.ExpectQuery("SELECT \\* FROM alerts WHERE state = \\?").
+		WithArgs(model.AlertFiring).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_id", "id1", "id2", "state"}).AddRow(5, 1, "A", 1, model.AlertFiring))
+	mock.ExpectExec("UPDATE alerts SET state").
+		WithArgs(model.AlertResolved, t0, uint64(5), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, e.Load())
+	require.Len(t, e.rules, 1) // disabled rules are not evaluated
+	// the alert fired before the restart resolves instead of firing again
+	e.Observe(reading(-3, 0))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// events records what the engine publishes
+type events []string
+
+func (ev *events) Publish(eventType string, data interface{}) {
+	a := data.(model.Alert)
+	*ev = append(*ev, eventType+" "+a.State+" "+sensorKey(a.ID1, a.ID2))
+}
+
+func TestEngine_PublishesTransitions(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 1, Name: "hot", Type: model.AlertThreshold, Operator: ptr(">"), Threshold: ptr(80.0), Severity: "critical"})
+	var published events
+	e.SetPublisher(&published)
+	mock.ExpectExec("INSERT INTO alerts").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE id = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO alerts").WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE id = \\?").
+		WithArgs(model.AlertResolved, t0, uint64(6), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE rule_id = \\?").
+		WithArgs(model.AlertResolved, t0, uint64(1), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	e.Observe(reading(85, 0))
+	e.Observe(reading(70, time.Second))
+	e.Observe(reading(90, 2*time.Second))
+	// e.g. the rule is deleted
+	require.NoError(t, e.ResolveRule(1, t0))
+
+	assert.Equal(t, events{
+		"alert.firing firing A/1",
+		"alert.resolved resolved A/1",
+		"alert.firing firing A/1",
+		"alert.resolved resolved A/1",
+	}, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEngine_AnomalyRule(t *testing.T) {
+	e, mock := newTestEngine(t, model.AlertRule{ID: 5, Name: "odd", Type: model.AlertAnomaly, SensorType: ptr("Temperature"), Threshold: ptr(4.0), Hysteresis: 1, Severity: "warning"})
+	mock.ExpectExec("INSERT INTO alerts").
+		WithArgs(uint64(5), "odd", "warning", "A", 1, "Temperature", model.AlertFiring, 5.5, "anomaly score 5.5 >= 4", t0.Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE id = \\? AND state = \\?").
+		WithArgs(model.AlertResolved, t0.Add(3*time.Minute), uint64(7), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	score := func(z float64, at time.Duration) []model.AnomalyScore {
+		return []model.AnomalyScore{{ID1: "A", ID2: 1, SensorType: "Temperature", Score: z, TS: t0.Add(at)}}
+	}
+	// the raw values are not what anomaly rules look at
+	e.Observe(reading(1000, 0))
+	e.ObserveAnomalies(score(1.2, 0))
+	e.ObserveAnomalies(score(-5.5, time.Minute))  // below the baseline counts as well
+	e.ObserveAnomalies(score(3.5, 2*time.Minute)) // within the hysteresis
+	e.ObserveAnomalies(score(0.4, 3*time.Minute)) // resolves
+	e.ObserveAnomalies([]model.AnomalyScore{{ID1: "B", ID2: 1, SensorType: "Light", Score: 9, TS: t0}})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
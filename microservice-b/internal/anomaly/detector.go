@@ -0,0 +1,190 @@
+package anomaly
+
+import (
+	"log"
+	"math"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+)
+
+// DefaultWindow is how many readings of a sensor the rolling baseline covers;
+// the EWMA baselines have the same span
+const DefaultWindow = 100
+
+// DefaultThreshold is the absolute z-score from which a reading is anomalous
+const DefaultThreshold = 4.0
+
+// minSamples is how many readings a baseline needs before it scores, per hour
+// of day for the seasonal one
+const minSamples = 30
+
+// Detector scores every stored reading against three baselines of its sensor,
+// kept in memory and built from the readings before it: a rolling window, an
+// exponentially weighted moving average and one such average per hour of day,
+// which all follow a drifting sensor. A reading is anomalous when it deviates
+// from any of them by threshold stddevs; those readings are stored. Baselines
+// are rebuilt from scratch after a restart.
+type Detector struct {
+	repo      *repository.AnomalyRepository
+	window    int
+	alpha     float64 // EWMA smoothing factor
+	threshold float64
+
+	mu      sync.Mutex
+	sensors map[string]*baseline // per id1/id2
+}
+
+func New(repo *repository.AnomalyRepository, window int, threshold float64) *Detector {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	// a smaller window would never score
+	window = max(window, minSamples)
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Detector{
+		repo:      repo,
+		window:    window,
+		alpha:     2 / float64(window+1),
+		threshold: threshold,
+		sensors:   make(map[string]*baseline),
+	}
+}
+
+// baseline is what the detector knows about the readings of a sensor
+type baseline struct {
+	recent []float64 // ring buffer of the last window readings
+	next   int
+
+	ewma  ewma
+	hours [24]ewma
+}
+
+// moments are the running mean and variance of a series (Welford)
+type moments struct {
+	n        int
+	mean, m2 float64
+}
+
+func (m *moments) add(x float64) {
+	m.n++
+	d := x - m.mean
+	m.mean += d / float64(m.n)
+	m.m2 += d * (x - m.mean)
+}
+
+func (m *moments) stddev() float64 {
+	if m.n < 2 {
+		return 0
+	}
+	return math.Sqrt(m.m2 / float64(m.n-1))
+}
+
+// ewma is an exponentially weighted mean and variance of a series
+type ewma struct {
+	n              int
+	mean, variance float64
+}
+
+func (m *ewma) add(x, alpha float64) {
+	if m.n == 0 {
+		m.mean = x
+	} else {
+		diff := x - m.mean
+		incr := alpha * diff
+		m.mean += incr
+		m.variance = (1 - alpha) * (m.variance + diff*incr)
+	}
+	m.n++
+}
+
+// Observe scores stored readings, stores the anomalous ones and returns the
+// scores of the readings whose sensor has a baseline. Failures are logged
+// only, they must not fail ingestion.
+func (d *Detector) Observe(readings []*pb.SensorData) []model.AnomalyScore {
+	if d == nil {
+		return nil
+	}
+	var scores, anomalies []model.AnomalyScore
+	d.mu.Lock()
+	for _, data := range readings {
+		id2, err := strconv.Atoi(data.Id2)
+		if err != nil {
+			continue
+		}
+		ts := time.Now()
+		if data.Timestamp != nil {
+			ts = data.Timestamp.AsTime()
+		}
+		key := data.Id1 + "/" + data.Id2
+		b, ok := d.sensors[key]
+		if !ok {
+			b = &baseline{}
+			d.sensors[key] = b
+		}
+		s := model.AnomalyScore{ID1: data.Id1, ID2: id2, SensorType: data.SensorType, Value: data.Value, TS: ts}
+		if d.score(b, &s) {
+			scores = append(scores, s)
+			if s.Anomalous {
+				anomalies = append(anomalies, s)
+			}
+		}
+		d.add(b, data.Value, ts)
+	}
+	d.mu.Unlock()
+
+	if err := d.repo.Insert(anomalies); err != nil {
+		log.Printf("anomaly: failed to store %d anomalies: %v", len(anomalies), err)
+	}
+	return scores
+}
+
+// score sets the z-scores of s against b and reports whether any baseline scored it
+func (d *Detector) score(b *baseline, s *model.AnomalyScore) bool {
+	scored := false
+	consider := func(method string, z **float64, mean, sd float64) {
+		if sd == 0 {
+			return
+		}
+		v := (s.Value - mean) / sd
+		*z = &v
+		if !scored || math.Abs(v) > math.Abs(s.Score) {
+			s.Method, s.Score, s.Expected = method, v, mean
+		}
+		scored = true
+	}
+
+	if len(b.recent) >= minSamples {
+		var m moments
+		for _, v := range b.recent {
+			m.add(v)
+		}
+		consider(model.AnomalyRolling, &s.ZRolling, m.mean, m.stddev())
+	}
+	if b.ewma.n >= minSamples {
+		consider(model.AnomalyEWMA, &s.ZEWMA, b.ewma.mean, math.Sqrt(b.ewma.variance))
+	}
+	if h := &b.hours[s.TS.UTC().Hour()]; h.n >= minSamples {
+		consider(model.AnomalySeasonal, &s.ZSeasonal, h.mean, math.Sqrt(h.variance))
+	}
+	s.Anomalous = scored && math.Abs(s.Score) >= d.threshold
+	return scored
+}
+
+// add moves the baselines of b on with the value v taken at ts
+func (d *Detector) add(b *baseline, v float64, ts time.Time) {
+	if len(b.recent) < d.window {
+		b.recent = append(b.recent, v)
+	} else {
+		b.recent[b.next] = v
+		b.next = (b.next + 1) % d.window
+	}
+	b.ewma.add(v, d.alpha)
+	b.hours[ts.UTC().Hour()].add(v, d.alpha)
+}
@@ -0,0 +1,153 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var t0 = time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+
+func newTestDetector(t *testing.T) (*Detector, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return New(repository.NewAnomalyRepository(sqlx.NewDb(db, "mysql")), 50, 4), mock
+}
+
+func reading(value float64, at time.Duration) []*pb.SensorData {
+	return []*pb.SensorData{{Id1: "A", Id2: "1", SensorType: "Temperature", Value: value, Timestamp: timestamppb.New(t0.Add(at))}}
+}
+
+// warmUp feeds n readings around 20, one a minute
+func warmUp(d *Detector, n int) {
+	for i := 0; i < n; i++ {
+		d.Observe(reading(20+float64(i%5-2)*0.1, time.Duration(i)*time.Minute))
+	}
+}
+
+func TestDetector_WarmsUpBeforeScoring(t *testing.T) {
+	d, mock := newTestDetector(t)
+
+	for i := 0; i < minSamples; i++ {
+		assert.Empty(t, d.Observe(reading(20+float64(i%5-2)*0.1, time.Duration(i)*time.Second)))
+	}
+	scores := d.Observe(reading(20, time.Minute))
+	require.Len(t, scores, 1)
+	assert.False(t, scores[0].Anomalous)
+	assert.NotNil(t, scores[0].ZRolling)
+	assert.NotNil(t, scores[0].ZEWMA)
+	assert.NotNil(t, scores[0].ZSeasonal)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDetector_FlagsAndStoresSpike(t *testing.T) {
+	d, mock := newTestDetector(t)
+	warmUp(d, 40)
+
+	mock.ExpectExec("INSERT INTO sensor_anomalies").
+		WithArgs("A", 1, "Temperature", 25.0, t0.Add(40*time.Minute), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	scores := d.Observe(reading(25, 40*time.Minute))
+
+	require.Len(t, scores, 1)
+	s := scores[0]
+	assert.True(t, s.Anomalous)
+	assert.Greater(t, s.Score, 4.0)
+	assert.InDelta(t, 20, s.Expected, 0.2)
+	// the score is that of the method the reading deviates most by
+	for _, z := range []*float64{s.ZRolling, s.ZEWMA, s.ZSeasonal} {
+		require.NotNil(t, z)
+		assert.LessOrEqual(t, *z, s.Score)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDetector_FollowsDriftingBaseline(t *testing.T) {
+	d, mock := newTestDetector(t)
+
+	// a baseline drifting by 0.01 a reading is never anomalous, although the
+	// last readings are far above the first ones
+	for i := 0; i < 500; i++ {
+		v := 20 + float64(i)*0.01 + float64(i%5-2)*0.1
+		for _, s := range d.Observe([]*pb.SensorData{{Id1: "A", Id2: "1", Value: v, Timestamp: timestamppb.New(t0.Add(time.Duration(i) * time.Second))}}) {
+			require.Falsef(t, s.Anomalous, "reading %d flagged by %s with %v", i, s.Method, s.Score)
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDetector_SeasonalBaselinePerHour(t *testing.T) {
+	d, _ := newTestDetector(t)
+	warmUp(d, 40) // 10:00 to 10:39
+
+	// 11:00 has no seasonal baseline yet
+	scores := d.Observe(reading(20, time.Hour))
+	require.Len(t, scores, 1)
+	assert.Nil(t, scores[0].ZSeasonal)
+	assert.NotNil(t, scores[0].ZEWMA)
+
+	// the same hour of another day has one
+	scores = d.Observe(reading(20, 24*time.Hour+10*time.Minute))
+	require.Len(t, scores, 1)
+	assert.NotNil(t, scores[0].ZSeasonal)
+}
+
+func TestDetector_SeasonalBaselineFollowsDrift(t *testing.T) {
+	d, mock := newTestDetector(t)
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < 20; i++ {
+		mock.ExpectExec("INSERT INTO sensor_anomalies").WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	hour := func(day, level int) {
+		for i := 0; i < 60; i++ {
+			at := time.Duration(day)*24*time.Hour + time.Duration(i)*time.Minute
+			d.Observe(reading(float64(level)+float64(i%5-2)*0.1, at))
+		}
+	}
+	// the sensor moved from 20 to 30 between days; its 10:00 baseline forgets
+	// the old level instead of averaging over every day
+	hour(0, 20)
+	for day := 1; day < 6; day++ {
+		hour(day, 30)
+	}
+
+	scores := d.Observe(reading(30, 6*24*time.Hour))
+	require.Len(t, scores, 1)
+	assert.False(t, scores[0].Anomalous)
+	scores = d.Observe(reading(31, 6*24*time.Hour+time.Minute))
+	require.Len(t, scores, 1)
+	require.NotNil(t, scores[0].ZSeasonal)
+	assert.Greater(t, *scores[0].ZSeasonal, 4.0)
+}
+
+func TestDetector_FlatBaselineDoesNotScore(t *testing.T) {
+	d, _ := newTestDetector(t)
+	for i := 0; i < 40; i++ {
+		d.Observe(reading(20, time.Duration(i)*time.Minute))
+	}
+	assert.Empty(t, d.Observe(reading(21, 40*time.Minute)))
+}
+
+func TestDetector_NilIsNoop(t *testing.T) {
+	var d *Detector
+	assert.Nil(t, d.Observe(reading(20, 0)))
+}
+
+func TestNew_Defaults(t *testing.T) {
+	d := New(nil, 0, 0)
+	assert.Equal(t, DefaultWindow, d.window)
+	assert.Equal(t, DefaultThreshold, d.threshold)
+	assert.Equal(t, minSamples, New(nil, 5, 3).window)
+}
@@ -0,0 +1,231 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodPermissions is the permission each user-facing RPC requires, keyed by
+// full method name (e.g. "/sensor.SensorService/Query"). RPCs not listed are
+// device calls, authenticated with a device token instead of a user role.
+var methodPermissions = map[string]myMiddleware.Permission{
+	pb.SensorService_QueryReadings_FullMethodName: myMiddleware.PermRead,
+	pb.SensorService_GetLatest_FullMethodName:     myMiddleware.PermRead,
+	pb.SensorService_Subscribe_FullMethodName:     myMiddleware.PermRead,
+}
+
+// DeviceAuthenticator resolves the device token a generator presents on a
+// device call
+type DeviceAuthenticator interface {
+	AuthenticateDevice(token string) (*model.DeviceToken, error)
+}
+
+type userKey struct{}
+
+type deviceKey struct{}
+
+// UserFromContext returns the caller authenticated by the auth interceptors
+func UserFromContext(ctx context.Context) (myMiddleware.CurrentUser, bool) {
+	u, ok := ctx.Value(userKey{}).(myMiddleware.CurrentUser)
+	return u, ok
+}
+
+// DeviceFromContext returns the device token a device call was authenticated
+// with; there is none when device authentication is disabled
+func DeviceFromContext(ctx context.Context) (*model.DeviceToken, bool) {
+	t, ok := ctx.Value(deviceKey{}).(*model.DeviceToken)
+	return t, ok
+}
+
+// bearerToken returns the bearer token in the "authorization" metadata of a call
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		return strings.TrimPrefix(values[0], "Bearer ")
+	}
+	return ""
+}
+
+// apiKey returns the API key of a call, from the "x-api-key" metadata or sent
+// as a bearer token
+func apiKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		return values[0]
+	}
+	if token := bearerToken(ctx); strings.HasPrefix(token, myMiddleware.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authorize checks the bearer token or API key in the metadata of a call to
+// method against the permissions, like middleware.Authorize does for REST. API
+// keys are only accepted when keys is not nil.
+func authorize(ctx context.Context, secret string, revoked myMiddleware.RevocationList, keys myMiddleware.APIKeyAuthenticator, method string, methods map[string]myMiddleware.Permission) (context.Context, error) {
+	p, ok := methods[method]
+	if !ok {
+		return ctx, nil
+	}
+	var u myMiddleware.CurrentUser
+	if key := apiKey(ctx); key != "" && keys != nil {
+		var err error
+		if u, err = keys.AuthenticateAPIKey(key); err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+	} else {
+		token := bearerToken(ctx)
+		if token == "" {
+			return ctx, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		var err error
+		if u, err = myMiddleware.ParseJWT(token, secret, revoked); err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid token")
+		}
+	}
+	if u.PasswordChangeRequired && p != myMiddleware.PermSelf {
+		return ctx, status.Error(codes.PermissionDenied, "password change required")
+	}
+	if !u.Can(p) {
+		if u.APIKeyID != 0 {
+			return ctx, status.Errorf(codes.PermissionDenied, "api key lacks %s permission", p)
+		}
+		return ctx, status.Errorf(codes.PermissionDenied, "role %q lacks %s permission", u.Role, p)
+	}
+	return context.WithValue(ctx, userKey{}, u), nil
+}
+
+// checkID1 limits callers whose API key has an id1 allowlist to reads filtered
+// by an id1 from it, like middleware.RestrictID1 does for REST
+func checkID1(ctx context.Context, id1 string) error {
+	u, _ := UserFromContext(ctx)
+	if u.AllowsID1(id1) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "api key requires id1 to be one of %s", strings.Join(u.ID1Allowlist, ", "))
+}
+
+// authenticateDevice checks the device token in the "authorization" metadata
+// of a device call
+func authenticateDevice(ctx context.Context, devices DeviceAuthenticator) (context.Context, *model.DeviceToken, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return ctx, nil, status.Error(codes.Unauthenticated, "missing device token")
+	}
+	t, err := devices.AuthenticateDevice(token)
+	if errors.Is(err, utils.ErrInvalidDeviceToken) {
+		return ctx, nil, status.Error(codes.Unauthenticated, "invalid device token")
+	}
+	if err != nil {
+		log.Printf("Failed to check device token: %v", err)
+		return ctx, nil, status.Error(codes.Internal, "failed to check device token")
+	}
+	return context.WithValue(ctx, deviceKey{}, t), t, nil
+}
+
+// checkSensors rejects a device call message naming a sensor the token is not
+// bound to: readings, batches, the hello of a control stream and registrations
+func checkSensors(t *model.DeviceToken, msg interface{}) error {
+	check := func(id1, id2 string) error {
+		if t.Allows(id1, id2) {
+			return nil
+		}
+		return status.Errorf(codes.PermissionDenied, "device token %s may not write %s/%s", t.Prefix, id1, id2)
+	}
+	switch m := msg.(type) {
+	case *pb.SensorData:
+		return check(m.Id1, m.Id2)
+	case *pb.SensorBatch:
+		for _, d := range m.Readings {
+			if err := check(d.Id1, d.Id2); err != nil {
+				return err
+			}
+		}
+	case *pb.ControlMessage:
+		for _, s := range m.GetHello().GetSensors() {
+			if err := check(s.Id1, s.Id2); err != nil {
+				return err
+			}
+		}
+	case *pb.RegisterRequest:
+		for _, s := range m.Sensors {
+			if err := check(s.Id1, s.Id2); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AuthInterceptors return the unary and stream interceptors enforcing methods,
+// for callers with an access token or, when keys is set, an API key. Device
+// calls require a device token when devices is set, otherwise they are not
+// checked.
+func AuthInterceptors(secret string, revoked myMiddleware.RevocationList, keys myMiddleware.APIKeyAuthenticator, methods map[string]myMiddleware.Permission, devices DeviceAuthenticator) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := methods[info.FullMethod]; !ok && devices != nil {
+			ctx, t, err := authenticateDevice(ctx, devices)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkSensors(t, req); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
+		ctx, err := authorize(ctx, secret, revoked, keys, info.FullMethod, methods)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := methods[info.FullMethod]; !ok && devices != nil {
+			ctx, t, err := authenticateDevice(ss.Context(), devices)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &deviceStream{authStream: authStream{ServerStream: ss, ctx: ctx}, token: t})
+		}
+		ctx, err := authorize(ss.Context(), secret, revoked, keys, info.FullMethod, methods)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// authStream carries the authenticated caller in the stream context
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context { return s.ctx }
+
+// deviceStream checks every message a generator sends against its device token
+type deviceStream struct {
+	authStream
+	token *model.DeviceToken
+}
+
+func (s *deviceStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkSensors(s.token, m)
+}
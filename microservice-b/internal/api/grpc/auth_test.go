@@ -0,0 +1,215 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type noneRevoked struct{}
+
+func (noneRevoked) IsRevoked(string) (bool, error) { return false, nil }
+
+func TestAuthorize(t *testing.T) {
+	const secret = "test-secret"
+	methods := map[string]myMiddleware.Permission{"/sensor.SensorService/Admin": myMiddleware.PermAdmin}
+	withToken := func(role string) context.Context {
+		token, err := myMiddleware.GenerateJWT(7, "a@b.c", role, secret, 1)
+		require.NoError(t, err)
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	// device calls are not checked
+	_, err := authorize(context.Background(), secret, noneRevoked{}, nil, "/sensor.SensorService/SendSensorBatch", methods)
+	assert.NoError(t, err)
+
+	_, err = authorize(context.Background(), secret, noneRevoked{}, nil, "/sensor.SensorService/Admin", methods)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = authorize(withToken("analyst"), "other-secret", noneRevoked{}, nil, "/sensor.SensorService/Admin", methods)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = authorize(withToken("analyst"), secret, noneRevoked{}, nil, "/sensor.SensorService/Admin", methods)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx, err := authorize(withToken("admin"), secret, noneRevoked{}, nil, "/sensor.SensorService/Admin", methods)
+	require.NoError(t, err)
+	u, ok := UserFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), u.ID)
+	assert.Equal(t, "admin", u.Role)
+}
+
+type apiKeys map[string]myMiddleware.CurrentUser
+
+func (k apiKeys) AuthenticateAPIKey(key string) (myMiddleware.CurrentUser, error) {
+	if u, ok := k[key]; ok {
+		return u, nil
+	}
+	return myMiddleware.CurrentUser{}, utils.ErrInvalidAPIKey
+}
+
+func TestAuthorize_APIKey(t *testing.T) {
+	methods := map[string]myMiddleware.Permission{pb.SensorService_QueryReadings_FullMethodName: myMiddleware.PermRead}
+	keys := apiKeys{
+		"smk_read":  {ID: 7, Role: "analyst", APIKeyID: 1, Scopes: []string{model.ScopeSensorsRead}},
+		"smk_write": {ID: 7, Role: "admin", APIKeyID: 2, Scopes: []string{model.ScopeSensorsWrite}},
+	}
+	withMD := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+	}
+
+	ctx, err := authorize(withMD("x-api-key", "smk_read"), "secret", noneRevoked{}, keys, pb.SensorService_QueryReadings_FullMethodName, methods)
+	require.NoError(t, err)
+	u, ok := UserFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), u.APIKeyID)
+
+	// keys may be sent as bearer tokens too
+	_, err = authorize(withMD("authorization", "Bearer smk_read"), "secret", noneRevoked{}, keys, pb.SensorService_QueryReadings_FullMethodName, methods)
+	assert.NoError(t, err)
+
+	_, err = authorize(withMD("x-api-key", "smk_unknown"), "secret", noneRevoked{}, keys, pb.SensorService_QueryReadings_FullMethodName, methods)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the key scopes apply, not the role of its owner
+	_, err = authorize(withMD("x-api-key", "smk_write"), "secret", noneRevoked{}, keys, pb.SensorService_QueryReadings_FullMethodName, methods)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// without an authenticator keys are not accepted
+	_, err = authorize(withMD("authorization", "Bearer smk_read"), "secret", noneRevoked{}, nil, pb.SensorService_QueryReadings_FullMethodName, methods)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthorize_PasswordChangeRequired(t *testing.T) {
+	const secret = "test-secret"
+	methods := map[string]myMiddleware.Permission{pb.SensorService_GetLatest_FullMethodName: myMiddleware.PermRead}
+	token, _, err := myMiddleware.GenerateAccessToken(myMiddleware.CurrentUser{ID: 7, Role: "admin", ExpiresAt: time.Now().Add(time.Hour), PasswordChangeRequired: true}, secret)
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	_, err = authorize(ctx, secret, noneRevoked{}, nil, pb.SensorService_GetLatest_FullMethodName, methods)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+type deviceTokens map[string]*model.DeviceToken
+
+func (d deviceTokens) AuthenticateDevice(token string) (*model.DeviceToken, error) {
+	if t, ok := d[token]; ok {
+		return t, nil
+	}
+	return nil, utils.ErrInvalidDeviceToken
+}
+
+// recvStream is a server stream receiving msgs
+type recvStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []proto.Message
+}
+
+func (s *recvStream) Context() context.Context { return s.ctx }
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	if len(s.msgs) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.msgs[0])
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+func TestAuthInterceptors_DeviceToken(t *testing.T) {
+	devices := deviceTokens{"smd_a": {Prefix: "smd_a", Sensors: []string{"A/1", "B/*"}}}
+	unary, _ := AuthInterceptors("secret", noneRevoked{}, nil, nil, devices)
+	info := &grpc.UnaryServerInfo{FullMethod: "/sensor.SensorService/Register"}
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+	var called int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called++
+		device, ok := DeviceFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "smd_a", device.Prefix)
+		return &pb.RegisterReply{}, nil
+	}
+	register := func(sensors ...string) *pb.RegisterRequest {
+		req := &pb.RegisterRequest{}
+		for _, s := range sensors {
+			id1, id2, _ := strings.Cut(s, "/")
+			req.Sensors = append(req.Sensors, &pb.SensorRef{Id1: id1, Id2: id2})
+		}
+		return req
+	}
+
+	_, err := unary(context.Background(), register("A/1"), info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = unary(withToken("smd_unknown"), register("A/1"), info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = unary(withToken("smd_a"), register("A/1", "A/2"), info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = unary(withToken("smd_a"), register("A/1", "B/9"), info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, called)
+}
+
+func TestAuthInterceptors_DeviceStream(t *testing.T) {
+	devices := deviceTokens{"smd_a": {Prefix: "smd_a", Sensors: []string{"A/1"}}}
+	_, stream := AuthInterceptors("secret", noneRevoked{}, nil, nil, devices)
+	info := &grpc.StreamServerInfo{FullMethod: "/sensor.SensorService/SendSensorBatch"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer smd_a"))
+	// the handler drains the stream like SendSensorBatch does
+	var received int
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		for {
+			batch := &pb.SensorBatch{}
+			if err := ss.RecvMsg(batch); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			received += len(batch.Readings)
+		}
+	}
+
+	ss := &recvStream{ctx: ctx, msgs: []proto.Message{
+		&pb.SensorBatch{Readings: []*pb.SensorData{{Id1: "A", Id2: "1"}, {Id1: "A", Id2: "1"}}},
+		&pb.SensorBatch{Readings: []*pb.SensorData{{Id1: "A", Id2: "1"}, {Id1: "B", Id2: "1"}}},
+	}}
+	err := stream(nil, ss, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, 2, received)
+
+	err = stream(nil, &recvStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestCheckSensors_ControlHello(t *testing.T) {
+	token := &model.DeviceToken{Sensors: []string{"A/*"}}
+	hello := func(id1, id2 string) *pb.ControlMessage {
+		return &pb.ControlMessage{Payload: &pb.ControlMessage_Hello{Hello: &pb.Hello{Sensors: []*pb.SensorRef{{Id1: id1, Id2: id2}}}}}
+	}
+
+	assert.NoError(t, checkSensors(token, hello("A", "7")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(checkSensors(token, hello("B", "7"))))
+	// results and heartbeats name no sensors
+	assert.NoError(t, checkSensors(token, &pb.ControlMessage{Payload: &pb.ControlMessage_Heartbeat{Heartbeat: &pb.Heartbeat{}}}))
+}
@@ -1,47 +1,245 @@
 package grpc
 
 import (
+	"context"
 	"io"
 	"log"
+	"microservice-b/internal/alerting"
+	"microservice-b/internal/anomaly"
+	"microservice-b/internal/control"
+	"microservice-b/internal/live"
+	"microservice-b/internal/registry"
 	"microservice-b/internal/repository"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
 	"net"
 
 	pb "microservice-b/pb/shared-proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type SensorServer struct {
 	pb.UnimplementedSensorServiceServer
-	Repo *repository.SensorRepository
+	Repo      *repository.SensorRepository
+	Hub       *control.Hub
+	Registry  *registry.Registry
+	Live      *live.Hub         // live subscribers of the stored readings
+	Alerts    *alerting.Engine  // evaluates the alert rules on the stored readings
+	Anomalies *anomaly.Detector // scores the stored readings against the baselines of their sensors
 }
 
 func (s *SensorServer) SendSensorData(stream pb.SensorService_SendSensorDataServer) error {
+	addr := remoteAddr(stream.Context())
+	var total model.IngestResult
 	for {
 		data, err := stream.Recv()
 		if err == io.EOF {
-			return stream.SendAndClose(&pb.Ack{Ok: true, Message: "All data received"})
+			return stream.SendAndClose(ack(total))
 		}
 		if err != nil {
 			log.Printf("Stream error: %v", err)
 			return err
 		}
 
+		// sequenced readings go through the deduplicating path
+		if data.Seq > 0 {
+			res, err := s.Repo.SaveBatch([]*pb.SensorData{data})
+			if err != nil {
+				log.Printf("DB error: %v", err)
+				return status.Errorf(codes.Internal, "failed to save sensor data: %v", err)
+			}
+			total = addResult(total, res)
+			s.Registry.Seen([]*pb.SensorData{data}, addr)
+			s.Live.Publish(res.Stored)
+			s.Alerts.Observe(res.Stored)
+			s.Alerts.ObserveAnomalies(s.Anomalies.Observe(res.Stored))
+			continue
+		}
 		if err := s.Repo.Save(data); err != nil {
+			// fail the stream so the client does not treat the batch as acknowledged
 			log.Printf("DB error: %v", err)
+			return status.Errorf(codes.Internal, "failed to save sensor data: %v", err)
 		}
+		total.Saved++
+		s.Registry.Seen([]*pb.SensorData{data}, addr)
+		s.Live.Publish([]*pb.SensorData{data})
+		s.Alerts.Observe([]*pb.SensorData{data})
+		s.Alerts.ObserveAnomalies(s.Anomalies.Observe([]*pb.SensorData{data}))
 		log.Printf("Sent data: %v", data)
 	}
 }
 
-func StartGRPCServer(repo *repository.SensorRepository, port string) {
+// SendSensorBatch stores each batch in one transaction and acknowledges
+// the number of readings received once the client closes the stream
+func (s *SensorServer) SendSensorBatch(stream pb.SensorService_SendSensorBatchServer) error {
+	addr := remoteAddr(stream.Context())
+	var total model.IngestResult
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(ack(total))
+		}
+		if err != nil {
+			log.Printf("Stream error: %v", err)
+			return err
+		}
+
+		res, err := s.Repo.SaveBatch(batch.Readings)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			return status.Errorf(codes.Internal, "failed to save sensor batch: %v", err)
+		}
+		total = addResult(total, res)
+		s.Registry.Seen(batch.Readings, addr)
+		s.Live.Publish(res.Stored)
+		s.Alerts.Observe(res.Stored)
+		s.Alerts.ObserveAnomalies(s.Anomalies.Observe(res.Stored))
+		log.Printf("Saved batch of %d readings (%d duplicates, %d missing)", res.Saved, res.Duplicates, res.Gaps)
+	}
+}
+
+// GetHighWaterMarks reports the last accepted sequence of each sensor of a producer session
+func (s *SensorServer) GetHighWaterMarks(ctx context.Context, req *pb.HighWaterRequest) (*pb.HighWaterReply, error) {
+	if req.ProducerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "producer_id is required")
+	}
+	marks, err := s.Repo.GetHighWaterMarks(req.ProducerId)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to load high-water marks: %v", err)
+	}
+	reply := &pb.HighWaterReply{}
+	device, _ := DeviceFromContext(ctx)
+	for _, m := range marks {
+		// a producer session is not bound to a token, only report its own sensors
+		if device != nil && !device.Allows(m.ID1, m.ID2) {
+			continue
+		}
+		reply.Marks = append(reply.Marks, &pb.HighWaterMark{Id1: m.ID1, Id2: m.ID2, Seq: m.LastSeq})
+	}
+	return reply, nil
+}
+
+// Control keeps a generator's control stream attached to the hub: commands routed to
+// its sensors are pushed down the stream and its results resolve the waiting callers
+func (s *SensorServer) Control(stream pb.SensorService_ControlServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := first.GetHello()
+	if hello == nil {
+		return status.Error(codes.InvalidArgument, "first control message must be a hello")
+	}
+	session := s.Hub.Attach(hello)
+	defer s.Hub.Detach(session)
+	log.Printf("Generator %s attached control stream for %d sensors", hello.ProducerId, len(hello.Sensors))
+
+	if err := s.Registry.Connected(hello, remoteAddr(stream.Context())); err != nil {
+		log.Printf("Failed to register devices of %s: %v", hello.ProducerId, err)
+	}
+	defer func() {
+		if err := s.Registry.Disconnected(hello.ProducerId); err != nil {
+			log.Printf("Failed to mark devices of %s disconnected: %v", hello.ProducerId, err)
+		}
+	}()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			switch {
+			case msg.GetResult() != nil:
+				s.Hub.Resolve(session, msg.GetResult())
+			case msg.GetHeartbeat() != nil:
+				if err := s.Registry.Heartbeat(hello.ProducerId); err != nil {
+					log.Printf("Failed to record heartbeat of %s: %v", hello.ProducerId, err)
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case cmd := <-session.Commands:
+			if err := stream.Send(cmd); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			log.Printf("Generator %s detached control stream: %v", hello.ProducerId, err)
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Register records the sensors of a generator in the device registry
+func (s *SensorServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterReply, error) {
+	for _, ref := range req.Sensors {
+		if ref.Id1 == "" || ref.Id2 == "" || ref.SensorType == "" {
+			return nil, status.Error(codes.InvalidArgument, "id1, id2 and sensor_type are required")
+		}
+	}
+	n, err := s.Registry.Register(req.ProducerId, req.Firmware, remoteAddr(ctx), req.Sensors)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to register devices: %v", err)
+	}
+	return &pb.RegisterReply{Registered: int32(n)}, nil
+}
+
+// remoteAddr returns the address of the calling generator
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func addResult(total, res model.IngestResult) model.IngestResult {
+	total.Saved += res.Saved
+	total.Duplicates += res.Duplicates
+	total.Gaps += res.Gaps
+	return total
+}
+
+func ack(total model.IngestResult) *pb.Ack {
+	return &pb.Ack{
+		Ok:         true,
+		Message:    "All data received",
+		Received:   total.Saved,
+		Duplicates: total.Duplicates,
+		Gaps:       total.Gaps,
+	}
+}
+
+// StartGRPCServer serves the sensor service on port. Without creds the server
+// runs in plaintext; without devices device calls are not authenticated. Read
+// calls accept API keys resolved by keys.
+func StartGRPCServer(repo *repository.SensorRepository, hub *control.Hub, reg *registry.Registry, liveHub *live.Hub, alerts *alerting.Engine, anomalies *anomaly.Detector, jwtSecret string, revoked myMiddleware.RevocationList, keys myMiddleware.APIKeyAuthenticator, devices DeviceAuthenticator, creds credentials.TransportCredentials, port string) {
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterSensorServiceServer(grpcServer, &SensorServer{Repo: repo})
+	unary, stream := AuthInterceptors(jwtSecret, revoked, keys, methodPermissions, devices)
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream)}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterSensorServiceServer(grpcServer, &SensorServer{Repo: repo, Hub: hub, Registry: reg, Live: liveHub, Alerts: alerts, Anomalies: anomalies})
 
 	log.Printf("gRPC server running on %s", port)
 	if err := grpcServer.Serve(lis); err != nil {
@@ -0,0 +1,252 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"microservice-b/internal/live"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// QueryReadings returns stored readings like GET /api/sensors or, with an
+// aggregation, time buckets like GET /api/sensors/aggregate
+func (s *SensorServer) QueryReadings(ctx context.Context, req *pb.QueryReadingsRequest) (*pb.QueryReadingsReply, error) {
+	if err := checkID1(ctx, req.GetFilter().GetId1()); err != nil {
+		return nil, err
+	}
+	filters, err := readingFilters(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	if req.Aggregation != nil {
+		return s.aggregate(req.Aggregation, filters)
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 10
+	}
+	page := int(req.Page)
+	if page <= 0 {
+		page = 1
+	}
+	data, err := s.Repo.GetSensors(filters, limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Error(codes.Internal, "failed to query readings")
+	}
+	total, err := s.Repo.CountSensors(filters)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Error(codes.Internal, "failed to count readings")
+	}
+
+	reply := &pb.QueryReadingsReply{
+		Page:       int32(page),
+		Limit:      int32(limit),
+		Total:      total,
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	}
+	for i := range data {
+		reply.Readings = append(reply.Readings, toReading(&data[i]))
+	}
+	return reply, nil
+}
+
+func (s *SensorServer) aggregate(a *pb.Aggregation, filters map[string]interface{}) (*pb.QueryReadingsReply, error) {
+	bucketName := a.Bucket
+	if bucketName == "" {
+		bucketName = "1h"
+	}
+	bucket, ok := model.AggregateBuckets[bucketName]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "bucket must be one of 1m, 5m, 1h, 1d")
+	}
+	functions := a.Functions
+	if len(functions) == 0 {
+		functions = []string{"avg"}
+	}
+	for _, fn := range functions {
+		if !repository.ValidAggregateFunction(fn) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown aggregation function '%s'", fn)
+		}
+	}
+	for _, col := range a.GroupBy {
+		if !repository.ValidAggregateGroup(col) {
+			return nil, status.Error(codes.InvalidArgument, "group_by accepts id1, id2 and sensor_type")
+		}
+	}
+	switch a.Fill {
+	case model.FillNone, model.FillNull, model.FillPrevious, model.FillLinear:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "fill must be one of null, previous, linear")
+	}
+
+	points, err := s.Repo.Aggregate(model.AggregateQuery{
+		Bucket:    bucket,
+		Functions: functions,
+		GroupBy:   a.GroupBy,
+		Filters:   filters,
+		Fill:      a.Fill,
+	})
+	if errors.Is(err, repository.ErrTooManyBuckets) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Error(codes.Internal, "failed to aggregate readings")
+	}
+
+	reply := &pb.QueryReadingsReply{Total: int64(len(points))}
+	for _, p := range points {
+		b := &pb.AggregateBucket{
+			Bucket:     timestamppb.New(p.Bucket),
+			Id1:        p.ID1,
+			Id2:        p.ID2,
+			SensorType: p.SensorType,
+			Values:     make(map[string]float64, len(p.Values)),
+		}
+		for fn, v := range p.Values {
+			if v != nil {
+				b.Values[fn] = *v
+			}
+		}
+		reply.Buckets = append(reply.Buckets, b)
+	}
+	return reply, nil
+}
+
+// GetLatest returns the latest stored reading of every matching sensor
+func (s *SensorServer) GetLatest(ctx context.Context, req *pb.GetLatestRequest) (*pb.GetLatestReply, error) {
+	if err := checkID1(ctx, req.Id1); err != nil {
+		return nil, err
+	}
+	filters, err := readingFilters(&pb.ReadingFilter{Id1: req.Id1, Id2: req.Id2, SensorType: req.SensorType})
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Repo.GetLatest(filters)
+	if err != nil {
+		log.Printf("DB error: %v", err)
+		return nil, status.Error(codes.Internal, "failed to query readings")
+	}
+	reply := &pb.GetLatestReply{}
+	for i := range data {
+		reply.Readings = append(reply.Readings, toReading(&data[i]))
+	}
+	return reply, nil
+}
+
+// Subscribe streams readings as they are stored, like GET /api/stream. The
+// stream ends with RESOURCE_EXHAUSTED when a client with the DISCONNECT policy
+// falls behind.
+func (s *SensorServer) Subscribe(req *pb.SubscribeRequest, stream pb.SensorService_SubscribeServer) error {
+	ctx := stream.Context()
+	if err := checkID1(ctx, req.Id1); err != nil {
+		return err
+	}
+	if req.Id2 != "" {
+		if _, err := strconv.Atoi(req.Id2); err != nil {
+			return status.Error(codes.InvalidArgument, "id2 must be an integer")
+		}
+	}
+	r := live.Request{
+		Filter: live.Filter{ID1: req.Id1, ID2: req.Id2, SensorType: req.SensorType},
+		Policy: live.PolicyDrop,
+	}
+	if req.OnSlow == pb.SlowConsumerPolicy_DISCONNECT {
+		r.Policy = live.PolicyDisconnect
+	}
+	if req.Since != nil {
+		since := req.Since.AsTime()
+		r.Since = &since
+	}
+
+	var closed string
+	err := live.NewStreamer(s.Live, s.Repo).Run(ctx, r, func(e model.LiveEvent) error {
+		ev := &pb.SubscribeEvent{}
+		switch e.Type {
+		case model.LiveReading:
+			ev.Payload = &pb.SubscribeEvent_Reading{Reading: toReading(e.Reading)}
+		case model.LiveDropped:
+			ev.Payload = &pb.SubscribeEvent_Dropped{Dropped: e.Dropped}
+		case model.LiveTruncated:
+			ev.Payload = &pb.SubscribeEvent_Truncated{Truncated: true}
+		case model.LiveHeartbeat:
+			ev.Payload = &pb.SubscribeEvent_Heartbeat{Heartbeat: true}
+		case model.LiveError:
+			// reported as the status of the stream
+			closed = e.Error
+			return nil
+		}
+		return stream.Send(ev)
+	})
+	if err != nil {
+		log.Printf("Subscribe stream ended: %v", err)
+		return status.Error(codes.Internal, "failed to stream readings")
+	}
+	if closed != "" {
+		return status.Error(codes.ResourceExhausted, closed)
+	}
+	return nil
+}
+
+// readingFilters converts f into repository filters, with times in the zone
+// readings are stored in like the REST handlers
+func readingFilters(f *pb.ReadingFilter) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+	if f == nil {
+		return filters, nil
+	}
+	if f.Id1 != "" {
+		filters["id1"] = f.Id1
+	}
+	if f.Id2 != "" {
+		if _, err := strconv.Atoi(f.Id2); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "id2 must be an integer")
+		}
+		filters["id2"] = f.Id2
+	}
+	if f.SensorType != "" {
+		filters["sensor_type"] = f.SensorType
+	}
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	if f.From != nil {
+		filters["from"] = f.From.AsTime().In(loc)
+	}
+	if f.To != nil {
+		filters["to"] = f.To.AsTime().In(loc)
+	}
+	if f.IncludeArchived {
+		filters["include_archived"] = true
+	}
+	return filters, nil
+}
+
+func toReading(r *model.SensorReading) *pb.Reading {
+	reading := &pb.Reading{
+		Id:         r.ID,
+		Id1:        r.ID1,
+		Id2:        strconv.Itoa(r.ID2),
+		SensorType: r.SensorType,
+		Value:      r.Value,
+		Ts:         timestamppb.New(r.TS),
+	}
+	if !r.CreatedAt.IsZero() {
+		reading.CreatedAt = timestamppb.New(r.CreatedAt)
+	}
+	return reading
+}
@@ -0,0 +1,172 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"microservice-b/internal/live"
+	"microservice-b/internal/repository"
+	myMiddleware "microservice-b/middleware"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestServer(t *testing.T) (*SensorServer, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return &SensorServer{Repo: repository.NewSensorRepository(sqlx.NewDb(db, "mysql")), Live: live.NewHub(0)}, mock
+}
+
+func TestSensorServer_QueryReadings(t *testing.T) {
+	s, mock := newTestServer(t)
+	ts := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT \* FROM sensor_readings WHERE 1=1 AND id1 = \? AND archived_at IS NULL ORDER BY ts DESC LIMIT \? OFFSET \?`).
+		WithArgs("A", 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "value", "ts"}).AddRow(8, "A", 1, "Humidity", 42.5, ts))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM sensor_readings WHERE 1=1 AND id1 = \? AND archived_at IS NULL`).
+		WithArgs("A").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	reply, err := s.QueryReadings(context.Background(), &pb.QueryReadingsRequest{Filter: &pb.ReadingFilter{Id1: "A"}, Page: 2, Limit: 2})
+
+	require.NoError(t, err)
+	require.Len(t, reply.Readings, 1)
+	assert.Equal(t, "1", reply.Readings[0].Id2)
+	assert.Equal(t, ts, reply.Readings[0].Ts.AsTime())
+	assert.Equal(t, int64(3), reply.Total)
+	assert.Equal(t, int64(2), reply.TotalPages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorServer_QueryReadings_InvalidArguments(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, req := range []*pb.QueryReadingsRequest{
+		{Filter: &pb.ReadingFilter{Id2: "x"}},
+		{Aggregation: &pb.Aggregation{Bucket: "2h"}},
+		{Aggregation: &pb.Aggregation{Functions: []string{"median"}}},
+		{Aggregation: &pb.Aggregation{GroupBy: []string{"value"}}},
+		{Aggregation: &pb.Aggregation{Fill: "zero"}},
+	} {
+		_, err := s.QueryReadings(context.Background(), req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+}
+
+func TestSensorServer_QueryReadings_Aggregation(t *testing.T) {
+	s, mock := newTestServer(t)
+	bucket := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	// stddev is not served from rollups, so the raw readings are aggregated
+	mock.ExpectQuery(`SELECT bucket, sensor_type, COUNT\(\*\) AS .count., STDDEV_POP\(value\) AS .stddev. FROM \(SELECT .* FROM sensor_readings WHERE 1=1 AND sensor_type = \? AND archived_at IS NULL\) b GROUP BY bucket, sensor_type ORDER BY sensor_type, bucket`).
+		WithArgs(3600, 3600, "Humidity").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "sensor_type", "count", "stddev"}).AddRow(bucket, "Humidity", 4, nil))
+
+	reply, err := s.QueryReadings(context.Background(), &pb.QueryReadingsRequest{
+		Filter:      &pb.ReadingFilter{SensorType: "Humidity"},
+		Aggregation: &pb.Aggregation{Functions: []string{"count", "stddev"}, GroupBy: []string{"sensor_type"}},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, reply.Buckets, 1)
+	assert.Equal(t, "Humidity", reply.Buckets[0].SensorType)
+	// functions without a value are left out
+	assert.Equal(t, map[string]float64{"count": 4}, reply.Buckets[0].Values)
+	assert.Empty(t, reply.Readings)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorServer_GetLatest(t *testing.T) {
+	s, mock := newTestServer(t)
+	ts := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM sensor_readings WHERE archived_at IS NULL AND id1 = \? AND id2 = \?\) r WHERE rn = 1`).
+		WithArgs("A", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "value", "ts"}).AddRow(8, "A", 1, "Humidity", 42.5, ts))
+
+	reply, err := s.GetLatest(context.Background(), &pb.GetLatestRequest{Id1: "A", Id2: "1"})
+
+	require.NoError(t, err)
+	require.Len(t, reply.Readings, 1)
+	assert.Equal(t, 42.5, reply.Readings[0].Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorServer_ID1Allowlist(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx := context.WithValue(context.Background(), userKey{}, myMiddleware.CurrentUser{APIKeyID: 1, ID1Allowlist: []string{"A"}})
+
+	_, err := s.GetLatest(ctx, &pb.GetLatestRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = s.QueryReadings(ctx, &pb.QueryReadingsRequest{Filter: &pb.ReadingFilter{Id1: "B"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = s.Subscribe(&pb.SubscribeRequest{}, &subscribeStream{ctx: ctx})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// subscribeStream collects the events sent on a Subscribe stream
+type subscribeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *pb.SubscribeEvent
+}
+
+func (s *subscribeStream) Context() context.Context { return s.ctx }
+
+func (s *subscribeStream) Send(e *pb.SubscribeEvent) error {
+	s.events <- e
+	return nil
+}
+
+func TestSensorServer_Subscribe(t *testing.T) {
+	s, _ := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &subscribeStream{ctx: ctx, events: make(chan *pb.SubscribeEvent, 10)}
+	done := make(chan error)
+	go func() { done <- s.Subscribe(&pb.SubscribeRequest{Id1: "A"}, stream) }()
+
+	require.Eventually(t, func() bool { return s.Live.Subscribers() == 1 }, time.Second, time.Millisecond)
+	ts := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	s.Live.Publish([]*pb.SensorData{
+		{Id1: "B", Id2: "1", SensorType: "Humidity", Timestamp: timestamppb.New(ts)},
+		{Id1: "A", Id2: "2", SensorType: "Humidity", Value: 40.1, Timestamp: timestamppb.New(ts)},
+	})
+
+	e := <-stream.events
+	require.NotNil(t, e.GetReading())
+	assert.Equal(t, "2", e.GetReading().Id2)
+	assert.Equal(t, 40.1, e.GetReading().Value)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, s.Live.Subscribers())
+}
+
+func TestSensorServer_Subscribe_SlowConsumer(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Live = live.NewHub(1)
+	// the stream blocks until the test reads, so the buffer overflows
+	stream := &subscribeStream{ctx: context.Background(), events: make(chan *pb.SubscribeEvent)}
+	done := make(chan error)
+	go func() {
+		done <- s.Subscribe(&pb.SubscribeRequest{OnSlow: pb.SlowConsumerPolicy_DISCONNECT}, stream)
+	}()
+
+	require.Eventually(t, func() bool { return s.Live.Subscribers() == 1 }, time.Second, time.Millisecond)
+	for i := 0; i < 3; i++ {
+		s.Live.Publish([]*pb.SensorData{{Id1: "A", Id2: "1", Timestamp: timestamppb.Now()}})
+	}
+	go func() {
+		for range stream.events {
+		}
+	}()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(<-done))
+}
@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// testPKI writes a CA and a server and client certificate signed by it to dir
+type testPKI struct {
+	dir  string
+	pool *x509.CertPool
+	ca   *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	p := &testPKI{dir: t.TempDir(), pool: x509.NewCertPool(), ca: ca, key: key}
+	p.pool.AddCert(ca)
+	p.write(t, "ca.pem", "CERTIFICATE", der)
+	return p
+}
+
+func (p *testPKI) write(t *testing.T, name, blockType string, der []byte) string {
+	path := filepath.Join(p.dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// issue writes a certificate for name signed by the CA and returns its files
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return p.write(t, name+".pem", "CERTIFICATE", der), p.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestServerCredentials_Errors(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := pki.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+
+	_, err := ServerCredentials(filepath.Join(pki.dir, "missing.pem"), keyFile, "")
+	assert.Error(t, err)
+	_, err = ServerCredentials(certFile, keyFile, filepath.Join(pki.dir, "missing.pem"))
+	assert.Error(t, err)
+	_, err = ServerCredentials(certFile, keyFile, keyFile)
+	assert.ErrorContains(t, err, "no certificates")
+}
+
+func TestServerCredentials_MutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := pki.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	creds, err := ServerCredentials(certFile, keyFile, filepath.Join(pki.dir, "ca.pem"))
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(creds))
+	pb.RegisterSensorServiceServer(server, &SensorServer{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	call := func(cfg *tls.Config) error {
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// an empty producer id is rejected before the repository is used
+		_, err = pb.NewSensorServiceClient(conn).GetHighWaterMarks(ctx, &pb.HighWaterRequest{})
+		return err
+	}
+
+	// without a client certificate the handshake fails
+	err = call(&tls.Config{RootCAs: pki.pool, ServerName: "localhost"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	clientCert, clientKey := pki.issue(t, "generator", x509.ExtKeyUsageClientAuth)
+	pair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	err = call(&tls.Config{RootCAs: pki.pool, ServerName: "localhost", Certificates: []tls.Certificate{pair}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
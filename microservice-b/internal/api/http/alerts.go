@@ -0,0 +1,356 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/alerting"
+	"microservice-b/internal/repository"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AlertHandler struct {
+	repo   *repository.AlertRepository
+	engine *alerting.Engine
+}
+
+func NewAlertHandler(repo *repository.AlertRepository, engine *alerting.Engine) *AlertHandler {
+	return &AlertHandler{repo: repo, engine: engine}
+}
+
+// ListRules godoc
+// @Summary List alert rules
+// @Description Lists the alert rules, oldest first
+// @Tags Alerts
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Rules, e.g. {\"data\": [...], \"total\": 2}"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/alerts/rules [get]
+func (h *AlertHandler) ListRules(c echo.Context) error {
+	rules, err := h.repo.ListRules()
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	if rules == nil {
+		rules = []model.AlertRule{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": rules, "total": len(rules)})
+}
+
+// CreateRule godoc
+// @Summary Create an alert rule
+// @Description Creates a rule for a sensor type, an id1 or an id1/id2 pair, or for all sensors when none is given. Types: `threshold` fires when the value compares to `threshold` by `operator` (`>`, `>=`, `<`, `<=`); `range` fires when the value leaves [`min_value`, `max_value`]; `rate` fires when the change per minute between consecutive readings of a sensor compares to `threshold` by `operator` (e.g. `<` -5 for a drop faster than 5 per minute); `absence` fires when a sensor that reported before sends no reading for `for_seconds`; `anomaly` fires when the anomaly score of a reading, its distance from the baselines of its sensor in stddevs (see `GET /api/anomalies`), reaches `threshold`. Other rules fire once the condition held for `for_seconds` (default 0) and resolve once the value is back within the limit by `hysteresis`. Rules are evaluated on every stored reading; absence rules every `ALERT_INTERVAL`.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param payload body model.AlertRuleRequest true "Alert rule"
+// @Success 201 {object} model.AlertRule "Created rule"
+// @Failure 400 {object} model.ErrorResponse "Invalid rule"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/alerts/rules [post]
+func (h *AlertHandler) CreateRule(c echo.Context) error {
+	req := model.AlertRuleRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4601, "")
+	}
+	rule := &model.AlertRule{Severity: "warning", Enabled: true}
+	applyRuleRequest(rule, &req)
+	if err := validateRule(rule); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4602, "")
+	}
+	if caller, _ := myMiddleware.UserFromContext(c); caller.ID != 0 {
+		rule.CreatedBy = &caller.ID
+	}
+
+	created, err := h.repo.CreateRule(rule)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	h.reload(c)
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateRule godoc
+// @Summary Update an alert rule
+// @Description Updates the given fields of a rule. Changing its condition or disabling it resolves its firing alerts; the rule is then evaluated from scratch.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param payload body model.AlertRuleRequest true "Fields to change"
+// @Success 200 {object} model.AlertRule "Updated rule"
+// @Failure 400 {object} model.ErrorResponse "Invalid rule"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Rule not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/alerts/rules/{id} [patch]
+func (h *AlertHandler) UpdateRule(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid rule id", 4604, "")
+	}
+	req := model.AlertRuleRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4601, "")
+	}
+	existing, err := h.repo.GetRule(id)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	if existing == nil {
+		return utils.ErrorResponse(c, http.StatusNotFound, "rule not found", 4605, "")
+	}
+	rule := *existing
+	applyRuleRequest(&rule, &req)
+	if err := validateRule(&rule); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4602, "")
+	}
+
+	if rule.Condition() != existing.Condition() || !rule.Enabled {
+		if err := h.engine.ResolveRule(id, time.Now()); err != nil {
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+		}
+	}
+	updated, err := h.repo.UpdateRule(&rule)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	h.reload(c)
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRule godoc
+// @Summary Delete an alert rule
+// @Description Deletes a rule and resolves its firing alerts; its alerts stay listed
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"deleted\": 1}"
+// @Failure 400 {object} model.ErrorResponse "Invalid rule id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Rule not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/alerts/rules/{id} [delete]
+func (h *AlertHandler) DeleteRule(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid rule id", 4604, "")
+	}
+	ok, err := h.repo.DeleteRule(id)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	if !ok {
+		return utils.ErrorResponse(c, http.StatusNotFound, "rule not found", 4605, "")
+	}
+	if err := h.engine.ResolveRule(id, time.Now()); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	h.reload(c)
+	return c.JSON(http.StatusOK, map[string]interface{}{"deleted": id})
+}
+
+// ListAlerts godoc
+// @Summary List alerts
+// @Description Lists the alerts fired by the rules, most recently fired first. An alert is `firing` until its sensor is back within the limit of the rule, or until the rule is changed, disabled or deleted; then it is `resolved`.
+// @Tags Alerts
+// @Produce json
+// @Param state query string false "Filter by state" Enums(firing, resolved)
+// @Param rule_id query int false "Filter by rule"
+// @Param severity query string false "Filter by severity" example("critical")
+// @Param id1 query string false "Filter by ID1" example("A")
+// @Param id2 query int false "Filter by ID2" example(1)
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated alerts with metadata"
+// @Failure 400 {object} model.ErrorResponse "Invalid filter"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/alerts [get]
+func (h *AlertHandler) ListAlerts(c echo.Context) error {
+	filters := make(map[string]interface{})
+	switch state := c.QueryParam("state"); state {
+	case "":
+	case model.AlertFiring, model.AlertResolved:
+		filters["state"] = state
+	default:
+		return utils.ErrorResponse(c, http.StatusBadRequest, "state must be 'firing' or 'resolved'", 4606, "")
+	}
+	if v := c.QueryParam("rule_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "rule_id must be a number", 4606, "")
+		}
+		filters["rule_id"] = id
+	}
+	if v := c.QueryParam("severity"); v != "" {
+		filters["severity"] = v
+	}
+	if v := c.QueryParam("id1"); v != "" {
+		filters["id1"] = v
+	}
+	if v := c.QueryParam("id2"); v != "" {
+		id2, err := strconv.Atoi(v)
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "id2 must be an integer", 4606, "")
+		}
+		filters["id2"] = id2
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	alerts, err := h.repo.ListAlerts(filters, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	total, err := h.repo.CountAlerts(filters)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4603, err.Error())
+	}
+	if alerts == nil {
+		alerts = []model.Alert{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        alerts,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// reload makes the engine pick up a rule change right away; should it fail,
+// the change is picked up with the next periodic reload
+func (h *AlertHandler) reload(c echo.Context) {
+	if err := h.engine.Load(); err != nil {
+		c.Logger().Errorf("reloading alert rules failed: %v", err)
+	}
+}
+
+// applyRuleRequest copies the fields set in req onto rule
+func applyRuleRequest(rule *model.AlertRule, req *model.AlertRuleRequest) {
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		rule.Type = *req.Type
+	}
+	if req.SensorType != nil {
+		rule.SensorType = req.SensorType
+	}
+	if req.ID1 != nil {
+		rule.ID1 = req.ID1
+	}
+	if req.ID2 != nil {
+		rule.ID2 = req.ID2
+	}
+	if req.Operator != nil {
+		rule.Operator = req.Operator
+	}
+	if req.Threshold != nil {
+		rule.Threshold = req.Threshold
+	}
+	if req.MinValue != nil {
+		rule.MinValue = req.MinValue
+	}
+	if req.MaxValue != nil {
+		rule.MaxValue = req.MaxValue
+	}
+	if req.Hysteresis != nil {
+		rule.Hysteresis = *req.Hysteresis
+	}
+	if req.ForSeconds != nil {
+		rule.ForSeconds = *req.ForSeconds
+	}
+	if req.Severity != nil {
+		rule.Severity = *req.Severity
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
+
+// validateRule checks a rule and clears the fields its type does not use
+func validateRule(rule *model.AlertRule) error {
+	if rule.Name == "" || len(rule.Name) > 255 {
+		return errors.New("name is required and at most 255 characters")
+	}
+	if rule.ID2 != nil && rule.ID1 == nil {
+		return errors.New("id2 requires id1")
+	}
+	switch rule.Severity {
+	case "info", "warning", "critical":
+	default:
+		return errors.New("severity must be one of info, warning, critical")
+	}
+	if rule.Hysteresis < 0 || rule.ForSeconds < 0 {
+		return errors.New("hysteresis and for_seconds must not be negative")
+	}
+
+	switch rule.Type {
+	case model.AlertThreshold, model.AlertRate:
+		if rule.Operator == nil || !validOperator(*rule.Operator) {
+			return errors.New("operator must be one of >, >=, <, <=")
+		}
+		if rule.Threshold == nil {
+			return errors.New("threshold is required")
+		}
+		rule.MinValue, rule.MaxValue = nil, nil
+	case model.AlertRange:
+		if rule.MinValue == nil || rule.MaxValue == nil {
+			return errors.New("min_value and max_value are required")
+		}
+		if *rule.MinValue+rule.Hysteresis > *rule.MaxValue-rule.Hysteresis {
+			return errors.New("min_value must be below max_value by twice the hysteresis")
+		}
+		rule.Operator, rule.Threshold = nil, nil
+	case model.AlertAnomaly:
+		if rule.Threshold == nil || *rule.Threshold <= 0 {
+			return errors.New("threshold must be a positive anomaly score")
+		}
+		if rule.Hysteresis >= *rule.Threshold {
+			return errors.New("hysteresis must be below the threshold")
+		}
+		rule.Operator, rule.MinValue, rule.MaxValue = nil, nil, nil
+	case model.AlertAbsence:
+		if rule.ForSeconds <= 0 {
+			return errors.New("for_seconds is required for absence rules")
+		}
+		rule.Operator, rule.Threshold, rule.MinValue, rule.MaxValue = nil, nil, nil, nil
+		rule.Hysteresis = 0
+	default:
+		return errors.New("type must be one of threshold, range, rate, absence, anomaly")
+	}
+	return nil
+}
+
+func validOperator(op string) bool {
+	switch op {
+	case ">", ">=", "<", "<=":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice-b/internal/alerting"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlertHandler(t *testing.T) (*AlertHandler, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sqlxDB := sqlx.NewDb(db, "mysql")
+	repo := repository.NewAlertRepository(sqlxDB)
+	return NewAlertHandler(repo, alerting.New(repo, repository.NewSensorRepository(sqlxDB), time.Minute)), mock
+}
+
+// expectReload expects the engine to load rules and firing alerts
+func expectReload(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM alert_rules ORDER BY id").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM alerts WHERE state = \\?").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestAlertHandler_CreateRule(t *testing.T) {
+	e := echo.New()
+	handler, mock := newAlertHandler(t)
+
+	mock.ExpectExec("INSERT INTO alert_rules").
+		WithArgs("hot boiler", model.AlertThreshold, "Temperature", nil, nil, ">", 80.0, nil, nil, 2.0, 60, "warning", true, nil).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectQuery("SELECT \\* FROM alert_rules WHERE id = \\?").
+		WithArgs(uint64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "sensor_type", "operator", "threshold", "hysteresis", "for_seconds", "severity", "enabled"}).
+			AddRow(3, "hot boiler", model.AlertThreshold, "Temperature", ">", 80.0, 2.0, 60, "warning", true))
+	expectReload(mock)
+
+	rec := httptest.NewRecorder()
+	// min_value is not used by threshold rules and dropped
+	body := `{"name": " hot boiler ", "type": "threshold", "sensor_type": "Temperature", "operator": ">", "threshold": 80, "min_value": 1, "hysteresis": 2, "for_seconds": 60}`
+	require.NoError(t, handler.CreateRule(jsonContext(e, http.MethodPost, body, rec)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created model.AlertRule
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, uint64(3), created.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlertHandler_CreateRule_Invalid(t *testing.T) {
+	e := echo.New()
+	handler, _ := newAlertHandler(t)
+
+	tests := map[string]string{
+		"no name":             `{"type": "threshold", "operator": ">", "threshold": 1}`,
+		"unknown type":        `{"name": "x", "type": "spike"}`,
+		"no operator":         `{"name": "x", "type": "threshold", "threshold": 1}`,
+		"unknown operator":    `{"name": "x", "type": "rate", "operator": "==", "threshold": 1}`,
+		"no threshold":        `{"name": "x", "type": "threshold", "operator": ">"}`,
+		"range without max":   `{"name": "x", "type": "range", "min_value": 1}`,
+		"range too narrow":    `{"name": "x", "type": "range", "min_value": 1, "max_value": 2, "hysteresis": 1}`,
+		"absence without for": `{"name": "x", "type": "absence"}`,
+		"id2 without id1":     `{"name": "x", "type": "absence", "for_seconds": 60, "id2": 1}`,
+		"unknown severity":    `{"name": "x", "type": "absence", "for_seconds": 60, "severity": "fatal"}`,
+		"negative for":        `{"name": "x", "type": "threshold", "operator": ">", "threshold": 1, "for_seconds": -1}`,
+		"anomaly threshold":   `{"name": "x", "type": "anomaly"}`,
+		"anomaly hysteresis":  `{"name": "x", "type": "anomaly", "threshold": 4, "hysteresis": 4}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.NoError(t, handler.CreateRule(jsonContext(e, http.MethodPost, body, rec)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestAlertHandler_UpdateRule_ConditionChangeResolvesAlerts(t *testing.T) {
+	e := echo.New()
+	handler, mock := newAlertHandler(t)
+	rule := func(threshold float64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "type", "operator", "threshold", "severity", "enabled"}).
+			AddRow(3, "hot boiler", model.AlertThreshold, ">", threshold, "warning", true)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM alert_rules WHERE id = \\?").WithArgs(uint64(3)).WillReturnRows(rule(80))
+	mock.ExpectExec("UPDATE alerts SET state = \\?, resolved_at = \\? WHERE rule_id = \\? AND state = \\?").
+		WithArgs(model.AlertResolved, sqlmock.AnyArg(), uint64(3), model.AlertFiring).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE alert_rules SET").
+		WithArgs("hot boiler", model.AlertThreshold, nil, nil, nil, ">", 90.0, nil, nil, 0.0, 0, "warning", true, uint64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM alert_rules WHERE id = \\?").WithArgs(uint64(3)).WillReturnRows(rule(90))
+	expectReload(mock)
+
+	rec := httptest.NewRecorder()
+	c := jsonContext(e, http.MethodPatch, `{"threshold": 90}`, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+	require.NoError(t, handler.UpdateRule(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlertHandler_UpdateRule_RenameKeepsAlerts(t *testing.T) {
+	e := echo.New()
+	handler, mock := newAlertHandler(t)
+	rows := func(name string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "type", "for_seconds", "severity", "enabled"}).
+			AddRow(4, name, model.AlertAbsence, 300, "warning", true)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM alert_rules WHERE id = \\?").WithArgs(uint64(4)).WillReturnRows(rows("silent"))
+	mock.ExpectExec("UPDATE alert_rules SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM alert_rules WHERE id = \\?").WithArgs(uint64(4)).WillReturnRows(rows("silent sensor"))
+	expectReload(mock)
+
+	rec := httptest.NewRecorder()
+	c := jsonContext(e, http.MethodPatch, `{"name": "silent sensor", "severity": "critical"}`, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("4")
+	require.NoError(t, handler.UpdateRule(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlertHandler_ListAlerts(t *testing.T) {
+	e := echo.New()
+	handler, mock := newAlertHandler(t)
+
+	mock.ExpectQuery("SELECT \\* FROM alerts WHERE 1=1 AND state = \\? AND id1 = \\? ORDER BY fired_at DESC, id DESC LIMIT \\? OFFSET \\?").
+		WithArgs(model.AlertFiring, "A", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_id", "id1", "id2", "state", "message"}).AddRow(5, 3, "A", 1, model.AlertFiring, "value 85 > 80"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM alerts WHERE 1=1 AND state = \\? AND id1 = \\?").
+		WithArgs(model.AlertFiring, "A").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/alerts?state=firing&id1=A", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ListAlerts(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"state":"firing"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/alerts?state=pending", nil)
+	rec = httptest.NewRecorder()
+	require.NoError(t, handler.ListAlerts(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AnomalyHandler struct {
+	repo *repository.AnomalyRepository
+}
+
+func NewAnomalyHandler(repo *repository.AnomalyRepository) *AnomalyHandler {
+	return &AnomalyHandler{repo: repo}
+}
+
+// ListAnomalies godoc
+// @Summary List anomalous readings
+// @Description Lists the readings flagged as anomalous when they arrived, latest first. Every reading is scored against three baselines of its sensor built from the readings before it: `rolling` (mean and stddev of the last `ANOMALY_WINDOW` readings), `ewma` (exponentially weighted mean and variance of the same span) and `seasonal` (exponentially weighted mean and variance of the readings in the same UTC hour of day, with the same span). `z_rolling`, `z_ewma` and `z_seasonal` are the distances from each baseline in stddevs; `method`, `score` and `expected` are those of the largest one. A reading is anomalous from an absolute score of `ANOMALY_THRESHOLD` (default 4). Baselines score after 30 readings and are kept in memory, so they warm up again after a restart.
+// @Tags Anomalies
+// @Produce json
+// @Param id1 query string false "Filter by ID1" example("A")
+// @Param id2 query int false "Filter by ID2" example(1)
+// @Param sensor_type query string false "Filter by sensor type" example("Temperature")
+// @Param method query string false "Filter by the method with the largest deviation" Enums(rolling, ewma, seasonal)
+// @Param min_score query number false "Only anomalies with at least this absolute score" example(6)
+// @Param from query string false "Reading time from (RFC3339)" example("2025-09-08T00:00:00Z")
+// @Param to query string false "Reading time to (RFC3339)" example("2025-09-09T00:00:00Z")
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated anomalies with metadata"
+// @Failure 400 {object} model.ErrorResponse "Invalid filter"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/anomalies [get]
+func (h *AnomalyHandler) ListAnomalies(c echo.Context) error {
+	filters := make(map[string]interface{})
+	for _, k := range []string{"id1", "sensor_type"} {
+		if v := c.QueryParam(k); v != "" {
+			filters[k] = v
+		}
+	}
+	if v := c.QueryParam("id2"); v != "" {
+		id2, err := strconv.Atoi(v)
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "id2 must be an integer", 4801, "")
+		}
+		filters["id2"] = id2
+	}
+	switch method := c.QueryParam("method"); method {
+	case "":
+	case model.AnomalyRolling, model.AnomalyEWMA, model.AnomalySeasonal:
+		filters["method"] = method
+	default:
+		return utils.ErrorResponse(c, http.StatusBadRequest, "method must be one of rolling, ewma, seasonal", 4801, "")
+	}
+	if v := c.QueryParam("min_score"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "min_score must be a number", 4801, "")
+		}
+		filters["min_score"] = score
+	}
+	for _, k := range []string{"from", "to"} {
+		if v := c.QueryParam(k); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return utils.ErrorResponse(c, http.StatusBadRequest, "invalid '"+k+"' time format", 4801, "")
+			}
+			filters[k] = t
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	anomalies, err := h.repo.List(filters, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4802, err.Error())
+	}
+	total, err := h.repo.Count(filters)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4802, err.Error())
+	}
+	if anomalies == nil {
+		anomalies = []model.Anomaly{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        anomalies,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyHandler_ListAnomalies(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	handler := NewAnomalyHandler(repository.NewAnomalyRepository(sqlx.NewDb(db, "mysql")))
+
+	from := time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM sensor_anomalies WHERE 1=1 AND id1 = \\? AND id2 = \\? AND method = \\? AND ts >= \\? AND ABS\\(score\\) >= \\? ORDER BY ts DESC, id DESC LIMIT \\? OFFSET \\?").
+		WithArgs("A", 1, model.AnomalyEWMA, from, 6.0, 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "value", "ts", "method", "score", "expected", "z_rolling", "z_ewma", "z_seasonal"}).
+			AddRow(3, "A", 1, "Temperature", 12.5, from.Add(time.Hour), model.AnomalyEWMA, -7.25, 21.0, -6.5, -7.25, nil))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM sensor_anomalies WHERE 1=1 AND id1 = \\? AND id2 = \\? AND method = \\? AND ts >= \\? AND ABS\\(score\\) >= \\?").
+		WithArgs("A", 1, model.AnomalyEWMA, from, 6.0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/anomalies?id1=A&id2=1&method=ewma&min_score=6&from=2025-09-08T00:00:00Z&page=2&limit=5", nil), rec)
+	require.NoError(t, handler.ListAnomalies(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"score":-7.25`)
+	assert.Contains(t, rec.Body.String(), `"total_pages":2`)
+	assert.NotContains(t, rec.Body.String(), "z_seasonal")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnomalyHandler_ListAnomalies_InvalidFilter(t *testing.T) {
+	e := echo.New()
+	handler := NewAnomalyHandler(nil)
+
+	for _, query := range []string{"id2=x", "method=median", "min_score=high", "to=yesterday"} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/anomalies?"+query, nil), rec)
+		require.NoError(t, handler.ListAnomalies(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/usecase"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type APIKeyHandler struct {
+	keys usecase.IAPIKeyManager
+}
+
+func NewAPIKeyHandler(keys usecase.IAPIKeyManager) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates an API key for the caller. Machine clients send it in the `X-API-Key` header or as a Bearer token instead of a JWT. Scopes (`sensors:read`, `sensors:write`, `sensors:delete`) cannot exceed the caller's role, and the key acts with its owner's current role. With `id1_allowlist` the key can only call the sensor routes filtered by `id1` with one of the listed values. Admins can create `service_account` keys owned by no user. The key is returned only in this response.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param payload body model.CreateAPIKeyRequest true "Key settings"
+// @Success 201 {object} model.CreateAPIKeyResponse "API key and the key itself"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 403 {object} model.ErrorResponse "Scope or service account not allowed"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	req := model.CreateAPIKeyRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4301, "")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "name is required and at most 100 characters", 4302, "")
+	}
+	if len(req.Scopes) == 0 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "at least one scope is required", 4303, "")
+	}
+	for _, scope := range req.Scopes {
+		if _, ok := myMiddleware.ScopePermissions[scope]; !ok {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "unknown scope "+scope, 4303, "valid scopes are sensors:read, sensors:write and sensors:delete")
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "expires_at must be in the future", 4304, "")
+	}
+	for i, id1 := range req.ID1Allowlist {
+		req.ID1Allowlist[i] = strings.TrimSpace(id1)
+		if req.ID1Allowlist[i] == "" || len(req.ID1Allowlist[i]) > 16 || strings.Contains(id1, ",") {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "invalid id1 in id1_allowlist", 4305, "")
+		}
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+	if req.ServiceAccount && caller.Role != "admin" {
+		return utils.ErrorResponse(c, http.StatusForbidden, "only admins can create service account keys", 4306, "")
+	}
+
+	key, err := h.keys.CreateAPIKey(caller, &req)
+	if err != nil {
+		if errors.Is(err, utils.ErrScopeNotAllowed) {
+			return utils.ErrorResponse(c, http.StatusForbidden, err.Error(), 4307, "")
+		}
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4308, err.Error())
+	}
+	return c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Lists the caller's API keys, newest first, with their last use. Admins list every key, service account keys included, with `all=true`.
+// @Tags API Keys
+// @Produce json
+// @Param all query bool false "All users' keys (admins only)"
+// @Param include_revoked query bool false "Include revoked keys"
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated API keys with metadata"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c echo.Context) error {
+	caller, _ := myMiddleware.UserFromContext(c)
+	filters := make(map[string]interface{})
+	if all, _ := strconv.ParseBool(c.QueryParam("all")); !all || caller.Role != "admin" {
+		filters["user_id"] = caller.ID
+	}
+	if include, _ := strconv.ParseBool(c.QueryParam("include_revoked")); include {
+		filters["include_revoked"] = true
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	keys, total, err := h.keys.ListAPIKeys(filters, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4308, err.Error())
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        keys,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revokes one of the caller's API keys; admins can revoke any key.
+// @Tags API Keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"revoked\": 3}"
+// @Failure 400 {object} model.ErrorResponse "Invalid API key id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "No active key with this id"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid api key id", 4309, "")
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+	if err := h.keys.RevokeAPIKey(caller, id); err != nil {
+		if errors.Is(err, utils.ErrAPIKeyNotFound) {
+			return utils.ErrorResponse(c, http.StatusNotFound, err.Error(), 4310, "")
+		}
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4308, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"revoked": id})
+}
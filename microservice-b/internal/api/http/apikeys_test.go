@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAPIKeyManager struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyManager) CreateAPIKey(caller myMiddleware.CurrentUser, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	args := m.Called(caller, req)
+	k, _ := args.Get(0).(*model.CreateAPIKeyResponse)
+	return k, args.Error(1)
+}
+
+func (m *MockAPIKeyManager) ListAPIKeys(filters map[string]interface{}, limit, offset int) ([]model.APIKey, int64, error) {
+	args := m.Called(filters, limit, offset)
+	return args.Get(0).([]model.APIKey), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAPIKeyManager) RevokeAPIKey(caller myMiddleware.CurrentUser, id uint64) error {
+	return m.Called(caller, id).Error(0)
+}
+
+// analystContext returns a context for a request by analyst 7
+func analystContext(e *echo.Echo, method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"user_id": float64(7), "role": "analyst"}})
+	return c, rec
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	e := echo.New()
+	keys := new(MockAPIKeyManager)
+	handler := NewAPIKeyHandler(keys)
+	keys.On("CreateAPIKey", mock.Anything, mock.Anything).Return(&model.CreateAPIKeyResponse{Key: "smk_1a2b3c4d_secret"}, nil)
+
+	for body, want := range map[string]int{
+		`{"scopes":["sensors:read"]}`:             http.StatusBadRequest,
+		`{"name":"etl"}`:                          http.StatusBadRequest,
+		`{"name":"etl","scopes":["users:admin"]}`: http.StatusBadRequest,
+		`{"name":"etl","scopes":["sensors:read"],"expires_at":"2020-01-01T00:00:00Z"}`: http.StatusBadRequest,
+		`{"name":"etl","scopes":["sensors:read"],"id1_allowlist":["A,B"]}`:             http.StatusBadRequest,
+		`{"name":"etl","scopes":["sensors:read"],"service_account":true}`:              http.StatusForbidden,
+		`{"name":"etl","scopes":["sensors:read"],"id1_allowlist":[" A "]}`:             http.StatusCreated,
+	} {
+		c, rec := analystContext(e, http.MethodPost, "/api/keys", body)
+		require.NoError(t, handler.CreateAPIKey(c))
+		assert.Equal(t, want, rec.Code, body)
+	}
+	keys.AssertNumberOfCalls(t, "CreateAPIKey", 1)
+	req := keys.Calls[0].Arguments.Get(1).(*model.CreateAPIKeyRequest)
+	assert.Equal(t, []string{"A"}, req.ID1Allowlist)
+}
+
+func TestAPIKeyHandler_ListAPIKeys_OwnKeysOnly(t *testing.T) {
+	e := echo.New()
+	keys := new(MockAPIKeyManager)
+	handler := NewAPIKeyHandler(keys)
+	keys.On("ListAPIKeys", map[string]interface{}{"user_id": uint64(7)}, 10, 0).Return([]model.APIKey{}, int64(0), nil)
+
+	// all=true is ignored for analysts
+	c, rec := analystContext(e, http.MethodGet, "/api/keys?all=true", "")
+	require.NoError(t, handler.ListAPIKeys(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	keys.AssertExpectations(t)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditHandler(repo *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// ListAuditEvents godoc
+// @Summary List audit events
+// @Description Lists the recorded API actions, newest first. Every authenticated request and every login attempt is recorded with the caller, the action (method and route), its path and query parameters, the response status and, for changes to readings, the number of affected rows.
+// @Tags Audit
+// @Produce json
+// @Param user_id query int false "Filter by user ID"
+// @Param email query string false "Filter by user email"
+// @Param action query string false "Filter by action (method and route)" example("DELETE /api/sensors")
+// @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
+// @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated audit events with metadata"
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/audit [get]
+func (h *AuditHandler) ListAuditEvents(c echo.Context) error {
+	filters := make(map[string]interface{})
+	if userID := c.QueryParam("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid user_id"})
+		}
+		filters["user_id"] = id
+	}
+	if email := c.QueryParam("email"); email != "" {
+		filters["email"] = email
+	}
+	if action := c.QueryParam("action"); action != "" {
+		filters["action"] = action
+	}
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid 'from' time format"})
+		}
+		filters["from"] = t.UTC()
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid 'to' time format"})
+		}
+		filters["to"] = t.UTC()
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	events, err := h.repo.List(filters, limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if events == nil {
+		events = []model.AuditEvent{}
+	}
+	total, err := h.repo.Count(filters)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        events,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
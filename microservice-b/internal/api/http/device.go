@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"microservice-b/internal/control"
+	"microservice-b/internal/registry"
+	"microservice-b/model"
+	"net/http"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/labstack/echo/v4"
+)
+
+// commandTimeout bounds how long an admin request waits for the generator's reply
+const commandTimeout = 10 * time.Second
+
+type DeviceHandler struct {
+	hub      *control.Hub
+	registry *registry.Registry
+	timeout  time.Duration
+}
+
+func NewDeviceHandler(hub *control.Hub, reg *registry.Registry) *DeviceHandler {
+	return &DeviceHandler{hub: hub, registry: reg, timeout: commandTimeout}
+}
+
+// ListDevices godoc
+// @Summary List registered devices
+// @Description Lists every sensor known to the device registry with its sensor type, unit, firmware, remote address, first/last seen time and whether its generator has a control stream attached. A device is `online` while it sent readings or heartbeats recently, otherwise `offline`.
+// @Tags Devices
+// @Produce json
+// @Param status query string false "Filter by status" Enums(online, offline)
+// @Success 200 {object} map[string]interface{} "Devices, e.g. {\"data\": [...], \"total\": 5}"
+// @Failure 400 {object} map[string]string "Invalid status filter"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/devices [get]
+func (h *DeviceHandler) ListDevices(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status != "" && status != registry.StatusOnline && status != registry.StatusOffline {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "status must be 'online' or 'offline'"})
+	}
+	devices, err := h.registry.List(status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": devices, "total": len(devices)})
+}
+
+// SetFrequency godoc
+// @Summary Change the frequency of a sensor
+// @Description Pushes a frequency change over the control stream to the generator hosting the sensor and reports whether it was applied. The frequency is a Go duration, e.g. `500ms` or `2s`.
+// @Tags Devices
+// @Accept json
+// @Produce json
+// @Param id1 path string true "Sensor ID1" example("A")
+// @Param id2 path string true "Sensor ID2" example("1")
+// @Param payload body model.SetFrequencyRequest true "New frequency"
+// @Success 200 {object} control.Result "Command applied"
+// @Failure 400 {object} map[string]string "Invalid frequency"
+// @Failure 404 {object} map[string]string "No generator connected for the sensor"
+// @Failure 422 {object} control.Result "Generator rejected the command"
+// @Failure 504 {object} map[string]string "Generator did not reply in time"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/devices/{id1}/{id2}/frequency [post]
+func (h *DeviceHandler) SetFrequency(c echo.Context) error {
+	req := new(model.SetFrequencyRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	}
+	freq, err := time.ParseDuration(req.Frequency)
+	if err != nil || freq <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "frequency must be a positive duration, e.g. 2s"})
+	}
+	return h.dispatch(c, &pb.Command{Type: pb.CommandType_SET_FREQUENCY, FrequencyMs: freq.Milliseconds()})
+}
+
+// Pause godoc
+// @Summary Pause a sensor
+// @Description Stops the generator from producing readings for the sensor until it is resumed.
+// @Tags Devices
+// @Produce json
+// @Param id1 path string true "Sensor ID1" example("A")
+// @Param id2 path string true "Sensor ID2" example("1")
+// @Success 200 {object} control.Result "Command applied"
+// @Failure 404 {object} map[string]string "No generator connected for the sensor"
+// @Failure 422 {object} control.Result "Generator rejected the command"
+// @Failure 504 {object} map[string]string "Generator did not reply in time"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/devices/{id1}/{id2}/pause [post]
+func (h *DeviceHandler) Pause(c echo.Context) error {
+	return h.dispatch(c, &pb.Command{Type: pb.CommandType_PAUSE})
+}
+
+// Resume godoc
+// @Summary Resume a paused sensor
+// @Tags Devices
+// @Produce json
+// @Param id1 path string true "Sensor ID1" example("A")
+// @Param id2 path string true "Sensor ID2" example("1")
+// @Success 200 {object} control.Result "Command applied"
+// @Failure 404 {object} map[string]string "No generator connected for the sensor"
+// @Failure 422 {object} control.Result "Generator rejected the command"
+// @Failure 504 {object} map[string]string "Generator did not reply in time"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/devices/{id1}/{id2}/resume [post]
+func (h *DeviceHandler) Resume(c echo.Context) error {
+	return h.dispatch(c, &pb.Command{Type: pb.CommandType_RESUME})
+}
+
+// SetValueModel godoc
+// @Summary Change the value model of a sensor
+// @Description Replaces the value model (constant, uniform, gaussian, random_walk, sine, step, poisson) the generator uses for the sensor. Params are model specific, e.g. `{"mean": "21", "amplitude": "4", "period": "24h"}` for sine.
+// @Tags Devices
+// @Accept json
+// @Produce json
+// @Param id1 path string true "Sensor ID1" example("A")
+// @Param id2 path string true "Sensor ID2" example("1")
+// @Param payload body model.ValueModelRequest true "Value model"
+// @Success 200 {object} control.Result "Command applied"
+// @Failure 400 {object} map[string]string "Invalid body"
+// @Failure 404 {object} map[string]string "No generator connected for the sensor"
+// @Failure 422 {object} control.Result "Generator rejected the command"
+// @Failure 504 {object} map[string]string "Generator did not reply in time"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/devices/{id1}/{id2}/model [put]
+func (h *DeviceHandler) SetValueModel(c echo.Context) error {
+	req := new(model.ValueModelRequest)
+	if err := c.Bind(req); err != nil || req.Type == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body, type is required"})
+	}
+	return h.dispatch(c, &pb.Command{
+		Type:       pb.CommandType_SET_VALUE_MODEL,
+		ValueModel: &pb.ValueModelConfig{Type: req.Type, Seed: req.Seed, Params: req.Params},
+	})
+}
+
+// FlushSpool godoc
+// @Summary Flush the spool of a generator
+// @Description Makes the generator hosting the sensor deliver its spooled readings right away instead of waiting for batches to fill up.
+// @Tags Devices
+// @Produce json
+// @Param id1 path string true "Sensor ID1" example("A")
+// @Param id2 path string true "Sensor ID2" example("1")
+// @Success 200 {object} control.Result "Command applied"
+// @Failure 404 {object} map[string]string "No generator connected for the sensor"
+// @Failure 422 {object} control.Result "Generator rejected the command"
+// @Failure 504 {object} map[string]string "Generator did not reply in time"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/devices/{id1}/{id2}/flush [post]
+func (h *DeviceHandler) FlushSpool(c echo.Context) error {
+	return h.dispatch(c, &pb.Command{Type: pb.CommandType_FLUSH_SPOOL})
+}
+
+// dispatch routes cmd to the live control stream of the sensor in the path
+func (h *DeviceHandler) dispatch(c echo.Context, cmd *pb.Command) error {
+	cmd.Id1, cmd.Id2 = c.Param("id1"), c.Param("id2")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+	res, err := h.hub.Dispatch(ctx, cmd)
+	switch {
+	case errors.Is(err, control.ErrNotConnected):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, control.ErrDisconnected):
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "generator did not reply in time"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !res.Applied {
+		return c.JSON(http.StatusUnprocessableEntity, res)
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice-b/internal/control"
+	"microservice-b/internal/registry"
+	"microservice-b/internal/repository"
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deviceContext(e *echo.Echo, method, body string, rec *httptest.ResponseRecorder) echo.Context {
+	req := httptest.NewRequest(method, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id1", "id2")
+	c.SetParamValues("A", "1")
+	return c
+}
+
+func TestDeviceHandler_SetFrequency(t *testing.T) {
+	e := echo.New()
+	hub := control.NewHub()
+	handler := NewDeviceHandler(hub, nil)
+
+	session := hub.Attach(&pb.Hello{ProducerId: "p1", Sensors: []*pb.SensorRef{{Id1: "A", Id2: "1"}}})
+	defer hub.Detach(session)
+	received := make(chan *pb.Command, 1)
+	go func() {
+		cmd := <-session.Commands
+		received <- cmd
+		hub.Resolve(session, &pb.CommandResult{CommandId: cmd.Id, Ok: true})
+	}()
+
+	rec := httptest.NewRecorder()
+	err := handler.SetFrequency(deviceContext(e, http.MethodPost, `{"frequency":"2s"}`, rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cmd := <-received
+	assert.Equal(t, pb.CommandType_SET_FREQUENCY, cmd.Type)
+	assert.Equal(t, int64(2000), cmd.FrequencyMs)
+
+	var res control.Result
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.True(t, res.Applied)
+}
+
+func TestDeviceHandler_SetFrequency_InvalidDuration(t *testing.T) {
+	e := echo.New()
+	handler := NewDeviceHandler(control.NewHub(), nil)
+
+	rec := httptest.NewRecorder()
+	err := handler.SetFrequency(deviceContext(e, http.MethodPost, `{"frequency":"fast"}`, rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDeviceHandler_NotConnected(t *testing.T) {
+	e := echo.New()
+	handler := NewDeviceHandler(control.NewHub(), nil)
+
+	rec := httptest.NewRecorder()
+	err := handler.Pause(deviceContext(e, http.MethodPost, "", rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeviceHandler_Rejected(t *testing.T) {
+	e := echo.New()
+	hub := control.NewHub()
+	handler := NewDeviceHandler(hub, nil)
+
+	session := hub.Attach(&pb.Hello{ProducerId: "p1", Sensors: []*pb.SensorRef{{Id1: "A", Id2: "1"}}})
+	defer hub.Detach(session)
+	go func() {
+		cmd := <-session.Commands
+		hub.Resolve(session, &pb.CommandResult{CommandId: cmd.Id, Error: "unknown value model \"foo\""})
+	}()
+
+	rec := httptest.NewRecorder()
+	err := handler.SetValueModel(deviceContext(e, http.MethodPut, `{"type":"foo"}`, rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unknown value model")
+}
+
+func TestDeviceHandler_Timeout(t *testing.T) {
+	e := echo.New()
+	hub := control.NewHub()
+	handler := NewDeviceHandler(hub, nil)
+	handler.timeout = 20 * time.Millisecond
+
+	session := hub.Attach(&pb.Hello{ProducerId: "p1", Sensors: []*pb.SensorRef{{Id1: "A", Id2: "1"}}})
+	defer hub.Detach(session)
+
+	rec := httptest.NewRecorder()
+	err := handler.Resume(deviceContext(e, http.MethodPost, "", rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+}
+
+func TestDeviceHandler_ListDevices(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	reg := registry.New(repository.NewDeviceRepository(sqlx.NewDb(db, "mysql")), time.Minute)
+	handler := NewDeviceHandler(control.NewHub(), reg)
+
+	rows := sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "unit", "firmware", "producer_id", "remote_addr", "connected", "first_seen", "last_seen"}).
+		AddRow(1, "A", "1", "Temperature", "°C", "", "p1", "10.0.0.5:4242", true, time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM devices").WillReturnRows(rows)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/devices?status=online", nil)
+	rec := httptest.NewRecorder()
+	err = handler.ListDevices(e.NewContext(req, rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response struct {
+		Data  []map[string]interface{} `json:"data"`
+		Total int                      `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Total)
+	assert.Equal(t, "online", response.Data[0]["status"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeviceHandler_ListDevices_InvalidStatus(t *testing.T) {
+	e := echo.New()
+	handler := NewDeviceHandler(control.NewHub(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/devices?status=sleeping", nil)
+	rec := httptest.NewRecorder()
+	err := handler.ListDevices(e.NewContext(req, rec))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
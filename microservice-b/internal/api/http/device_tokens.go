@@ -0,0 +1,137 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/usecase"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DeviceTokenHandler struct {
+	tokens usecase.IDeviceTokenManager
+}
+
+func NewDeviceTokenHandler(tokens usecase.IDeviceTokenManager) *DeviceTokenHandler {
+	return &DeviceTokenHandler{tokens: tokens}
+}
+
+// CreateDeviceToken godoc
+// @Summary Create a device token
+// @Description Creates a token a generator presents on the gRPC ingest port as `authorization: Bearer <token>` metadata. The token may only write readings of the listed `id1/id2` sensors; `id1/*` allows every sensor of an id1. Readings, registrations and control streams naming other sensors are rejected with PermissionDenied. The token is returned only in this response.
+// @Tags Device Tokens
+// @Accept json
+// @Produce json
+// @Param payload body model.CreateDeviceTokenRequest true "Token settings"
+// @Success 201 {object} model.CreateDeviceTokenResponse "Device token and the token itself"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/device-tokens [post]
+func (h *DeviceTokenHandler) CreateDeviceToken(c echo.Context) error {
+	req := model.CreateDeviceTokenRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4401, "")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "name is required and at most 100 characters", 4402, "")
+	}
+	if len(req.Sensors) == 0 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "at least one sensor is required", 4403, "")
+	}
+	for i, s := range req.Sensors {
+		req.Sensors[i] = strings.TrimSpace(s)
+		id1, id2, ok := strings.Cut(req.Sensors[i], "/")
+		if !ok || !validSensorID(id1) || !validSensorID(id2) {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "invalid sensor "+s, 4403, "sensors are id1/id2 pairs, id1/* allows every id2 of id1")
+		}
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+
+	token, err := h.tokens.CreateDeviceToken(caller.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4404, err.Error())
+	}
+	return c.JSON(http.StatusCreated, token)
+}
+
+// validSensorID reports whether id can be part of a device token sensor
+func validSensorID(id string) bool {
+	return id != "" && len(id) <= 16 && !strings.ContainsAny(id, ",/")
+}
+
+// ListDeviceTokens godoc
+// @Summary List device tokens
+// @Description Lists device tokens, newest first, with their sensors and last use
+// @Tags Device Tokens
+// @Produce json
+// @Param include_revoked query bool false "Include revoked tokens"
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated device tokens with metadata"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/device-tokens [get]
+func (h *DeviceTokenHandler) ListDeviceTokens(c echo.Context) error {
+	includeRevoked, _ := strconv.ParseBool(c.QueryParam("include_revoked"))
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	tokens, total, err := h.tokens.ListDeviceTokens(includeRevoked, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4404, err.Error())
+	}
+	if tokens == nil {
+		tokens = []model.DeviceToken{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        tokens,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RevokeDeviceToken godoc
+// @Summary Revoke a device token
+// @Description Revokes a device token. Streams already open keep running; the generator is refused once it reconnects.
+// @Tags Device Tokens
+// @Produce json
+// @Param id path int true "Device token ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"revoked\": 3}"
+// @Failure 400 {object} model.ErrorResponse "Invalid device token id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "No active token with this id"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/device-tokens/{id} [delete]
+func (h *DeviceTokenHandler) RevokeDeviceToken(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid device token id", 4405, "")
+	}
+	if err := h.tokens.RevokeDeviceToken(id); err != nil {
+		if errors.Is(err, utils.ErrDeviceTokenNotFound) {
+			return utils.ErrorResponse(c, http.StatusNotFound, err.Error(), 4406, "")
+		}
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4404, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"revoked": id})
+}
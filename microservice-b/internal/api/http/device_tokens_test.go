@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDeviceTokenManager struct {
+	mock.Mock
+}
+
+func (m *MockDeviceTokenManager) CreateDeviceToken(createdBy uint64, req *model.CreateDeviceTokenRequest) (*model.CreateDeviceTokenResponse, error) {
+	args := m.Called(createdBy, req)
+	t, _ := args.Get(0).(*model.CreateDeviceTokenResponse)
+	return t, args.Error(1)
+}
+
+func (m *MockDeviceTokenManager) ListDeviceTokens(includeRevoked bool, limit, offset int) ([]model.DeviceToken, int64, error) {
+	args := m.Called(includeRevoked, limit, offset)
+	return args.Get(0).([]model.DeviceToken), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDeviceTokenManager) RevokeDeviceToken(id uint64) error {
+	return m.Called(id).Error(0)
+}
+
+func TestDeviceTokenHandler_CreateDeviceToken(t *testing.T) {
+	e := echo.New()
+	tokens := new(MockDeviceTokenManager)
+	handler := NewDeviceTokenHandler(tokens)
+	tokens.On("CreateDeviceToken", uint64(1), mock.Anything).Return(&model.CreateDeviceTokenResponse{Token: "smd_1a2b3c4d_secret"}, nil)
+
+	for body, want := range map[string]int{
+		`{"sensors":["A/1"]}`:                        http.StatusBadRequest,
+		`{"name":"gen-a"}`:                           http.StatusBadRequest,
+		`{"name":"gen-a","sensors":["A"]}`:           http.StatusBadRequest,
+		`{"name":"gen-a","sensors":["/1"]}`:          http.StatusBadRequest,
+		`{"name":"gen-a","sensors":["A/1,B/2"]}`:     http.StatusBadRequest,
+		`{"name":"gen-a","sensors":["A/1/2"]}`:       http.StatusBadRequest,
+		`{"name":"gen-a","sensors":[" A/1 ","B/*"]}`: http.StatusCreated,
+	} {
+		c, rec := adminContext(e, http.MethodPost, "", body)
+		require.NoError(t, handler.CreateDeviceToken(c))
+		assert.Equal(t, want, rec.Code, body)
+	}
+	tokens.AssertNumberOfCalls(t, "CreateDeviceToken", 1)
+	req := tokens.Calls[0].Arguments.Get(1).(*model.CreateDeviceTokenRequest)
+	assert.Equal(t, []string{"A/1", "B/*"}, req.Sensors)
+}
+
+func TestDeviceTokenHandler_RevokeDeviceToken(t *testing.T) {
+	e := echo.New()
+	tokens := new(MockDeviceTokenManager)
+	handler := NewDeviceTokenHandler(tokens)
+	tokens.On("RevokeDeviceToken", uint64(3)).Return(nil)
+	tokens.On("RevokeDeviceToken", uint64(4)).Return(utils.ErrDeviceTokenNotFound)
+
+	for id, want := range map[string]int{"3": http.StatusOK, "4": http.StatusNotFound, "x": http.StatusBadRequest} {
+		c, rec := adminContext(e, http.MethodDelete, id, "")
+		require.NoError(t, handler.RevokeDeviceToken(c))
+		assert.Equal(t, want, rec.Code, id)
+	}
+}
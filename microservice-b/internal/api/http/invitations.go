@@ -0,0 +1,147 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/usecase"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InvitationHandler struct {
+	invitations usecase.IInvitationManager
+}
+
+func NewInvitationHandler(invitations usecase.IInvitationManager) *InvitationHandler {
+	return &InvitationHandler{invitations: invitations}
+}
+
+// CreateInvitation godoc
+// @Summary Invite a user
+// @Description Creates a single-use invitation for an email and role that expires after `INVITATION_TTL` (default 7 days). The token is returned only in this response; the invitee passes it as `invite_token` to `POST /signup` with the invited email.
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param payload body model.CreateInvitationRequest true "Invitee"
+// @Success 201 {object} model.CreateInvitationResponse "Invitation and its token"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 409 {object} model.ErrorResponse "User with email already exists"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/invitations [post]
+func (h *InvitationHandler) CreateInvitation(c echo.Context) error {
+	req := model.CreateInvitationRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4201, "")
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if !usecase.IsValidEmail(req.Email) {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid email format", 4202, "")
+	}
+	if req.Role == "" {
+		req.Role = "analyst"
+	} else if !validRole(req.Role) {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "role must be 'admin' or 'analyst'", 4203, "")
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+	inv, err := h.invitations.CreateInvitation(req.Email, req.Role, caller.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return utils.ErrorResponse(c, http.StatusConflict, err.Error(), 4204, "")
+		}
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4205, err.Error())
+	}
+	return c.JSON(http.StatusCreated, inv)
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description Lists invitations, newest first, optionally filtered by email and status.
+// @Tags Invitations
+// @Produce json
+// @Param email query string false "Invited email"
+// @Param status query string false "Invitation status" Enums(pending, accepted, revoked, expired)
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated invitations with metadata"
+// @Failure 400 {object} model.ErrorResponse "Invalid filter"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/invitations [get]
+func (h *InvitationHandler) ListInvitations(c echo.Context) error {
+	filters := make(map[string]interface{})
+	if email := strings.TrimSpace(c.QueryParam("email")); email != "" {
+		filters["email"] = email
+	}
+	switch status := c.QueryParam("status"); status {
+	case "":
+	case model.InvitationPending, model.InvitationAccepted, model.InvitationRevoked, model.InvitationExpired:
+		filters["status"] = status
+	default:
+		return utils.ErrorResponse(c, http.StatusBadRequest, "status must be 'pending', 'accepted', 'revoked' or 'expired'", 4206, "")
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	invitations, total, err := h.invitations.ListInvitations(filters, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4205, err.Error())
+	}
+	if invitations == nil {
+		invitations = []model.InvitationResponse{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        invitations,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Revokes a pending invitation so its token can no longer be used.
+// @Tags Invitations
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"revoked\": 3}"
+// @Failure 400 {object} model.ErrorResponse "Invalid invitation id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Invitation not found"
+// @Failure 409 {object} model.ErrorResponse "Invitation already accepted or revoked"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid invitation id", 4207, "")
+	}
+	if err := h.invitations.RevokeInvitation(id); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvitationNotFound):
+			return utils.ErrorResponse(c, http.StatusNotFound, err.Error(), 4208, "")
+		case errors.Is(err, utils.ErrInvalidInvitation):
+			return utils.ErrorResponse(c, http.StatusConflict, "invitation already accepted or revoked", 4209, "")
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4205, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"revoked": id})
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockInvitationManager struct {
+	mock.Mock
+}
+
+func (m *MockInvitationManager) CreateInvitation(email, role string, createdBy uint64) (*model.CreateInvitationResponse, error) {
+	args := m.Called(email, role, createdBy)
+	inv, _ := args.Get(0).(*model.CreateInvitationResponse)
+	return inv, args.Error(1)
+}
+
+func (m *MockInvitationManager) ListInvitations(filters map[string]interface{}, limit, offset int) ([]model.InvitationResponse, int64, error) {
+	args := m.Called(filters, limit, offset)
+	return args.Get(0).([]model.InvitationResponse), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockInvitationManager) RevokeInvitation(id uint64) error {
+	return m.Called(id).Error(0)
+}
+
+func TestInvitationHandler_CreateInvitation(t *testing.T) {
+	e := echo.New()
+	invitations := new(MockInvitationManager)
+	handler := NewInvitationHandler(invitations)
+	invitations.On("CreateInvitation", "new@example.com", "analyst", uint64(1)).
+		Return(&model.CreateInvitationResponse{Token: "secret"}, nil)
+
+	for body, want := range map[string]int{
+		`{"email":"invalid"}`:                          http.StatusBadRequest,
+		`{"email":"new@example.com","role":"root"}`:    http.StatusBadRequest,
+		`{"email":" new@example.com "}`:                http.StatusCreated,
+		`{"email":"new@example.com","role":"analyst"}`: http.StatusCreated,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/invitations", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"user_id": float64(1), "role": "admin"}})
+
+		require.NoError(t, handler.CreateInvitation(c))
+		assert.Equal(t, want, rec.Code, body)
+	}
+	invitations.AssertNumberOfCalls(t, "CreateInvitation", 2)
+}
+
+func TestInvitationHandler_RevokeInvitation(t *testing.T) {
+	e := echo.New()
+	invitations := new(MockInvitationManager)
+	handler := NewInvitationHandler(invitations)
+	invitations.On("RevokeInvitation", uint64(1)).Return(nil)
+	invitations.On("RevokeInvitation", uint64(2)).Return(utils.ErrInvitationNotFound)
+	invitations.On("RevokeInvitation", uint64(3)).Return(utils.ErrInvalidInvitation)
+
+	for id, want := range map[string]int{"x": http.StatusBadRequest, "1": http.StatusOK, "2": http.StatusNotFound, "3": http.StatusConflict} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+
+		require.NoError(t, handler.RevokeInvitation(c))
+		assert.Equal(t, want, rec.Code, id)
+	}
+}
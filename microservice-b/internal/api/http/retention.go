@@ -0,0 +1,158 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/repository"
+	"microservice-b/internal/retention"
+	"microservice-b/model"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RetentionHandler struct {
+	repo     *repository.RetentionRepository
+	enforcer *retention.Enforcer
+}
+
+func NewRetentionHandler(repo *repository.RetentionRepository, enforcer *retention.Enforcer) *RetentionHandler {
+	return &RetentionHandler{repo: repo, enforcer: enforcer}
+}
+
+// ListPolicies godoc
+// @Summary List retention policies
+// @Description Lists the retention policies. A policy archives the readings it covers after `archive_after_days` and purges them after `purge_after_days`; when several policies cover a reading, the most specific one (id1, then id2, then sensor_type) applies and ties go to the older policy.
+// @Tags Retention
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Policies, e.g. {\"data\": [...], \"total\": 2}"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/retention/policies [get]
+func (h *RetentionHandler) ListPolicies(c echo.Context) error {
+	policies, err := h.repo.List()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if policies == nil {
+		policies = []model.RetentionPolicy{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": policies, "total": len(policies)})
+}
+
+// CreatePolicy godoc
+// @Summary Create a retention policy
+// @Description Creates a retention policy for a sensor type, an id1 or an id1/id2 pair, or for all readings when none is given. At least one of `archive_after_days` and `purge_after_days` is required; purging must come after archiving.
+// @Tags Retention
+// @Accept json
+// @Produce json
+// @Param payload body model.RetentionPolicyRequest true "Retention policy"
+// @Success 201 {object} model.RetentionPolicy "Created policy"
+// @Failure 400 {object} map[string]string "Invalid policy"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/retention/policies [post]
+func (h *RetentionHandler) CreatePolicy(c echo.Context) error {
+	p, err := bindPolicy(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	created, err := h.repo.Create(p)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// DeletePolicy godoc
+// @Summary Delete a retention policy
+// @Tags Retention
+// @Produce json
+// @Param id path int true "Policy ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"deleted\": 1}"
+// @Failure 404 {object} map[string]string "Policy not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/retention/policies/{id} [delete]
+func (h *RetentionHandler) DeletePolicy(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid policy id"})
+	}
+	ok, err := h.repo.Delete(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "policy not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"deleted": id})
+}
+
+// DryRunPolicies godoc
+// @Summary Dry-run retention policies
+// @Description Reports per policy how many readings the retention job would archive and purge if it ran now, without changing anything. With a body, the given policy is evaluated as if it had been created, alongside the stored ones.
+// @Tags Retention
+// @Accept json
+// @Produce json
+// @Param payload body model.RetentionPolicyRequest false "Candidate policy"
+// @Success 200 {object} map[string]interface{} "Results, e.g. {\"data\": [{\"policy_id\": 1, \"archived\": 120, \"purged\": 0}]}"
+// @Failure 400 {object} map[string]string "Invalid candidate policy"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Router /api/retention/policies/dry-run [post]
+func (h *RetentionHandler) DryRunPolicies(c echo.Context) error {
+	var candidate *model.RetentionPolicy
+	if c.Request().ContentLength != 0 {
+		p, err := bindPolicy(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		candidate = p
+	}
+	results, err := h.enforcer.DryRun(candidate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": results})
+}
+
+// bindPolicy reads and validates a RetentionPolicyRequest
+func bindPolicy(c echo.Context) (*model.RetentionPolicy, error) {
+	req := new(model.RetentionPolicyRequest)
+	if err := c.Bind(req); err != nil {
+		return nil, errors.New("invalid body")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.ID2 != nil && req.ID1 == nil {
+		return nil, errors.New("id2 requires id1")
+	}
+	if req.ArchiveAfterDays == nil && req.PurgeAfterDays == nil {
+		return nil, errors.New("archive_after_days or purge_after_days is required")
+	}
+	if (req.ArchiveAfterDays != nil && *req.ArchiveAfterDays <= 0) || (req.PurgeAfterDays != nil && *req.PurgeAfterDays <= 0) {
+		return nil, errors.New("retention periods must be positive")
+	}
+	if req.ArchiveAfterDays != nil && req.PurgeAfterDays != nil && *req.PurgeAfterDays <= *req.ArchiveAfterDays {
+		return nil, errors.New("purge_after_days must be greater than archive_after_days")
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return &model.RetentionPolicy{
+		Name:             req.Name,
+		SensorType:       req.SensorType,
+		ID1:              req.ID1,
+		ID2:              req.ID2,
+		ArchiveAfterDays: req.ArchiveAfterDays,
+		PurgeAfterDays:   req.PurgeAfterDays,
+		Enabled:          enabled,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/internal/retention"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonContext(e *echo.Echo, method, body string, rec *httptest.ResponseRecorder) echo.Context {
+	req := httptest.NewRequest(method, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return e.NewContext(req, rec)
+}
+
+func TestRetentionHandler_CreatePolicy(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewRetentionRepository(sqlx.NewDb(db, "mysql"))
+	handler := NewRetentionHandler(repo, retention.New(repo, time.Hour))
+
+	mock.ExpectExec("INSERT INTO retention_policies").
+		WithArgs("raw temperature", "Temperature", nil, nil, 30, 365, true).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectQuery("SELECT \\* FROM retention_policies WHERE id = \\?").
+		WithArgs(uint64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sensor_type", "archive_after_days", "purge_after_days", "enabled"}).
+			AddRow(7, "raw temperature", "Temperature", 30, 365, true))
+
+	rec := httptest.NewRecorder()
+	body := `{"name": "raw temperature", "sensor_type": "Temperature", "archive_after_days": 30, "purge_after_days": 365}`
+	require.NoError(t, handler.CreatePolicy(jsonContext(e, http.MethodPost, body, rec)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created model.RetentionPolicy
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, uint64(7), created.ID)
+	assert.Equal(t, 365, *created.PurgeAfterDays)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetentionHandler_CreatePolicy_Invalid(t *testing.T) {
+	e := echo.New()
+	handler := NewRetentionHandler(nil, nil)
+
+	tests := map[string]string{
+		"no name":              `{"archive_after_days": 30}`,
+		"no period":            `{"name": "x"}`,
+		"id2 without id1":      `{"name": "x", "id2": 1, "archive_after_days": 30}`,
+		"negative period":      `{"name": "x", "purge_after_days": -1}`,
+		"purge before archive": `{"name": "x", "archive_after_days": 30, "purge_after_days": 7}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.NoError(t, handler.CreatePolicy(jsonContext(e, http.MethodPost, body, rec)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
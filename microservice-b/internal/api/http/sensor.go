@@ -1,11 +1,14 @@
 package http
 
 import (
+	"errors"
 	"log"
 	"microservice-b/internal/repository"
+	myMiddleware "microservice-b/middleware"
 	"microservice-b/model"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -29,11 +32,13 @@ func NewSensorHandler(repo *repository.SensorRepository) *SensorHandler {
 // @Param id2 query int false "Filter by ID2 (integer identifier)" example(1)
 // @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
 // @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
+// @Param include_archived query bool false "Include readings archived by retention policies" default(false)
 // @Param page query int false "Page number (starting from 1)" default(1) example(1)
 // @Param limit query int false "Page size (number of records per page)" default(10) example(10)
 // @Success 200 {object} map[string]interface{} "Paginated sensor readings with metadata"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/sensors [get]
 func (h *SensorHandler) GetSensors(c echo.Context) error {
 	filters := make(map[string]interface{})
@@ -64,6 +69,9 @@ func (h *SensorHandler) GetSensors(c echo.Context) error {
 		}
 		filters["to"] = t.In(loc)
 	}
+	if err := parseIncludeArchived(c, filters); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	limit, _ := strconv.Atoi(c.QueryParam("limit"))
 	if limit <= 0 {
@@ -96,9 +104,140 @@ func (h *SensorHandler) GetSensors(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// AggregateSensors godoc
+// @Summary Aggregate sensor readings into time buckets
+// @Description Buckets sensor readings by time and computes aggregation functions per bucket in the database. Functions: `avg`, `min`, `max`, `sum`, `count`, `first`, `last`, `stddev` and percentiles like `p50`, `p95`, `p99.9`. Results can be grouped by `id1`, `id2` and `sensor_type` and filtered like `GET /api/sensors`. With `fill`, empty buckets between `from` and `to` are returned with `null` values, the `previous` bucket's values or a `linear` interpolation; `count` is 0 for empty buckets. Queries using only `avg`, `min`, `max`, `sum` and `count` are served from rollups, which keep the aggregates of readings archived or purged by retention policies; they always include archived readings, the other functions only with `include_archived`.
+// @Tags MicroserviceB
+// @Produce json
+// @Param bucket query string false "Bucket size" Enums(1m, 5m, 1h, 1d) default(1h)
+// @Param agg query string false "Comma separated aggregation functions" default(avg) example("avg,max,p95")
+// @Param group_by query string false "Comma separated group columns (id1, id2, sensor_type)" example("id1,id2")
+// @Param fill query string false "Gap filling mode" Enums(null, previous, linear)
+// @Param id1 query string false "Filter by ID1 (string identifier)" example("A")
+// @Param id2 query int false "Filter by ID2 (integer identifier)" example(1)
+// @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
+// @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
+// @Param include_archived query bool false "Include readings archived by retention policies" default(false)
+// @Success 200 {object} map[string]interface{} "Aggregated buckets, e.g. {\"bucket\": \"5m\", \"data\": [{\"bucket\": \"2025-09-06T10:00:00Z\", \"id1\": \"A\", \"values\": {\"avg\": 21.5}}]}"
+// @Failure 400 {object} map[string]string "Invalid parameters"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/sensors/aggregate [get]
+func (h *SensorHandler) AggregateSensors(c echo.Context) error {
+	bucketParam := c.QueryParam("bucket")
+	if bucketParam == "" {
+		bucketParam = "1h"
+	}
+	bucket, ok := model.AggregateBuckets[bucketParam]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "bucket must be one of 1m, 5m, 1h, 1d"})
+	}
+
+	functions := splitList(c.QueryParam("agg"))
+	if len(functions) == 0 {
+		functions = []string{"avg"}
+	}
+	for _, fn := range functions {
+		if !repository.ValidAggregateFunction(fn) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "unknown aggregation function '" + fn + "'"})
+		}
+	}
+	groupBy := splitList(c.QueryParam("group_by"))
+	for _, col := range groupBy {
+		if !repository.ValidAggregateGroup(col) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "group_by accepts id1, id2 and sensor_type"})
+		}
+	}
+	fill := c.QueryParam("fill")
+	switch fill {
+	case model.FillNone, model.FillNull, model.FillPrevious, model.FillLinear:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fill must be one of null, previous, linear"})
+	}
+
+	filters := make(map[string]interface{})
+	if id1 := c.QueryParam("id1"); id1 != "" {
+		filters["id1"] = id1
+	}
+	if id2 := c.QueryParam("id2"); id2 != "" {
+		filters["id2"] = id2
+	}
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid 'from' time format"})
+		}
+		filters["from"] = t.In(loc)
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid 'to' time format"})
+		}
+		filters["to"] = t.In(loc)
+	}
+	if err := parseIncludeArchived(c, filters); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	data, err := h.repo.Aggregate(model.AggregateQuery{
+		Bucket:    bucket,
+		Functions: functions,
+		GroupBy:   groupBy,
+		Filters:   filters,
+		Fill:      fill,
+	})
+	if errors.Is(err, repository.ErrTooManyBuckets) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if data == nil {
+		data = []model.AggregatePoint{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"bucket":    bucketParam,
+		"functions": functions,
+		"group_by":  groupBy,
+		"data":      data,
+	})
+}
+
+// parseIncludeArchived sets filters["include_archived"] from the include_archived query parameter
+func parseIncludeArchived(c echo.Context, filters map[string]interface{}) error {
+	v := c.QueryParam("include_archived")
+	if v == "" {
+		return nil
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return errors.New("include_archived must be true or false")
+	}
+	filters["include_archived"] = include
+	return nil
+}
+
+// splitList splits a comma separated query parameter, dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 // DeleteSensors godoc
 // @Summary Delete sensor readings with filters
-// @Description This endpoint deletes sensor readings from the database.You can filter records by `id1`, `id2`, or by a time range (`from`, `to`).You can also combine filters (e.g., ID1 + time range).Time parameters must be in RFC3339 format (UTC).Example: `2025-09-06T15:04:05Z`If no filters are provided, **no rows will be deleted**.
+// @Description This endpoint moves sensor readings to the trash under a new delete batch; they can be restored with `POST /api/sensors/restore` until the trash is purged.You can filter records by `id1`, `id2`, or by a time range (`from`, `to`).You can also combine filters (e.g., ID1 + time range).Time parameters must be in RFC3339 format (UTC).Example: `2025-09-06T15:04:05Z`If no filters are provided, **no rows will be deleted**.
 // @Tags MicroserviceB
 // @Accept json
 // @Produce json
@@ -106,9 +245,12 @@ func (h *SensorHandler) GetSensors(c echo.Context) error {
 // @Param id2 query int false "Filter by ID2 (integer identifier)" example(1)
 // @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
 // @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
-// @Success 200 {object} map[string]interface{} "Number of deleted rows, e.g. {\"deleted\": 3}"
+// @Param reason query string false "Reason recorded in the revision history"
+// @Success 200 {object} map[string]interface{} "Number of deleted rows and their delete batch, e.g. {\"deleted\": 3, \"batch_id\": \"9f86d081884c7d659a2feaa0c55ad015\"}"
 // @Failure 500 {object} map[string]string "Internal server error, e.g. {\"error\": \"database failure\"}"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/sensors [delete]
 func (h *SensorHandler) DeleteSensors(c echo.Context) error {
 	filters := make(map[string]interface{})
@@ -127,11 +269,137 @@ func (h *SensorHandler) DeleteSensors(c echo.Context) error {
 		filters["to"] = t
 	}
 
-	rows, err := h.repo.DeleteSensors(filters)
+	batchID, rows, err := h.repo.DeleteSensors(filters, changeInfo(c, c.QueryParam("reason")))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	myMiddleware.SetAffected(c, rows)
+	return c.JSON(http.StatusOK, map[string]interface{}{"deleted": rows, "batch_id": batchID})
+}
+
+// GetTrash godoc
+// @Summary List deleted sensor readings
+// @Description Lists the readings in the trash, most recently deleted first. `archived_at` is the deletion time and `delete_batch` the batch id returned by `DELETE /api/sensors`. Trashed readings are purged once the trash grace period has passed.
+// @Tags MicroserviceB
+// @Produce json
+// @Param batch_id query string false "Filter by delete batch"
+// @Param id1 query string false "Filter by ID1 (string identifier)" example("A")
+// @Param id2 query int false "Filter by ID2 (integer identifier)" example(1)
+// @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
+// @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated trashed readings with metadata"
+// @Failure 400 {object} map[string]string "Invalid time format"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/sensors/trash [get]
+func (h *SensorHandler) GetTrash(c echo.Context) error {
+	filters, err := trashFilters(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	data, err := h.repo.GetTrash(filters, limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	total, err := h.repo.CountTrash(filters)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        data,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RestoreSensors godoc
+// @Summary Restore deleted sensor readings
+// @Description Moves readings out of the trash, either a whole delete batch (`batch_id` in the body) or the trashed readings matching the query filters. At least one of them is required.
+// @Tags MicroserviceB
+// @Accept json
+// @Produce json
+// @Param id1 query string false "Filter by ID1 (string identifier)" example("A")
+// @Param id2 query int false "Filter by ID2 (integer identifier)" example(1)
+// @Param from query string false "Filter from timestamp (RFC3339 format)" example("2025-09-06T10:00:00Z")
+// @Param to query string false "Filter to timestamp (RFC3339 format)" example("2025-09-06T12:00:00Z")
+// @Param payload body model.RestoreSensorsRequest false "Delete batch to restore"
+// @Success 200 {object} map[string]interface{} "Number of restored rows and the change set recording them, e.g. {\"restored\": 3, \"change_set\": \"...\"}"
+// @Failure 400 {object} map[string]string "Neither batch id nor filters given"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/sensors/restore [post]
+func (h *SensorHandler) RestoreSensors(c echo.Context) error {
+	req := new(model.RestoreSensorsRequest)
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		}
+	}
+	filters, err := trashFilters(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.BatchID != "" {
+		filters["batch_id"] = req.BatchID
+	}
+	if len(filters) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "batch_id or a filter is required"})
+	}
+
+	changeSet, rows, err := h.repo.RestoreSensors(filters, changeInfo(c, req.Reason))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"deleted": rows})
+	myMiddleware.SetAffected(c, rows)
+	return c.JSON(http.StatusOK, map[string]interface{}{"restored": rows, "change_set": changeSet})
+}
+
+// trashFilters reads the batch_id, id1, id2, from and to query parameters
+func trashFilters(c echo.Context) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+	if batchID := c.QueryParam("batch_id"); batchID != "" {
+		filters["batch_id"] = batchID
+	}
+	if id1 := c.QueryParam("id1"); id1 != "" {
+		filters["id1"] = id1
+	}
+	if id2 := c.QueryParam("id2"); id2 != "" {
+		filters["id2"] = id2
+	}
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, errors.New("invalid 'from' time format")
+		}
+		filters["from"] = t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, errors.New("invalid 'to' time format")
+		}
+		filters["to"] = t
+	}
+	return filters, nil
 }
 
 // EditSensors godoc
@@ -145,10 +413,12 @@ func (h *SensorHandler) DeleteSensors(c echo.Context) error {
 // @Param from query string false "Start timestamp in RFC3339 format (e.g., 2025-09-06T10:00:00Z)"
 // @Param to query string false "End timestamp in RFC3339 format (e.g., 2025-09-06T12:00:00Z)"
 // @Param payload body model.EditSensorsRequest true "Sensor update request payload"
-// @Success 200 {object} map[string]interface{} "Number of updated rows, e.g. {\"updated\": 5}"
+// @Success 200 {object} map[string]interface{} "Number of updated rows and the change set recording them, e.g. {\"updated\": 5, \"change_set\": \"...\"}"
 // @Failure 400 {object} map[string]string "Invalid request body, e.g. {\"error\": \"invalid body\"}"
 // @Failure 500 {object} map[string]string "Internal server error, e.g. {\"error\": \"database failure\"}"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/sensors [patch]
 func (h *SensorHandler) EditSensors(c echo.Context) error {
 	req := new(model.EditSensorsRequest)
@@ -174,9 +444,79 @@ func (h *SensorHandler) EditSensors(c echo.Context) error {
 		filters["to"] = t.In(loc)
 	}
 
-	rows, err := h.repo.EditSensors(filters, req.Value)
+	changeSet, rows, err := h.repo.EditSensors(filters, req.Value, changeInfo(c, req.Reason))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	myMiddleware.SetAffected(c, rows)
+	return c.JSON(http.StatusOK, map[string]interface{}{"updated": rows, "change_set": changeSet})
+}
+
+// GetHistory godoc
+// @Summary Revision history of a sensor reading
+// @Description Lists every change made to a reading, oldest first: edits, deletes, restores and reverts, with the old and new value, the user who made it and the reason given. Revisions sharing a `change_set` were made by the same request and can be reverted together.
+// @Tags MicroserviceB
+// @Produce json
+// @Param id path int true "Reading ID"
+// @Success 200 {object} map[string]interface{} "Revisions, e.g. {\"data\": [...], \"total\": 2}"
+// @Failure 400 {object} map[string]string "Invalid reading id"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/sensors/{id}/history [get]
+func (h *SensorHandler) GetHistory(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid reading id"})
+	}
+	revisions, err := h.repo.History(id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"updated": rows})
+	if revisions == nil {
+		revisions = []model.Revision{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": revisions, "total": len(revisions)})
+}
+
+// RevertChangeSet godoc
+// @Summary Revert a change set
+// @Description Undoes the changes recorded under a change set, as a new change set. Edits and reverts are undone by restoring the previous value of every reading that still holds the value the change set gave it; deletes by restoring the delete batch from the trash. Readings changed again, restored or purged since are reported as conflicts and left alone.
+// @Tags MicroserviceB
+// @Accept json
+// @Produce json
+// @Param change_set path string true "Change set"
+// @Param payload body model.RevertRequest false "Reason for the revert"
+// @Success 200 {object} model.RevertResult "Outcome of the revert"
+// @Failure 404 {object} map[string]string "Change set not found"
+// @Failure 409 {object} map[string]string "Change set cannot be reverted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/sensors/changes/{change_set}/revert [post]
+func (h *SensorHandler) RevertChangeSet(c echo.Context) error {
+	req := new(model.RevertRequest)
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		}
+	}
+	res, err := h.repo.RevertChangeSet(c.Param("change_set"), changeInfo(c, req.Reason))
+	switch {
+	case errors.Is(err, repository.ErrChangeSetNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotRevertible):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	myMiddleware.SetAffected(c, res.Reverted)
+	return c.JSON(http.StatusOK, res)
+}
+
+// changeInfo attributes a change to the authenticated caller
+func changeInfo(c echo.Context, reason string) model.ChangeInfo {
+	u, _ := myMiddleware.UserFromContext(c)
+	return model.ChangeInfo{UserID: u.ID, Reason: reason}
 }
@@ -93,4 +93,116 @@ func TestSensorHandler_EditSensors_InvalidBody(t *testing.T) {
 	err = json.Unmarshal(rec.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, "invalid body", response["error"])
-}
\ No newline at end of file
+}
+func TestSensorHandler_AggregateSensors(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	handler := NewSensorHandler(repository.NewSensorRepository(sqlx.NewDb(db, "mysql")))
+
+	mock.ExpectQuery("SELECT name, watermark FROM rollup_state").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "watermark"}))
+	rows := sqlmock.NewRows([]string{"bucket", "id1", "avg", "max"}).
+		AddRow(time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC), "A", 21.5, 25.0)
+	mock.ExpectQuery("SELECT bucket, id1, AVG\\(value\\) AS `avg`, MAX\\(value\\) AS `max` FROM").
+		WithArgs(int64(3600), int64(3600)).
+		WillReturnRows(rows)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sensors/aggregate?bucket=1h&agg=avg,max&group_by=id1", nil)
+	rec := httptest.NewRecorder()
+	err = handler.AggregateSensors(e.NewContext(req, rec))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response struct {
+		Bucket string `json:"bucket"`
+		Data   []struct {
+			ID1    string             `json:"id1"`
+			Values map[string]float64 `json:"values"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "1h", response.Bucket)
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "A", response.Data[0].ID1)
+	assert.Equal(t, 25.0, response.Data[0].Values["max"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorHandler_AggregateSensors_InvalidParams(t *testing.T) {
+	e := echo.New()
+	handler := NewSensorHandler(repository.NewSensorRepository(nil))
+
+	for _, query := range []string{"bucket=2m", "agg=median", "group_by=value", "fill=zero", "from=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors/aggregate?"+query, nil)
+		rec := httptest.NewRecorder()
+		err := handler.AggregateSensors(e.NewContext(req, rec))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestSensorHandler_RestoreSensors(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	handler := NewSensorHandler(repository.NewSensorRepository(sqlx.NewDb(db, "mysql")))
+
+	// without a batch id or filters nothing is restored
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/sensors/restore", nil)
+	require.NoError(t, handler.RestoreSensors(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty").WithArgs("b1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions").
+		WithArgs(sqlmock.AnyArg(), "restore", nil, "mistake", "b1").
+		WillReturnResult(sqlmock.NewResult(1, 4))
+	mock.ExpectExec("UPDATE sensor_readings SET archived_at = NULL, delete_batch = NULL WHERE delete_batch IS NOT NULL AND delete_batch = \\?").
+		WithArgs("b1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/sensors/restore", bytes.NewBufferString(`{"batch_id": "b1", "reason": "mistake"}`))
+	req.Header.Set("Content-Type", "application/json")
+	require.NoError(t, handler.RestoreSensors(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, float64(4), body["restored"])
+	assert.Len(t, body["change_set"], 32)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorHandler_RevertChangeSet(t *testing.T) {
+	e := echo.New()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	handler := NewSensorHandler(repository.NewSensorRepository(sqlx.NewDb(db, "mysql")))
+
+	for set, want := range map[string]int{"missing": http.StatusNotFound, "restored": http.StatusConflict} {
+		rows := sqlmock.NewRows([]string{"action", "count"})
+		if set == "restored" {
+			rows.AddRow("restore", 2)
+		}
+		mock.ExpectQuery("SELECT action, COUNT").WithArgs(set).WillReturnRows(rows)
+
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		c.SetParamNames("change_set")
+		c.SetParamValues(set)
+		require.NoError(t, handler.RevertChangeSet(c))
+		assert.Equal(t, want, rec.Code, set)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"microservice-b/internal/live"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+type StreamHandler struct {
+	streamer *live.Streamer
+}
+
+func NewStreamHandler(hub *live.Hub, repo *repository.SensorRepository) *StreamHandler {
+	return &StreamHandler{streamer: live.NewStreamer(hub, repo)}
+}
+
+func parseStreamRequest(c echo.Context) (live.Request, error) {
+	req := live.Request{
+		Filter: live.Filter{ID1: c.QueryParam("id1"), ID2: c.QueryParam("id2"), SensorType: c.QueryParam("sensor_type")},
+		Policy: live.PolicyDrop,
+	}
+	if req.Filter.ID2 != "" {
+		if _, err := strconv.Atoi(req.Filter.ID2); err != nil {
+			return req, fmt.Errorf("id2 must be an integer")
+		}
+	}
+	switch p := live.Policy(c.QueryParam("on_slow")); p {
+	case "":
+	case live.PolicyDrop, live.PolicyDisconnect:
+		req.Policy = p
+	default:
+		return req, fmt.Errorf("on_slow must be 'drop' or 'disconnect'")
+	}
+	if v := c.QueryParam("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return req, fmt.Errorf("since must be in RFC3339 format")
+		}
+		req.Since = &since
+	}
+	return req, nil
+}
+
+// StreamSSE godoc
+// @Summary Live stream of incoming readings (Server-Sent Events)
+// @Description Streams readings as they are stored, as Server-Sent Events whose event name is the `type` of the JSON data: `reading`, `dropped` (readings lost because the client was too slow, with their count), `truncated`, `heartbeat` (every 15s) or `error` (the stream ends). Readings can be filtered by `id1`, `id2` and `sensor_type`; live readings carry no `id`. With `since` the stored readings taken after it are replayed first, oldest first and at most 10000 (then `truncated` is sent). Every client has a bounded buffer: with `on_slow=drop` (default) readings that do not fit are dropped and counted, with `on_slow=disconnect` the stream ends. Browsers, which cannot set headers on EventSource requests, may pass the access token as `access_token`.
+// @Tags Live
+// @Produce text/event-stream
+// @Param id1 query string false "Filter by ID1" example("A")
+// @Param id2 query int false "Filter by ID2" example(1)
+// @Param sensor_type query string false "Filter by sensor type" example("Humidity")
+// @Param since query string false "Replay readings taken after this time first (RFC3339)" example("2025-09-06T10:00:00Z")
+// @Param on_slow query string false "Slow consumer policy" Enums(drop, disconnect) default(drop)
+// @Param access_token query string false "Access token, for clients that cannot set headers"
+// @Success 200 {object} model.LiveEvent "Stream of events"
+// @Failure 400 {object} model.ErrorResponse "Invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/stream [get]
+func (h *StreamHandler) StreamSSE(c echo.Context) error {
+	req, err := parseStreamRequest(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4501, "")
+	}
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	err = h.streamer.Run(c.Request().Context(), req, func(e model.LiveEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	})
+	if err != nil {
+		c.Logger().Warnf("live stream ended: %v", err)
+	}
+	return nil
+}
+
+// StreamWebSocket godoc
+// @Summary Live stream of incoming readings (WebSocket)
+// @Description Same stream as `GET /api/stream`, over a WebSocket: every event is a JSON text message with a `type` of `reading`, `dropped`, `truncated`, `heartbeat` or `error`. Messages sent by the client are ignored.
+// @Tags Live
+// @Param id1 query string false "Filter by ID1" example("A")
+// @Param id2 query int false "Filter by ID2" example(1)
+// @Param sensor_type query string false "Filter by sensor type" example("Humidity")
+// @Param since query string false "Replay readings taken after this time first (RFC3339)" example("2025-09-06T10:00:00Z")
+// @Param on_slow query string false "Slow consumer policy" Enums(drop, disconnect) default(drop)
+// @Param access_token query string false "Access token, for clients that cannot set headers"
+// @Success 101 {object} model.LiveEvent "Switching protocols, then a stream of events"
+// @Failure 400 {object} model.ErrorResponse "Invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/stream/ws [get]
+func (h *StreamHandler) StreamWebSocket(c echo.Context) error {
+	req, err := parseStreamRequest(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4501, "")
+	}
+	// no origin check: the stream is authorized by token, not by cookies
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// the connection is hijacked, reading is how a client leaving is noticed
+		go func() {
+			var msg string
+			for websocket.Message.Receive(ws, &msg) == nil {
+			}
+			cancel()
+		}()
+		err := h.streamer.Run(ctx, req, func(e model.LiveEvent) error {
+			return websocket.JSON.Send(ws, e)
+		})
+		if err != nil {
+			c.Logger().Warnf("live stream ended: %v", err)
+		}
+	}}.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
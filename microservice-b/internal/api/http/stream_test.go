@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"microservice-b/internal/live"
+	"microservice-b/model"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// backlog serves stored readings and runs stored when it is queried, like
+// readings arriving while a stream resumes
+type backlog struct {
+	readings []model.SensorReading
+	filters  map[string]interface{}
+	stored   func()
+}
+
+func (b *backlog) GetReadingsAfter(filters map[string]interface{}, ts time.Time, id uint64, limit int) ([]model.SensorReading, error) {
+	b.filters = filters
+	if b.stored != nil {
+		b.stored()
+		b.stored = nil
+	}
+	var page []model.SensorReading
+	for _, r := range b.readings {
+		if (r.TS.After(ts) || (r.TS.Equal(ts) && r.ID > id)) && len(page) < limit {
+			page = append(page, r)
+		}
+	}
+	return page, nil
+}
+
+func sensorData(id1, id2 string, ts time.Time) *pb.SensorData {
+	return &pb.SensorData{Id1: id1, Id2: id2, SensorType: "Humidity", Value: 1, Timestamp: timestamppb.New(ts)}
+}
+
+func TestStreamHandler_InvalidParams(t *testing.T) {
+	e := echo.New()
+	handler := NewStreamHandler(live.NewHub(0), nil)
+
+	for _, query := range []string{"id2=x", "on_slow=block", "since=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/stream?"+query, nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler.StreamSSE(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestStreamHandler_SSE_ResumesThenStreamsLive(t *testing.T) {
+	e := echo.New()
+	hub := live.NewHub(0)
+	t0 := time.Date(2025, 9, 6, 10, 0, 0, 0, time.UTC)
+	repo := &backlog{readings: []model.SensorReading{
+		{ID: 1, ID1: "A", ID2: 1, SensorType: "Humidity", TS: t0.Add(time.Second)},
+		{ID: 2, ID1: "A", ID2: 1, SensorType: "Humidity", TS: t0.Add(2 * time.Second)},
+	}}
+	// the second reading is stored, and published, while the backlog is loaded
+	repo.stored = func() { hub.Publish([]*pb.SensorData{sensorData("A", "1", t0.Add(2*time.Second))}) }
+	handler := &StreamHandler{streamer: &live.Streamer{Hub: hub, Repo: repo, Heartbeat: time.Hour, ResumeLimit: 100}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/api/stream?id1=A&id2=1&since="+t0.Format(time.RFC3339), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, handler.StreamSSE(e.NewContext(req, rec)))
+	}()
+
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+	hub.Publish([]*pb.SensorData{sensorData("B", "1", t0.Add(3*time.Second)), sensorData("A", "1", t0.Add(3*time.Second))})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, map[string]interface{}{"id1": "A", "id2": 1}, repo.filters)
+	assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+	body := rec.Body.String()
+	// two replayed readings and the new live one; the reading stored during the
+	// replay is not sent twice
+	assert.Equal(t, 3, strings.Count(body, "event: reading\n"), body)
+	assert.Contains(t, body, `"id":2`)
+	assert.Contains(t, body, `"ts":"2025-09-06T10:00:03Z"`)
+	assert.NotContains(t, body, `"id1":"B"`)
+}
+
+func TestStreamHandler_SSE_Truncated(t *testing.T) {
+	e := echo.New()
+	t0 := time.Date(2025, 9, 6, 10, 0, 0, 0, time.UTC)
+	repo := &backlog{}
+	for i := 1; i <= 3; i++ {
+		repo.readings = append(repo.readings, model.SensorReading{ID: uint64(i), ID1: "A", ID2: 1, TS: t0.Add(time.Duration(i) * time.Second)})
+	}
+	hub := live.NewHub(0)
+	handler := &StreamHandler{streamer: &live.Streamer{Hub: hub, Repo: repo, Heartbeat: time.Hour, ResumeLimit: 2}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/api/stream?since="+t0.Format(time.RFC3339), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, handler.StreamSSE(e.NewContext(req, rec)))
+	}()
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "event: reading\n"), body)
+	assert.Contains(t, body, "event: truncated\n")
+}
+
+func TestStreamHandler_WebSocket(t *testing.T) {
+	e := echo.New()
+	hub := live.NewHub(0)
+	handler := &StreamHandler{streamer: &live.Streamer{Hub: hub, Heartbeat: 20 * time.Millisecond, ResumeLimit: 100}}
+	e.GET("/api/stream/ws", handler.StreamWebSocket)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/stream/ws?sensor_type=humidity", "", server.URL)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	hub.Publish([]*pb.SensorData{sensorData("A", "1", time.Now())})
+	var events []model.LiveEvent
+	for len(events) < 2 {
+		var ev model.LiveEvent
+		require.NoError(t, websocket.JSON.Receive(ws, &ev))
+		events = append(events, ev)
+	}
+	var reading *model.SensorReading
+	for _, ev := range events {
+		if ev.Type == model.LiveReading {
+			reading = ev.Reading
+		}
+	}
+	require.NotNil(t, reading)
+	assert.Equal(t, "A", reading.ID1)
+
+	// the subscription ends with the connection
+	require.NoError(t, ws.Close())
+	require.Eventually(t, func() bool { return hub.Subscribers() == 0 }, time.Second, time.Millisecond)
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"microservice-b/internal/usecase"
+	myMiddleware "microservice-b/middleware"
 	"microservice-b/model"
 	"microservice-b/utils"
 	"net/http"
@@ -21,12 +22,14 @@ func NewUserHandler(repo usecase.IUserRepository) *UserHandler {
 // Signup godoc
 // @Summary Create a new user account
 // @Description Register a new user with email, password, optional name fields, and role. The email must be unique. Passwords must be between 6 and 60 characters. Role defaults to "analyst" if not provided.
+// @Description Depends on `SIGNUP_MODE`: `invite` (default) requires the `invite_token` of a pending invitation for the email, `open` lets anyone sign up as an analyst, `disabled` rejects every signup. With an invitation the user gets the invited role.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body model.SignupRequest true "User signup payload"
 // @Success 201 {object} model.SignupResponse "User created successfully"
 // @Failure 400 {object} model.ErrorResponse "Invalid request or validation failed"
+// @Failure 403 {object} model.ErrorResponse "Signup disabled, invitation required or invalid, or admin role without invitation"
 // @Failure 409 {object} model.ErrorResponse "User with email already exists"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
 // @Router /signup [post]
@@ -42,6 +45,7 @@ func (c *UserHandler) Signup(ctx echo.Context) error {
 	u.Email = strings.TrimSpace(u.Email)
 	u.Password = strings.TrimSpace(u.Password)
 	u.Role = strings.TrimSpace(u.Role)
+	u.InviteToken = strings.TrimSpace(u.InviteToken)
 
 	// Validate required fields
 	if u.Email == "" || u.Password == "" {
@@ -71,6 +75,16 @@ func (c *UserHandler) Signup(ctx echo.Context) error {
 
 	// Call service to create user
 	if err := c.userRepo.Signup(u); err != nil {
+		switch err {
+		case utils.ErrSignupDisabled:
+			return utils.ErrorResponse(ctx, http.StatusForbidden, err.Error(), 1009, "")
+		case utils.ErrInvitationRequired:
+			return utils.ErrorResponse(ctx, http.StatusForbidden, err.Error(), 1010, "")
+		case utils.ErrInvalidInvitation:
+			return utils.ErrorResponse(ctx, http.StatusForbidden, err.Error(), 1011, "")
+		case utils.ErrRoleNotAllowed:
+			return utils.ErrorResponse(ctx, http.StatusForbidden, err.Error(), 1012, "")
+		}
 		if strings.Contains(err.Error(), "already exists") {
 			return utils.ErrorResponse(ctx, http.StatusConflict, err.Error(), 1007, "")
 		}
@@ -82,12 +96,12 @@ func (c *UserHandler) Signup(ctx echo.Context) error {
 
 // Login godoc
 // @Summary Authenticate a user and return a JWT token
-// @Description Authenticates a user by validating the provided email and password. Returns a JWT token upon successful login.
+// @Description Authenticates a user by validating the provided email and password. Returns a short-lived JWT access token and a refresh token that `POST /refresh` exchanges for new ones.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param credentials body model.Login true "User login credentials payload"
-// @Success 200 {object}  model.LoginResponse "JWT token response"
+// @Success 200 {object}  model.LoginResponse "Access and refresh token"
 // @Failure 400 {object} model.ErrorResponse "invalid request payload"}
 // @Failure 401 {object} model.ErrorResponse "invalid credentials"}
 // @Failure 500 {object} model.ErrorResponse "something went wrong"}
@@ -97,7 +111,8 @@ func (c *UserHandler) Login(ctx echo.Context) error {
 	if err := ctx.Bind(&req); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid request payload", 2001, "")
 	}
-	token, err := c.userRepo.Login(req.Email, req.Password)
+	myMiddleware.SetAuditUser(ctx, myMiddleware.CurrentUser{Email: req.Email})
+	tokens, err := c.userRepo.Login(req.Email, req.Password)
 	if err != nil {
 		switch err {
 		case utils.ErrEmailNotFound:
@@ -108,5 +123,59 @@ func (c *UserHandler) Login(ctx echo.Context) error {
 			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "something went wrong", 2004, err.Error())
 		}
 	}
-	return ctx.JSON(http.StatusOK, model.LoginResponse{Token: token})
+	return ctx.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Exchange a refresh token for new tokens
+// @Description Returns a new access token and a new refresh token; the refresh token presented is revoked. Reusing a revoked refresh token revokes every session of its user.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param payload body model.RefreshRequest true "Refresh token"
+// @Success 200 {object} model.LoginResponse "Access and refresh token"
+// @Failure 400 {object} model.ErrorResponse "invalid request payload"
+// @Failure 401 {object} model.ErrorResponse "invalid refresh token"
+// @Failure 500 {object} model.ErrorResponse "something went wrong"
+// @Router /refresh [post]
+func (c *UserHandler) Refresh(ctx echo.Context) error {
+	req := model.RefreshRequest{}
+	if err := ctx.Bind(&req); err != nil || req.RefreshToken == "" {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid request payload", 2101, "")
+	}
+	tokens, err := c.userRepo.Refresh(req.RefreshToken)
+	if err != nil {
+		if err == utils.ErrInvalidRefreshToken {
+			return utils.ErrorResponse(ctx, http.StatusUnauthorized, err.Error(), 2102, "")
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "something went wrong", 2103, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revokes the access token of the request and, when given, the refresh token of the session.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param payload body model.RefreshRequest false "Refresh token of the session"
+// @Success 200 {object} model.SignupResponse "logged out"
+// @Failure 400 {object} model.ErrorResponse "invalid request payload"
+// @Failure 401 {object} map[string]string "unauthorized"
+// @Failure 500 {object} model.ErrorResponse "something went wrong"
+// @Security BearerAuth
+// @Router /logout [post]
+func (c *UserHandler) Logout(ctx echo.Context) error {
+	req := model.RefreshRequest{}
+	if ctx.Request().ContentLength != 0 {
+		if err := ctx.Bind(&req); err != nil {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid request payload", 2201, "")
+		}
+	}
+	caller, _ := myMiddleware.UserFromContext(ctx)
+	if err := c.userRepo.Logout(req.RefreshToken, caller); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "something went wrong", 2202, err.Error())
+	}
+	return ctx.JSON(http.StatusOK, echo.Map{"message": "logged out"})
 }
@@ -134,7 +134,9 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	myMiddleware "microservice-b/middleware"
 	"microservice-b/model"
 	"microservice-b/utils"
 	"net/http"
@@ -157,9 +159,25 @@ func (m *MockUserRepo) Signup(u *model.SignupRequest) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) Login(email, password string) (string, error) {
+func (m *MockUserRepo) Login(email, password string) (*model.LoginResponse, error) {
 	args := m.Called(email, password)
-	return args.String(0), args.Error(1)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return &model.LoginResponse{Token: args.String(0)}, nil
+}
+
+func (m *MockUserRepo) Refresh(refreshToken string) (*model.LoginResponse, error) {
+	args := m.Called(refreshToken)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return &model.LoginResponse{Token: args.String(0), RefreshToken: "next"}, nil
+}
+
+func (m *MockUserRepo) Logout(refreshToken string, caller myMiddleware.CurrentUser) error {
+	args := m.Called(refreshToken, caller)
+	return args.Error(0)
 }
 
 func TestUserHandler_Signup(t *testing.T) {
@@ -307,3 +325,58 @@ func TestUserHandler_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_Refresh(t *testing.T) {
+	e := echo.New()
+	mockRepo := new(MockUserRepo)
+	handler := NewUserHandler(mockRepo)
+
+	tests := []struct {
+		name           string
+		payload        string
+		mockErr        error
+		expectedStatus int
+	}{
+		{name: "missing token", payload: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid token", payload: `{"refresh_token":"old"}`, mockErr: utils.ErrInvalidRefreshToken, expectedStatus: http.StatusUnauthorized},
+		{name: "internal error", payload: `{"refresh_token":"old"}`, mockErr: errors.New("db down"), expectedStatus: http.StatusInternalServerError},
+		{name: "success refresh", payload: `{"refresh_token":"old"}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewBufferString(tt.payload))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockRepo.ExpectedCalls = nil
+			mockRepo.On("Refresh", "old").Return("token123", tt.mockErr)
+
+			require.NoError(t, handler.Refresh(c))
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestUserHandler_Signup_Forbidden(t *testing.T) {
+	e := echo.New()
+	for err, code := range map[error]int{
+		utils.ErrSignupDisabled:     1009,
+		utils.ErrInvitationRequired: 1010,
+		utils.ErrInvalidInvitation:  1011,
+		utils.ErrRoleNotAllowed:     1012,
+	} {
+		mockRepo := new(MockUserRepo)
+		mockRepo.On("Signup", mock.MatchedBy(func(u *model.SignupRequest) bool { return u.InviteToken == "token" })).Return(err)
+		req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewBufferString(`{"email":"new@example.com","password":"password123","invite_token":" token "}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		require.NoError(t, NewUserHandler(mockRepo).Signup(e.NewContext(req, rec)))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		var body model.ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Equal(t, code, body.Code)
+	}
+}
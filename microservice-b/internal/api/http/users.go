@@ -0,0 +1,330 @@
+package http
+
+import (
+	"errors"
+	"microservice-b/internal/usecase"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type UsersHandler struct {
+	users usecase.IUserManager
+}
+
+func NewUsersHandler(users usecase.IUserManager) *UsersHandler {
+	return &UsersHandler{users: users}
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description Lists users ordered by id. `q` matches a substring of the email or the names; `status` selects active (default), archived or all users.
+// @Tags Users
+// @Produce json
+// @Param q query string false "Search email, first and last name"
+// @Param role query string false "Filter by role" Enums(admin, analyst)
+// @Param status query string false "active, archived or all" default(active)
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated users with metadata"
+// @Failure 400 {object} model.ErrorResponse "Invalid filter"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users [get]
+func (h *UsersHandler) ListUsers(c echo.Context) error {
+	filters := make(map[string]interface{})
+	if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+		filters["q"] = q
+	}
+	if role := c.QueryParam("role"); role != "" {
+		if !validRole(role) {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "role must be 'admin' or 'analyst'", 4001, "")
+		}
+		filters["role"] = role
+	}
+	switch status := c.QueryParam("status"); status {
+	case "", "active":
+	case "archived", "all":
+		filters["status"] = status
+	default:
+		return utils.ErrorResponse(c, http.StatusBadRequest, "status must be 'active', 'archived' or 'all'", 4002, "")
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	users, total, err := h.users.ListUsers(filters, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4003, err.Error())
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        users,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User "User"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users/{id} [get]
+func (h *UsersHandler) GetUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id", 4004, "")
+	}
+	u, err := h.users.GetUser(id)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// UpdateUser godoc
+// @Summary Update a user
+// @Description Updates the names and role of an active user; omitted fields are left unchanged. Changing the role logs the user out everywhere. Admins cannot change their own role.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param payload body model.UpdateUserRequest true "Fields to change"
+// @Success 200 {object} model.User "Updated user"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users/{id} [patch]
+func (h *UsersHandler) UpdateUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id", 4004, "")
+	}
+	req := new(model.UpdateUserRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4005, "")
+	}
+	if err := validateNames(req.FirstName, req.LastName); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4006, "")
+	}
+	if req.Role != nil {
+		if !validRole(*req.Role) {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "role must be 'admin' or 'analyst'", 4001, "")
+		}
+		if caller, _ := myMiddleware.UserFromContext(c); caller.ID == id && *req.Role != caller.Role {
+			return utils.ErrorResponse(c, http.StatusBadRequest, "admins cannot change their own role", 4007, "")
+		}
+	}
+	u, err := h.users.UpdateUser(id, req)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// ArchiveUser godoc
+// @Summary Archive a user
+// @Description Disables an account: the user can no longer log in and all their sessions are revoked. Admins cannot archive themselves.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"archived\": 3}"
+// @Failure 400 {object} model.ErrorResponse "Cannot archive own account"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "No active user with this id"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users/{id}/archive [post]
+func (h *UsersHandler) ArchiveUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id", 4004, "")
+	}
+	if caller, _ := myMiddleware.UserFromContext(c); caller.ID == id {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "admins cannot archive their own account", 4008, "")
+	}
+	if err := h.users.Archive(id); err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"archived": id})
+}
+
+// UnarchiveUser godoc
+// @Summary Unarchive a user
+// @Description Re-enables an archived account; the user logs in with their previous password.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"unarchived\": 3}"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "No archived user with this id"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users/{id}/unarchive [post]
+func (h *UsersHandler) UnarchiveUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id", 4004, "")
+	}
+	if err := h.users.Unarchive(id); err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"unarchived": id})
+}
+
+// ResetPassword godoc
+// @Summary Force a password reset
+// @Description Replaces the user's password with a temporary one, returned once, and revokes all their sessions. After logging in with it the user can only use `/api/me` until they set a new password with `POST /api/me/password`.
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.PasswordResetResponse "Temporary password"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "No active user with this id"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/users/{id}/password-reset [post]
+func (h *UsersHandler) ResetPassword(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id", 4004, "")
+	}
+	temporary, err := h.users.ResetPassword(id)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, model.PasswordResetResponse{TemporaryPassword: temporary})
+}
+
+// GetMe godoc
+// @Summary Get own account
+// @Tags Me
+// @Produce json
+// @Success 200 {object} model.User "Own account"
+// @Failure 404 {object} model.ErrorResponse "User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/me [get]
+func (h *UsersHandler) GetMe(c echo.Context) error {
+	caller, _ := myMiddleware.UserFromContext(c)
+	u, err := h.users.GetUser(caller.ID)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// UpdateMe godoc
+// @Summary Update own profile
+// @Description Updates the caller's first and last name; omitted fields are left unchanged.
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param payload body model.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} model.User "Updated account"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 404 {object} model.ErrorResponse "User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/me [patch]
+func (h *UsersHandler) UpdateMe(c echo.Context) error {
+	req := new(model.UpdateProfileRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4101, "")
+	}
+	if err := validateNames(req.FirstName, req.LastName); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4102, "")
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+	u, err := h.users.UpdateProfile(caller.ID, req)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// ChangeMyPassword godoc
+// @Summary Change own password
+// @Description Replaces the caller's password after checking the current one. All sessions of the caller, including the current one, are revoked; log in again with the new password.
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Param payload body model.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} model.SignupResponse "password changed"
+// @Failure 400 {object} model.ErrorResponse "Invalid request"
+// @Failure 401 {object} model.ErrorResponse "Current password is wrong"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/me/password [post]
+func (h *UsersHandler) ChangeMyPassword(c echo.Context) error {
+	req := new(model.ChangePasswordRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4103, "")
+	}
+	req.NewPassword = strings.TrimSpace(req.NewPassword)
+	if !usecase.IsValidPassword(req.NewPassword) {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "password must be 6-60 characters", 4104, "")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "new password must differ from the current one", 4105, "")
+	}
+	caller, _ := myMiddleware.UserFromContext(c)
+	if err := h.users.ChangePassword(caller.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		if err == utils.ErrPasswordMismatch {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "current password is wrong", 4106, "")
+		}
+		return userError(c, err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "password changed"})
+}
+
+// userError maps usecase errors to responses
+func userError(c echo.Context, err error) error {
+	if errors.Is(err, utils.ErrUserNotFound) {
+		return utils.ErrorResponse(c, http.StatusNotFound, err.Error(), 4009, "")
+	}
+	return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4010, err.Error())
+}
+
+func validRole(role string) bool {
+	return role == "admin" || role == "analyst"
+}
+
+// validateNames applies the signup limits to optional names
+func validateNames(names ...*string) error {
+	for _, n := range names {
+		if n == nil {
+			continue
+		}
+		*n = strings.TrimSpace(*n)
+		if len(*n) > 255 {
+			return errors.New("first_name/last_name too long")
+		}
+	}
+	return nil
+}
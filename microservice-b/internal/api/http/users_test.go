@@ -0,0 +1,129 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUserManager struct {
+	mock.Mock
+}
+
+func (m *MockUserManager) ListUsers(filters map[string]interface{}, limit, offset int) ([]model.User, int64, error) {
+	args := m.Called(filters, limit, offset)
+	return args.Get(0).([]model.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserManager) GetUser(userID uint64) (*model.User, error) {
+	args := m.Called(userID)
+	u, _ := args.Get(0).(*model.User)
+	return u, args.Error(1)
+}
+
+func (m *MockUserManager) UpdateUser(userID uint64, req *model.UpdateUserRequest) (*model.User, error) {
+	args := m.Called(userID, req)
+	u, _ := args.Get(0).(*model.User)
+	return u, args.Error(1)
+}
+
+func (m *MockUserManager) UpdateProfile(userID uint64, req *model.UpdateProfileRequest) (*model.User, error) {
+	args := m.Called(userID, req)
+	u, _ := args.Get(0).(*model.User)
+	return u, args.Error(1)
+}
+
+func (m *MockUserManager) Archive(userID uint64) error {
+	return m.Called(userID).Error(0)
+}
+
+func (m *MockUserManager) Unarchive(userID uint64) error {
+	return m.Called(userID).Error(0)
+}
+
+func (m *MockUserManager) ChangePassword(userID uint64, current, next string) error {
+	return m.Called(userID, current, next).Error(0)
+}
+
+func (m *MockUserManager) ResetPassword(userID uint64) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+// adminContext returns a context for a request by admin 1 on a route with an id parameter
+func adminContext(e *echo.Echo, method, id, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"user_id": float64(1), "role": "admin"}})
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	return c, rec
+}
+
+func TestUsersHandler_ArchiveUser(t *testing.T) {
+	e := echo.New()
+	users := new(MockUserManager)
+	handler := NewUsersHandler(users)
+	users.On("Archive", uint64(2)).Return(nil)
+	users.On("Archive", uint64(3)).Return(utils.ErrUserNotFound)
+
+	for id, want := range map[uint64]int{1: http.StatusBadRequest, 2: http.StatusOK, 3: http.StatusNotFound} {
+		c, rec := adminContext(e, http.MethodPost, strconv.FormatUint(id, 10), "")
+		require.NoError(t, handler.ArchiveUser(c))
+		assert.Equal(t, want, rec.Code, "user %d", id)
+	}
+	// admins cannot archive themselves
+	users.AssertNotCalled(t, "Archive", uint64(1))
+}
+
+func TestUsersHandler_UpdateUser(t *testing.T) {
+	e := echo.New()
+	users := new(MockUserManager)
+	handler := NewUsersHandler(users)
+	users.On("UpdateUser", uint64(2), mock.Anything).Return(&model.User{ID: 2, Role: "admin"}, nil)
+
+	c, rec := adminContext(e, http.MethodPatch, "2", `{"role":"superuser"}`)
+	require.NoError(t, handler.UpdateUser(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = adminContext(e, http.MethodPatch, "1", `{"role":"analyst"}`)
+	require.NoError(t, handler.UpdateUser(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = adminContext(e, http.MethodPatch, "2", `{"role":"admin"}`)
+	require.NoError(t, handler.UpdateUser(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"role":"admin"`)
+	assert.NotContains(t, rec.Body.String(), `"password"`)
+}
+
+func TestUsersHandler_ChangeMyPassword(t *testing.T) {
+	e := echo.New()
+	users := new(MockUserManager)
+	handler := NewUsersHandler(users)
+	users.On("ChangePassword", uint64(1), "wrong", "new-password").Return(utils.ErrPasswordMismatch)
+	users.On("ChangePassword", uint64(1), "old-password", "new-password").Return(nil)
+
+	for body, want := range map[string]int{
+		`{"current_password":"old-password","new_password":"123"}`:          http.StatusBadRequest,
+		`{"current_password":"wrong","new_password":"new-password"}`:        http.StatusUnauthorized,
+		`{"current_password":"old-password","new_password":"new-password"}`: http.StatusOK,
+	} {
+		c, rec := adminContext(e, http.MethodPost, "", body)
+		require.NoError(t, handler.ChangeMyPassword(c))
+		assert.Equal(t, want, rec.Code, body)
+	}
+}
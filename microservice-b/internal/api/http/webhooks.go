@@ -0,0 +1,359 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"microservice-b/internal/repository"
+	"microservice-b/internal/webhook"
+	myMiddleware "microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// webhookSecretPrefix starts generated webhook secrets
+const webhookSecretPrefix = "whsec_"
+
+type WebhookHandler struct {
+	repo       *repository.WebhookRepository
+	dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookHandler(repo *repository.WebhookRepository, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{repo: repo, dispatcher: dispatcher}
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Lists the webhooks, oldest first. Their secrets are not returned.
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Webhooks, e.g. {\"data\": [...], \"total\": 2}"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c echo.Context) error {
+	hooks, err := h.repo.ListWebhooks()
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if hooks == nil {
+		hooks = []model.Webhook{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": hooks, "total": len(hooks)})
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribes an http(s) URL to event types: `alert.firing`, `alert.resolved`, `device.offline`, `device.online`. Every event is POSTed as JSON `{"id", "type", "created_at", "data"}` with the headers `X-Webhook-Event`, `X-Webhook-Delivery`, `X-Webhook-Timestamp` (Unix seconds) and `X-Webhook-Signature`: `sha256=` followed by the hex HMAC-SHA256 of `<timestamp>.<body>` keyed with the secret. A secret is generated when none is given; it is only returned here. Non-2xx answers are retried with exponential backoff from 30s up to 1h, `WEBHOOK_MAX_ATTEMPTS` times (default 8), after which the delivery is dead.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param payload body model.WebhookRequest true "Webhook"
+// @Success 201 {object} model.CreateWebhookResponse "Created webhook with its secret"
+// @Failure 400 {object} model.ErrorResponse "Invalid webhook"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	req := model.WebhookRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4701, "")
+	}
+	w := &model.Webhook{Enabled: true}
+	applyWebhookRequest(w, &req)
+	if w.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+		}
+		w.Secret = secret
+	}
+	if err := validateWebhook(w); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4702, "")
+	}
+	if caller, _ := myMiddleware.UserFromContext(c); caller.ID != 0 {
+		w.CreatedBy = &caller.ID
+	}
+
+	created, err := h.repo.CreateWebhook(w)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	return c.JSON(http.StatusCreated, model.CreateWebhookResponse{Webhook: *created, Secret: created.Secret})
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Updates the given fields of a webhook; a new `secret` rotates it. Pending deliveries of a disabled webhook are dead and can be retried once it is enabled again.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param payload body model.WebhookRequest true "Fields to change"
+// @Success 200 {object} model.Webhook "Updated webhook"
+// @Failure 400 {object} model.ErrorResponse "Invalid webhook"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Webhook not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c echo.Context) error {
+	existing, err := h.webhook(c)
+	if existing == nil {
+		return err
+	}
+	req := model.WebhookRequest{}
+	if err := c.Bind(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request payload", 4701, "")
+	}
+	w := *existing
+	applyWebhookRequest(&w, &req)
+	if err := validateWebhook(&w); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), 4702, "")
+	}
+
+	updated, err := h.repo.UpdateWebhook(&w)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Deletes a webhook with its delivery history; pending deliveries are dropped
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]interface{} "e.g. {\"deleted\": 1}"
+// @Failure 400 {object} model.ErrorResponse "Invalid webhook id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Webhook not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid webhook id", 4704, "")
+	}
+	ok, err := h.repo.DeleteWebhook(id)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if !ok {
+		return utils.ErrorResponse(c, http.StatusNotFound, "webhook not found", 4705, "")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"deleted": id})
+}
+
+// TestWebhook godoc
+// @Summary Test a webhook
+// @Description Sends a signed `webhook.test` event to the webhook right away, enabled or not, and returns the delivery: `delivered`, or `dead` with the status code or error of the single attempt. The test is listed in the delivery history.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} model.WebhookDelivery "Outcome of the test delivery"
+// @Failure 400 {object} model.ErrorResponse "Invalid webhook id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Webhook not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks/{id}/test [post]
+func (h *WebhookHandler) TestWebhook(c echo.Context) error {
+	w, err := h.webhook(c)
+	if w == nil {
+		return err
+	}
+	del, err := h.dispatcher.Test(c.Request().Context(), w)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	return c.JSON(http.StatusOK, del)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Lists the deliveries of a webhook, newest first, with their attempts and the status code or error of the last one. A delivery is `pending` until it is `delivered` or, after its last attempt failed, `dead`.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Filter by status" Enums(pending, delivered, dead)
+// @Param page query int false "Page number (starting from 1)" default(1) example(1)
+// @Param limit query int false "Page size (number of records per page)" default(10) example(10)
+// @Success 200 {object} map[string]interface{} "Paginated deliveries with metadata"
+// @Failure 400 {object} model.ErrorResponse "Invalid filter"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Webhook not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c echo.Context) error {
+	w, err := h.webhook(c)
+	if w == nil {
+		return err
+	}
+	status := c.QueryParam("status")
+	switch status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryDead:
+	default:
+		return utils.ErrorResponse(c, http.StatusBadRequest, "status must be one of pending, delivered, dead", 4706, "")
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	deliveries, err := h.repo.ListDeliveries(w.ID, status, limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	total, err := h.repo.CountDeliveries(w.ID, status)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if deliveries == nil {
+		deliveries = []model.WebhookDelivery{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":        deliveries,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RetryDelivery godoc
+// @Summary Retry a dead webhook delivery
+// @Description Queues a dead delivery again with a fresh set of attempts; the same payload is sent, signed anew
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Delivery ID"
+// @Success 202 {object} map[string]interface{} "e.g. {\"queued\": 12}"
+// @Failure 400 {object} model.ErrorResponse "Invalid delivery id"
+// @Failure 403 {object} model.ErrorResponse "Insufficient permission"
+// @Failure 404 {object} model.ErrorResponse "Delivery not found"
+// @Failure 409 {object} model.ErrorResponse "Delivery is not dead"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /api/webhooks/deliveries/{id}/retry [post]
+func (h *WebhookHandler) RetryDelivery(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid delivery id", 4707, "")
+	}
+	del, err := h.repo.GetDelivery(id)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if del == nil {
+		return utils.ErrorResponse(c, http.StatusNotFound, "delivery not found", 4708, "")
+	}
+	ok, err := h.dispatcher.Redeliver(id)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if !ok {
+		return utils.ErrorResponse(c, http.StatusConflict, "only dead deliveries can be retried", 4709, "")
+	}
+	return c.JSON(http.StatusAccepted, map[string]interface{}{"queued": id})
+}
+
+// webhook returns the webhook of the id path parameter. When it returns nil it
+// has written the error response; the error is that of writing it.
+func (h *WebhookHandler) webhook(c echo.Context) (*model.Webhook, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, http.StatusBadRequest, "invalid webhook id", 4704, "")
+	}
+	w, err := h.repo.GetWebhook(id)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error", 4703, err.Error())
+	}
+	if w == nil {
+		return nil, utils.ErrorResponse(c, http.StatusNotFound, "webhook not found", 4705, "")
+	}
+	return w, nil
+}
+
+// applyWebhookRequest copies the fields set in req onto w
+func applyWebhookRequest(w *model.Webhook, req *model.WebhookRequest) {
+	if req.Name != nil {
+		w.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		w.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Events != nil {
+		w.Events = nil
+		seen := make(map[string]bool)
+		for _, e := range req.Events {
+			if e = strings.TrimSpace(e); !seen[e] {
+				seen[e] = true
+				w.Events = append(w.Events, e)
+			}
+		}
+	}
+	if req.Secret != nil {
+		w.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+}
+
+// validateWebhook checks the fields of a webhook
+func validateWebhook(w *model.Webhook) error {
+	if w.Name == "" || len(w.Name) > 255 {
+		return errors.New("name is required and at most 255 characters")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(w.URL) > 2048 {
+		return errors.New("url must be an absolute http or https URL of at most 2048 characters")
+	}
+	if len(w.Events) == 0 {
+		return errors.New("events must name at least one event type")
+	}
+	for _, e := range w.Events {
+		if !validWebhookEvent(e) {
+			return errors.New("events must be of " + strings.Join(model.WebhookEvents, ", "))
+		}
+	}
+	if len(w.Secret) < 16 || len(w.Secret) > 255 {
+		return errors.New("secret must be between 16 and 255 characters")
+	}
+	return nil
+}
+
+func validWebhookEvent(e string) bool {
+	for _, known := range model.WebhookEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/internal/webhook"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookHandler(t *testing.T) (*WebhookHandler, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := repository.NewWebhookRepository(sqlx.NewDb(db, "mysql"))
+	return NewWebhookHandler(repo, webhook.New(repo, time.Minute, 3)), mock
+}
+
+func TestWebhookHandler_CreateWebhook_GeneratesSecret(t *testing.T) {
+	e := echo.New()
+	handler, mock := newWebhookHandler(t)
+
+	events := model.StringList{model.EventAlertFiring, model.EventDeviceOffline}
+	mock.ExpectExec("INSERT INTO webhooks").
+		WithArgs("on-call", "https://oncall.example.com/hooks", events, sqlmock.AnyArg(), true, nil).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectQuery("SELECT \\* FROM webhooks WHERE id = \\?").
+		WithArgs(uint64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+			AddRow(4, "on-call", "https://oncall.example.com/hooks", "alert.firing,device.offline", "whsec_generated_secret", true))
+
+	rec := httptest.NewRecorder()
+	body := `{"name": "on-call", "url": "https://oncall.example.com/hooks", "events": ["alert.firing", "device.offline", "alert.firing"]}`
+	require.NoError(t, handler.CreateWebhook(jsonContext(e, http.MethodPost, body, rec)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created model.CreateWebhookResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "whsec_generated_secret", created.Secret)
+	assert.Equal(t, []string{"alert.firing", "device.offline"}, []string(created.Events))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookHandler_CreateWebhook_Invalid(t *testing.T) {
+	e := echo.New()
+	handler, _ := newWebhookHandler(t)
+
+	tests := map[string]string{
+		"no name":       `{"url": "https://a.example.com", "events": ["alert.firing"]}`,
+		"relative url":  `{"name": "x", "url": "/hooks", "events": ["alert.firing"]}`,
+		"ftp url":       `{"name": "x", "url": "ftp://a.example.com", "events": ["alert.firing"]}`,
+		"no events":     `{"name": "x", "url": "https://a.example.com"}`,
+		"unknown event": `{"name": "x", "url": "https://a.example.com", "events": ["alert.*"]}`,
+		"test event":    `{"name": "x", "url": "https://a.example.com", "events": ["webhook.test"]}`,
+		"short secret":  `{"name": "x", "url": "https://a.example.com", "events": ["alert.firing"], "secret": "hunter2"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.NoError(t, handler.CreateWebhook(jsonContext(e, http.MethodPost, body, rec)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestWebhookHandler_ListWebhooks_HidesSecrets(t *testing.T) {
+	e := echo.New()
+	handler, mock := newWebhookHandler(t)
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+			AddRow(4, "on-call", "https://oncall.example.com/hooks", "alert.firing", "whsec_generated_secret", true))
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ListWebhooks(jsonContext(e, http.MethodGet, "", rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "whsec_")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookHandler_TestWebhook(t *testing.T) {
+	e := echo.New()
+	handler, mock := newWebhookHandler(t)
+	var event string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get(webhook.HeaderEvent)
+	}))
+	defer receiver.Close()
+
+	// disabled webhooks can be tested too
+	mock.ExpectQuery("SELECT \\* FROM webhooks WHERE id = \\?").
+		WithArgs(uint64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+			AddRow(4, "on-call", receiver.URL, "alert.firing", "whsec_generated_secret", false))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryDelivered, 1, nil, sqlmock.AnyArg(), nil, sqlmock.AnyArg(), uint64(13)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec := httptest.NewRecorder()
+	c := jsonContext(e, http.MethodPost, "", rec)
+	c.SetParamNames("id")
+	c.SetParamValues("4")
+	require.NoError(t, handler.TestWebhook(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, model.EventWebhookTest, event)
+	var del model.WebhookDelivery
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &del))
+	assert.Equal(t, model.DeliveryDelivered, del.Status)
+	assert.Contains(t, string(del.Payload), `"type":"webhook.test"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookHandler_ListDeliveries(t *testing.T) {
+	e := echo.New()
+	handler, mock := newWebhookHandler(t)
+	mock.ExpectQuery("SELECT \\* FROM webhooks WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+			AddRow(4, "on-call", "https://oncall.example.com/hooks", "alert.firing", "whsec_generated_secret", true))
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries WHERE webhook_id = \\? AND status = \\? ORDER BY id DESC LIMIT \\? OFFSET \\?").
+		WithArgs(uint64(4), model.DeliveryDead, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "last_status_code", "last_error"}).
+			AddRow(12, 4, "9f86d081884c7d659a2feaa0c55ad015", model.EventAlertFiring, []byte(`{"type":"alert.firing"}`), model.DeliveryDead, 8, 502, "receiver answered 502 Bad Gateway"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM webhook_deliveries WHERE webhook_id = \\? AND status = \\?").
+		WithArgs(uint64(4), model.DeliveryDead).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/webhooks/4/deliveries?status=dead", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("4")
+	require.NoError(t, handler.ListDeliveries(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"payload":{"type":"alert.firing"}`)
+	assert.Contains(t, rec.Body.String(), `"last_status_code":502`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookHandler_RetryDelivery(t *testing.T) {
+	e := echo.New()
+	handler, mock := newWebhookHandler(t)
+	delivery := func(status string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "webhook_id", "status"}).AddRow(12, 4, status)
+	}
+	retry := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := jsonContext(e, http.MethodPost, "", rec)
+		c.SetParamNames("id")
+		c.SetParamValues("12")
+		require.NoError(t, handler.RetryDelivery(c))
+		return rec
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries WHERE id = \\?").WithArgs(uint64(12)).WillReturnRows(delivery(model.DeliveryDead))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = \\?, attempts = 0, next_attempt_at = \\? WHERE id = \\? AND status = \\?").
+		WithArgs(model.DeliveryPending, sqlmock.AnyArg(), uint64(12), model.DeliveryDead).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Equal(t, http.StatusAccepted, retry().Code)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries WHERE id = \\?").WithArgs(uint64(12)).WillReturnRows(delivery(model.DeliveryPending))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.Equal(t, http.StatusConflict, retry().Code)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries WHERE id = \\?").WithArgs(uint64(12)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	assert.Equal(t, http.StatusNotFound, retry().Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,166 @@
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	pb "microservice-b/pb/shared-proto"
+)
+
+var (
+	ErrNotConnected = errors.New("no generator connected for sensor")
+	ErrDisconnected = errors.New("generator disconnected before replying")
+)
+
+// Result tells whether a generator applied a command
+type Result struct {
+	CommandID string `json:"command_id"`
+	Applied   bool   `json:"applied"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Session is one live control stream of a generator
+type Session struct {
+	ProducerID string
+	Commands   chan *pb.Command // drained by the stream handler
+	done       chan struct{}
+	keys       []string
+}
+
+// Done is closed once the session detached
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Hub routes commands to the control stream of the generator hosting a sensor
+type Hub struct {
+	mu       sync.Mutex
+	sessions map[string]*Session  // keyed by id1/id2
+	pending  map[string]*inFlight // keyed by command id
+}
+
+// inFlight is a command waiting for the reply of the session it was sent to
+type inFlight struct {
+	session *Session
+	reply   chan *pb.CommandResult
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		sessions: make(map[string]*Session),
+		pending:  make(map[string]*inFlight),
+	}
+}
+
+func key(id1, id2 string) string {
+	return id1 + "/" + id2
+}
+
+// Attach registers a generator and the sensors it announced. A sensor that was
+// attached to another session moves to the new one.
+func (h *Hub) Attach(hello *pb.Hello) *Session {
+	s := &Session{
+		ProducerID: hello.ProducerId,
+		Commands:   make(chan *pb.Command, 16),
+		done:       make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ref := range hello.Sensors {
+		k := key(ref.Id1, ref.Id2)
+		h.sessions[k] = s
+		s.keys = append(s.keys, k)
+	}
+	return s
+}
+
+// Detach removes a session; commands waiting for its reply fail with ErrDisconnected
+func (h *Hub) Detach(s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range s.keys {
+		if h.sessions[k] == s {
+			delete(h.sessions, k)
+		}
+	}
+	close(s.done)
+}
+
+// Resolve hands the reply of session s to the waiting Dispatch call. Replies
+// to commands that were not sent to s are ignored, so a generator cannot
+// answer for the sensors of another one.
+func (h *Hub) Resolve(s *Session, res *pb.CommandResult) {
+	h.mu.Lock()
+	cmd, ok := h.pending[res.CommandId]
+	if !ok || cmd.session != s {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.pending, res.CommandId)
+	h.mu.Unlock()
+	cmd.reply <- res
+}
+
+// Connected reports whether a generator hosting id1/id2 is attached
+func (h *Hub) Connected(id1, id2 string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.sessions[key(id1, id2)]
+	return ok
+}
+
+// Dispatch sends cmd to the generator hosting cmd.Id1/cmd.Id2 and waits for its reply or ctx
+func (h *Hub) Dispatch(ctx context.Context, cmd *pb.Command) (Result, error) {
+	h.mu.Lock()
+	s, ok := h.sessions[key(cmd.Id1, cmd.Id2)]
+	if !ok {
+		h.mu.Unlock()
+		return Result{}, fmt.Errorf("%w: %s", ErrNotConnected, key(cmd.Id1, cmd.Id2))
+	}
+	id, err := newCommandID()
+	if err != nil {
+		h.mu.Unlock()
+		return Result{}, err
+	}
+	cmd.Id = id
+	reply := make(chan *pb.CommandResult, 1)
+	h.pending[cmd.Id] = &inFlight{session: s, reply: reply}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.pending, cmd.Id)
+		h.mu.Unlock()
+	}()
+
+	select {
+	case s.Commands <- cmd:
+	case <-s.done:
+		return Result{CommandID: cmd.Id}, ErrDisconnected
+	case <-ctx.Done():
+		return Result{CommandID: cmd.Id}, ctx.Err()
+	}
+
+	select {
+	case res := <-reply:
+		return Result{CommandID: cmd.Id, Applied: res.Ok, Error: res.Error}, nil
+	case <-s.done:
+		return Result{CommandID: cmd.Id}, ErrDisconnected
+	case <-ctx.Done():
+		return Result{CommandID: cmd.Id}, ctx.Err()
+	}
+}
+
+// newCommandID returns a random command id, so that replies cannot be forged by
+// guessing the ids of commands sent to other generators
+func newCommandID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,119 @@
+package control
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hello(producer string, ids ...[2]string) *pb.Hello {
+	h := &pb.Hello{ProducerId: producer}
+	for _, id := range ids {
+		h.Sensors = append(h.Sensors, &pb.SensorRef{Id1: id[0], Id2: id[1]})
+	}
+	return h
+}
+
+// reply answers every command of s like a generator would
+func reply(h *Hub, s *Session, ok bool) {
+	go func() {
+		for {
+			select {
+			case cmd := <-s.Commands:
+				res := &pb.CommandResult{CommandId: cmd.Id, Ok: ok}
+				if !ok {
+					res.Error = "rejected"
+				}
+				h.Resolve(s, res)
+			case <-s.Done():
+				return
+			}
+		}
+	}()
+}
+
+func TestHub_DispatchRoutesToSession(t *testing.T) {
+	h := NewHub()
+	a := h.Attach(hello("a", [2]string{"A", "1"}))
+	b := h.Attach(hello("b", [2]string{"B", "2"}))
+	reply(h, a, true)
+	reply(h, b, false)
+
+	res, err := h.Dispatch(context.Background(), &pb.Command{Id1: "A", Id2: "1", Type: pb.CommandType_PAUSE})
+	require.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.NotEmpty(t, res.CommandID)
+
+	res, err = h.Dispatch(context.Background(), &pb.Command{Id1: "B", Id2: "2", Type: pb.CommandType_PAUSE})
+	require.NoError(t, err)
+	assert.False(t, res.Applied)
+	assert.Equal(t, "rejected", res.Error)
+}
+
+func TestHub_DispatchNotConnected(t *testing.T) {
+	h := NewHub()
+	_, err := h.Dispatch(context.Background(), &pb.Command{Id1: "A", Id2: "1"})
+	assert.ErrorIs(t, err, ErrNotConnected)
+
+	s := h.Attach(hello("a", [2]string{"A", "1"}))
+	assert.True(t, h.Connected("A", "1"))
+	h.Detach(s)
+	assert.False(t, h.Connected("A", "1"))
+}
+
+func TestHub_DetachFailsWaitingCommand(t *testing.T) {
+	h := NewHub()
+	s := h.Attach(hello("a", [2]string{"A", "1"}))
+	go func() {
+		<-s.Commands // received but never answered
+		h.Detach(s)
+	}()
+
+	_, err := h.Dispatch(context.Background(), &pb.Command{Id1: "A", Id2: "1"})
+	assert.ErrorIs(t, err, ErrDisconnected)
+}
+
+func TestHub_DispatchTimesOut(t *testing.T) {
+	h := NewHub()
+	h.Attach(hello("a", [2]string{"A", "1"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := h.Dispatch(ctx, &pb.Command{Id1: "A", Id2: "1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestHub_IgnoresRepliesOfOtherSessions(t *testing.T) {
+	h := NewHub()
+	target := h.Attach(hello("a", [2]string{"A", "1"}))
+	other := h.Attach(hello("b", [2]string{"B", "2"}))
+	go func() {
+		cmd := <-target.Commands
+		// another generator answering for the command is not trusted
+		h.Resolve(other, &pb.CommandResult{CommandId: cmd.Id, Ok: false, Error: "forged"})
+		h.Resolve(target, &pb.CommandResult{CommandId: cmd.Id, Ok: true})
+	}()
+
+	res, err := h.Dispatch(context.Background(), &pb.Command{Id1: "A", Id2: "1"})
+	require.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.Len(t, res.CommandID, 32)
+}
+
+func TestHub_ReattachKeepsNewSession(t *testing.T) {
+	h := NewHub()
+	old := h.Attach(hello("old", [2]string{"A", "1"}))
+	current := h.Attach(hello("new", [2]string{"A", "1"}))
+	reply(h, current, true)
+
+	// the old stream going away must not detach the sensor from the new one
+	h.Detach(old)
+	res, err := h.Dispatch(context.Background(), &pb.Command{Id1: "A", Id2: "1"})
+	require.NoError(t, err)
+	assert.True(t, res.Applied)
+}
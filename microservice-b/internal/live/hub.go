@@ -0,0 +1,168 @@
+package live
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"microservice-b/model"
+
+	pb "microservice-b/pb/shared-proto"
+)
+
+// DefaultBuffer is how many readings a subscriber may lag behind before its
+// slow consumer policy applies
+const DefaultBuffer = 256
+
+// ErrSlowConsumer closes subscriptions with PolicyDisconnect whose buffer overflowed
+var ErrSlowConsumer = errors.New("subscriber too slow, buffer overflowed")
+
+// Policy decides what happens to a reading that does not fit into the buffer
+// of a subscriber
+type Policy string
+
+const (
+	PolicyDrop       Policy = "drop"       // the reading is dropped and counted
+	PolicyDisconnect Policy = "disconnect" // the subscription is closed with ErrSlowConsumer
+)
+
+// Filter selects the readings of a subscription; empty fields match everything
+type Filter struct {
+	ID1        string
+	ID2        string
+	SensorType string
+}
+
+// Match reports whether r passes the filter
+func (f Filter) Match(r *model.SensorReading) bool {
+	return (f.ID1 == "" || f.ID1 == r.ID1) &&
+		(f.ID2 == "" || f.ID2 == strconv.Itoa(r.ID2)) &&
+		(f.SensorType == "" || strings.EqualFold(f.SensorType, r.SensorType))
+}
+
+// Subscription receives the readings published to the hub that match its filter
+type Subscription struct {
+	C <-chan model.SensorReading
+
+	c       chan model.SensorReading
+	filter  Filter
+	policy  Policy
+	dropped atomic.Uint64
+	done    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// Done is closed once the subscription was closed by the hub or unsubscribed
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err tells why the hub closed the subscription; nil after Unsubscribe
+func (s *Subscription) Err() error {
+	<-s.done
+	return s.err
+}
+
+// TakeDropped returns the number of readings dropped since the last call
+func (s *Subscription) TakeDropped() uint64 {
+	return s.dropped.Swap(0)
+}
+
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// Hub fans incoming readings out to live subscribers. Publishing never blocks
+// ingestion: every subscriber has a bounded buffer and slow subscribers lose
+// readings or their subscription, depending on their policy.
+type Hub struct {
+	buffer int
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Hub{buffer: buffer, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe starts receiving the readings matching f
+func (h *Hub) Subscribe(f Filter, p Policy) *Subscription {
+	c := make(chan model.SensorReading, h.buffer)
+	s := &Subscription{C: c, c: c, filter: f, policy: p, done: make(chan struct{})}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// Unsubscribe stops a subscription and releases it
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+	s.close(nil)
+}
+
+// Subscribers returns the number of live subscriptions
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Publish hands stored readings to the matching subscribers. A nil hub
+// publishes nothing.
+func (h *Hub) Publish(readings []*pb.SensorData) {
+	if h == nil || len(readings) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.subs) == 0 {
+		return
+	}
+	for _, data := range readings {
+		r := Reading(data, now)
+		for s := range h.subs {
+			if !s.filter.Match(&r) {
+				continue
+			}
+			select {
+			case <-s.done:
+			case s.c <- r:
+			default:
+				if s.policy == PolicyDisconnect {
+					s.close(ErrSlowConsumer)
+				} else {
+					s.dropped.Add(1)
+				}
+			}
+		}
+	}
+}
+
+// Reading converts an incoming reading the way it is stored: its timestamp is
+// rounded to the microseconds of the ts column. Live readings have no id yet.
+func Reading(data *pb.SensorData, receivedAt time.Time) model.SensorReading {
+	id2, _ := strconv.Atoi(data.Id2)
+	return model.SensorReading{
+		ID1:        data.Id1,
+		ID2:        id2,
+		SensorType: data.SensorType,
+		Value:      data.Value,
+		TS:         data.Timestamp.AsTime().Round(time.Microsecond),
+		CreatedAt:  receivedAt,
+	}
+}
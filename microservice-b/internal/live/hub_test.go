@@ -0,0 +1,81 @@
+package live
+
+import (
+	"testing"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func data(id1, id2, sensorType string) *pb.SensorData {
+	return &pb.SensorData{Id1: id1, Id2: id2, SensorType: sensorType, Value: 1.5, Timestamp: timestamppb.Now()}
+}
+
+func TestHub_PublishFilters(t *testing.T) {
+	h := NewHub(8)
+	all := h.Subscribe(Filter{}, PolicyDrop)
+	a1 := h.Subscribe(Filter{ID1: "A", ID2: "1"}, PolicyDrop)
+	humidity := h.Subscribe(Filter{SensorType: "humidity"}, PolicyDrop)
+
+	h.Publish([]*pb.SensorData{data("A", "1", "Temperature"), data("A", "2", "Humidity"), data("B", "1", "Humidity")})
+
+	assert.Len(t, all.C, 3)
+	require.Len(t, a1.C, 1)
+	r := <-a1.C
+	assert.Equal(t, "A", r.ID1)
+	assert.Equal(t, 1, r.ID2)
+	assert.Equal(t, 1.5, r.Value)
+	assert.Len(t, humidity.C, 2)
+}
+
+func TestHub_SlowConsumerPolicies(t *testing.T) {
+	h := NewHub(2)
+	dropping := h.Subscribe(Filter{}, PolicyDrop)
+	disconnecting := h.Subscribe(Filter{}, PolicyDisconnect)
+
+	h.Publish([]*pb.SensorData{data("A", "1", "T"), data("A", "1", "T"), data("A", "1", "T"), data("A", "1", "T")})
+
+	assert.Len(t, dropping.C, 2)
+	assert.Equal(t, uint64(2), dropping.TakeDropped())
+	assert.Equal(t, uint64(0), dropping.TakeDropped())
+	select {
+	case <-dropping.Done():
+		t.Fatal("dropping subscription was closed")
+	default:
+	}
+
+	select {
+	case <-disconnecting.Done():
+	case <-time.After(time.Second):
+		t.Fatal("slow subscription was not closed")
+	}
+	assert.ErrorIs(t, disconnecting.Err(), ErrSlowConsumer)
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	h := NewHub(0)
+	s := h.Subscribe(Filter{}, PolicyDrop)
+	assert.Equal(t, 1, h.Subscribers())
+
+	h.Unsubscribe(s)
+
+	assert.Equal(t, 0, h.Subscribers())
+	assert.NoError(t, s.Err())
+	h.Publish([]*pb.SensorData{data("A", "1", "T")})
+	assert.Len(t, s.C, 0)
+
+	// a nil hub publishes nothing
+	var none *Hub
+	none.Publish([]*pb.SensorData{data("A", "1", "T")})
+}
+
+func TestReading_RoundsToMicroseconds(t *testing.T) {
+	ts := time.Date(2025, 9, 6, 10, 0, 0, 1500, time.UTC)
+	r := Reading(&pb.SensorData{Id1: "A", Id2: "x", Timestamp: timestamppb.New(ts)}, time.Now())
+	assert.Equal(t, time.Date(2025, 9, 6, 10, 0, 0, 2000, time.UTC), r.TS)
+	assert.Equal(t, 0, r.ID2)
+}
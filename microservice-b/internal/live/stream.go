@@ -0,0 +1,132 @@
+package live
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"microservice-b/model"
+)
+
+const (
+	StreamHeartbeat = 15 * time.Second // keeps idle streams from being closed by proxies
+	ResumeLimit     = 10000            // readings replayed at most when resuming
+	resumePage      = 500              // readings loaded per query when resuming
+)
+
+// ReadingsAfter pages through stored readings in the order they were taken
+type ReadingsAfter interface {
+	GetReadingsAfter(filters map[string]interface{}, ts time.Time, id uint64, limit int) ([]model.SensorReading, error)
+}
+
+// Request is what a stream client subscribes to
+type Request struct {
+	Filter Filter
+	Policy Policy
+	Since  *time.Time // replay the stored readings taken after it first
+}
+
+// Streamer feeds a client the stored readings it missed and then the live ones;
+// it is shared by the REST streams and the gRPC Subscribe call
+type Streamer struct {
+	Hub         *Hub
+	Repo        ReadingsAfter
+	Heartbeat   time.Duration
+	ResumeLimit int
+}
+
+func NewStreamer(hub *Hub, repo ReadingsAfter) *Streamer {
+	return &Streamer{Hub: hub, Repo: repo, Heartbeat: StreamHeartbeat, ResumeLimit: ResumeLimit}
+}
+
+// Run sends the readings stored after the resume time, then the live readings,
+// until ctx is done or the hub closes the subscription
+func (s *Streamer) Run(ctx context.Context, req Request, send func(model.LiveEvent) error) error {
+	// subscribe first so nothing stored while the backlog is loaded is missed
+	sub := s.Hub.Subscribe(req.Filter, req.Policy)
+	defer s.Hub.Unsubscribe(sub)
+
+	var replayed map[string]bool
+	if req.Since != nil {
+		var err error
+		if replayed, err = s.resume(req, send); err != nil {
+			return err
+		}
+	}
+	// the readings buffered by now may have been replayed already
+	buffered := len(sub.C)
+
+	heartbeat := time.NewTicker(s.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var e model.LiveEvent
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			return send(model.LiveEvent{Type: model.LiveError, Error: sub.Err().Error()})
+		case r := <-sub.C:
+			if buffered > 0 {
+				buffered--
+				if replayed[readingKey(&r)] {
+					continue
+				}
+			}
+			e = model.LiveEvent{Type: model.LiveReading, Reading: &r}
+		case <-heartbeat.C:
+			e = model.LiveEvent{Type: model.LiveHeartbeat}
+		}
+		if n := sub.TakeDropped(); n > 0 {
+			if err := send(model.LiveEvent{Type: model.LiveDropped, Dropped: n}); err != nil {
+				return err
+			}
+		}
+		if err := send(e); err != nil {
+			return err
+		}
+	}
+}
+
+// resume replays the stored readings taken after req.Since and returns the
+// keys of those it sent
+func (s *Streamer) resume(req Request, send func(model.LiveEvent) error) (map[string]bool, error) {
+	filters := make(map[string]interface{})
+	if req.Filter.ID1 != "" {
+		filters["id1"] = req.Filter.ID1
+	}
+	if req.Filter.ID2 != "" {
+		filters["id2"], _ = strconv.Atoi(req.Filter.ID2)
+	}
+	if req.Filter.SensorType != "" {
+		filters["sensor_type"] = req.Filter.SensorType
+	}
+
+	replayed := make(map[string]bool)
+	ts, id := *req.Since, uint64(0)
+	for sent := 0; sent < s.ResumeLimit; {
+		limit := min(resumePage, s.ResumeLimit-sent)
+		page, err := s.Repo.GetReadingsAfter(filters, ts, id, limit)
+		if err != nil {
+			_ = send(model.LiveEvent{Type: model.LiveError, Error: "failed to load readings"})
+			return nil, err
+		}
+		for i := range page {
+			if err := send(model.LiveEvent{Type: model.LiveReading, Reading: &page[i]}); err != nil {
+				return nil, err
+			}
+			replayed[readingKey(&page[i])] = true
+		}
+		if len(page) < limit {
+			return replayed, nil
+		}
+		sent += len(page)
+		ts, id = page[len(page)-1].TS, page[len(page)-1].ID
+	}
+	return replayed, send(model.LiveEvent{Type: model.LiveTruncated})
+}
+
+// readingKey identifies a reading without its id, which live readings lack
+func readingKey(r *model.SensorReading) string {
+	return fmt.Sprintf("%s/%d/%d", r.ID1, r.ID2, r.TS.UnixMicro())
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"context"
+	"log"
+	"microservice-b/model"
+	"time"
+)
+
+// Publisher is told about devices going offline and coming back online, e.g.
+// to notify webhooks
+type Publisher interface {
+	Publish(eventType string, data interface{})
+}
+
+// DefaultCheckInterval is how often the monitor compares device statuses
+const DefaultCheckInterval = 10 * time.Second
+
+// Monitor publishes device.offline when a device goes offline and device.online
+// when it comes back. Statuses are known from the first check on: devices that
+// are offline at startup are not reported, nor are changes while the service
+// was down.
+type Monitor struct {
+	registry  *Registry
+	publisher Publisher
+	interval  time.Duration
+
+	status map[string]string // status per id1/id2 at the last check; nil before the first
+}
+
+func NewMonitor(r *Registry, p Publisher, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	return &Monitor{registry: r, publisher: p, interval: interval}
+}
+
+// Start checks the device statuses every interval until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Check(); err != nil {
+			log.Printf("registry: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check publishes the devices whose status changed since the last check
+func (m *Monitor) Check() error {
+	devices, err := m.registry.List("")
+	if err != nil {
+		return err
+	}
+	status := make(map[string]string, len(devices))
+	for _, d := range devices {
+		key := d.ID1 + "/" + d.ID2
+		status[key] = d.Status
+		if m.status == nil {
+			continue
+		}
+		prev := m.status[key]
+		switch {
+		case d.Status == StatusOffline && prev == StatusOnline:
+			m.publisher.Publish(model.EventDeviceOffline, d)
+		case d.Status == StatusOnline && prev == StatusOffline:
+			m.publisher.Publish(model.EventDeviceOnline, d)
+		}
+	}
+	m.status = status
+	return nil
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// events records what the monitor publishes
+type events []string
+
+func (ev *events) Publish(eventType string, data interface{}) {
+	d := data.(model.Device)
+	*ev = append(*ev, eventType+" "+d.ID1+"/"+d.ID2)
+}
+
+func TestMonitor_PublishesStatusChanges(t *testing.T) {
+	r, mock, now := newTestRegistry(t)
+	var published events
+	m := NewMonitor(r, &published, time.Minute)
+
+	columns := []string{"id", "id1", "id2", "last_seen"}
+	lastSeenA, lastSeenB := now.Add(-10*time.Second), now.Add(-time.Hour)
+	check := func() {
+		mock.ExpectQuery("SELECT (.+) FROM devices").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "A", "1", lastSeenA).AddRow(2, "B", "2", lastSeenB))
+		require.NoError(t, m.Check())
+	}
+
+	check() // B is offline at startup and not reported
+	assert.Empty(t, published)
+
+	*now = now.Add(time.Minute) // A falls silent
+	check()
+	assert.Equal(t, events{"device.offline A/1"}, published)
+
+	check()
+	assert.Len(t, published, 1, "reported once")
+
+	lastSeenA, lastSeenB = *now, *now // both report again
+	check()
+	assert.Equal(t, events{"device.offline A/1", "device.online A/1", "device.online B/2"}, published)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
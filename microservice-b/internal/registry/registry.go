@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"log"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"sync"
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+)
+
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// DefaultOfflineAfter is how long a device may stay silent before it is reported offline
+const DefaultOfflineAfter = 30 * time.Second
+
+// touchEvery throttles last_seen writes caused by readings of the same device
+const touchEvery = 5 * time.Second
+
+// Registry keeps track of the sensors that stream to microservice-b
+type Registry struct {
+	repo         *repository.DeviceRepository
+	offlineAfter time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	touched map[string]time.Time // last registry write per id1/id2
+}
+
+func New(repo *repository.DeviceRepository, offlineAfter time.Duration) *Registry {
+	if offlineAfter <= 0 {
+		offlineAfter = DefaultOfflineAfter
+	}
+	return &Registry{
+		repo:         repo,
+		offlineAfter: offlineAfter,
+		now:          time.Now,
+		touched:      make(map[string]time.Time),
+	}
+}
+
+// Register records the announced sensors of a generator
+func (r *Registry) Register(producerID, firmware, remoteAddr string, sensors []*pb.SensorRef) (int, error) {
+	now := r.now()
+	for _, s := range sensors {
+		d := &model.Device{
+			ID1:        s.Id1,
+			ID2:        s.Id2,
+			SensorType: s.SensorType,
+			Unit:       s.Unit,
+			Firmware:   firmware,
+			ProducerID: producerID,
+			RemoteAddr: remoteAddr,
+			LastSeen:   now,
+		}
+		if err := r.repo.Upsert(d); err != nil {
+			return 0, err
+		}
+		r.markTouched(s.Id1+"/"+s.Id2, now)
+	}
+	return len(sensors), nil
+}
+
+// Seen auto-registers the senders of readings and refreshes their last_seen.
+// Failures are logged only, they must not fail ingestion.
+func (r *Registry) Seen(readings []*pb.SensorData, remoteAddr string) {
+	now := r.now()
+	for _, data := range readings {
+		key := data.Id1 + "/" + data.Id2
+		if !r.markTouched(key, now) {
+			continue
+		}
+		// the producer is left to the control stream, spooled readings of an
+		// earlier session must not take the device over
+		d := &model.Device{
+			ID1:        data.Id1,
+			ID2:        data.Id2,
+			SensorType: data.SensorType,
+			RemoteAddr: remoteAddr,
+			LastSeen:   now,
+		}
+		if err := r.repo.Upsert(d); err != nil {
+			log.Printf("Failed to register device %s: %v", key, err)
+			r.forget(key)
+		}
+	}
+}
+
+// Connected registers the sensors announced on a control stream and marks them connected
+func (r *Registry) Connected(hello *pb.Hello, remoteAddr string) error {
+	if _, err := r.Register(hello.ProducerId, hello.Firmware, remoteAddr, hello.Sensors); err != nil {
+		return err
+	}
+	return r.repo.SetConnected(hello.ProducerId, true)
+}
+
+// Heartbeat keeps the devices of a producer online
+func (r *Registry) Heartbeat(producerID string) error {
+	return r.repo.TouchProducer(producerID, r.now())
+}
+
+// Disconnected marks the devices of a producer as no longer having a control stream
+func (r *Registry) Disconnected(producerID string) error {
+	return r.repo.SetConnected(producerID, false)
+}
+
+// List returns the registered devices with their online status; status filters
+// by online or offline when not empty
+func (r *Registry) List(status string) ([]model.Device, error) {
+	devices, err := r.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+	out := make([]model.Device, 0, len(devices))
+	for _, d := range devices {
+		d.Status = StatusOffline
+		if now.Sub(d.LastSeen) <= r.offlineAfter {
+			d.Status = StatusOnline
+		}
+		if status == "" || status == d.Status {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// markTouched reports whether key is due for a registry write and records it
+func (r *Registry) markTouched(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.touched[key]; ok && now.Sub(last) < touchEvery {
+		return false
+	}
+	r.touched[key] = now
+	return true
+}
+
+func (r *Registry) forget(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.touched, key)
+}
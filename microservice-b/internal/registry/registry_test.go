@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T) (*Registry, sqlmock.Sqlmock, *time.Time) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	r := New(repository.NewDeviceRepository(sqlx.NewDb(db, "mysql")), 30*time.Second)
+	now := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, mock, &now
+}
+
+func TestRegistry_SeenThrottlesWrites(t *testing.T) {
+	r, mock, now := newTestRegistry(t)
+
+	mock.ExpectExec("INSERT INTO devices").
+		WithArgs("A", "1", "Temperature", "", "", "", "10.0.0.5:4242", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO devices").
+		WithArgs("A", "1", "Temperature", "", "", "", "10.0.0.5:4242", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	reading := &pb.SensorData{SensorType: "Temperature", Id1: "A", Id2: "1", ProducerId: "p1"}
+	r.Seen([]*pb.SensorData{reading, reading}, "10.0.0.5:4242")
+	*now = now.Add(time.Second)
+	r.Seen([]*pb.SensorData{reading}, "10.0.0.5:4242") // throttled
+	*now = now.Add(touchEvery)
+	r.Seen([]*pb.SensorData{reading}, "10.0.0.5:4242")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegistry_ConnectedRegistersAnnouncedSensors(t *testing.T) {
+	r, mock, _ := newTestRegistry(t)
+
+	mock.ExpectExec("INSERT INTO devices").
+		WithArgs("A", "1", "Temperature", "°C", "1.2.0", "p1", "10.0.0.5:4242", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE devices SET connected = \\? WHERE producer_id = \\?").
+		WithArgs(true, "p1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := r.Connected(&pb.Hello{
+		ProducerId: "p1",
+		Firmware:   "1.2.0",
+		Sensors:    []*pb.SensorRef{{Id1: "A", Id2: "1", SensorType: "Temperature", Unit: "°C"}},
+	}, "10.0.0.5:4242")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegistry_ListDerivesStatus(t *testing.T) {
+	r, mock, now := newTestRegistry(t)
+
+	columns := []string{"id", "id1", "id2", "sensor_type", "unit", "firmware", "producer_id", "remote_addr", "connected", "first_seen", "last_seen"}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow(1, "A", "1", "Temperature", "°C", "1.2.0", "p1", "10.0.0.5:4242", true, *now, now.Add(-10*time.Second)).
+			AddRow(2, "B", "2", "Light", "lux", "1.2.0", "p2", "10.0.0.6:4242", false, *now, now.Add(-time.Hour))
+	}
+	mock.ExpectQuery("SELECT (.+) FROM devices").WillReturnRows(rows())
+	mock.ExpectQuery("SELECT (.+) FROM devices").WillReturnRows(rows())
+
+	devices, err := r.List("")
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, StatusOnline, devices[0].Status)
+	assert.Equal(t, StatusOffline, devices[1].Status)
+
+	devices, err = r.List(StatusOffline)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "B", devices[0].ID1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
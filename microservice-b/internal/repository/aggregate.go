@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"microservice-b/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxAggregateBuckets caps the buckets per group an aggregation may return
+const MaxAggregateBuckets = 10000
+
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// aggregateExprs maps simple aggregation functions to their SQL
+var aggregateExprs = map[string]string{
+	"avg":    "AVG(value)",
+	"min":    "MIN(value)",
+	"max":    "MAX(value)",
+	"sum":    "SUM(value)",
+	"count":  "COUNT(*)",
+	"stddev": "STDDEV_POP(value)",
+	"first":  "CAST(SUBSTRING_INDEX(GROUP_CONCAT(value ORDER BY ts ASC), ',', 1) AS DOUBLE)",
+	"last":   "CAST(SUBSTRING_INDEX(GROUP_CONCAT(value ORDER BY ts DESC), ',', 1) AS DOUBLE)",
+}
+
+// rollupExprs computes the functions that rollups can serve from rollup rows; raw
+// readings are unioned in as rows with min = max = sum = value and cnt = 1
+var rollupExprs = map[string]string{
+	"avg":   "SUM(sum_value) / SUM(cnt)",
+	"min":   "MIN(min_value)",
+	"max":   "MAX(max_value)",
+	"sum":   "SUM(sum_value)",
+	"count": "SUM(cnt)",
+}
+
+var aggregateGroups = map[string]bool{"id1": true, "id2": true, "sensor_type": true}
+
+// ParsePercentile returns the fraction of a percentile function like p95 or p99.9
+func ParsePercentile(fn string) (float64, bool) {
+	if !strings.HasPrefix(fn, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(fn[1:], 64)
+	if err != nil || p <= 0 || p >= 100 {
+		return 0, false
+	}
+	return p / 100, true
+}
+
+// ValidAggregateFunction reports whether fn can be used in an AggregateQuery
+func ValidAggregateFunction(fn string) bool {
+	if _, ok := aggregateExprs[fn]; ok {
+		return true
+	}
+	_, ok := ParsePercentile(fn)
+	return ok
+}
+
+// ValidAggregateGroup reports whether col can be used to group an AggregateQuery
+func ValidAggregateGroup(col string) bool {
+	return aggregateGroups[col]
+}
+
+// Aggregate buckets readings by time and the group columns and computes the requested
+// functions in MySQL. Percentiles use the nearest-rank method over window functions.
+// Queries that rollups can answer read the coarsest suitable rollup instead of raw readings.
+// The rollups keep the aggregates of archived and purged readings, so these queries
+// always include archived readings, whether served from rollups or raw readings;
+// the others include them only with include_archived.
+func (r *SensorRepository) Aggregate(q model.AggregateQuery) ([]model.AggregatePoint, error) {
+	seconds := int64(q.Bucket / time.Second)
+	if seconds <= 0 {
+		return nil, fmt.Errorf("bucket must be at least one second")
+	}
+	if len(q.Functions) == 0 {
+		return nil, fmt.Errorf("at least one aggregation function is required")
+	}
+	for _, col := range q.GroupBy {
+		if !ValidAggregateGroup(col) {
+			return nil, fmt.Errorf("cannot group by %q", col)
+		}
+	}
+
+	source, args, rollup, err := r.rollupSource(q, seconds)
+	if err != nil {
+		return nil, err
+	}
+	exprs := aggregateExprs
+	if rollup {
+		exprs = rollupExprs
+	}
+
+	selects := []string{"bucket"}
+	selects = append(selects, q.GroupBy...)
+	percentiles := false
+	for _, fn := range q.Functions {
+		if expr, ok := exprs[fn]; ok {
+			selects = append(selects, fmt.Sprintf("%s AS `%s`", expr, fn))
+			continue
+		}
+		p, ok := ParsePercentile(fn)
+		if !ok {
+			return nil, fmt.Errorf("unknown aggregation function %q", fn)
+		}
+		percentiles = true
+		selects = append(selects, fmt.Sprintf("MAX(CASE WHEN rn = GREATEST(1, CEIL(%s * cnt)) THEN value END) AS `%s`", strconv.FormatFloat(p, 'f', -1, 64), fn))
+	}
+
+	if !rollup {
+		inner := "SELECT id1, id2, sensor_type, value, ts, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(ts) / ?) * ?) AS bucket FROM sensor_readings WHERE 1=1"
+		args = []interface{}{seconds, seconds}
+		for k, v := range q.Filters {
+			switch k {
+			case "id1", "id2", "sensor_type":
+				inner += fmt.Sprintf(" AND %s = ?", k)
+				args = append(args, v)
+			case "from":
+				inner += " AND ts >= ?"
+				args = append(args, v)
+			case "to":
+				inner += " AND ts <= ?"
+				args = append(args, v)
+			}
+		}
+		if rollupFunctions(q.Functions) {
+			inner += " AND delete_batch IS NULL"
+		} else {
+			inner += archivedFilter(q.Filters)
+		}
+		source = "(" + inner + ") b"
+	}
+	if percentiles {
+		partition := strings.Join(append(append([]string{}, q.GroupBy...), "bucket"), ", ")
+		source = fmt.Sprintf("(SELECT b.*, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY value) AS rn, COUNT(*) OVER (PARTITION BY %s) AS cnt FROM %s) b", partition, partition, source)
+	}
+
+	groupBy := strings.Join(append([]string{"bucket"}, q.GroupBy...), ", ")
+	orderBy := strings.Join(append(append([]string{}, q.GroupBy...), "bucket"), ", ")
+	query := fmt.Sprintf("SELECT %s FROM %s GROUP BY %s ORDER BY %s", strings.Join(selects, ", "), source, groupBy, orderBy)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []model.AggregatePoint
+	for rows.Next() {
+		var p model.AggregatePoint
+		groups := make([]string, len(q.GroupBy))
+		values := make([]sql.NullFloat64, len(q.Functions))
+		dest := []interface{}{&p.Bucket}
+		for i := range groups {
+			dest = append(dest, &groups[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, col := range q.GroupBy {
+			switch col {
+			case "id1":
+				p.ID1 = groups[i]
+			case "id2":
+				p.ID2 = groups[i]
+			case "sensor_type":
+				p.SensorType = groups[i]
+			}
+		}
+		p.Values = make(map[string]*float64, len(q.Functions))
+		for i, fn := range q.Functions {
+			if values[i].Valid {
+				v := values[i].Float64
+				p.Values[fn] = &v
+			} else {
+				p.Values[fn] = nil
+			}
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Fill == model.FillNone {
+		return points, nil
+	}
+	from, _ := q.Filters["from"].(time.Time)
+	to, _ := q.Filters["to"].(time.Time)
+	return FillGaps(points, q.Bucket, q.Functions, q.Fill, from, to)
+}
+
+// rollupSource returns the rows to aggregate from the coarsest rollup whose resolution
+// divides the bucket, unioned with raw readings for the parts of the range the rollup
+// does not cover: after its watermark and partial buckets at either end. Raw readings
+// count whether archived or not, like the rollups. It reports false when the query
+// needs raw values or no rollup is materialized yet.
+func (r *SensorRepository) rollupSource(q model.AggregateQuery, seconds int64) (string, []interface{}, bool, error) {
+	if !rollupFunctions(q.Functions) {
+		return "", nil, false, nil
+	}
+	from, hasFrom := q.Filters["from"].(time.Time)
+	to, hasTo := q.Filters["to"].(time.Time)
+	hasFrom = hasFrom && !from.IsZero()
+	hasTo = hasTo && !to.IsZero()
+
+	marks, err := rollupStates(r.DB)
+	if err != nil {
+		return "", nil, false, err
+	}
+	for i := len(Rollups) - 1; i >= 0; i-- {
+		rollup := Rollups[i]
+		watermark, ok := marks[rollup.Name]
+		if !ok || q.Bucket%rollup.Size != 0 {
+			continue
+		}
+		// the rollup serves the whole buckets in [lo, hi)
+		hi := watermark
+		if hasTo && to.Truncate(rollup.Size).Before(hi) {
+			hi = to.Truncate(rollup.Size)
+		}
+		var lo time.Time
+		if hasFrom {
+			lo = from.Truncate(rollup.Size)
+			if lo.Before(from) {
+				lo = lo.Add(rollup.Size)
+			}
+			if !lo.Before(hi) {
+				continue
+			}
+		}
+
+		rolled := "SELECT id1, id2, sensor_type, min_value, max_value, sum_value, cnt, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(bucket) / ?) * ?) AS bucket FROM " + rollup.Table + " WHERE bucket < ?"
+		rolledArgs := []interface{}{seconds, seconds, hi}
+		raw := "SELECT id1, id2, sensor_type, value, value, value, 1, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(ts) / ?) * ?) AS bucket FROM sensor_readings WHERE ts >= ?"
+		rawArgs := []interface{}{seconds, seconds, hi}
+		if hasFrom {
+			rolled += " AND bucket >= ?"
+			rolledArgs = append(rolledArgs, lo)
+			raw = "SELECT id1, id2, sensor_type, value, value, value, 1, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(ts) / ?) * ?) AS bucket FROM sensor_readings WHERE (ts >= ? OR ts < ?) AND ts >= ?"
+			rawArgs = append(rawArgs, lo, from)
+		}
+		if hasTo {
+			raw += " AND ts <= ?"
+			rawArgs = append(rawArgs, to)
+		}
+		raw += " AND delete_batch IS NULL"
+		for _, k := range []string{"id1", "id2", "sensor_type"} {
+			if v, ok := q.Filters[k]; ok {
+				rolled += fmt.Sprintf(" AND %s = ?", k)
+				rolledArgs = append(rolledArgs, v)
+				raw += fmt.Sprintf(" AND %s = ?", k)
+				rawArgs = append(rawArgs, v)
+			}
+		}
+		return "(" + rolled + " UNION ALL " + raw + ") b", append(rolledArgs, rawArgs...), true, nil
+	}
+	return "", nil, false, nil
+}
+
+// rollupFunctions reports whether rollups can compute all of functions
+func rollupFunctions(functions []string) bool {
+	for _, fn := range functions {
+		if _, ok := rollupExprs[fn]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// FillGaps inserts the empty buckets of every group between from and to (or its first
+// and last bucket when unset). Empty buckets get null values, the previous bucket's
+// values or a linear interpolation between their neighbours; count is always 0.
+func FillGaps(points []model.AggregatePoint, bucket time.Duration, functions []string, mode string, from, to time.Time) ([]model.AggregatePoint, error) {
+	var out []model.AggregatePoint
+	for start := 0; start < len(points); {
+		end := start + 1
+		for end < len(points) && sameGroup(points[start], points[end]) {
+			end++
+		}
+		filled, err := fillSeries(points[start:end], bucket, functions, mode, from, to)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, filled...)
+		start = end
+	}
+	return out, nil
+}
+
+func sameGroup(a, b model.AggregatePoint) bool {
+	return a.ID1 == b.ID1 && a.ID2 == b.ID2 && a.SensorType == b.SensorType
+}
+
+// fillSeries fills the gaps of one group's points, which are ordered by bucket
+func fillSeries(series []model.AggregatePoint, bucket time.Duration, functions []string, mode string, from, to time.Time) ([]model.AggregatePoint, error) {
+	// step from the buckets MySQL returned so the series stays aligned with them
+	first, last := series[0].Bucket, series[len(series)-1].Bucket
+	for !from.IsZero() && first.After(from) {
+		first = first.Add(-bucket)
+	}
+	for !to.IsZero() && !last.Add(bucket).After(to) {
+		last = last.Add(bucket)
+	}
+	if n := int64(last.Sub(first)/bucket) + 1; n > MaxAggregateBuckets {
+		return nil, fmt.Errorf("%w: gap filling would produce %d buckets, more than %d", ErrTooManyBuckets, n, MaxAggregateBuckets)
+	}
+
+	byBucket := make(map[int64]model.AggregatePoint, len(series))
+	for _, p := range series {
+		byBucket[p.Bucket.UnixNano()] = p
+	}
+
+	var out []model.AggregatePoint
+	var missing []int // indexes of filled buckets waiting for the next real one
+	prev := -1        // index of the last real bucket
+	for t := first; !t.After(last); t = t.Add(bucket) {
+		if p, ok := byBucket[t.UnixNano()]; ok {
+			if mode == model.FillLinear && prev >= 0 {
+				interpolate(out, missing, out[prev], p, functions)
+			}
+			missing = missing[:0]
+			out = append(out, p)
+			prev = len(out) - 1
+			continue
+		}
+
+		p := model.AggregatePoint{Bucket: t, ID1: series[0].ID1, ID2: series[0].ID2, SensorType: series[0].SensorType, Values: map[string]*float64{}}
+		for _, fn := range functions {
+			p.Values[fn] = nil
+			if mode == model.FillPrevious && prev >= 0 {
+				p.Values[fn] = out[prev].Values[fn]
+			}
+			if fn == "count" {
+				zero := 0.0
+				p.Values[fn] = &zero
+			}
+		}
+		missing = append(missing, len(out))
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// interpolate fills the values of out[missing] on the line between prev and next
+func interpolate(out []model.AggregatePoint, missing []int, prev, next model.AggregatePoint, functions []string) {
+	span := float64(next.Bucket.Sub(prev.Bucket))
+	for _, i := range missing {
+		frac := float64(out[i].Bucket.Sub(prev.Bucket)) / span
+		for _, fn := range functions {
+			a, b := prev.Values[fn], next.Values[fn]
+			if fn == "count" || a == nil || b == nil {
+				continue
+			}
+			v := *a + (*b-*a)*frac
+			out[i].Values[fn] = &v
+		}
+	}
+}
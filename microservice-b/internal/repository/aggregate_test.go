@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func f(v float64) *float64 { return &v }
+
+func TestSensorRepository_Aggregate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	t0 := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"bucket", "id1", "avg", "count", "p95"}).
+		AddRow(t0, "A", 21.5, 60, 23.0).
+		AddRow(t0.Add(5*time.Minute), "A", nil, 0, nil)
+	mock.ExpectQuery("SELECT bucket, id1, AVG\\(value\\) AS `avg`, COUNT\\(\\*\\) AS `count`, "+
+		"MAX\\(CASE WHEN rn = GREATEST\\(1, CEIL\\(0.95 \\* cnt\\)\\) THEN value END\\) AS `p95` "+
+		"FROM \\(SELECT b.\\*, ROW_NUMBER\\(\\) OVER \\(PARTITION BY id1, bucket ORDER BY value\\) AS rn, .+"+
+		"FROM sensor_readings WHERE 1=1 AND id1 = \\? AND archived_at IS NULL\\) b\\) b GROUP BY bucket, id1 ORDER BY id1, bucket").
+		WithArgs(int64(300), int64(300), "A").
+		WillReturnRows(rows)
+
+	points, err := repo.Aggregate(model.AggregateQuery{
+		Bucket:    5 * time.Minute,
+		Functions: []string{"avg", "count", "p95"},
+		GroupBy:   []string{"id1"},
+		Filters:   map[string]interface{}{"id1": "A"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "A", points[0].ID1)
+	assert.Equal(t, 21.5, *points[0].Values["avg"])
+	assert.Equal(t, 60.0, *points[0].Values["count"])
+	assert.Equal(t, 23.0, *points[0].Values["p95"])
+	assert.Nil(t, points[1].Values["avg"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_Aggregate_UsesRollup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	day := time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)
+	from, to := day.Add(7*time.Hour+30*time.Minute), day.Add(12*time.Hour)
+
+	mock.ExpectQuery("SELECT name, watermark FROM rollup_state").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "watermark"}).
+			AddRow("1m", day.Add(10*time.Hour+5*time.Minute)).
+			AddRow("1h", day.Add(10*time.Hour)).
+			AddRow("1d", day))
+	// whole hours up to the 1h watermark come from the rollup, the rest from raw readings
+	mock.ExpectQuery("SELECT bucket, SUM\\(sum_value\\) / SUM\\(cnt\\) AS `avg`, SUM\\(cnt\\) AS `count` "+
+		"FROM \\(SELECT .+ FROM sensor_rollups_1h WHERE bucket < \\? AND bucket >= \\? AND id1 = \\? "+
+		"UNION ALL SELECT .+ FROM sensor_readings WHERE \\(ts >= \\? OR ts < \\?\\) AND ts >= \\? AND ts <= \\? AND delete_batch IS NULL AND id1 = \\?\\) b "+
+		"GROUP BY bucket ORDER BY bucket").
+		WithArgs(int64(3600), int64(3600), day.Add(10*time.Hour), day.Add(8*time.Hour), "A",
+			int64(3600), int64(3600), day.Add(10*time.Hour), day.Add(8*time.Hour), from, to, "A").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "avg", "count"}).AddRow(day.Add(8*time.Hour), 20.0, 3600))
+
+	points, err := repo.Aggregate(model.AggregateQuery{
+		Bucket:    time.Hour,
+		Functions: []string{"avg", "count"},
+		Filters:   map[string]interface{}{"id1": "A", "from": from, "to": to},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, 3600.0, *points[0].Values["count"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_Aggregate_RollupFunctionsCountArchivedReadings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	mock.ExpectQuery("SELECT name, watermark FROM rollup_state").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "watermark"}))
+	// no rollup yet, but the result matches what the rollups, which keep archived
+	// readings, will return
+	mock.ExpectQuery("SELECT bucket, AVG\\(value\\) AS `avg` FROM \\(SELECT .+ FROM sensor_readings WHERE 1=1 AND delete_batch IS NULL\\) b GROUP BY bucket ORDER BY bucket").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "avg"}))
+
+	_, err = repo.Aggregate(model.AggregateQuery{
+		Bucket:    time.Hour,
+		Functions: []string{"avg"},
+		Filters:   map[string]interface{}{},
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_Aggregate_RejectsUnknownFunction(t *testing.T) {
+	repo := NewSensorRepository(nil)
+	_, err := repo.Aggregate(model.AggregateQuery{Bucket: time.Minute, Functions: []string{"median"}})
+	assert.Error(t, err)
+	_, err = repo.Aggregate(model.AggregateQuery{Bucket: time.Minute, Functions: []string{"avg"}, GroupBy: []string{"value"}})
+	assert.Error(t, err)
+}
+
+func TestParsePercentile(t *testing.T) {
+	p, ok := ParsePercentile("p99.9")
+	assert.True(t, ok)
+	assert.InDelta(t, 0.999, p, 1e-9)
+	for _, fn := range []string{"p0", "p100", "px", "avg"} {
+		_, ok := ParsePercentile(fn)
+		assert.False(t, ok, fn)
+	}
+}
+
+func TestFillGaps(t *testing.T) {
+	t0 := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	series := func() []model.AggregatePoint {
+		return []model.AggregatePoint{
+			{Bucket: t0.Add(time.Minute), ID1: "A", Values: map[string]*float64{"avg": f(10), "count": f(2)}},
+			{Bucket: t0.Add(4 * time.Minute), ID1: "A", Values: map[string]*float64{"avg": f(40), "count": f(3)}},
+			{Bucket: t0, ID1: "B", Values: map[string]*float64{"avg": f(1), "count": f(1)}},
+		}
+	}
+	fns := []string{"avg", "count"}
+
+	tests := map[string][]*float64{
+		model.FillNull:     {nil, f(10), nil, nil, f(40), nil},
+		model.FillPrevious: {nil, f(10), f(10), f(10), f(40), f(40)},
+		model.FillLinear:   {nil, f(10), f(20), f(30), f(40), nil},
+	}
+	for mode, want := range tests {
+		t.Run(mode, func(t *testing.T) {
+			out, err := FillGaps(series(), time.Minute, fns, mode, t0, t0.Add(5*time.Minute))
+			require.NoError(t, err)
+
+			var a []model.AggregatePoint
+			for _, p := range out {
+				if p.ID1 == "A" {
+					a = append(a, p)
+				}
+			}
+			require.Len(t, a, 6, "buckets 10:00 to 10:05")
+			for i, p := range a {
+				assert.Equal(t, t0.Add(time.Duration(i)*time.Minute), p.Bucket)
+				assert.Equal(t, want[i], p.Values["avg"], "bucket %d", i)
+			}
+			assert.Equal(t, 0.0, *a[2].Values["count"], "empty buckets count 0")
+			assert.Len(t, out, 12, "every group is filled on its own")
+		})
+	}
+}
+
+func TestFillGaps_TooManyBuckets(t *testing.T) {
+	t0 := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	points := []model.AggregatePoint{{Bucket: t0, Values: map[string]*float64{"avg": f(1)}}}
+	_, err := FillGaps(points, time.Minute, []string{"avg"}, model.FillNull, t0, t0.Add(365*24*time.Hour))
+	assert.ErrorIs(t, err, ErrTooManyBuckets)
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"microservice-b/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AlertRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAlertRepository(db *sqlx.DB) *AlertRepository {
+	return &AlertRepository{DB: db}
+}
+
+// ListRules returns all alert rules, oldest first
+func (r *AlertRepository) ListRules() ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	err := r.DB.Select(&rules, "SELECT * FROM alert_rules ORDER BY id")
+	return rules, err
+}
+
+// GetRule returns a rule by id, or nil when it does not exist
+func (r *AlertRepository) GetRule(id uint64) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	err := r.DB.Get(&rule, "SELECT * FROM alert_rules WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule stores a new rule and returns it as stored
+func (r *AlertRepository) CreateRule(rule *model.AlertRule) (*model.AlertRule, error) {
+	res, err := r.DB.Exec(`INSERT INTO alert_rules (name, type, sensor_type, id1, id2, operator, threshold, min_value, max_value, hysteresis, for_seconds, severity, enabled, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.Name, rule.Type, rule.SensorType, rule.ID1, rule.ID2, rule.Operator, rule.Threshold, rule.MinValue, rule.MaxValue,
+		rule.Hysteresis, rule.ForSeconds, rule.Severity, rule.Enabled, rule.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.GetRule(uint64(id))
+}
+
+// UpdateRule stores every field of rule but its creator and returns it as stored
+func (r *AlertRepository) UpdateRule(rule *model.AlertRule) (*model.AlertRule, error) {
+	_, err := r.DB.Exec(`UPDATE alert_rules SET name = ?, type = ?, sensor_type = ?, id1 = ?, id2 = ?, operator = ?, threshold = ?,
+		min_value = ?, max_value = ?, hysteresis = ?, for_seconds = ?, severity = ?, enabled = ? WHERE id = ?`,
+		rule.Name, rule.Type, rule.SensorType, rule.ID1, rule.ID2, rule.Operator, rule.Threshold, rule.MinValue, rule.MaxValue,
+		rule.Hysteresis, rule.ForSeconds, rule.Severity, rule.Enabled, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetRule(rule.ID)
+}
+
+// DeleteRule removes a rule and reports whether it existed. Its alerts are kept.
+func (r *AlertRepository) DeleteRule(id uint64) (bool, error) {
+	res, err := r.DB.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Fire stores a firing alert and returns its id
+func (r *AlertRepository) Fire(a *model.Alert) (uint64, error) {
+	res, err := r.DB.Exec(`INSERT INTO alerts (rule_id, rule_name, severity, id1, id2, sensor_type, state, value, message, fired_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.RuleID, a.RuleName, a.Severity, a.ID1, a.ID2, a.SensorType, model.AlertFiring, a.Value, a.Message, a.FiredAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return uint64(id), err
+}
+
+// Resolve marks a firing alert resolved at at
+func (r *AlertRepository) Resolve(id uint64, at time.Time) error {
+	_, err := r.DB.Exec("UPDATE alerts SET state = ?, resolved_at = ? WHERE id = ? AND state = ?", model.AlertResolved, at, id, model.AlertFiring)
+	return err
+}
+
+// ResolveRule marks all firing alerts of a rule resolved at at
+func (r *AlertRepository) ResolveRule(ruleID uint64, at time.Time) (int64, error) {
+	res, err := r.DB.Exec("UPDATE alerts SET state = ?, resolved_at = ? WHERE rule_id = ? AND state = ?", model.AlertResolved, at, ruleID, model.AlertFiring)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListFiring returns the alerts that are firing
+func (r *AlertRepository) ListFiring() ([]model.Alert, error) {
+	var alerts []model.Alert
+	err := r.DB.Select(&alerts, "SELECT * FROM alerts WHERE state = ?", model.AlertFiring)
+	return alerts, err
+}
+
+// alertFilter returns the condition selecting alerts by rule_id, state, severity, id1 and id2
+func alertFilter(filters map[string]interface{}) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	for _, k := range []string{"rule_id", "state", "severity", "id1", "id2"} {
+		if v, ok := filters[k]; ok {
+			where += fmt.Sprintf(" AND %s = ?", k)
+			args = append(args, v)
+		}
+	}
+	return where, args
+}
+
+// ListAlerts returns the alerts matching filters, most recently fired first
+func (r *AlertRepository) ListAlerts(filters map[string]interface{}, limit, offset int) ([]model.Alert, error) {
+	where, args := alertFilter(filters)
+	var alerts []model.Alert
+	err := r.DB.Select(&alerts, "SELECT * FROM alerts WHERE "+where+" ORDER BY fired_at DESC, id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return alerts, err
+}
+
+// CountAlerts counts the alerts matching filters
+func (r *AlertRepository) CountAlerts(filters map[string]interface{}) (int64, error) {
+	where, args := alertFilter(filters)
+	var total int64
+	err := r.DB.Get(&total, "SELECT COUNT(*) FROM alerts WHERE "+where, args...)
+	return total, err
+}
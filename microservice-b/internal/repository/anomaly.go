@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"microservice-b/model"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AnomalyRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAnomalyRepository(db *sqlx.DB) *AnomalyRepository {
+	return &AnomalyRepository{DB: db}
+}
+
+// Insert stores anomalous readings with one multi-row INSERT
+func (r *AnomalyRepository) Insert(anomalies []model.AnomalyScore) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+	query := "INSERT INTO sensor_anomalies (id1, id2, sensor_type, value, ts, method, score, expected, z_rolling, z_ewma, z_seasonal) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?),", len(anomalies)), ",")
+	args := make([]interface{}, 0, len(anomalies)*11)
+	for _, a := range anomalies {
+		args = append(args, a.ID1, a.ID2, a.SensorType, a.Value, a.TS, a.Method, a.Score, a.Expected, a.ZRolling, a.ZEWMA, a.ZSeasonal)
+	}
+	_, err := r.DB.Exec(query, args...)
+	return err
+}
+
+// anomalyFilter returns the condition selecting anomalies by id1, id2,
+// sensor_type, method, from and to (on the reading time) and min_score (on the
+// absolute score)
+func anomalyFilter(filters map[string]interface{}) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	for _, k := range []string{"id1", "id2", "sensor_type", "method"} {
+		if v, ok := filters[k]; ok {
+			where += fmt.Sprintf(" AND %s = ?", k)
+			args = append(args, v)
+		}
+	}
+	if v, ok := filters["from"]; ok {
+		where += " AND ts >= ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["to"]; ok {
+		where += " AND ts <= ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["min_score"]; ok {
+		where += " AND ABS(score) >= ?"
+		args = append(args, v)
+	}
+	return where, args
+}
+
+// List returns the anomalies matching filters, latest reading first
+func (r *AnomalyRepository) List(filters map[string]interface{}, limit, offset int) ([]model.Anomaly, error) {
+	where, args := anomalyFilter(filters)
+	var anomalies []model.Anomaly
+	err := r.DB.Select(&anomalies, "SELECT * FROM sensor_anomalies WHERE "+where+" ORDER BY ts DESC, id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return anomalies, err
+}
+
+// Count counts the anomalies matching filters
+func (r *AnomalyRepository) Count(filters map[string]interface{}) (int64, error) {
+	where, args := anomalyFilter(filters)
+	var total int64
+	err := r.DB.Get(&total, "SELECT COUNT(*) FROM sensor_anomalies WHERE "+where, args...)
+	return total, err
+}
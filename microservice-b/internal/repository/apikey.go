@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"time"
+)
+
+// CreateAPIKey stores an API key and sets its id
+func (r *UserRepo) CreateAPIKey(k *model.APIKey) error {
+	res, err := r.DB.Exec(`INSERT INTO api_keys (name, prefix, key_hash, user_id, scopes, id1_allowlist, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, k.Name, k.Prefix, k.KeyHash, k.UserID, k.Scopes, k.ID1Allowlist, k.CreatedBy, k.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	k.ID = uint64(id)
+	return err
+}
+
+// GetAPIKey returns an API key by id
+func (r *UserRepo) GetAPIKey(id uint64) (*model.APIKey, error) {
+	k := &model.APIKey{}
+	err := r.DB.Get(k, "SELECT * FROM api_keys WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GetAPIKeyByHash returns the API key with the given hash, or nil when there
+// is none
+func (r *UserRepo) GetAPIKeyByHash(hash string) (*model.APIKey, error) {
+	k := &model.APIKey{}
+	err := r.DB.Get(k, "SELECT * FROM api_keys WHERE key_hash = ?", hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// ListAPIKeys returns the API keys matching filters, newest first
+func (r *UserRepo) ListAPIKeys(filters map[string]interface{}, limit, offset int) ([]model.APIKey, error) {
+	where, args := apiKeyWhere(filters)
+	var keys []model.APIKey
+	err := r.DB.Select(&keys, "SELECT * FROM api_keys WHERE "+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return keys, err
+}
+
+// CountAPIKeys returns the number of API keys matching filters
+func (r *UserRepo) CountAPIKeys(filters map[string]interface{}) (int64, error) {
+	where, args := apiKeyWhere(filters)
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM api_keys WHERE "+where, args...)
+	return n, err
+}
+
+// apiKeyWhere builds the condition for the user_id (owner) and include_revoked
+// filters; revoked keys are skipped by default
+func apiKeyWhere(filters map[string]interface{}) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	if v, ok := filters["user_id"]; ok {
+		where += " AND user_id = ?"
+		args = append(args, v)
+	}
+	if v, _ := filters["include_revoked"].(bool); !v {
+		where += " AND revoked_at IS NULL"
+	}
+	return where, args
+}
+
+// RevokeAPIKey revokes an API key. It reports false when the key was already
+// revoked.
+func (r *UserRepo) RevokeAPIKey(id uint64) (bool, error) {
+	res, err := r.DB.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIKey records that an API key was used at now. To spare a write per
+// request, last_used_at is only moved once it is older than resolution.
+func (r *UserRepo) TouchAPIKey(id uint64, now time.Time, resolution time.Duration) error {
+	_, err := r.DB.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now, id, now.Add(-resolution))
+	return err
+}
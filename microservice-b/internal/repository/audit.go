@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"microservice-b/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditRepository stores audit events. The table is append-only: events are
+// never updated or deleted, which triggers enforce in the database.
+type AuditRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// Insert appends an audit event
+func (r *AuditRepository) Insert(e *model.AuditEvent) error {
+	_, err := r.DB.Exec(`INSERT INTO audit_events (user_id, email, role, action, path, params, status, affected, remote_ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, e.UserID, e.Email, e.Role, e.Action, e.Path, e.Params, e.Status, e.Affected, e.RemoteIP)
+	return err
+}
+
+// List returns the audit events matching filters, newest first
+func (r *AuditRepository) List(filters map[string]interface{}, limit, offset int) ([]model.AuditEvent, error) {
+	where, args := auditWhere(filters)
+	query := "SELECT * FROM audit_events WHERE " + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	var events []model.AuditEvent
+	err := r.DB.Select(&events, query, append(args, limit, offset)...)
+	return events, err
+}
+
+// Count returns the number of audit events matching filters
+func (r *AuditRepository) Count(filters map[string]interface{}) (int64, error) {
+	where, args := auditWhere(filters)
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM audit_events WHERE "+where, args...)
+	return n, err
+}
+
+// auditWhere builds the condition for the user_id, email, action, from and to filters
+func auditWhere(filters map[string]interface{}) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	if v, ok := filters["user_id"]; ok {
+		where += " AND user_id = ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["email"]; ok {
+		where += " AND email = ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["action"]; ok {
+		where += " AND action = ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["from"]; ok {
+		where += " AND created_at >= ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["to"]; ok {
+		where += " AND created_at <= ?"
+		args = append(args, v)
+	}
+	return where, args
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewAuditRepository(sqlx.NewDb(db, "mysql"))
+
+	from := time.Date(2025, 9, 6, 10, 0, 0, 0, time.UTC)
+	filters := map[string]interface{}{"user_id": uint64(7), "action": "DELETE /api/sensors", "from": from}
+
+	mock.ExpectQuery("SELECT \\* FROM audit_events WHERE 1=1 AND user_id = \\? AND action = \\? AND created_at >= \\? ORDER BY created_at DESC, id DESC LIMIT \\? OFFSET \\?").
+		WithArgs(uint64(7), "DELETE /api/sensors", from, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "email", "role", "action", "path", "params", "status", "affected", "remote_ip", "created_at"}).
+			AddRow(1, 7, "a@b.c", "admin", "DELETE /api/sensors", "/api/sensors", `{"id1":"A"}`, 200, 3, "10.0.0.1", from))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM audit_events WHERE 1=1 AND user_id = \\? AND action = \\? AND created_at >= \\?").
+		WithArgs(uint64(7), "DELETE /api/sensors", from).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	events, err := repo.List(filters, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), *events[0].Affected)
+	total, err := repo.Count(filters)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewAuditRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs(nil, "a@b.c", "", "POST /login", "/login", nil, 401, nil, "10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.Insert(&model.AuditEvent{Email: "a@b.c", Action: "POST /login", Path: "/login", Status: 401, RemoteIP: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"microservice-b/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DeviceRepository struct {
+	DB *sqlx.DB
+}
+
+func NewDeviceRepository(db *sqlx.DB) *DeviceRepository {
+	return &DeviceRepository{DB: db}
+}
+
+// Upsert registers a device or refreshes it; empty unit, firmware, producer and
+// address keep the stored values
+func (r *DeviceRepository) Upsert(d *model.Device) error {
+	query := `INSERT INTO devices(
+                            id1,
+                            id2,
+                            sensor_type,
+                            unit,
+                            firmware,
+                            producer_id,
+                            remote_addr,
+                            first_seen,
+                            last_seen)
+                     VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+              ON DUPLICATE KEY UPDATE
+                     sensor_type = VALUES(sensor_type),
+                     unit = IF(VALUES(unit) = '', unit, VALUES(unit)),
+                     firmware = IF(VALUES(firmware) = '', firmware, VALUES(firmware)),
+                     producer_id = IF(VALUES(producer_id) = '', producer_id, VALUES(producer_id)),
+                     remote_addr = IF(VALUES(remote_addr) = '', remote_addr, VALUES(remote_addr)),
+                     last_seen = VALUES(last_seen)`
+	_, err := r.DB.Exec(query, d.ID1, d.ID2, d.SensorType, d.Unit, d.Firmware, d.ProducerID, d.RemoteAddr, d.LastSeen, d.LastSeen)
+	return err
+}
+
+// SetConnected flags every device of a producer as having a control stream attached or not
+func (r *DeviceRepository) SetConnected(producerID string, connected bool) error {
+	_, err := r.DB.Exec("UPDATE devices SET connected = ? WHERE producer_id = ?", connected, producerID)
+	return err
+}
+
+// TouchProducer refreshes last_seen of every device of a producer
+func (r *DeviceRepository) TouchProducer(producerID string, ts time.Time) error {
+	_, err := r.DB.Exec("UPDATE devices SET last_seen = ? WHERE producer_id = ?", ts, producerID)
+	return err
+}
+
+// List returns all registered devices ordered by their ids
+func (r *DeviceRepository) List() ([]model.Device, error) {
+	query := `SELECT id, id1, id2, sensor_type, unit, firmware, producer_id, remote_addr, connected, first_seen, last_seen
+          FROM devices
+          ORDER BY id1, id2`
+	var devices []model.Device
+	err := r.DB.Select(&devices, query)
+	return devices, err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"time"
+)
+
+// CreateToken stores a device token and sets its id
+func (r *DeviceRepository) CreateToken(t *model.DeviceToken) error {
+	res, err := r.DB.Exec("INSERT INTO device_tokens (name, prefix, token_hash, sensors, created_by) VALUES (?, ?, ?, ?, ?)",
+		t.Name, t.Prefix, t.TokenHash, t.Sensors, t.CreatedBy)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	t.ID = uint64(id)
+	return err
+}
+
+// GetTokenByHash returns the device token with the given hash, or nil when
+// there is none
+func (r *DeviceRepository) GetTokenByHash(hash string) (*model.DeviceToken, error) {
+	t := &model.DeviceToken{}
+	err := r.DB.Get(t, "SELECT * FROM device_tokens WHERE token_hash = ?", hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTokens returns device tokens, newest first; revoked tokens only when
+// includeRevoked is set
+func (r *DeviceRepository) ListTokens(includeRevoked bool, limit, offset int) ([]model.DeviceToken, error) {
+	var tokens []model.DeviceToken
+	err := r.DB.Select(&tokens, "SELECT * FROM device_tokens WHERE "+deviceTokenWhere(includeRevoked)+" ORDER BY id DESC LIMIT ? OFFSET ?", limit, offset)
+	return tokens, err
+}
+
+// CountTokens returns the number of device tokens ListTokens pages through
+func (r *DeviceRepository) CountTokens(includeRevoked bool) (int64, error) {
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM device_tokens WHERE "+deviceTokenWhere(includeRevoked))
+	return n, err
+}
+
+func deviceTokenWhere(includeRevoked bool) string {
+	if includeRevoked {
+		return "1=1"
+	}
+	return "revoked_at IS NULL"
+}
+
+// RevokeToken revokes a device token. It reports false when there is no
+// active token with this id.
+func (r *DeviceRepository) RevokeToken(id uint64) (bool, error) {
+	res, err := r.DB.Exec("UPDATE device_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchToken records that a device token was used at now. To spare a write per
+// stream, last_used_at is only moved once it is older than resolution.
+func (r *DeviceRepository) TouchToken(id uint64, now time.Time, resolution time.Duration) error {
+	_, err := r.DB.Exec("UPDATE device_tokens SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now, id, now.Add(-resolution))
+	return err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"time"
+)
+
+// CreateInvitation stores an invitation and sets its id
+func (r *UserRepo) CreateInvitation(inv *model.Invitation) error {
+	res, err := r.DB.Exec(`INSERT INTO invitations (token_hash, email, role, created_by, expires_at)
+		VALUES (?, ?, ?, ?, ?)`, inv.TokenHash, inv.Email, inv.Role, inv.CreatedBy, inv.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	inv.ID = uint64(id)
+	return err
+}
+
+// GetInvitation returns an invitation by id
+func (r *UserRepo) GetInvitation(id uint64) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+	err := r.DB.Get(inv, "SELECT * FROM invitations WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetInvitationByToken returns the invitation with the given token hash, or nil
+// when there is none
+func (r *UserRepo) GetInvitationByToken(hash string) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+	err := r.DB.Get(inv, "SELECT * FROM invitations WHERE token_hash = ?", hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// ListInvitations returns the invitations matching filters, newest first
+func (r *UserRepo) ListInvitations(filters map[string]interface{}, limit, offset int) ([]model.Invitation, error) {
+	where, args := invitationWhere(filters, time.Now().UTC())
+	var invitations []model.Invitation
+	err := r.DB.Select(&invitations, "SELECT * FROM invitations WHERE "+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return invitations, err
+}
+
+// CountInvitations returns the number of invitations matching filters
+func (r *UserRepo) CountInvitations(filters map[string]interface{}) (int64, error) {
+	where, args := invitationWhere(filters, time.Now().UTC())
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM invitations WHERE "+where, args...)
+	return n, err
+}
+
+// invitationWhere builds the condition for the email and status (pending,
+// accepted, revoked or expired) filters
+func invitationWhere(filters map[string]interface{}, now time.Time) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	if v, ok := filters["email"]; ok {
+		where += " AND email = ?"
+		args = append(args, v)
+	}
+	switch filters["status"] {
+	case model.InvitationPending:
+		where += " AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?"
+		args = append(args, now)
+	case model.InvitationAccepted:
+		where += " AND accepted_at IS NOT NULL"
+	case model.InvitationRevoked:
+		where += " AND accepted_at IS NULL AND revoked_at IS NOT NULL"
+	case model.InvitationExpired:
+		where += " AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?"
+		args = append(args, now)
+	}
+	return where, args
+}
+
+// RevokeInvitation revokes a pending invitation. It reports false when the
+// invitation was already accepted or revoked.
+func (r *UserRepo) RevokeInvitation(id uint64) (bool, error) {
+	res, err := r.DB.Exec("UPDATE invitations SET revoked_at = ? WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// AcceptInvitation creates user and marks the invitation with id accepted by
+// them, in one transaction. It reports false, creating nothing, when the
+// invitation is no longer pending, so a token cannot be used twice.
+func (r *UserRepo) AcceptInvitation(id uint64, user *model.SignupRequest) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO users (first_name, last_name, email, password, role) VALUES (?, ?, ?, ?, ?)",
+		user.FirstName, user.LastName, user.Email, user.Password, user.Role)
+	if err != nil {
+		return false, err
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UTC()
+	res, err = tx.Exec(`UPDATE invitations SET accepted_at = ?, accepted_by = ?
+		WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?`, now, userID, id, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// CreateFirstAdmin creates an admin with the given email and password hash when
+// the users table is empty, archived users included. It reports whether the
+// admin was created; the check and the insert are one statement so concurrent
+// instances cannot both create one.
+func (r *UserRepo) CreateFirstAdmin(email, hash string) (bool, error) {
+	res, err := r.DB.Exec(`INSERT INTO users (email, password, role)
+		SELECT ?, ?, 'admin' FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM users)`, email, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepo_AcceptInvitation_UsedConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewUserRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("UPDATE invitations SET accepted_at = \\?, accepted_by = \\? WHERE id = \\? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > \\?").
+		WithArgs(sqlmock.AnyArg(), int64(9), uint64(4), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// the user is not kept when the invitation was used in the meantime
+	mock.ExpectRollback()
+
+	ok, err := repo.AcceptInvitation(4, &model.SignupRequest{Email: "new@example.com", Role: "analyst"})
+
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_CreateFirstAdmin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewUserRepository(sqlx.NewDb(db, "mysql"))
+
+	query := "INSERT INTO users \\(email, password, role\\) SELECT \\?, \\?, 'admin' FROM DUAL WHERE NOT EXISTS \\(SELECT 1 FROM users\\)"
+	mock.ExpectExec(query).WithArgs("admin@example.com", "hash").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query).WithArgs("admin@example.com", "hash").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	created, err := repo.CreateFirstAdmin("admin@example.com", "hash")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = repo.CreateFirstAdmin("admin@example.com", "hash")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvitationWhere(t *testing.T) {
+	now := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	where, args := invitationWhere(map[string]interface{}{"email": "a@b.c", "status": model.InvitationExpired}, now)
+	assert.Equal(t, "1=1 AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", where)
+	assert.Equal(t, []interface{}{"a@b.c", now}, args)
+
+	where, args = invitationWhere(map[string]interface{}{"status": model.InvitationAccepted}, now)
+	assert.Equal(t, "1=1 AND accepted_at IS NOT NULL", where)
+	assert.Empty(t, args)
+}
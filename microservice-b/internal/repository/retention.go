@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// retentionBatch caps the rows archived or purged per statement to keep locks short
+const retentionBatch = 5000
+
+type RetentionRepository struct {
+	DB *sqlx.DB
+}
+
+func NewRetentionRepository(db *sqlx.DB) *RetentionRepository {
+	return &RetentionRepository{DB: db}
+}
+
+// List returns all retention policies, oldest first
+func (r *RetentionRepository) List() ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	err := r.DB.Select(&policies, "SELECT * FROM retention_policies ORDER BY id")
+	return policies, err
+}
+
+// Get returns a policy by id, or nil when it does not exist
+func (r *RetentionRepository) Get(id uint64) (*model.RetentionPolicy, error) {
+	var p model.RetentionPolicy
+	err := r.DB.Get(&p, "SELECT * FROM retention_policies WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create stores a new policy and returns it as stored
+func (r *RetentionRepository) Create(p *model.RetentionPolicy) (*model.RetentionPolicy, error) {
+	res, err := r.DB.Exec(`INSERT INTO retention_policies (name, sensor_type, id1, id2, archive_after_days, purge_after_days, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, p.Name, p.SensorType, p.ID1, p.ID2, p.ArchiveAfterDays, p.PurgeAfterDays, p.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(uint64(id))
+}
+
+// Delete removes a policy and reports whether it existed
+func (r *RetentionRepository) Delete(id uint64) (bool, error) {
+	res, err := r.DB.Exec("DELETE FROM retention_policies WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Archive marks the unarchived readings governed by p with timestamps before
+// before as archived at at. policies are all policies, for precedence. The
+// rollups keep archived readings, so they are not re-rolled.
+func (r *RetentionRepository) Archive(p model.RetentionPolicy, policies []model.RetentionPolicy, before, at time.Time) (int64, error) {
+	scope, args := policyScope(p, policies)
+	query := "UPDATE sensor_readings SET archived_at = ? WHERE archived_at IS NULL AND ts < ? AND " + scope + " LIMIT ?"
+	return r.execBatches(query, append(append([]interface{}{at, before}, args...), retentionBatch))
+}
+
+// Purge deletes the readings governed by p with timestamps before before. Like
+// archiving it leaves the rollups alone, which keep the aggregates of purged readings.
+func (r *RetentionRepository) Purge(p model.RetentionPolicy, policies []model.RetentionPolicy, before time.Time) (int64, error) {
+	scope, args := policyScope(p, policies)
+	query := "DELETE FROM sensor_readings WHERE ts < ? AND " + scope + " LIMIT ?"
+	return r.execBatches(query, append(append([]interface{}{before}, args...), retentionBatch))
+}
+
+// PurgeTrash deletes the readings trashed before before
+func (r *RetentionRepository) PurgeTrash(before time.Time) (int64, error) {
+	return r.execBatches("DELETE FROM sensor_readings WHERE delete_batch IS NOT NULL AND archived_at < ? LIMIT ?", []interface{}{before, retentionBatch})
+}
+
+// PurgeSequences deletes the sequence marks of producer sessions that last
+// stored a reading before before
+func (r *RetentionRepository) PurgeSequences(before time.Time) (int64, error) {
+	return r.execBatches("DELETE FROM sensor_sequences WHERE updated_at < ? LIMIT ?", []interface{}{before, retentionBatch})
+}
+
+// CountArchivable counts the readings Archive would mark
+func (r *RetentionRepository) CountArchivable(p model.RetentionPolicy, policies []model.RetentionPolicy, before time.Time) (int64, error) {
+	scope, args := policyScope(p, policies)
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM sensor_readings WHERE archived_at IS NULL AND ts < ? AND "+scope, append([]interface{}{before}, args...)...)
+	return n, err
+}
+
+// CountPurgeable counts the readings Purge would delete
+func (r *RetentionRepository) CountPurgeable(p model.RetentionPolicy, policies []model.RetentionPolicy, before time.Time) (int64, error) {
+	scope, args := policyScope(p, policies)
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM sensor_readings WHERE ts < ? AND "+scope, append([]interface{}{before}, args...)...)
+	return n, err
+}
+
+// execBatches repeats a LIMITed statement until it affects fewer rows than the limit
+func (r *RetentionRepository) execBatches(query string, args []interface{}) (int64, error) {
+	var total int64
+	for {
+		res, err := r.DB.Exec(query, args...)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < retentionBatch {
+			return total, nil
+		}
+	}
+}
+
+// specificity ranks how narrowly a policy selects readings
+func specificity(p model.RetentionPolicy) int {
+	n := 0
+	if p.ID1 != nil {
+		n += 4
+	}
+	if p.ID2 != nil {
+		n += 2
+	}
+	if p.SensorType != nil {
+		n++
+	}
+	return n
+}
+
+// precedes reports whether a wins over b for readings both match: the more
+// specific policy wins, then the older one. Unsaved policies (id 0) are newest.
+func precedes(a, b model.RetentionPolicy) bool {
+	if sa, sb := specificity(a), specificity(b); sa != sb {
+		return sa > sb
+	}
+	return a.ID != 0 && (b.ID == 0 || a.ID < b.ID)
+}
+
+// policyMatch returns the condition selecting the readings p names
+func policyMatch(p model.RetentionPolicy) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if p.SensorType != nil {
+		conds = append(conds, "sensor_type = ?")
+		args = append(args, *p.SensorType)
+	}
+	if p.ID1 != nil {
+		conds = append(conds, "id1 = ?")
+		args = append(args, *p.ID1)
+	}
+	if p.ID2 != nil {
+		conds = append(conds, "id2 = ?")
+		args = append(args, *p.ID2)
+	}
+	if len(conds) == 0 {
+		return "1=1", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// policyScope returns the condition selecting the readings p governs: the ones
+// it matches that no enabled policy taking precedence over it matches
+func policyScope(p model.RetentionPolicy, policies []model.RetentionPolicy) (string, []interface{}) {
+	cond, args := policyMatch(p)
+	scope := "(" + cond + ")"
+	for _, o := range policies {
+		if o.ID == p.ID || !o.Enabled || !precedes(o, p) {
+			continue
+		}
+		oc, oa := policyMatch(o)
+		scope += " AND NOT (" + oc + ")"
+		args = append(args, oa...)
+	}
+	return scope, args
+}
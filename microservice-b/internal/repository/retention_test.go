@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func str(s string) *string { return &s }
+func num(n int) *int       { return &n }
+
+func TestPolicyScope_Precedence(t *testing.T) {
+	all := model.RetentionPolicy{ID: 1, Name: "default", Enabled: true}
+	temperature := model.RetentionPolicy{ID: 2, Name: "temperature", SensorType: str("Temperature"), Enabled: true}
+	sensor := model.RetentionPolicy{ID: 3, Name: "E/1", ID1: str("E"), ID2: num(1), Enabled: true}
+	disabled := model.RetentionPolicy{ID: 4, Name: "off", ID1: str("A"), Enabled: false}
+	policies := []model.RetentionPolicy{all, temperature, sensor, disabled}
+
+	scope, args := policyScope(all, policies)
+	assert.Equal(t, "(1=1) AND NOT (sensor_type = ?) AND NOT (id1 = ? AND id2 = ?)", scope)
+	assert.Equal(t, []interface{}{"Temperature", "E", 1}, args)
+
+	scope, args = policyScope(temperature, policies)
+	assert.Equal(t, "(sensor_type = ?) AND NOT (id1 = ? AND id2 = ?)", scope)
+	assert.Equal(t, []interface{}{"Temperature", "E", 1}, args)
+
+	scope, _ = policyScope(sensor, policies)
+	assert.Equal(t, "(id1 = ? AND id2 = ?)", scope)
+
+	// an unsaved candidate loses ties against stored policies
+	candidate := model.RetentionPolicy{Name: "new default", Enabled: true}
+	scope, _ = policyScope(candidate, append(policies, candidate))
+	assert.Contains(t, scope, "AND NOT (1=1)")
+	scope, _ = policyScope(all, append(policies, candidate))
+	assert.NotContains(t, scope, "NOT (1=1)")
+}
+
+func TestRetentionRepository_ArchiveInBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRetentionRepository(sqlx.NewDb(db, "mysql"))
+	p := model.RetentionPolicy{ID: 1, SensorType: str("Light"), Enabled: true}
+	before := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	at := before.Add(30 * 24 * time.Hour)
+
+	query := "UPDATE sensor_readings SET archived_at = \\? WHERE archived_at IS NULL AND ts < \\? AND \\(sensor_type = \\?\\) LIMIT \\?"
+	mock.ExpectExec(query).
+		WithArgs(at, before, "Light", retentionBatch).
+		WillReturnResult(sqlmock.NewResult(0, retentionBatch))
+	mock.ExpectExec(query).
+		WithArgs(at, before, "Light", retentionBatch).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	n, err := repo.Archive(p, []model.RetentionPolicy{p}, before, at)
+	require.NoError(t, err)
+	assert.Equal(t, int64(retentionBatch+12), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
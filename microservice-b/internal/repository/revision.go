@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"microservice-b/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrChangeSetNotFound = errors.New("change set not found")
+	ErrNotRevertible     = errors.New("change set cannot be reverted")
+)
+
+// change is a modification of the sensor readings matched by where
+type change struct {
+	query     string // statement performing the change
+	args      []interface{}
+	where     string // condition selecting the readings the statement changes
+	whereArgs []interface{}
+
+	changeSet string
+	action    string
+	oldValue  string // SQL for the values recorded in the revisions, evaluated before the change
+	newValue  string
+	valueArgs []interface{} // arguments of oldValue and newValue, in that order
+	info      model.ChangeInfo
+}
+
+// applyChange records a revision for every affected reading, queues their spans
+// for re-rollup and runs the change, all in one transaction
+func (r *SensorRepository) applyChange(c change) (int64, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := markRollupsDirty(tx, c.where, c.whereArgs); err != nil {
+		return 0, err
+	}
+	if err := recordRevisions(tx, c); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(c.query, c.args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// recordRevisions stores the revisions of a change; it runs before the change so
+// the old values can still be read
+func recordRevisions(tx *sqlx.Tx, c change) error {
+	var userID interface{}
+	if c.info.UserID != 0 {
+		userID = c.info.UserID
+	}
+	query := "INSERT INTO sensor_reading_revisions (change_set, reading_id, action, old_value, new_value, user_id, reason) " +
+		"SELECT ?, id, ?, " + c.oldValue + ", " + c.newValue + ", ?, ? FROM sensor_readings WHERE " + c.where
+	args := append([]interface{}{c.changeSet, c.action}, c.valueArgs...)
+	args = append(args, userID, c.info.Reason)
+	_, err := tx.Exec(query, append(args, c.whereArgs...)...)
+	return err
+}
+
+// History returns the revisions of a reading, oldest first
+func (r *SensorRepository) History(readingID uint64) ([]model.Revision, error) {
+	var revisions []model.Revision
+	err := r.DB.Select(&revisions, "SELECT * FROM sensor_reading_revisions WHERE reading_id = ? ORDER BY id", readingID)
+	return revisions, err
+}
+
+// RevertChangeSet undoes a change set in a new one. Edits and reverts are undone by
+// restoring the old value of every reading that still holds the value the change
+// set gave it; deletes by restoring the batch from the trash. Readings changed,
+// restored or purged since then are counted as conflicts and left alone.
+func (r *SensorRepository) RevertChangeSet(changeSet string, info model.ChangeInfo) (model.RevertResult, error) {
+	var res model.RevertResult
+	var sets []struct {
+		Action string `db:"action"`
+		Count  int64  `db:"count"`
+	}
+	if err := r.DB.Select(&sets, "SELECT action, COUNT(*) AS count FROM sensor_reading_revisions WHERE change_set = ? GROUP BY action", changeSet); err != nil {
+		return res, err
+	}
+	if len(sets) == 0 {
+		return res, ErrChangeSetNotFound
+	}
+	if len(sets) > 1 {
+		return res, ErrNotRevertible
+	}
+
+	newSet, err := newBatchID()
+	if err != nil {
+		return res, err
+	}
+	c := change{changeSet: newSet, info: info}
+	switch sets[0].Action {
+	case model.RevisionUpdate, model.RevisionRevert:
+		c.where = "archived_at IS NULL AND EXISTS (SELECT 1 FROM sensor_reading_revisions v " +
+			"WHERE v.reading_id = sensor_readings.id AND v.change_set = ? AND v.new_value = sensor_readings.value)"
+		c.whereArgs = []interface{}{changeSet}
+		previous := "(SELECT v.old_value FROM sensor_reading_revisions v WHERE v.reading_id = sensor_readings.id AND v.change_set = ? LIMIT 1)"
+		c.query = "UPDATE sensor_readings SET value = " + previous + " WHERE " + c.where
+		c.args = []interface{}{changeSet, changeSet}
+		c.action = model.RevisionRevert
+		c.oldValue, c.newValue = "value", previous
+		c.valueArgs = []interface{}{changeSet}
+	case model.RevisionDelete:
+		c.where = "delete_batch = ?"
+		c.whereArgs = []interface{}{changeSet}
+		c.query = "UPDATE sensor_readings SET archived_at = NULL, delete_batch = NULL WHERE " + c.where
+		c.args = c.whereArgs
+		c.action = model.RevisionRestore
+		c.oldValue, c.newValue = "NULL", "value"
+	default:
+		return res, ErrNotRevertible
+	}
+
+	n, err := r.applyChange(c)
+	if err != nil {
+		return res, err
+	}
+	return model.RevertResult{ChangeSet: newSet, Reverted: n, Conflicts: sets[0].Count - n}, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertChangeSet_Edit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectQuery("SELECT action, COUNT\\(\\*\\) AS count FROM sensor_reading_revisions WHERE change_set = \\? GROUP BY action").
+		WithArgs("set-1").
+		WillReturnRows(sqlmock.NewRows([]string{"action", "count"}).AddRow(model.RevisionUpdate, 5))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty .+ WHERE archived_at IS NULL AND EXISTS .+ v.new_value = sensor_readings.value\\) GROUP BY id1, id2").
+		WithArgs("set-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions .+ SELECT \\?, id, \\?, value, \\(SELECT v.old_value .+\\), \\?, \\? FROM sensor_readings WHERE archived_at IS NULL AND EXISTS").
+		WithArgs(sqlmock.AnyArg(), model.RevisionRevert, "set-1", uint64(3), "wrong offset", "set-1").
+		WillReturnResult(sqlmock.NewResult(1, 4))
+	mock.ExpectExec("UPDATE sensor_readings SET value = \\(SELECT v.old_value .+\\) WHERE archived_at IS NULL AND EXISTS").
+		WithArgs("set-1", "set-1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	res, err := repo.RevertChangeSet("set-1", model.ChangeInfo{UserID: 3, Reason: "wrong offset"})
+
+	require.NoError(t, err)
+	assert.Len(t, res.ChangeSet, 32)
+	assert.Equal(t, int64(4), res.Reverted)
+	assert.Equal(t, int64(1), res.Conflicts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevertChangeSet_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectQuery("SELECT action, COUNT").
+		WithArgs("batch-1").
+		WillReturnRows(sqlmock.NewRows([]string{"action", "count"}).AddRow(model.RevisionDelete, 3))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty .+ WHERE delete_batch = \\? GROUP BY id1, id2").
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions .+ SELECT \\?, id, \\?, NULL, value, \\?, \\? FROM sensor_readings WHERE delete_batch = \\?").
+		WithArgs(sqlmock.AnyArg(), model.RevisionRestore, nil, "", "batch-1").
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectExec("UPDATE sensor_readings SET archived_at = NULL, delete_batch = NULL WHERE delete_batch = \\?").
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	res, err := repo.RevertChangeSet("batch-1", model.ChangeInfo{})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), res.Reverted)
+	assert.Equal(t, int64(0), res.Conflicts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevertChangeSet_Errors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectQuery("SELECT action, COUNT").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"action", "count"}))
+	_, err = repo.RevertChangeSet("missing", model.ChangeInfo{})
+	assert.ErrorIs(t, err, ErrChangeSetNotFound)
+
+	// a restore is undone by deleting again, not by reverting
+	mock.ExpectQuery("SELECT action, COUNT").
+		WithArgs("restored").
+		WillReturnRows(sqlmock.NewRows([]string{"action", "count"}).AddRow(model.RevisionRestore, 2))
+	_, err = repo.RevertChangeSet("restored", model.ChangeInfo{})
+	assert.ErrorIs(t, err, ErrNotRevertible)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_History(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	at := time.Date(2025, 9, 6, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT \\* FROM sensor_reading_revisions WHERE reading_id = \\? ORDER BY id").
+		WithArgs(uint64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "change_set", "reading_id", "action", "old_value", "new_value", "user_id", "reason", "created_at"}).
+			AddRow(1, "set-1", 42, model.RevisionUpdate, 20.5, 30.0, 7, "recalibrated", at).
+			AddRow(2, "set-2", 42, model.RevisionDelete, 30.0, nil, nil, "", at))
+
+	revisions, err := repo.History(42)
+
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 20.5, *revisions[0].OldValue)
+	assert.Equal(t, uint64(7), *revisions[0].UserID)
+	assert.Nil(t, revisions[1].NewValue)
+	assert.Nil(t, revisions[1].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
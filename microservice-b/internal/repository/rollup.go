@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"microservice-b/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Rollup is one materialized resolution of sensor_readings
+type Rollup struct {
+	Name  string
+	Size  time.Duration
+	Table string
+}
+
+// Rollups lists the rollup resolutions from finest to coarsest. The 1m rollup is
+// built from sensor_readings and every other one from its predecessor.
+var Rollups = []Rollup{
+	{Name: "1m", Size: time.Minute, Table: "sensor_rollups_1m"},
+	{Name: "1h", Size: time.Hour, Table: "sensor_rollups_1h"},
+	{Name: "1d", Size: 24 * time.Hour, Table: "sensor_rollups_1d"},
+}
+
+// LateScanState is the rollup_state entry holding when late readings were last scanned for
+const LateScanState = "late_scan"
+
+type RollupRepository struct {
+	DB *sqlx.DB
+}
+
+func NewRollupRepository(db *sqlx.DB) *RollupRepository {
+	return &RollupRepository{DB: db}
+}
+
+// States returns the rollup_state entries: the watermark of every rollup, below which
+// all buckets are materialized, and the late reading scan time
+func (r *RollupRepository) States() (map[string]time.Time, error) {
+	return rollupStates(r.DB)
+}
+
+func rollupStates(db *sqlx.DB) (map[string]time.Time, error) {
+	rows, err := db.Query("SELECT name, watermark FROM rollup_state")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var t time.Time
+		if err := rows.Scan(&name, &t); err != nil {
+			return nil, err
+		}
+		states[name] = t.UTC()
+	}
+	return states, rows.Err()
+}
+
+// SetState stores a rollup_state entry
+func (r *RollupRepository) SetState(name string, t time.Time) error {
+	_, err := r.DB.Exec(`INSERT INTO rollup_state (name, watermark) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE watermark = VALUES(watermark)`, name, t)
+	return err
+}
+
+// OldestReading returns the timestamp of the oldest stored reading
+func (r *RollupRepository) OldestReading() (time.Time, bool, error) {
+	var ts sql.NullTime
+	if err := r.DB.Get(&ts, "SELECT MIN(ts) FROM sensor_readings"); err != nil {
+		return time.Time{}, false, err
+	}
+	return ts.Time.UTC(), ts.Valid, nil
+}
+
+// Rebuild recomputes the buckets of rollup level in [from, to) from its source,
+// optionally for a single sensor. Buckets left without data are removed. Trashed
+// readings are left out; archived ones are kept, so the rollups hold the history
+// of readings that retention policies archive or purge.
+func (r *RollupRepository) Rebuild(level int, from, to time.Time, id1, id2 *string) error {
+	rollup := Rollups[level]
+	seconds := int64(rollup.Size / time.Second)
+
+	source := "SELECT id1, id2, MAX(sensor_type), FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(ts) / ?) * ?) AS b, " +
+		"MIN(value), MAX(value), SUM(value), COUNT(*) FROM sensor_readings WHERE ts >= ? AND ts < ? AND delete_batch IS NULL"
+	if level > 0 {
+		source = "SELECT id1, id2, MAX(sensor_type), FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(bucket) / ?) * ?) AS b, " +
+			"MIN(min_value), MAX(max_value), SUM(sum_value), SUM(cnt) FROM " + Rollups[level-1].Table + " WHERE bucket >= ? AND bucket < ?"
+	}
+	sourceArgs := []interface{}{seconds, seconds, from, to}
+
+	del := "DELETE FROM " + rollup.Table + " WHERE bucket >= ? AND bucket < ?"
+	delArgs := []interface{}{from, to}
+	if id1 != nil {
+		source += " AND id1 = ?"
+		sourceArgs = append(sourceArgs, *id1)
+		del += " AND id1 = ?"
+		delArgs = append(delArgs, *id1)
+	}
+	if id2 != nil {
+		source += " AND id2 = ?"
+		sourceArgs = append(sourceArgs, *id2)
+		del += " AND id2 = ?"
+		delArgs = append(delArgs, *id2)
+	}
+	source += " GROUP BY id1, id2, b"
+
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(del, delArgs...); err != nil {
+		return fmt.Errorf("clear %s rollup: %w", rollup.Name, err)
+	}
+	insert := "INSERT INTO " + rollup.Table + " (id1, id2, sensor_type, bucket, min_value, max_value, sum_value, cnt) " + source
+	if _, err := tx.Exec(insert, sourceArgs...); err != nil {
+		return fmt.Errorf("build %s rollup: %w", rollup.Name, err)
+	}
+	return tx.Commit()
+}
+
+// LateRanges returns, per sensor, the span of readings stored since since whose
+// timestamps fall before before, i.e. into buckets that were already rolled up
+func (r *RollupRepository) LateRanges(since, before time.Time) ([]model.RollupRange, error) {
+	var ranges []model.RollupRange
+	err := r.DB.Select(&ranges, `SELECT id1, CAST(id2 AS CHAR) AS id2, MIN(ts) AS from_ts, MAX(ts) AS to_ts
+		FROM sensor_readings WHERE created_at >= ? AND ts < ? GROUP BY id1, id2`, since, before)
+	return ranges, err
+}
+
+// DirtyRanges returns the ranges queued by edits and deletes, oldest first
+func (r *RollupRepository) DirtyRanges() ([]model.RollupRange, error) {
+	var ranges []model.RollupRange
+	err := r.DB.Select(&ranges, "SELECT id, id1, CAST(id2 AS CHAR) AS id2, from_ts, to_ts FROM rollup_dirty ORDER BY id")
+	return ranges, err
+}
+
+// ClearDirty removes the queued ranges up to and including id
+func (r *RollupRepository) ClearDirty(id uint64) error {
+	_, err := r.DB.Exec("DELETE FROM rollup_dirty WHERE id <= ?", id)
+	return err
+}
+
+// markRollupsDirty queues the time span of the readings matched by where, per
+// sensor, for re-rollup. It runs before the statement that modifies them, so
+// spans stay bounded by data that still exists and rollups of purged readings
+// are left alone.
+func markRollupsDirty(tx *sqlx.Tx, where string, args []interface{}) error {
+	_, err := tx.Exec(`INSERT INTO rollup_dirty (id1, id2, from_ts, to_ts)
+		SELECT id1, id2, MIN(ts), MAX(ts) FROM sensor_readings WHERE `+where+` GROUP BY id1, id2`, args...)
+	return err
+}
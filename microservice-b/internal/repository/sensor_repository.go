@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"microservice-b/model"
+	"strings"
 	"time"
 
 	pb "microservice-b/pb/shared-proto"
@@ -35,6 +38,52 @@ func (r *SensorRepository) Save(data *pb.SensorData) error {
 	return nil
 }
 
+// insertChunkSize caps rows per INSERT statement to stay well below
+// MySQL's placeholder limit and max_allowed_packet
+const insertChunkSize = 500
+
+// SaveBatch inserts readings with multi-row INSERTs in a single transaction,
+// so either the whole batch is stored or none of it. Sequenced readings whose
+// sequence number was already accepted for their sensor are dropped.
+func (r *SensorRepository) SaveBatch(readings []*pb.SensorData) (model.IngestResult, error) {
+	var res model.IngestResult
+	if len(readings) == 0 {
+		return res, nil
+	}
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	readings, res, err = dedupe(tx, readings)
+	if err != nil {
+		return res, err
+	}
+
+	for start := 0; start < len(readings); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(readings))
+		chunk := readings[start:end]
+
+		query := "INSERT INTO sensor_readings(value, sensor_type, id1, id2, ts) VALUES " +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?),", len(chunk)), ",")
+		args := make([]interface{}, 0, len(chunk)*5)
+		for _, data := range chunk {
+			args = append(args, data.Value, data.SensorType, data.Id1, data.Id2, data.Timestamp.AsTime())
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			log.Printf("Failed to insert sensor batch: %v", err)
+			return res, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return res, err
+	}
+	res.Saved = int64(len(readings))
+	res.Stored = readings
+	return res, nil
+}
+
 // GetSensors with optional filters and pagination
 func (r *SensorRepository) GetSensors(filters map[string]interface{}, limit, offset int) ([]model.SensorReading, error) {
 	query := "SELECT * FROM sensor_readings WHERE 1=1"
@@ -42,7 +91,7 @@ func (r *SensorRepository) GetSensors(filters map[string]interface{}, limit, off
 
 	for k, v := range filters {
 		switch k {
-		case "id1", "id2":
+		case "id1", "id2", "sensor_type":
 			query += fmt.Sprintf(" AND %s = ?", k)
 			args = append(args, v)
 		case "from":
@@ -58,6 +107,7 @@ func (r *SensorRepository) GetSensors(filters map[string]interface{}, limit, off
 		}
 	}
 
+	query += archivedFilter(filters)
 	query += " ORDER BY ts DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
@@ -72,7 +122,7 @@ func (r *SensorRepository) CountSensors(filters map[string]interface{}) (int64,
 
 	for k, v := range filters {
 		switch k {
-		case "id1", "id2":
+		case "id1", "id2", "sensor_type":
 			query += fmt.Sprintf(" AND %s = ?", k)
 			args = append(args, v)
 		case "from":
@@ -88,63 +138,226 @@ func (r *SensorRepository) CountSensors(filters map[string]interface{}) (int64,
 		}
 	}
 
+	query += archivedFilter(filters)
+
 	var total int64
 	err := r.DB.Get(&total, query, args...)
 	return total, err
 }
 
-// DeleteSensors based on filters
-func (r *SensorRepository) DeleteSensors(filters map[string]interface{}) (int64, error) {
-	query := "DELETE FROM sensor_readings WHERE 1=1"
+// GetReadingsAfter returns up to limit readings after the position (ts, id),
+// oldest first, to page through readings in the order they were taken. Filters
+// are id1, id2 and sensor_type; archived and trashed readings are skipped.
+func (r *SensorRepository) GetReadingsAfter(filters map[string]interface{}, ts time.Time, id uint64, limit int) ([]model.SensorReading, error) {
+	query := "SELECT * FROM sensor_readings WHERE (ts > ? OR (ts = ? AND id > ?))"
+	args := []interface{}{ts, ts, id}
+	for _, k := range []string{"id1", "id2", "sensor_type"} {
+		if v, ok := filters[k]; ok {
+			query += fmt.Sprintf(" AND %s = ?", k)
+			args = append(args, v)
+		}
+	}
+	query += " AND archived_at IS NULL ORDER BY ts, id LIMIT ?"
+	args = append(args, limit)
+
+	var readings []model.SensorReading
+	err := r.DB.Select(&readings, query, args...)
+	return readings, err
+}
+
+// GetLatest returns the latest reading of every sensor matched by the id1, id2
+// and sensor_type filters; archived and trashed readings are skipped
+func (r *SensorRepository) GetLatest(filters map[string]interface{}) ([]model.SensorReading, error) {
+	inner := "SELECT *, ROW_NUMBER() OVER (PARTITION BY id1, id2 ORDER BY ts DESC, id DESC) AS rn FROM sensor_readings WHERE archived_at IS NULL"
+	args := []interface{}{}
+	for _, k := range []string{"id1", "id2", "sensor_type"} {
+		if v, ok := filters[k]; ok {
+			inner += fmt.Sprintf(" AND %s = ?", k)
+			args = append(args, v)
+		}
+	}
+	query := "SELECT id, id1, id2, sensor_type, value, ts, created_at, updated_at, archived_at, delete_batch FROM (" + inner + ") r WHERE rn = 1 ORDER BY id1, id2"
+
+	var readings []model.SensorReading
+	err := r.DB.Select(&readings, query, args...)
+	return readings, err
+}
+
+// DeleteSensors moves the readings matched by filters to the trash under a new
+// delete batch, from which they can be restored until the trash is purged.
+// Every deleted reading gets a revision in the change set named after the batch
+// and the deleted range is queued for re-rollup.
+func (r *SensorRepository) DeleteSensors(filters map[string]interface{}, info model.ChangeInfo) (string, int64, error) {
+	where := "1=1"
 	args := []interface{}{}
 
 	for k, v := range filters {
 		switch k {
 		case "id1", "id2":
-			query += fmt.Sprintf(" AND %s = ?", k)
+			where += fmt.Sprintf(" AND %s = ?", k)
 			args = append(args, v)
 		case "from":
-			query += " AND ts >= ?"
+			where += " AND ts >= ?"
 			args = append(args, v)
 		case "to":
-			query += " AND ts <= ?"
+			where += " AND ts <= ?"
 			args = append(args, v)
 		}
 	}
+	where += " AND archived_at IS NULL"
+
+	batchID, err := newBatchID()
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := r.applyChange(change{
+		query:     "UPDATE sensor_readings SET archived_at = ?, delete_batch = ? WHERE " + where,
+		args:      append([]interface{}{time.Now().UTC(), batchID}, args...),
+		where:     where,
+		whereArgs: args,
+		changeSet: batchID,
+		action:    model.RevisionDelete,
+		oldValue:  "value",
+		newValue:  "NULL",
+		info:      info,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return batchID, n, nil
+}
+
+// GetTrash lists trashed readings, most recently deleted first
+func (r *SensorRepository) GetTrash(filters map[string]interface{}, limit, offset int) ([]model.SensorReading, error) {
+	where, args := trashWhere(filters)
+	query := "SELECT * FROM sensor_readings WHERE " + where + " ORDER BY archived_at DESC, ts DESC LIMIT ? OFFSET ?"
+
+	var sensors []model.SensorReading
+	err := r.DB.Select(&sensors, query, append(args, limit, offset)...)
+	return sensors, err
+}
+
+// CountTrash counts trashed readings
+func (r *SensorRepository) CountTrash(filters map[string]interface{}) (int64, error) {
+	where, args := trashWhere(filters)
 
-	res, err := r.DB.Exec(query, args...)
+	var total int64
+	err := r.DB.Get(&total, "SELECT COUNT(*) FROM sensor_readings WHERE "+where, args...)
+	return total, err
+}
+
+// RestoreSensors takes the trashed readings matched by filters, which may include
+// a batch_id, out of the trash, recording the restore in a new change set. The
+// restored range is queued for re-rollup.
+func (r *SensorRepository) RestoreSensors(filters map[string]interface{}, info model.ChangeInfo) (string, int64, error) {
+	where, args := trashWhere(filters)
+	changeSet, err := newBatchID()
 	if err != nil {
-		return 0, err
+		return "", 0, err
 	}
-	return res.RowsAffected()
+	n, err := r.applyChange(change{
+		query:     "UPDATE sensor_readings SET archived_at = NULL, delete_batch = NULL WHERE " + where,
+		args:      args,
+		where:     where,
+		whereArgs: args,
+		changeSet: changeSet,
+		action:    model.RevisionRestore,
+		oldValue:  "NULL",
+		newValue:  "value",
+		info:      info,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return changeSet, n, nil
 }
 
-// EditSensors update value based on filters
-func (r *SensorRepository) EditSensors(filters map[string]interface{}, newValue float64) (int64, error) {
-	query := "UPDATE sensor_readings SET value = ? WHERE 1=1"
-	args := []interface{}{newValue}
+// trashWhere builds the condition selecting trashed readings by batch_id, id1, id2, from and to
+func trashWhere(filters map[string]interface{}) (string, []interface{}) {
+	where := "delete_batch IS NOT NULL"
+	args := []interface{}{}
+	if v, ok := filters["batch_id"]; ok {
+		where += " AND delete_batch = ?"
+		args = append(args, v)
+	}
+	for _, k := range []string{"id1", "id2"} {
+		if v, ok := filters[k]; ok {
+			where += fmt.Sprintf(" AND %s = ?", k)
+			args = append(args, v)
+		}
+	}
+	if v, ok := filters["from"]; ok {
+		where += " AND ts >= ?"
+		args = append(args, v)
+	}
+	if v, ok := filters["to"]; ok {
+		where += " AND ts <= ?"
+		args = append(args, v)
+	}
+	return where, args
+}
+
+// newBatchID returns a random id for a delete batch or change set
+func newBatchID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// EditSensors update value based on filters. The prior value of every edited reading
+// is recorded in a new change set and the edited range is queued for re-rollup.
+func (r *SensorRepository) EditSensors(filters map[string]interface{}, newValue float64, info model.ChangeInfo) (string, int64, error) {
+	where := "1=1"
+	args := []interface{}{}
 
 	for k, v := range filters {
 		switch k {
 		case "id1", "id2":
-			query += fmt.Sprintf(" AND %s = ?", k)
+			where += fmt.Sprintf(" AND %s = ?", k)
 			args = append(args, v)
 		case "from":
-			query += " AND ts >= ?"
+			where += " AND ts >= ?"
 			if t, ok := v.(time.Time); ok {
 				args = append(args, t)
 			}
 		case "to":
-			query += " AND ts <= ?"
+			where += " AND ts <= ?"
 			if t, ok := v.(time.Time); ok {
 				args = append(args, t)
 			}
 		}
 	}
+	where += archivedFilter(filters)
 
-	res, err := r.DB.Exec(query, args...)
+	changeSet, err := newBatchID()
 	if err != nil {
-		return 0, err
+		return "", 0, err
+	}
+	n, err := r.applyChange(change{
+		query:     "UPDATE sensor_readings SET value = ? WHERE " + where,
+		args:      append([]interface{}{newValue}, args...),
+		where:     where,
+		whereArgs: args,
+		changeSet: changeSet,
+		action:    model.RevisionUpdate,
+		oldValue:  "value",
+		newValue:  "?",
+		valueArgs: []interface{}{newValue},
+		info:      info,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return changeSet, n, nil
+}
+
+// archivedFilter hides archived readings unless filters["include_archived"] is true.
+// Trashed readings are always hidden.
+func archivedFilter(filters map[string]interface{}) string {
+	if include, _ := filters["include_archived"].(bool); include {
+		return " AND delete_batch IS NULL"
 	}
-	return res.RowsAffected()
+	return " AND archived_at IS NULL"
 }
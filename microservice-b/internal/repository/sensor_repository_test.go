@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"microservice-b/model"
 	pb "microservice-b/pb/shared-proto"
 	"testing"
 	"time"
@@ -42,6 +43,159 @@ func TestSensorRepository_Save(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestSensorRepository_SaveBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	readings := make([]*pb.SensorData, insertChunkSize+2)
+	for i := range readings {
+		readings[i] = &pb.SensorData{Value: float64(i), SensorType: "Temperature", Id1: "A", Id2: "1", Timestamp: timestamppb.Now()}
+	}
+
+	// one multi-row insert per chunk, all in one transaction
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO sensor_readings").
+		WillReturnResult(sqlmock.NewResult(1, insertChunkSize))
+	mock.ExpectExec(`INSERT INTO sensor_readings\(value, sensor_type, id1, id2, ts\) VALUES \(\?, \?, \?, \?, \?\),\(\?, \?, \?, \?, \?\)$`).
+		WithArgs(float64(insertChunkSize), "Temperature", "A", "1", sqlmock.AnyArg(),
+			float64(insertChunkSize+1), "Temperature", "A", "1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	res, err := repo.SaveBatch(readings)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(readings)), res.Saved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_SaveBatch_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO sensor_readings").
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	_, err = repo.SaveBatch([]*pb.SensorData{{Value: 1, SensorType: "Temperature", Id1: "A", Id2: "1", Timestamp: timestamppb.Now()}})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_SaveBatch_DropsDuplicateSequences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+
+	reading := func(seq uint64) *pb.SensorData {
+		return &pb.SensorData{Value: float64(seq), SensorType: "Temperature", Id1: "A", Id2: "1", Timestamp: timestamppb.Now(), Seq: seq, ProducerId: "p1"}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id1, id2, producer_id, last_seq FROM sensor_sequences WHERE id1 = \\? AND id2 = \\? AND producer_id = \\? FOR UPDATE").
+		WithArgs("A", "1", "p1").
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "producer_id", "last_seq"}).AddRow("A", "1", "p1", 5))
+	mock.ExpectExec("INSERT INTO sensor_sequences").
+		WithArgs("A", "1", "p1", uint64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// 4 and 5 were already stored, 7 and 8 are missing
+	mock.ExpectExec("INSERT INTO sensor_readings").
+		WithArgs(6.0, "Temperature", "A", "1", sqlmock.AnyArg(), 9.0, "Temperature", "A", "1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	res, err := repo.SaveBatch([]*pb.SensorData{reading(4), reading(5), reading(6), reading(9)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), res.Saved)
+	assert.Equal(t, int64(2), res.Duplicates)
+	assert.Equal(t, int64(2), res.Gaps)
+	require.Len(t, res.Stored, 2)
+	assert.Equal(t, uint64(6), res.Stored[0].Seq)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_SaveBatch_DiscardsLateGapFills(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	reading := func(seq uint64) *pb.SensorData {
+		return &pb.SensorData{Value: float64(seq), SensorType: "Temperature", Id1: "A", Id2: "1", Timestamp: timestamppb.Now(), Seq: seq, ProducerId: "p1"}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id1, id2, producer_id, last_seq FROM sensor_sequences").
+		WithArgs("A", "1", "p1").
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "producer_id", "last_seq"}).AddRow("A", "1", "p1", 5))
+	mock.ExpectExec("INSERT INTO sensor_sequences").
+		WithArgs("A", "1", "p1", uint64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// 7 arrives after 8 and is dropped although it fills the gap 8 left
+	mock.ExpectExec("INSERT INTO sensor_readings").
+		WithArgs(8.0, "Temperature", "A", "1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	res, err := repo.SaveBatch([]*pb.SensorData{reading(8), reading(7)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.Saved)
+	assert.Equal(t, int64(1), res.Duplicates)
+	assert.Equal(t, int64(2), res.Gaps)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_SaveBatch_ProducerSessionsKeepTheirOwnSequence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	reading := func(producer string, seq uint64) *pb.SensorData {
+		return &pb.SensorData{Value: float64(seq), SensorType: "Temperature", Id1: "A", Id2: "1", Timestamp: timestamppb.Now(), Seq: seq, ProducerId: producer}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id1, id2, producer_id, last_seq FROM sensor_sequences").
+		WithArgs("A", "1", "old").
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "producer_id", "last_seq"}).AddRow("A", "1", "old", 500))
+	mock.ExpectQuery("SELECT id1, id2, producer_id, last_seq FROM sensor_sequences").
+		WithArgs("A", "1", "new").
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "producer_id", "last_seq"}))
+	mock.ExpectExec("INSERT INTO sensor_sequences").
+		WithArgs("A", "1", "old", uint64(501)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO sensor_sequences").
+		WithArgs("A", "1", "new", uint64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// the resent 500 of the old session is a duplicate although the new
+	// session came in between, and nothing is missing
+	mock.ExpectExec("INSERT INTO sensor_readings").
+		WithArgs(1.0, "Temperature", "A", "1", sqlmock.AnyArg(), 501.0, "Temperature", "A", "1", sqlmock.AnyArg(), 2.0, "Temperature", "A", "1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectCommit()
+
+	res, err := repo.SaveBatch([]*pb.SensorData{reading("old", 500), reading("new", 1), reading("old", 501), reading("new", 2)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), res.Saved)
+	assert.Equal(t, int64(1), res.Duplicates)
+	assert.Zero(t, res.Gaps)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestSensorRepository_GetSensors(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -115,6 +269,48 @@ func TestSensorRepository_CountSensors(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestSensorRepository_GetReadingsAfter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	ts := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM sensor_readings WHERE \(ts > \? OR \(ts = \? AND id > \?\)\) AND id1 = \? AND sensor_type = \? AND archived_at IS NULL ORDER BY ts, id LIMIT \?`).
+		WithArgs(ts, ts, uint64(7), "A", "Humidity", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "ts", "value"}).AddRow(8, "A", 1, ts, 42.5))
+
+	readings, err := repo.GetReadingsAfter(map[string]interface{}{"id1": "A", "sensor_type": "Humidity"}, ts, 7, 100)
+
+	require.NoError(t, err)
+	require.Len(t, readings, 1)
+	assert.Equal(t, uint64(8), readings[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_GetLatest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	ts := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, id1, id2, sensor_type, value, ts, created_at, updated_at, archived_at, delete_batch FROM \(SELECT \*, ROW_NUMBER\(\) OVER \(PARTITION BY id1, id2 ORDER BY ts DESC, id DESC\) AS rn FROM sensor_readings WHERE archived_at IS NULL AND id1 = \? AND sensor_type = \?\) r WHERE rn = 1 ORDER BY id1, id2`).
+		WithArgs("A", "Humidity").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "sensor_type", "ts", "value"}).
+			AddRow(8, "A", 1, "Humidity", ts, 42.5).
+			AddRow(9, "A", 2, "Humidity", ts, 40.1))
+
+	readings, err := repo.GetLatest(map[string]interface{}{"id1": "A", "sensor_type": "Humidity"})
+
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	assert.Equal(t, 2, readings[1].ID2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestSensorRepository_DeleteSensors(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -129,16 +325,53 @@ func TestSensorRepository_DeleteSensors(t *testing.T) {
 	}
 
 	// Mock expectations
-	mock.ExpectExec("DELETE FROM sensor_readings").
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty .+ FROM sensor_readings WHERE 1=1 AND id1 = \\? AND archived_at IS NULL GROUP BY id1, id2").
 		WithArgs("A").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions .+ SELECT \\?, id, \\?, value, NULL, \\?, \\? FROM sensor_readings WHERE 1=1 AND id1 = \\? AND archived_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), model.RevisionDelete, uint64(7), "cleanup", "A").
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectExec("UPDATE sensor_readings SET archived_at = \\?, delete_batch = \\? WHERE 1=1 AND id1 = \\? AND archived_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "A").
 		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
 
 	// Execute
-	deleted, err := repo.DeleteSensors(filters)
+	batchID, deleted, err := repo.DeleteSensors(filters, model.ChangeInfo{UserID: 7, Reason: "cleanup"})
 
 	// Assertions
 	assert.NoError(t, err)
 	assert.Equal(t, int64(3), deleted)
+	assert.Len(t, batchID, 32)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSensorRepository_RestoreSensors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewSensorRepository(sqlx.NewDb(db, "mysql"))
+	where := "delete_batch IS NOT NULL AND delete_batch = \\? AND id1 = \\?"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty .+ WHERE "+where+" GROUP BY id1, id2").
+		WithArgs("batch-1", "A").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions .+ SELECT \\?, id, \\?, NULL, value, \\?, \\? FROM sensor_readings WHERE "+where).
+		WithArgs(sqlmock.AnyArg(), model.RevisionRestore, nil, "", "batch-1", "A").
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectExec("UPDATE sensor_readings SET archived_at = NULL, delete_batch = NULL WHERE "+where).
+		WithArgs("batch-1", "A").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	changeSet, restored, err := repo.RestoreSensors(map[string]interface{}{"batch_id": "batch-1", "id1": "A"}, model.ChangeInfo{})
+
+	assert.NoError(t, err)
+	assert.Len(t, changeSet, 32)
+	assert.Equal(t, int64(3), restored)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -157,15 +390,24 @@ func TestSensorRepository_EditSensors(t *testing.T) {
 	newValue := 30.0
 
 	// Mock expectations
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rollup_dirty .+ FROM sensor_readings WHERE 1=1 AND id1 = \\? AND archived_at IS NULL GROUP BY id1, id2").
+		WithArgs("A").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sensor_reading_revisions .+ SELECT \\?, id, \\?, value, \\?, \\?, \\? FROM sensor_readings WHERE 1=1 AND id1 = \\? AND archived_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), model.RevisionUpdate, 30.0, uint64(7), "recalibrated", "A").
+		WillReturnResult(sqlmock.NewResult(1, 2))
 	mock.ExpectExec("UPDATE sensor_readings SET value").
 		WithArgs(30.0, "A").
 		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
 
 	// Execute
-	updated, err := repo.EditSensors(filters, newValue)
+	changeSet, updated, err := repo.EditSensors(filters, newValue, model.ChangeInfo{UserID: 7, Reason: "recalibrated"})
 
 	// Assertions
 	assert.NoError(t, err)
+	assert.Len(t, changeSet, 32)
 	assert.Equal(t, int64(2), updated)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
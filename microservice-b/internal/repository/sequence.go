@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"microservice-b/model"
+
+	pb "microservice-b/pb/shared-proto"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dedupe drops sequenced readings that were already accepted and advances the
+// high-water mark of every sensor and producer session in the batch. Every
+// session has its own mark, so readings of an old session still spooled after
+// a restart are checked against that session only. The marks are row-locked
+// until the transaction ends so concurrent streams of the same session cannot
+// both accept a sequence number. Unsequenced readings (seq 0) always pass.
+//
+// A mark is a high-water mark, not a record of every sequence seen: a reading
+// below it is dropped as a duplicate even when it would fill a gap counted
+// earlier. Producers send each session in sequence order, so a reading only
+// arrives below the mark when it is resent; readings that went missing stay
+// missing. Marks of sessions idle for longer than the sequence TTL are purged
+// by the retention jobs.
+func dedupe(tx *sqlx.Tx, readings []*pb.SensorData) ([]*pb.SensorData, model.IngestResult, error) {
+	var res model.IngestResult
+	marks := map[string]*model.SensorSequence{}
+	var order []string
+
+	accepted := readings[:0:0]
+	for _, data := range readings {
+		if data.Seq == 0 {
+			accepted = append(accepted, data)
+			continue
+		}
+
+		key := data.Id1 + "/" + data.Id2
+		markKey := key + "/" + data.ProducerId
+		mark, ok := marks[markKey]
+		if !ok {
+			var err error
+			if mark, err = lockSequence(tx, data.Id1, data.Id2, data.ProducerId); err != nil {
+				return nil, res, err
+			}
+			marks[markKey] = mark
+			order = append(order, markKey)
+		}
+
+		if data.Seq <= mark.LastSeq {
+			res.Duplicates++
+			continue
+		}
+		if gap := data.Seq - mark.LastSeq - 1; gap > 0 {
+			log.Printf("Sensor %s: %d readings missing before seq %d", key, gap, data.Seq)
+			res.Gaps += int64(gap)
+		}
+		mark.LastSeq = data.Seq
+		accepted = append(accepted, data)
+	}
+
+	for _, key := range order {
+		if err := storeSequence(tx, marks[key]); err != nil {
+			return nil, res, err
+		}
+	}
+	return accepted, res, nil
+}
+
+func lockSequence(tx *sqlx.Tx, id1, id2, producerID string) (*model.SensorSequence, error) {
+	mark := &model.SensorSequence{ID1: id1, ID2: id2, ProducerID: producerID}
+	query := "SELECT id1, id2, producer_id, last_seq FROM sensor_sequences WHERE id1 = ? AND id2 = ? AND producer_id = ? FOR UPDATE"
+	err := tx.Get(mark, query, id1, id2, producerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return mark, nil
+	}
+	return mark, err
+}
+
+func storeSequence(tx *sqlx.Tx, mark *model.SensorSequence) error {
+	query := `INSERT INTO sensor_sequences(id1, id2, producer_id, last_seq) VALUES (?, ?, ?, ?)
+                     ON DUPLICATE KEY UPDATE last_seq = VALUES(last_seq)`
+	_, err := tx.Exec(query, mark.ID1, mark.ID2, mark.ProducerID, mark.LastSeq)
+	return err
+}
+
+// GetHighWaterMarks returns the last accepted sequence of every sensor fed by producerID
+func (r *SensorRepository) GetHighWaterMarks(producerID string) ([]model.SensorSequence, error) {
+	var marks []model.SensorSequence
+	query := "SELECT id1, id2, producer_id, last_seq FROM sensor_sequences WHERE producer_id = ?"
+	err := r.DB.Select(&marks, query, producerID)
+	return marks, err
+}
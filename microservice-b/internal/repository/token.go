@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateRefreshToken stores a new session
+func (r *UserRepo) CreateRefreshToken(t *model.RefreshToken) error {
+	return createRefreshToken(r.DB, t)
+}
+
+func createRefreshToken(db sqlx.Execer, t *model.RefreshToken) error {
+	_, err := db.Exec(`INSERT INTO refresh_tokens (user_id, token_hash, access_jti, access_expires_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)`, t.UserID, t.TokenHash, t.AccessJTI, t.AccessExpiresAt, t.ExpiresAt)
+	return err
+}
+
+// GetRefreshToken returns the session with the given token hash, or nil when
+// there is none
+func (r *UserRepo) GetRefreshToken(hash string) (*model.RefreshToken, error) {
+	t := &model.RefreshToken{}
+	err := r.DB.Get(t, "SELECT * FROM refresh_tokens WHERE token_hash = ?", hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RotateRefreshToken revokes the session with id and stores next in its place.
+// It reports false, storing nothing, when the session was revoked concurrently.
+func (r *UserRepo) RotateRefreshToken(id uint64, next *model.RefreshToken) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := createRefreshToken(tx, next); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RevokeRefreshToken revokes the session with the given token hash
+func (r *UserRepo) RevokeRefreshToken(hash string) error {
+	_, err := r.DB.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL", time.Now().UTC(), hash)
+	return err
+}
+
+// RevokeAccessToken adds an access token to the revocation list until it
+// expires, dropping the entries of tokens that have expired since
+func (r *UserRepo) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	if _, err := r.DB.Exec("INSERT IGNORE INTO revoked_tokens (jti, expires_at) VALUES (?, ?)", jti, expiresAt); err != nil {
+		return err
+	}
+	_, err := r.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < ?", time.Now().UTC())
+	return err
+}
+
+// IsRevoked reports whether the access token with jti has been revoked
+func (r *UserRepo) IsRevoked(jti string) (bool, error) {
+	var n int
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?", jti)
+	return n > 0, err
+}
+
+// RevokeSessions revokes every session of a user along with the access tokens
+// issued to them that have not expired yet
+func (r *UserRepo) RevokeSessions(userID uint64) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := revokeSessions(tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func revokeSessions(tx *sqlx.Tx, userID uint64) error {
+	now := time.Now().UTC()
+	// rotated sessions are included: their access tokens may still be live
+	if _, err := tx.Exec(`INSERT IGNORE INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens WHERE user_id = ? AND access_expires_at > ?`, userID, now); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now, userID)
+	return err
+}
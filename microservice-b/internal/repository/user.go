@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"microservice-b/model"
 	"microservice-b/utils"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -30,7 +31,7 @@ func (r *UserRepo) CreateUser(user *model.SignupRequest) error {
 
 func (r *UserRepo) GetByEmail(email string) (*model.User, error) {
 	u := &model.User{}
-	query := `SELECT id,first_name, last_name,email, password, role, archived_at
+	query := `SELECT id,first_name, last_name,email, password, role, archived_at, must_change_password
           FROM users
           WHERE email = ? AND archived_at IS NULL`
 	err := r.DB.Get(u, query, email)
@@ -43,6 +44,13 @@ func (r *UserRepo) GetByEmail(email string) (*model.User, error) {
 	return u, nil
 }
 
+// EmailExists reports whether a user, archived or not, has email
+func (r *UserRepo) EmailExists(email string) (bool, error) {
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM users WHERE email = ?", email)
+	return n > 0, err
+}
+
 func (r *UserRepo) UpdateLastLogin(id uint64) error {
 	query := `UPDATE users SET 
                         last_login = NOW() WHERE id = ?`
@@ -52,3 +60,153 @@ func (r *UserRepo) UpdateLastLogin(id uint64) error {
 	}
 	return nil
 }
+
+// userColumns selects a full model.User; names are NULL for users created
+// before they were collected
+const userColumns = `id, COALESCE(first_name, '') AS first_name, COALESCE(last_name, '') AS last_name, email, password, role,
+       must_change_password, last_login, created_at, updated_at, archived_at`
+
+// GetByID returns an active user by id
+func (r *UserRepo) GetByID(id uint64) (*model.User, error) {
+	return r.get("SELECT "+userColumns+" FROM users WHERE id = ? AND archived_at IS NULL", id)
+}
+
+// Get returns a user by id, archived or not
+func (r *UserRepo) Get(id uint64) (*model.User, error) {
+	return r.get("SELECT "+userColumns+" FROM users WHERE id = ?", id)
+}
+
+func (r *UserRepo) get(query string, args ...interface{}) (*model.User, error) {
+	u := &model.User{}
+	err := r.DB.Get(u, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// List returns the users matching filters ordered by id
+func (r *UserRepo) List(filters map[string]interface{}, limit, offset int) ([]model.User, error) {
+	where, args := userWhere(filters)
+	var users []model.User
+	err := r.DB.Select(&users, "SELECT "+userColumns+" FROM users WHERE "+where+" ORDER BY id LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return users, err
+}
+
+// Count returns the number of users matching filters
+func (r *UserRepo) Count(filters map[string]interface{}) (int64, error) {
+	where, args := userWhere(filters)
+	var n int64
+	err := r.DB.Get(&n, "SELECT COUNT(*) FROM users WHERE "+where, args...)
+	return n, err
+}
+
+// userWhere builds the condition for the q (email or name substring), role and
+// status (active, archived or all; active by default) filters
+func userWhere(filters map[string]interface{}) (string, []interface{}) {
+	where := "1=1"
+	var args []interface{}
+	if v, ok := filters["q"].(string); ok {
+		like := "%" + v + "%"
+		where += " AND (email LIKE ? OR first_name LIKE ? OR last_name LIKE ?)"
+		args = append(args, like, like, like)
+	}
+	if v, ok := filters["role"]; ok {
+		where += " AND role = ?"
+		args = append(args, v)
+	}
+	switch filters["status"] {
+	case "archived":
+		where += " AND archived_at IS NOT NULL"
+	case "all":
+	default:
+		where += " AND archived_at IS NULL"
+	}
+	return where, args
+}
+
+// UpdateProfile updates the names and, when role is not nil, the role of an
+// active user. A role change revokes every session of the user so the new role
+// applies at once. It reports false when there is no active user with id.
+func (r *UserRepo) UpdateProfile(id uint64, firstName, lastName string, role *string) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.Get(&current, "SELECT role FROM users WHERE id = ? AND archived_at IS NULL FOR UPDATE", id); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	newRole := current
+	if role != nil {
+		newRole = *role
+	}
+	if _, err := tx.Exec("UPDATE users SET first_name = ?, last_name = ?, role = ? WHERE id = ?", firstName, lastName, newRole, id); err != nil {
+		return false, err
+	}
+	if newRole != current {
+		if err := revokeSessions(tx, id); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Unarchive restores an archived user. It reports false when there is no
+// archived user with id.
+func (r *UserRepo) Unarchive(id uint64) (bool, error) {
+	res, err := r.DB.Exec("UPDATE users SET archived_at = NULL WHERE id = ? AND archived_at IS NOT NULL", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpdatePassword stores a new password hash, flagging whether the user has to
+// change it at the next login, and revokes every session of the user
+func (r *UserRepo) UpdatePassword(id uint64, hash string, mustChange bool) error {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET password = ?, must_change_password = ? WHERE id = ?", hash, mustChange, id); err != nil {
+		return err
+	}
+	if err := revokeSessions(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Archive archives a user and revokes every session of the user. It reports
+// false when there is no active user with id.
+func (r *UserRepo) Archive(id uint64) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET archived_at = ? WHERE id = ? AND archived_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := revokeSessions(tx, id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
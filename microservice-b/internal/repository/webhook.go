@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"microservice-b/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type WebhookRepository struct {
+	DB *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// ListWebhooks returns all webhooks, oldest first
+func (r *WebhookRepository) ListWebhooks() ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := r.DB.Select(&hooks, "SELECT * FROM webhooks ORDER BY id")
+	return hooks, err
+}
+
+// GetWebhook returns a webhook by id, or nil when it does not exist
+func (r *WebhookRepository) GetWebhook(id uint64) (*model.Webhook, error) {
+	var w model.Webhook
+	err := r.DB.Get(&w, "SELECT * FROM webhooks WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// CreateWebhook stores a new webhook and returns it as stored
+func (r *WebhookRepository) CreateWebhook(w *model.Webhook) (*model.Webhook, error) {
+	res, err := r.DB.Exec("INSERT INTO webhooks (name, url, events, secret, enabled, created_by) VALUES (?, ?, ?, ?, ?, ?)",
+		w.Name, w.URL, w.Events, w.Secret, w.Enabled, w.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.GetWebhook(uint64(id))
+}
+
+// UpdateWebhook stores every field of w but its creator and returns it as stored
+func (r *WebhookRepository) UpdateWebhook(w *model.Webhook) (*model.Webhook, error) {
+	_, err := r.DB.Exec("UPDATE webhooks SET name = ?, url = ?, events = ?, secret = ?, enabled = ? WHERE id = ?",
+		w.Name, w.URL, w.Events, w.Secret, w.Enabled, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetWebhook(w.ID)
+}
+
+// DeleteWebhook removes a webhook with its deliveries and reports whether it existed
+func (r *WebhookRepository) DeleteWebhook(id uint64) (bool, error) {
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Enqueue stores a pending delivery, due at d.NextAttemptAt, and sets its id
+func (r *WebhookRepository) Enqueue(d *model.WebhookDelivery) error {
+	res, err := r.DB.Exec(`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		d.WebhookID, d.EventID, d.EventType, string(d.Payload), model.DeliveryPending, d.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	d.ID = uint64(id)
+	d.Status = model.DeliveryPending
+	return err
+}
+
+// DueDeliveries returns up to limit pending deliveries due at now, longest due first
+func (r *WebhookRepository) DueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.DB.Select(&deliveries, `SELECT * FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id LIMIT ?`, model.DeliveryPending, now, limit)
+	return deliveries, err
+}
+
+// GetDelivery returns a delivery by id, or nil when it does not exist
+func (r *WebhookRepository) GetDelivery(id uint64) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := r.DB.Get(&d, "SELECT * FROM webhook_deliveries WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt: its status, attempts,
+// next attempt, last status code and error, and delivery time
+func (r *WebhookRepository) RecordAttempt(d *model.WebhookDelivery) error {
+	_, err := r.DB.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt, d.ID)
+	return err
+}
+
+// Redeliver queues a dead delivery again, due at at with a fresh set of
+// attempts, and reports whether it was dead
+func (r *WebhookRepository) Redeliver(id uint64, at time.Time) (bool, error) {
+	res, err := r.DB.Exec("UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status = ?",
+		model.DeliveryPending, at, id, model.DeliveryDead)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// deliveryFilter returns the condition selecting the deliveries of a webhook,
+// optionally by status
+func deliveryFilter(webhookID uint64, status string) (string, []interface{}) {
+	where, args := "webhook_id = ?", []interface{}{webhookID}
+	if status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	return where, args
+}
+
+// ListDeliveries returns the deliveries of a webhook, newest first
+func (r *WebhookRepository) ListDeliveries(webhookID uint64, status string, limit, offset int) ([]model.WebhookDelivery, error) {
+	where, args := deliveryFilter(webhookID, status)
+	var deliveries []model.WebhookDelivery
+	err := r.DB.Select(&deliveries, "SELECT * FROM webhook_deliveries WHERE "+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return deliveries, err
+}
+
+// CountDeliveries counts the deliveries ListDeliveries pages through
+func (r *WebhookRepository) CountDeliveries(webhookID uint64, status string) (int64, error) {
+	where, args := deliveryFilter(webhookID, status)
+	var total int64
+	err := r.DB.Get(&total, "SELECT COUNT(*) FROM webhook_deliveries WHERE "+where, args...)
+	return total, err
+}
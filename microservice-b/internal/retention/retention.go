@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"time"
+)
+
+// DefaultInterval is how often retention policies are enforced
+const DefaultInterval = time.Hour
+
+const day = 24 * time.Hour
+
+// Enforcer periodically archives and purges readings according to the stored
+// retention policies. Neither re-rolls the rollups, which keep the aggregates of
+// archived and purged readings, so aggregated history outlives the raw readings.
+type Enforcer struct {
+	repo     *repository.RetentionRepository
+	interval time.Duration
+	now      func() time.Time
+}
+
+func New(repo *repository.RetentionRepository, interval time.Duration) *Enforcer {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Enforcer{repo: repo, interval: interval, now: time.Now}
+}
+
+// Start enforces the policies every interval until ctx is cancelled
+func (e *Enforcer) Start(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		results, err := e.RunOnce()
+		if err != nil {
+			log.Printf("retention: %v", err)
+		}
+		for _, res := range results {
+			if res.Archived > 0 || res.Purged > 0 {
+				log.Printf("retention: policy %d (%s) archived %d and purged %d readings", res.PolicyID, res.Name, res.Archived, res.Purged)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce archives and purges the readings due under every enabled policy
+func (e *Enforcer) RunOnce() ([]model.RetentionResult, error) {
+	policies, err := e.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return e.run(policies, false)
+}
+
+// DryRun reports what RunOnce would archive and purge right now. A candidate
+// policy is evaluated as if it had been created, including its effect on the
+// precedence of the stored policies.
+func (e *Enforcer) DryRun(candidate *model.RetentionPolicy) ([]model.RetentionResult, error) {
+	policies, err := e.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	if candidate != nil {
+		c := *candidate
+		c.ID = 0
+		c.Enabled = true
+		policies = append(policies, c)
+	}
+	return e.run(policies, true)
+}
+
+func (e *Enforcer) run(policies []model.RetentionPolicy, dry bool) ([]model.RetentionResult, error) {
+	now := e.now()
+	results := []model.RetentionResult{}
+	for _, p := range policies {
+		if !p.Enabled {
+			continue
+		}
+		res := model.RetentionResult{PolicyID: p.ID, Name: p.Name}
+		// purge first so rows about to be deleted are not archived needlessly
+		if p.PurgeAfterDays != nil {
+			before := now.Add(-time.Duration(*p.PurgeAfterDays) * day)
+			res.PurgeBefore = &before
+			var err error
+			if dry {
+				res.Purged, err = e.repo.CountPurgeable(p, policies, before)
+			} else {
+				res.Purged, err = e.repo.Purge(p, policies, before)
+			}
+			if err != nil {
+				return results, err
+			}
+		}
+		if p.ArchiveAfterDays != nil {
+			before := now.Add(-time.Duration(*p.ArchiveAfterDays) * day)
+			res.ArchiveBefore = &before
+			var err error
+			if dry {
+				res.Archived, err = e.repo.CountArchivable(p, policies, before)
+				if res.PurgeBefore != nil {
+					// purged rows would be gone before archiving
+					purgedUnarchived, cerr := e.repo.CountArchivable(p, policies, *res.PurgeBefore)
+					if cerr != nil {
+						return results, cerr
+					}
+					res.Archived -= purgedUnarchived
+				}
+			} else {
+				res.Archived, err = e.repo.Archive(p, policies, before, now)
+			}
+			if err != nil {
+				return results, err
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
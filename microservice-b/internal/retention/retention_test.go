@@ -0,0 +1,113 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var policyColumns = []string{"id", "name", "sensor_type", "id1", "id2", "archive_after_days", "purge_after_days", "enabled", "created_at", "updated_at"}
+
+func newEnforcer(t *testing.T, now time.Time) (*Enforcer, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	e := New(repository.NewRetentionRepository(sqlx.NewDb(db, "mysql")), time.Hour)
+	e.now = func() time.Time { return now }
+	return e, mock
+}
+
+func TestEnforcer_RunOnce(t *testing.T) {
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+	e, mock := newEnforcer(t, now)
+
+	mock.ExpectQuery("SELECT \\* FROM retention_policies").
+		WillReturnRows(sqlmock.NewRows(policyColumns).
+			AddRow(1, "raw", nil, nil, nil, 30, 365, true, now, nil).
+			AddRow(2, "paused", "Motion", nil, nil, 1, nil, false, now, nil))
+	mock.ExpectExec("DELETE FROM sensor_readings WHERE ts < \\? AND \\(1=1\\) LIMIT \\?").
+		WithArgs(now.AddDate(0, 0, -365), 5000).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	// rollups keep archived and purged readings, so nothing is queued for re-rollup
+	mock.ExpectExec("UPDATE sensor_readings SET archived_at = \\?").
+		WithArgs(now, now.AddDate(0, 0, -30), 5000).
+		WillReturnResult(sqlmock.NewResult(0, 90))
+
+	results, err := e.RunOnce()
+	require.NoError(t, err)
+	require.Len(t, results, 1, "disabled policies are skipped")
+	assert.Equal(t, int64(4), results[0].Purged)
+	assert.Equal(t, int64(90), results[0].Archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnforcer_DryRunWithCandidate(t *testing.T) {
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+	e, mock := newEnforcer(t, now)
+	light, days := "Light", 7
+
+	mock.ExpectQuery("SELECT \\* FROM retention_policies").
+		WillReturnRows(sqlmock.NewRows(policyColumns).
+			AddRow(1, "raw", nil, nil, nil, 30, nil, true, now, nil))
+	// the stored default no longer covers Light readings once the candidate exists
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM sensor_readings WHERE archived_at IS NULL AND ts < \\? AND \\(1=1\\) AND NOT \\(sensor_type = \\?\\)").
+		WithArgs(now.AddDate(0, 0, -30), "Light").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(50))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM sensor_readings WHERE archived_at IS NULL AND ts < \\? AND \\(sensor_type = \\?\\)$").
+		WithArgs(now.AddDate(0, 0, -7), "Light").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(20))
+
+	results, err := e.DryRun(&model.RetentionPolicy{Name: "light", SensorType: &light, ArchiveAfterDays: &days})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(50), results[0].Archived)
+	assert.Equal(t, uint64(0), results[1].PolicyID)
+	assert.Equal(t, int64(20), results[1].Archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrashPurger_RunOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+	p := NewTrashPurger(repository.NewRetentionRepository(sqlx.NewDb(db, "mysql")), time.Hour, 48*time.Hour)
+	p.now = func() time.Time { return now }
+
+	mock.ExpectExec("DELETE FROM sensor_readings WHERE delete_batch IS NOT NULL AND archived_at < \\? LIMIT \\?").
+		WithArgs(now.Add(-48*time.Hour), 5000).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := p.RunOnce()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSequencePurger_RunOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+	p := NewSequencePurger(repository.NewRetentionRepository(sqlx.NewDb(db, "mysql")), time.Hour, 0)
+	p.now = func() time.Time { return now }
+
+	mock.ExpectExec("DELETE FROM sensor_sequences WHERE updated_at < \\? LIMIT \\?").
+		WithArgs(now.Add(-DefaultSequenceTTL), 5000).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	n, err := p.RunOnce()
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,56 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"microservice-b/internal/repository"
+	"time"
+)
+
+// DefaultSequenceTTL is how long the sequence marks of an idle producer session are kept
+const DefaultSequenceTTL = 30 * day
+
+// SequencePurger removes the sequence marks of producer sessions idle for longer
+// than the TTL. Every producer restart starts a new session with marks of its
+// own, so without it the marks grow by one row per sensor and restart. The TTL
+// must outlast the producers' spools: a reading of a purged session is no
+// longer recognised as a duplicate.
+type SequencePurger struct {
+	repo     *repository.RetentionRepository
+	interval time.Duration
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func NewSequencePurger(repo *repository.RetentionRepository, interval, ttl time.Duration) *SequencePurger {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if ttl <= 0 {
+		ttl = DefaultSequenceTTL
+	}
+	return &SequencePurger{repo: repo, interval: interval, ttl: ttl, now: time.Now}
+}
+
+// Start purges idle sequence marks every interval until ctx is cancelled
+func (p *SequencePurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := p.RunOnce(); err != nil {
+			log.Printf("sequence purge: %v", err)
+		} else if n > 0 {
+			log.Printf("sequence purge: removed %d idle producer marks", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes the marks not updated within the TTL
+func (p *SequencePurger) RunOnce() (int64, error) {
+	return p.repo.PurgeSequences(p.now().UTC().Add(-p.ttl))
+}
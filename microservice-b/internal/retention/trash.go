@@ -0,0 +1,52 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"microservice-b/internal/repository"
+	"time"
+)
+
+// DefaultTrashGrace is how long deleted readings stay restorable
+const DefaultTrashGrace = 7 * day
+
+// TrashPurger physically removes readings that have been in the trash for longer than the grace period
+type TrashPurger struct {
+	repo     *repository.RetentionRepository
+	interval time.Duration
+	grace    time.Duration
+	now      func() time.Time
+}
+
+func NewTrashPurger(repo *repository.RetentionRepository, interval, grace time.Duration) *TrashPurger {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if grace <= 0 {
+		grace = DefaultTrashGrace
+	}
+	return &TrashPurger{repo: repo, interval: interval, grace: grace, now: time.Now}
+}
+
+// Start purges the trash every interval until ctx is cancelled
+func (p *TrashPurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := p.RunOnce(); err != nil {
+			log.Printf("trash purge: %v", err)
+		} else if n > 0 {
+			log.Printf("trash purge: removed %d readings", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes the readings trashed before the grace period
+func (p *TrashPurger) RunOnce() (int64, error) {
+	return p.repo.PurgeTrash(p.now().UTC().Add(-p.grace))
+}
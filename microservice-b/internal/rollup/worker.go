@@ -0,0 +1,161 @@
+package rollup
+
+import (
+	"context"
+	"log"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often the worker rolls up closed buckets
+	DefaultInterval = time.Minute
+	// DefaultGrace is how long a minute stays open for in-flight readings before it is rolled up
+	DefaultGrace = 30 * time.Second
+)
+
+// chunkBuckets caps the buckets one rollup statement covers while catching up
+const chunkBuckets = 1440
+
+// lateScanOverlap rescans a little of the previous window so readings committed
+// while the last scan ran are not missed
+const lateScanOverlap = time.Minute
+
+// Worker maintains the rollup tables. Every run it recomputes the buckets touched by
+// late readings and by edits or deletes, then rolls up the buckets closed since the
+// previous run: raw readings into 1m, 1m into 1h and 1h into 1d.
+type Worker struct {
+	repo     *repository.RollupRepository
+	interval time.Duration
+	grace    time.Duration
+	now      func() time.Time
+}
+
+func New(repo *repository.RollupRepository, interval, grace time.Duration) *Worker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if grace < 0 {
+		grace = DefaultGrace
+	}
+	return &Worker{repo: repo, interval: interval, grace: grace, now: time.Now}
+}
+
+// Start runs the worker until ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(); err != nil {
+			log.Printf("rollup: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single rollup pass
+func (w *Worker) RunOnce() error {
+	now := w.now().UTC()
+	states, err := w.repo.States()
+	if err != nil {
+		return err
+	}
+	if err := w.refresh(states, now); err != nil {
+		return err
+	}
+	return w.advance(states, now)
+}
+
+// refresh recomputes the rolled up buckets affected by late readings and queued edits
+func (w *Worker) refresh(states map[string]time.Time, now time.Time) error {
+	var ranges []model.RollupRange
+	if scanned, ok := states[repository.LateScanState]; ok {
+		if watermark, ok := states[repository.Rollups[0].Name]; ok {
+			late, err := w.repo.LateRanges(scanned.Add(-lateScanOverlap), watermark)
+			if err != nil {
+				return err
+			}
+			ranges = append(ranges, late...)
+		}
+	}
+	dirty, err := w.repo.DirtyRanges()
+	if err != nil {
+		return err
+	}
+	ranges = append(ranges, dirty...)
+
+	for _, rg := range ranges {
+		for level, rollup := range repository.Rollups {
+			watermark, ok := states[rollup.Name]
+			if !ok {
+				break
+			}
+			from := time.Unix(0, 0).UTC()
+			if rg.From != nil {
+				from = rg.From.UTC().Truncate(rollup.Size)
+			}
+			to := watermark
+			if rg.To != nil {
+				if end := rg.To.UTC().Truncate(rollup.Size).Add(rollup.Size); end.Before(to) {
+					to = end
+				}
+			}
+			if !from.Before(to) {
+				break
+			}
+			if err := w.repo.Rebuild(level, from, to, rg.ID1, rg.ID2); err != nil {
+				return err
+			}
+		}
+	}
+	if len(dirty) > 0 {
+		if err := w.repo.ClearDirty(dirty[len(dirty)-1].ID); err != nil {
+			return err
+		}
+	}
+	return w.repo.SetState(repository.LateScanState, now)
+}
+
+// advance rolls up every bucket closed since the last run, level by level
+func (w *Worker) advance(states map[string]time.Time, now time.Time) error {
+	target := now.Add(-w.grace)
+	for level, rollup := range repository.Rollups {
+		target = target.Truncate(rollup.Size)
+		watermark, ok := states[rollup.Name]
+		if !ok {
+			oldest, found, err := w.repo.OldestReading()
+			if err != nil {
+				return err
+			}
+			watermark = target
+			if found && oldest.Before(target) {
+				watermark = oldest.Truncate(rollup.Size)
+			}
+			if err := w.repo.SetState(rollup.Name, watermark); err != nil {
+				return err
+			}
+		}
+		for watermark.Before(target) {
+			next := watermark.Add(chunkBuckets * rollup.Size)
+			if next.After(target) {
+				next = target
+			}
+			if err := w.repo.Rebuild(level, watermark, next, nil, nil); err != nil {
+				return err
+			}
+			if err := w.repo.SetState(rollup.Name, next); err != nil {
+				return err
+			}
+			watermark = next
+		}
+		states[rollup.Name] = watermark
+		// a coarser bucket is closed once the finer rollup has passed its end
+		target = watermark
+	}
+	return nil
+}
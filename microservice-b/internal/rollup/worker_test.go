@@ -0,0 +1,142 @@
+package rollup
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var day = time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)
+
+func newWorker(t *testing.T, now time.Time) (*Worker, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	w := New(repository.NewRollupRepository(sqlx.NewDb(db, "mysql")), time.Minute, 30*time.Second)
+	w.now = func() time.Time { return now }
+	return w, mock
+}
+
+func expectStates(mock sqlmock.Sqlmock, states map[string]time.Time) {
+	rows := sqlmock.NewRows([]string{"name", "watermark"})
+	for name, t := range states {
+		rows.AddRow(name, t)
+	}
+	mock.ExpectQuery("SELECT name, watermark FROM rollup_state").WillReturnRows(rows)
+}
+
+func expectRebuild(mock sqlmock.Sqlmock, table, source string, deleteArgs ...driver.Value) {
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM " + table + " WHERE bucket >= \\? AND bucket < \\?").
+		WithArgs(deleteArgs...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO " + table + " .+ FROM " + source).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+}
+
+func expectState(mock sqlmock.Sqlmock, name string, t time.Time) {
+	mock.ExpectExec("INSERT INTO rollup_state").
+		WithArgs(name, t).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestWorker_CatchesUpFromOldestReading(t *testing.T) {
+	now := day.Add(10*time.Hour + 2*time.Minute + 40*time.Second)
+	w, mock := newWorker(t, now)
+	oldest := sqlmock.NewRows([]string{"min"}).AddRow(day.Add(10*time.Hour + 20*time.Second))
+
+	expectStates(mock, nil)
+	mock.ExpectQuery("SELECT id, id1, .+ FROM rollup_dirty").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "from_ts", "to_ts"}))
+	expectState(mock, repository.LateScanState, now)
+
+	// minutes up to the one still open within the grace period
+	mock.ExpectQuery("SELECT MIN\\(ts\\) FROM sensor_readings").WillReturnRows(oldest)
+	expectState(mock, "1m", day.Add(10*time.Hour))
+	expectRebuild(mock, "sensor_rollups_1m", "sensor_readings", day.Add(10*time.Hour), day.Add(10*time.Hour+2*time.Minute))
+	expectState(mock, "1m", day.Add(10*time.Hour+2*time.Minute))
+
+	// the current hour and day are still open
+	mock.ExpectQuery("SELECT MIN\\(ts\\) FROM sensor_readings").
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(day.Add(10*time.Hour + 20*time.Second)))
+	expectState(mock, "1h", day.Add(10*time.Hour))
+	mock.ExpectQuery("SELECT MIN\\(ts\\) FROM sensor_readings").
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(day.Add(10*time.Hour + 20*time.Second)))
+	expectState(mock, "1d", day)
+
+	require.NoError(t, w.RunOnce())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWorker_AdvancesCoarserRollups(t *testing.T) {
+	now := day.Add(24*time.Hour + 5*time.Minute)
+	w, mock := newWorker(t, now)
+
+	expectStates(mock, map[string]time.Time{
+		"1m":                     day.Add(23*time.Hour + 58*time.Minute),
+		"1h":                     day.Add(23 * time.Hour),
+		"1d":                     day,
+		repository.LateScanState: now.Add(-time.Minute),
+	})
+	mock.ExpectQuery("SELECT id1, .+ FROM sensor_readings WHERE created_at >= \\? AND ts < \\?").
+		WithArgs(now.Add(-2*time.Minute), day.Add(23*time.Hour+58*time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "from_ts", "to_ts"}))
+	mock.ExpectQuery("FROM rollup_dirty").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "from_ts", "to_ts"}))
+	expectState(mock, repository.LateScanState, now)
+
+	next := day.Add(24*time.Hour + 4*time.Minute)
+	expectRebuild(mock, "sensor_rollups_1m", "sensor_readings", day.Add(23*time.Hour+58*time.Minute), next)
+	expectState(mock, "1m", next)
+	expectRebuild(mock, "sensor_rollups_1h", "sensor_rollups_1m", day.Add(23*time.Hour), day.Add(24*time.Hour))
+	expectState(mock, "1h", day.Add(24*time.Hour))
+	expectRebuild(mock, "sensor_rollups_1d", "sensor_rollups_1h", day, day.Add(24*time.Hour))
+	expectState(mock, "1d", day.Add(24*time.Hour))
+
+	require.NoError(t, w.RunOnce())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWorker_RebuildsLateAndEditedRanges(t *testing.T) {
+	now := day.Add(10*time.Hour + 20*time.Second)
+	w, mock := newWorker(t, now)
+	watermark := day.Add(10 * time.Hour)
+
+	expectStates(mock, map[string]time.Time{
+		"1m":                     watermark,
+		"1h":                     watermark,
+		"1d":                     day,
+		repository.LateScanState: now.Add(-time.Minute),
+	})
+	mock.ExpectQuery("FROM sensor_readings WHERE created_at >= \\? AND ts < \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id1", "id2", "from_ts", "to_ts"}).
+			AddRow("A", "1", day.Add(9*time.Hour+40*time.Minute+10*time.Second), day.Add(9*time.Hour+41*time.Minute+5*time.Second)))
+	mock.ExpectQuery("FROM rollup_dirty").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id1", "id2", "from_ts", "to_ts"}).
+			AddRow(7, "B", nil, nil, nil))
+
+	// the late reading of A/1 refreshes its minutes and hour; its day is still open
+	expectRebuild(mock, "sensor_rollups_1m", "sensor_readings", day.Add(9*time.Hour+40*time.Minute), day.Add(9*time.Hour+42*time.Minute), "A", "1")
+	expectRebuild(mock, "sensor_rollups_1h", "sensor_rollups_1m", day.Add(9*time.Hour), watermark, "A", "1")
+	// the unbounded edit of B refreshes everything rolled up so far
+	epoch := time.Unix(0, 0).UTC()
+	expectRebuild(mock, "sensor_rollups_1m", "sensor_readings", epoch, watermark, "B")
+	expectRebuild(mock, "sensor_rollups_1h", "sensor_rollups_1m", epoch, watermark, "B")
+	expectRebuild(mock, "sensor_rollups_1d", "sensor_rollups_1h", epoch, day, "B")
+	mock.ExpectExec("DELETE FROM rollup_dirty WHERE id <= \\?").
+		WithArgs(uint64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectState(mock, repository.LateScanState, now)
+
+	require.NoError(t, w.RunOnce())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
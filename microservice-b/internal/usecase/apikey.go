@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"time"
+)
+
+// apiKeyTouchResolution is how precisely the last use of an API key is tracked
+const apiKeyTouchResolution = time.Minute
+
+// IAPIKeyManager manages API keys: users manage their own, admins all keys
+// and service account keys
+type IAPIKeyManager interface {
+	CreateAPIKey(caller middleware.CurrentUser, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error)
+	ListAPIKeys(filters map[string]interface{}, limit, offset int) ([]model.APIKey, int64, error)
+	RevokeAPIKey(caller middleware.CurrentUser, id uint64) error
+}
+
+// CreateAPIKey creates an API key for the caller, or a service account key
+// owned by no user, and returns it with the key, which is not stored and
+// cannot be shown again. Scopes beyond the caller's role are rejected with
+// utils.ErrScopeNotAllowed.
+func (s *UserRepository) CreateAPIKey(caller middleware.CurrentUser, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	for _, scope := range req.Scopes {
+		if !middleware.Allowed(caller.Role, middleware.ScopePermissions[scope]) {
+			return nil, utils.ErrScopeNotAllowed
+		}
+	}
+	b := make([]byte, 28)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	prefix := middleware.APIKeyPrefix + hex.EncodeToString(b[:4])
+	key := prefix + "_" + hex.EncodeToString(b[4:])
+
+	k := model.APIKey{
+		Name:         req.Name,
+		Prefix:       prefix,
+		KeyHash:      hashToken(key),
+		Scopes:       req.Scopes,
+		ID1Allowlist: req.ID1Allowlist,
+		CreatedBy:    &caller.ID,
+		ExpiresAt:    req.ExpiresAt,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if !req.ServiceAccount {
+		k.UserID = &caller.ID
+	}
+	if err := s.Repo.CreateAPIKey(&k); err != nil {
+		return nil, err
+	}
+	return &model.CreateAPIKeyResponse{APIKey: k, Key: key}, nil
+}
+
+// ListAPIKeys returns a page of the API keys matching filters and their total
+// count
+func (s *UserRepository) ListAPIKeys(filters map[string]interface{}, limit, offset int) ([]model.APIKey, int64, error) {
+	keys, err := s.Repo.ListAPIKeys(filters, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.Repo.CountAPIKeys(filters)
+	return keys, total, err
+}
+
+// RevokeAPIKey revokes an API key of the caller; admins may revoke any key.
+// Keys the caller may not revoke are reported as utils.ErrAPIKeyNotFound.
+func (s *UserRepository) RevokeAPIKey(caller middleware.CurrentUser, id uint64) error {
+	k, err := s.Repo.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if caller.Role != "admin" && (k.UserID == nil || *k.UserID != caller.ID) {
+		return utils.ErrAPIKeyNotFound
+	}
+	ok, err := s.Repo.RevokeAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the caller an API key acts for. Keys act with the
+// current role of their owner, so keys of archived users stop working.
+func (s *UserRepository) AuthenticateAPIKey(key string) (middleware.CurrentUser, error) {
+	k, err := s.Repo.GetAPIKeyByHash(hashToken(key))
+	if err != nil {
+		return middleware.CurrentUser{}, err
+	}
+	now := time.Now()
+	if k == nil || k.RevokedAt != nil || (k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)) {
+		return middleware.CurrentUser{}, utils.ErrInvalidAPIKey
+	}
+	u := middleware.CurrentUser{
+		Email:        "service:" + k.Name,
+		APIKeyID:     k.ID,
+		Scopes:       k.Scopes,
+		ID1Allowlist: k.ID1Allowlist,
+	}
+	if k.UserID != nil {
+		owner, err := s.Repo.GetByID(*k.UserID)
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return middleware.CurrentUser{}, utils.ErrInvalidAPIKey
+		}
+		if err != nil {
+			return middleware.CurrentUser{}, err
+		}
+		u.ID, u.Email, u.Role = owner.ID, owner.Email, owner.Role
+	}
+
+	// Last-used tracking is best effort
+	_ = s.Repo.TouchAPIKey(k.ID, now.UTC(), apiKeyTouchResolution)
+
+	return u, nil
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/middleware"
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyColumns = []string{"id", "name", "prefix", "key_hash", "user_id", "scopes", "id1_allowlist", "created_by", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql"))}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT \\* FROM api_keys WHERE key_hash = \\?").
+		WithArgs(hashToken("smk_1a2b3c4d_secret")).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "etl", "smk_1a2b3c4d", "h", 7, "sensors:read,sensors:write", "A,B", 7, now.Add(time.Hour), nil, nil, now))
+	mock.ExpectQuery("SELECT .+ FROM users WHERE id = \\? AND archived_at IS NULL").
+		WithArgs(uint64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(7, "etl@example.com", "analyst"))
+	mock.ExpectExec("UPDATE api_keys SET last_used_at = \\? WHERE id = \\? AND \\(last_used_at IS NULL OR last_used_at < \\?\\)").
+		WithArgs(sqlmock.AnyArg(), uint64(3), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	u, err := s.AuthenticateAPIKey("smk_1a2b3c4d_secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CurrentUser{
+		ID: 7, Email: "etl@example.com", Role: "analyst",
+		APIKeyID: 3, Scopes: []string{"sensors:read", "sensors:write"}, ID1Allowlist: []string{"A", "B"},
+	}, u)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticateAPIKey_Invalid(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql"))}
+	now := time.Now()
+
+	// unknown
+	mock.ExpectQuery("SELECT \\* FROM api_keys").WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+	// expired
+	mock.ExpectQuery("SELECT \\* FROM api_keys").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "etl", "p", "h", nil, "sensors:read", nil, 1, now.Add(-time.Hour), nil, nil, now))
+	// revoked
+	mock.ExpectQuery("SELECT \\* FROM api_keys").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "etl", "p", "h", nil, "sensors:read", nil, 1, nil, nil, now, now))
+	// owner archived
+	mock.ExpectQuery("SELECT \\* FROM api_keys").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "etl", "p", "h", 7, "sensors:read", nil, 7, nil, nil, nil, now))
+	mock.ExpectQuery("SELECT .+ FROM users WHERE id = \\? AND archived_at IS NULL").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	for i := 0; i < 4; i++ {
+		_, err := s.AuthenticateAPIKey("smk_1a2b3c4d_secret")
+		assert.ErrorIs(t, err, utils.ErrInvalidAPIKey, "case %d", i)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql"))}
+	analyst := middleware.CurrentUser{ID: 7, Role: "analyst"}
+
+	_, err = s.CreateAPIKey(analyst, &model.CreateAPIKeyRequest{Name: "etl", Scopes: []string{"sensors:read", "sensors:write"}})
+	assert.ErrorIs(t, err, utils.ErrScopeNotAllowed)
+
+	mock.ExpectExec("INSERT INTO api_keys").
+		WithArgs("etl", sqlmock.AnyArg(), sqlmock.AnyArg(), uint64(7), "sensors:read", "A", uint64(7), nil).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	k, err := s.CreateAPIKey(analyst, &model.CreateAPIKeyRequest{Name: "etl", Scopes: []string{"sensors:read"}, ID1Allowlist: []string{"A"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), k.ID)
+	assert.Regexp(t, "^smk_[0-9a-f]{8}$", k.Prefix)
+	assert.Regexp(t, "^"+k.Prefix+"_[0-9a-f]{48}$", k.Key)
+	assert.Equal(t, hashToken(k.Key), k.KeyHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"time"
+)
+
+// DeviceTokenPrefix starts every device token, telling them apart from API keys
+const DeviceTokenPrefix = "smd_"
+
+// deviceTokenTouchResolution is how precisely the last use of a device token is tracked
+const deviceTokenTouchResolution = time.Minute
+
+// IDeviceTokenManager manages the tokens generators authenticate with on the
+// gRPC ingest port
+type IDeviceTokenManager interface {
+	CreateDeviceToken(createdBy uint64, req *model.CreateDeviceTokenRequest) (*model.CreateDeviceTokenResponse, error)
+	ListDeviceTokens(includeRevoked bool, limit, offset int) ([]model.DeviceToken, int64, error)
+	RevokeDeviceToken(id uint64) error
+}
+
+type DeviceTokenRepository struct {
+	Repo *repository.DeviceRepository
+}
+
+// CreateDeviceToken creates a token bound to the given sensors and returns it
+// with the token, which is not stored and cannot be shown again
+func (s *DeviceTokenRepository) CreateDeviceToken(createdBy uint64, req *model.CreateDeviceTokenRequest) (*model.CreateDeviceTokenResponse, error) {
+	b := make([]byte, 28)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	prefix := DeviceTokenPrefix + hex.EncodeToString(b[:4])
+	token := prefix + "_" + hex.EncodeToString(b[4:])
+
+	t := model.DeviceToken{
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hashToken(token),
+		Sensors:   req.Sensors,
+		CreatedBy: &createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Repo.CreateToken(&t); err != nil {
+		return nil, err
+	}
+	return &model.CreateDeviceTokenResponse{DeviceToken: t, Token: token}, nil
+}
+
+// ListDeviceTokens returns a page of device tokens and their total count
+func (s *DeviceTokenRepository) ListDeviceTokens(includeRevoked bool, limit, offset int) ([]model.DeviceToken, int64, error) {
+	tokens, err := s.Repo.ListTokens(includeRevoked, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.Repo.CountTokens(includeRevoked)
+	return tokens, total, err
+}
+
+// RevokeDeviceToken revokes a device token; streams already authenticated with
+// it are not cut, the next connection is refused
+func (s *DeviceTokenRepository) RevokeDeviceToken(id uint64) error {
+	ok, err := s.Repo.RevokeToken(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrDeviceTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateDevice returns the device token matching token. Unknown and
+// revoked tokens are rejected with utils.ErrInvalidDeviceToken.
+func (s *DeviceTokenRepository) AuthenticateDevice(token string) (*model.DeviceToken, error) {
+	t, err := s.Repo.GetTokenByHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.RevokedAt != nil {
+		return nil, utils.ErrInvalidDeviceToken
+	}
+
+	// Last-used tracking is best effort
+	_ = s.Repo.TouchToken(t.ID, time.Now().UTC(), deviceTokenTouchResolution)
+
+	return t, nil
+}
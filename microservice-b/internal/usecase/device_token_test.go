@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var deviceTokenColumns = []string{"id", "name", "prefix", "token_hash", "sensors", "created_by", "last_used_at", "revoked_at", "created_at"}
+
+func TestCreateDeviceToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &DeviceTokenRepository{Repo: repository.NewDeviceRepository(sqlx.NewDb(db, "mysql"))}
+
+	mock.ExpectExec("INSERT INTO device_tokens").
+		WithArgs("gen-a", sqlmock.AnyArg(), sqlmock.AnyArg(), "A/1,B/*", uint64(1)).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+
+	tok, err := s.CreateDeviceToken(1, &model.CreateDeviceTokenRequest{Name: "gen-a", Sensors: []string{"A/1", "B/*"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), tok.ID)
+	assert.True(t, strings.HasPrefix(tok.Token, tok.Prefix+"_"))
+	assert.True(t, strings.HasPrefix(tok.Prefix, DeviceTokenPrefix))
+	assert.Equal(t, hashToken(tok.Token), tok.TokenHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticateDevice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &DeviceTokenRepository{Repo: repository.NewDeviceRepository(sqlx.NewDb(db, "mysql"))}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT \\* FROM device_tokens WHERE token_hash = \\?").
+		WithArgs(hashToken("smd_1a2b3c4d_secret")).
+		WillReturnRows(sqlmock.NewRows(deviceTokenColumns).AddRow(5, "gen-a", "smd_1a2b3c4d", "h", "A/1,B/*", 1, nil, nil, now))
+	mock.ExpectExec("UPDATE device_tokens SET last_used_at = \\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), uint64(5), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tok, err := s.AuthenticateDevice("smd_1a2b3c4d_secret")
+
+	require.NoError(t, err)
+	assert.True(t, tok.Allows("A", "1"))
+	assert.False(t, tok.Allows("A", "2"))
+	assert.True(t, tok.Allows("B", "7"))
+
+	// unknown
+	mock.ExpectQuery("SELECT \\* FROM device_tokens").WillReturnRows(sqlmock.NewRows(deviceTokenColumns))
+	// revoked
+	mock.ExpectQuery("SELECT \\* FROM device_tokens").
+		WillReturnRows(sqlmock.NewRows(deviceTokenColumns).AddRow(5, "gen-a", "p", "h", "A/1", 1, nil, now, now))
+	for i := 0; i < 2; i++ {
+		_, err := s.AuthenticateDevice("smd_1a2b3c4d_secret")
+		assert.ErrorIs(t, err, utils.ErrInvalidDeviceToken)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeDeviceToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &DeviceTokenRepository{Repo: repository.NewDeviceRepository(sqlx.NewDb(db, "mysql"))}
+
+	mock.ExpectExec("UPDATE device_tokens SET revoked_at = \\? WHERE id = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), uint64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE device_tokens SET revoked_at").
+		WithArgs(sqlmock.AnyArg(), uint64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, s.RevokeDeviceToken(5))
+	assert.ErrorIs(t, s.RevokeDeviceToken(6), utils.ErrDeviceTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CreateInvitation invites email to sign up with role and returns the
+// invitation with its token, which is not stored and cannot be shown again
+func (s *UserRepository) CreateInvitation(email, role string, createdBy uint64) (*model.CreateInvitationResponse, error) {
+	exists, err := s.Repo.EmailExists(email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("user with email %s already exists", email)
+	}
+	ttl := s.InvitationTTL
+	if ttl <= 0 {
+		ttl = DefaultInvitationTTL
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(b)
+
+	inv := model.Invitation{
+		TokenHash: hashToken(token),
+		Email:     email,
+		Role:      role,
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if createdBy != 0 {
+		inv.CreatedBy = &createdBy
+	}
+	if err := s.Repo.CreateInvitation(&inv); err != nil {
+		return nil, err
+	}
+	return &model.CreateInvitationResponse{
+		InvitationResponse: model.InvitationResponse{Invitation: inv, Status: model.InvitationPending},
+		Token:              token,
+	}, nil
+}
+
+// ListInvitations returns a page of the invitations matching filters and their
+// total count
+func (s *UserRepository) ListInvitations(filters map[string]interface{}, limit, offset int) ([]model.InvitationResponse, int64, error) {
+	invitations, err := s.Repo.ListInvitations(filters, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.Repo.CountInvitations(filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	res := make([]model.InvitationResponse, len(invitations))
+	for i, inv := range invitations {
+		res[i] = model.InvitationResponse{Invitation: inv, Status: inv.Status(now)}
+	}
+	return res, total, nil
+}
+
+// RevokeInvitation revokes a pending invitation. It returns
+// utils.ErrInvitationNotFound for unknown ids and utils.ErrInvalidInvitation
+// for invitations already accepted or revoked.
+func (s *UserRepository) RevokeInvitation(id uint64) error {
+	if _, err := s.Repo.GetInvitation(id); err != nil {
+		return err
+	}
+	ok, err := s.Repo.RevokeInvitation(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrInvalidInvitation
+	}
+	return nil
+}
+
+// BootstrapAdmin creates an admin with email and password when there are no
+// users yet, so a fresh deployment can be administered without open signup.
+// It reports whether the admin was created.
+func (s *UserRepository) BootstrapAdmin(email, password string) (bool, error) {
+	if !IsValidEmail(email) {
+		return false, fmt.Errorf("invalid bootstrap admin email %q", email)
+	}
+	if !IsValidPassword(password) {
+		return false, fmt.Errorf("bootstrap admin password must be 6-60 characters")
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	return s.Repo.CreateFirstAdmin(email, string(hashed))
+}
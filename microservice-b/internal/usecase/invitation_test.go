@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"microservice-b/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var invitationColumns = []string{"id", "token_hash", "email", "role", "created_by", "expires_at", "accepted_at", "accepted_by", "revoked_at", "created_at"}
+
+func TestSignup_Modes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := repository.NewUserRepository(sqlx.NewDb(db, "mysql"))
+
+	for _, tc := range []struct {
+		mode, role string
+		want       error
+	}{
+		{SignupDisabled, "analyst", utils.ErrSignupDisabled},
+		{SignupInvite, "analyst", utils.ErrInvitationRequired},
+		{"", "analyst", utils.ErrInvitationRequired},
+		{SignupOpen, "admin", utils.ErrRoleNotAllowed},
+	} {
+		s := &UserRepository{Repo: repo, SignupMode: tc.mode}
+		err := s.Signup(&model.SignupRequest{Email: "a@b.c", Password: "password123", Role: tc.role})
+		assert.ErrorIs(t, err, tc.want, "mode %q role %q", tc.mode, tc.role)
+	}
+	// none of the rejected signups touch the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSignup_Invitation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql")), SignupMode: SignupInvite}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT \\* FROM invitations WHERE token_hash = \\?").
+		WithArgs(hashToken("invite")).
+		WillReturnRows(sqlmock.NewRows(invitationColumns).AddRow(4, hashToken("invite"), "new@example.com", "admin", 1, now.Add(time.Hour), nil, nil, nil, now))
+	mock.ExpectQuery("SELECT .+ FROM users WHERE email = \\?").
+		WithArgs("New@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs("", "", "New@example.com", sqlmock.AnyArg(), "admin").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("UPDATE invitations SET accepted_at = \\?, accepted_by = \\? WHERE id = \\? AND accepted_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), int64(9), uint64(4), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// the invitation decides the role
+	err = s.Signup(&model.SignupRequest{Email: "New@example.com", Password: "password123", Role: "analyst", InviteToken: "invite"})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSignup_InvalidInvitation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql")), SignupMode: SignupOpen}
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name  string
+		email string
+		row   []interface{}
+	}{
+		{"other email", "other@example.com", []interface{}{4, "h", "new@example.com", "analyst", 1, now.Add(time.Hour), nil, nil, nil, now}},
+		{"expired", "new@example.com", []interface{}{4, "h", "new@example.com", "analyst", 1, now.Add(-time.Hour), nil, nil, nil, now}},
+		{"used", "new@example.com", []interface{}{4, "h", "new@example.com", "analyst", 1, now.Add(time.Hour), now, 9, nil, now}},
+		{"revoked", "new@example.com", []interface{}{4, "h", "new@example.com", "analyst", 1, now.Add(time.Hour), nil, nil, now, now}},
+	} {
+		values := make([]driver.Value, len(tc.row))
+		for i, v := range tc.row {
+			values[i] = v
+		}
+		mock.ExpectQuery("SELECT \\* FROM invitations WHERE token_hash = \\?").WillReturnRows(sqlmock.NewRows(invitationColumns).AddRow(values...))
+
+		err := s.Signup(&model.SignupRequest{Email: tc.email, Password: "password123", InviteToken: "invite"})
+		assert.ErrorIs(t, err, utils.ErrInvalidInvitation, tc.name)
+	}
+	mock.ExpectQuery("SELECT \\* FROM invitations WHERE token_hash = \\?").WillReturnRows(sqlmock.NewRows(invitationColumns))
+	err = s.Signup(&model.SignupRequest{Email: "new@example.com", Password: "password123", InviteToken: "unknown"})
+	assert.ErrorIs(t, err, utils.ErrInvalidInvitation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateInvitation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql")), InvitationTTL: time.Hour}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM users WHERE email = \\?").
+		WithArgs("new@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO invitations").
+		WithArgs(sqlmock.AnyArg(), "new@example.com", "analyst", uint64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	inv, err := s.CreateInvitation("new@example.com", "analyst", 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), inv.ID)
+	assert.Len(t, inv.Token, 64)
+	assert.Equal(t, hashToken(inv.Token), inv.TokenHash)
+	assert.Equal(t, model.InvitationPending, inv.Status)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), inv.ExpiresAt, time.Minute)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM users WHERE email = \\?").
+		WithArgs("taken@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	_, err = s.CreateInvitation("taken@example.com", "analyst", 1)
+	assert.ErrorContains(t, err, "already exists")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/middleware"
+	"microservice-b/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var refreshTokenColumns = []string{"id", "user_id", "token_hash", "access_jti", "access_expires_at", "expires_at", "revoked_at", "created_at"}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql")), JWTSecret: "test-secret"}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT \\* FROM refresh_tokens WHERE token_hash = \\?").
+		WithArgs(hashToken("old")).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(5, 1, hashToken("old"), "jti", now, now.Add(time.Hour), nil, now))
+	mock.ExpectQuery("SELECT .+ FROM users WHERE id = \\? AND archived_at IS NULL").
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(1, "a@b.c", "analyst"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = \\? WHERE id = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), uint64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WithArgs(uint64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectCommit()
+
+	tokens, err := s.Refresh("old")
+
+	require.NoError(t, err)
+	assert.NotEqual(t, "old", tokens.RefreshToken)
+	assert.Equal(t, int64(DefaultAccessTTL/time.Second), tokens.ExpiresIn)
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+	u, err := middleware.ParseJWT(tokens.Token, "test-secret", s.Repo)
+	require.NoError(t, err)
+	assert.Equal(t, "a@b.c", u.Email)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefresh_ReuseRevokesAllSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql")), JWTSecret: "test-secret"}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT \\* FROM refresh_tokens WHERE token_hash = \\?").
+		WithArgs(hashToken("stolen")).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(5, 1, hashToken("stolen"), "jti", now, now.Add(time.Hour), now, now))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO revoked_tokens .+ FROM refresh_tokens WHERE user_id = \\? AND access_expires_at > \\?").
+		WithArgs(uint64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = \\? WHERE user_id = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err = s.Refresh("stolen")
+
+	assert.ErrorIs(t, err, utils.ErrInvalidRefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	s := &UserRepository{Repo: repository.NewUserRepository(sqlx.NewDb(db, "mysql"))}
+
+	mock.ExpectQuery("SELECT .+ FROM users WHERE id = \\? AND archived_at IS NULL").
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(1, "a@b.c", "analyst"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET password = \\?, must_change_password = \\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), true, uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO revoked_tokens").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	temporary, err := s.ResetPassword(1)
+	require.NoError(t, err)
+	assert.Len(t, temporary, 18)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,6 +1,10 @@
 package usecase
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"microservice-b/internal/repository"
 	"microservice-b/middleware"
@@ -8,22 +12,85 @@ import (
 	"microservice-b/utils"
 	"regexp"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// DefaultAccessTTL is how long an access token is valid
+	DefaultAccessTTL = 15 * time.Minute
+	// DefaultRefreshTTL is how long a refresh token is valid
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+	// DefaultInvitationTTL is how long an invitation can be accepted
+	DefaultInvitationTTL = 7 * 24 * time.Hour
+)
+
+// Signup modes
+const (
+	SignupOpen     = "open"     // anyone may sign up as an analyst; invitations grant other roles
+	SignupInvite   = "invite"   // only invited users may sign up
+	SignupDisabled = "disabled" // nobody may sign up
+)
+
 type IUserRepository interface {
 	Signup(u *model.SignupRequest) error
-	Login(email, password string) (string, error)
+	Login(email, password string) (*model.LoginResponse, error)
+	Refresh(refreshToken string) (*model.LoginResponse, error)
+	Logout(refreshToken string, caller middleware.CurrentUser) error
+}
+
+// IUserManager manages accounts: admins manage all users, users their own
+type IUserManager interface {
+	ListUsers(filters map[string]interface{}, limit, offset int) ([]model.User, int64, error)
+	GetUser(userID uint64) (*model.User, error)
+	UpdateUser(userID uint64, req *model.UpdateUserRequest) (*model.User, error)
+	UpdateProfile(userID uint64, req *model.UpdateProfileRequest) (*model.User, error)
+	Archive(userID uint64) error
+	Unarchive(userID uint64) error
+	ChangePassword(userID uint64, current, next string) error
+	ResetPassword(userID uint64) (string, error)
+}
+
+// IInvitationManager lets admins invite users
+type IInvitationManager interface {
+	CreateInvitation(email, role string, createdBy uint64) (*model.CreateInvitationResponse, error)
+	ListInvitations(filters map[string]interface{}, limit, offset int) ([]model.InvitationResponse, int64, error)
+	RevokeInvitation(id uint64) error
 }
 
 type UserRepository struct {
-	Repo      *repository.UserRepo
-	JWTSecret string
+	Repo          *repository.UserRepo
+	JWTSecret     string
+	AccessTTL     time.Duration // DefaultAccessTTL when zero
+	RefreshTTL    time.Duration // DefaultRefreshTTL when zero
+	SignupMode    string        // SignupInvite when empty
+	InvitationTTL time.Duration // DefaultInvitationTTL when zero
 }
 
-// Signup
+// Signup creates a user according to SignupMode. A user signing up with an
+// invitation gets the invited role; open signups without one are analysts.
 func (s *UserRepository) Signup(u *model.SignupRequest) error {
+	if s.SignupMode == SignupDisabled {
+		return utils.ErrSignupDisabled
+	}
+	var inv *model.Invitation
+	if u.InviteToken != "" {
+		var err error
+		inv, err = s.Repo.GetInvitationByToken(hashToken(u.InviteToken))
+		if err != nil {
+			return err
+		}
+		if inv == nil || inv.Status(time.Now()) != model.InvitationPending || !strings.EqualFold(inv.Email, u.Email) {
+			return utils.ErrInvalidInvitation
+		}
+		u.Role = inv.Role
+	} else if s.SignupMode != SignupOpen {
+		return utils.ErrInvitationRequired
+	} else if u.Role == "admin" {
+		return utils.ErrRoleNotAllowed
+	}
+
 	// Check if user already exists
 	existingUser, _ := s.Repo.GetByEmail(u.Email)
 	if existingUser != nil {
@@ -37,33 +104,254 @@ func (s *UserRepository) Signup(u *model.SignupRequest) error {
 	}
 	u.Password = string(hashed)
 
-	// Save to DB
-	return s.Repo.CreateUser(u)
+	// Save to DB, using up the invitation
+	if inv == nil {
+		return s.Repo.CreateUser(u)
+	}
+	ok, err := s.Repo.AcceptInvitation(inv.ID, u)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrInvalidInvitation
+	}
+	return nil
 }
 
 // Login
-func (s *UserRepository) Login(email, password string) (string, error) {
+func (s *UserRepository) Login(email, password string) (*model.LoginResponse, error) {
 	u, err := s.Repo.GetByEmail(email)
 	if err != nil {
 		if err == utils.ErrEmailNotFound {
-			return "", utils.ErrEmailNotFound
+			return nil, utils.ErrEmailNotFound
 		}
-		return "", err
+		return nil, err
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
-		return "", utils.ErrPasswordMismatch
+		return nil, utils.ErrPasswordMismatch
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateJWT(u.ID, u.Email, u.Role, s.JWTSecret, 24)
+	// Issue an access token and start a session
+	tokens, err := s.issueTokens(u, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Update last_login
 	_ = s.Repo.UpdateLastLogin(u.ID)
 
-	return token, nil
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token, revoking the old one. Presenting a revoked refresh token means it was
+// stolen or replayed, so every session of its user is revoked.
+func (s *UserRepository) Refresh(refreshToken string) (*model.LoginResponse, error) {
+	t, err := s.Repo.GetRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || time.Now().After(t.ExpiresAt) {
+		return nil, utils.ErrInvalidRefreshToken
+	}
+	if t.RevokedAt != nil {
+		if err := s.Repo.RevokeSessions(t.UserID); err != nil {
+			return nil, err
+		}
+		return nil, utils.ErrInvalidRefreshToken
+	}
+	u, err := s.Repo.GetByID(t.UserID)
+	if errors.Is(err, utils.ErrUserNotFound) {
+		return nil, utils.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(u, t)
+}
+
+// Logout revokes the caller's access token and, when given, the refresh token
+// of the session. Refresh tokens of other users are ignored.
+func (s *UserRepository) Logout(refreshToken string, caller middleware.CurrentUser) error {
+	if refreshToken != "" {
+		t, err := s.Repo.GetRefreshToken(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if t != nil && t.UserID == caller.ID {
+			if err := s.Repo.RevokeRefreshToken(t.TokenHash); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Repo.RevokeAccessToken(caller.TokenID, caller.ExpiresAt)
+}
+
+// ListUsers returns a page of the users matching filters and their total count
+func (s *UserRepository) ListUsers(filters map[string]interface{}, limit, offset int) ([]model.User, int64, error) {
+	users, err := s.Repo.List(filters, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.Repo.Count(filters)
+	return users, total, err
+}
+
+// GetUser returns a user by id, archived or not
+func (s *UserRepository) GetUser(userID uint64) (*model.User, error) {
+	return s.Repo.Get(userID)
+}
+
+// UpdateUser changes the names and role of an active user; changing the role
+// revokes all their sessions
+func (s *UserRepository) UpdateUser(userID uint64, req *model.UpdateUserRequest) (*model.User, error) {
+	u, err := s.Repo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.FirstName != nil {
+		u.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		u.LastName = *req.LastName
+	}
+	ok, err := s.Repo.UpdateProfile(userID, u.FirstName, u.LastName, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, utils.ErrUserNotFound
+	}
+	return s.Repo.Get(userID)
+}
+
+// UpdateProfile changes the names of a user
+func (s *UserRepository) UpdateProfile(userID uint64, req *model.UpdateProfileRequest) (*model.User, error) {
+	return s.UpdateUser(userID, &model.UpdateUserRequest{FirstName: req.FirstName, LastName: req.LastName})
+}
+
+// Archive archives a user, which revokes all their sessions
+func (s *UserRepository) Archive(userID uint64) error {
+	ok, err := s.Repo.Archive(userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	return nil
+}
+
+// Unarchive lets an archived user log in again
+func (s *UserRepository) Unarchive(userID uint64) error {
+	ok, err := s.Repo.Unarchive(userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	return nil
+}
+
+// ChangePassword replaces a user's password after checking the current one.
+// All sessions of the user, including the caller's, are revoked.
+func (s *UserRepository) ChangePassword(userID uint64, current, next string) error {
+	u, err := s.Repo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(current)); err != nil {
+		return utils.ErrPasswordMismatch
+	}
+	return s.setPassword(userID, next, false)
+}
+
+// ResetPassword replaces a user's password with a random temporary one the user
+// has to change after logging in, revoking all their sessions, and returns it
+func (s *UserRepository) ResetPassword(userID uint64) (string, error) {
+	if _, err := s.Repo.GetByID(userID); err != nil {
+		return "", err
+	}
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	temporary := hex.EncodeToString(b)
+	if err := s.setPassword(userID, temporary, true); err != nil {
+		return "", err
+	}
+	return temporary, nil
+}
+
+func (s *UserRepository) setPassword(userID uint64, password string, mustChange bool) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.Repo.UpdatePassword(userID, string(hashed), mustChange)
+}
+
+// issueTokens signs an access token for u and stores a session for it, in place
+// of rotated when refreshing
+func (s *UserRepository) issueTokens(u *model.User, rotated *model.RefreshToken) (*model.LoginResponse, error) {
+	accessTTL, refreshTTL := s.AccessTTL, s.RefreshTTL
+	if accessTTL <= 0 {
+		accessTTL = DefaultAccessTTL
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTTL
+	}
+	now := time.Now()
+	accessExpiresAt := now.Add(accessTTL)
+	access, jti, err := middleware.GenerateAccessToken(middleware.CurrentUser{
+		ID:                     u.ID,
+		Email:                  u.Email,
+		Role:                   u.Role,
+		ExpiresAt:              accessExpiresAt,
+		PasswordChangeRequired: u.MustChangePassword,
+	}, s.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	refresh := hex.EncodeToString(b)
+
+	session := &model.RefreshToken{
+		UserID:          u.ID,
+		TokenHash:       hashToken(refresh),
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExpiresAt.UTC(),
+		ExpiresAt:       now.Add(refreshTTL).UTC(),
+	}
+	if rotated == nil {
+		err = s.Repo.CreateRefreshToken(session)
+	} else {
+		var ok bool
+		ok, err = s.Repo.RotateRefreshToken(rotated.ID, session)
+		if err == nil && !ok {
+			// lost a race against another refresh with the same token
+			err = utils.ErrInvalidRefreshToken
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponse{
+		Token:                  access,
+		RefreshToken:           refresh,
+		ExpiresIn:              int64(accessTTL / time.Second),
+		PasswordChangeRequired: u.MustChangePassword,
+	}, nil
+}
+
+// hashToken returns the hex SHA-256 under which a refresh token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // IsValidEmail returns true if the email has a valid format
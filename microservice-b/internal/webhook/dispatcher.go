@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultInterval is how often due deliveries are looked for; published events
+// are delivered right away
+const DefaultInterval = 5 * time.Second
+
+// DefaultMaxAttempts is how often a delivery is attempted before it is dead
+const DefaultMaxAttempts = 8
+
+const (
+	firstRetry  = 30 * time.Second // backoff after the first failed attempt, doubled after every further one
+	maxBackoff  = time.Hour
+	timeout     = 10 * time.Second // per attempt
+	batchSize   = 50
+	maxErrorLen = 1024
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the signature of a body sent at timestamp (Unix seconds):
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of a body sent at
+// timestamp. Receivers should also reject timestamps too far from their clock.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Dispatcher queues events for the webhooks subscribed to them and delivers
+// them. Deliveries are stored before they are attempted, so they survive
+// restarts; failed attempts are retried with exponential backoff until
+// maxAttempts, after which the delivery is dead. Deliveries are attempted one
+// at a time, by a single instance.
+type Dispatcher struct {
+	repo        *repository.WebhookRepository
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	now         func() time.Time
+	wake        chan struct{}
+}
+
+func New(repo *repository.WebhookRepository, interval time.Duration, maxAttempts int) *Dispatcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		// a redirect is answered like any other non-2xx status
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &Dispatcher{
+		repo:        repo,
+		client:      client,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Publish queues an event for every enabled webhook subscribed to eventType.
+// Failures are logged only, they must not fail what caused the event.
+func (d *Dispatcher) Publish(eventType string, data interface{}) {
+	if d == nil {
+		return
+	}
+	if err := d.publish(eventType, data); err != nil {
+		log.Printf("webhook: failed to queue %s event: %v", eventType, err)
+	}
+}
+
+func (d *Dispatcher) publish(eventType string, data interface{}) error {
+	hooks, err := d.repo.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	var targets []model.Webhook
+	for _, w := range hooks {
+		if w.Enabled && w.Subscribed(eventType) {
+			targets = append(targets, w)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	now := d.now()
+	event, payload, err := newEvent(eventType, data, now)
+	if err != nil {
+		return err
+	}
+	for _, w := range targets {
+		del := &model.WebhookDelivery{WebhookID: w.ID, EventID: event.ID, EventType: eventType, Payload: payload, NextAttemptAt: &now}
+		if err := d.repo.Enqueue(del); err != nil {
+			return err
+		}
+	}
+	d.notify()
+	return nil
+}
+
+// Redeliver queues a dead delivery again with a fresh set of attempts and
+// reports whether it was dead
+func (d *Dispatcher) Redeliver(id uint64) (bool, error) {
+	ok, err := d.repo.Redeliver(id, d.now())
+	if ok {
+		d.notify()
+	}
+	return ok, err
+}
+
+// notify wakes the worker up for deliveries that just became due
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Test delivers a webhook.test event to w right away, whether w is enabled or
+// not, and returns the delivery. It is attempted once: a failed test is dead.
+func (d *Dispatcher) Test(ctx context.Context, w *model.Webhook) (*model.WebhookDelivery, error) {
+	event, payload, err := newEvent(model.EventWebhookTest, map[string]interface{}{"webhook_id": w.ID, "name": w.Name}, d.now())
+	if err != nil {
+		return nil, err
+	}
+	// without a next attempt the delivery is never due for the worker
+	del := &model.WebhookDelivery{WebhookID: w.ID, EventID: event.ID, EventType: event.Type, Payload: payload}
+	if err := d.repo.Enqueue(del); err != nil {
+		return nil, err
+	}
+	if err := d.attempt(ctx, w, del, 1); err != nil {
+		return nil, err
+	}
+	return del, nil
+}
+
+// Start attempts the due deliveries every interval, and whenever an event is
+// published, until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if err := d.DeliverDue(ctx); err != nil {
+			log.Printf("webhook: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts the deliveries that are due, until none is left
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for {
+		due, err := d.repo.DueDeliveries(d.now(), batchSize)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		hooks, err := d.repo.ListWebhooks()
+		if err != nil {
+			return err
+		}
+		byID := make(map[uint64]*model.Webhook, len(hooks))
+		for i := range hooks {
+			byID[hooks[i].ID] = &hooks[i]
+		}
+		for i := range due {
+			if ctx.Err() != nil {
+				return nil
+			}
+			del := &due[i]
+			w := byID[del.WebhookID]
+			if w == nil || !w.Enabled {
+				// kept for a manual retry once the webhook is enabled again
+				d.giveUp(del, "webhook disabled")
+				if err := d.repo.RecordAttempt(del); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.attempt(ctx, w, del, d.maxAttempts); err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// attempt POSTs a delivery to w and records the outcome: delivered, pending with
+// the next attempt after the backoff, or dead after maxAttempts
+func (d *Dispatcher) attempt(ctx context.Context, w *model.Webhook, del *model.WebhookDelivery, maxAttempts int) error {
+	del.Attempts++
+	code, err := d.post(ctx, w, del)
+	now := d.now()
+	del.LastStatusCode = code
+	switch {
+	case err == nil:
+		del.Status = model.DeliveryDelivered
+		del.NextAttemptAt, del.LastError = nil, nil
+		del.DeliveredAt = &now
+	case del.Attempts >= maxAttempts:
+		d.giveUp(del, err.Error())
+		log.Printf("webhook: delivery %d of %s event %s to webhook %d is dead after %d attempts: %v",
+			del.ID, del.EventType, del.EventID, w.ID, del.Attempts, err)
+	default:
+		next := now.Add(backoff(del.Attempts))
+		msg := truncate(err.Error())
+		del.Status = model.DeliveryPending
+		del.NextAttemptAt, del.LastError = &next, &msg
+	}
+	return d.repo.RecordAttempt(del)
+}
+
+func (d *Dispatcher) giveUp(del *model.WebhookDelivery, reason string) {
+	msg := truncate(reason)
+	del.Status = model.DeliveryDead
+	del.NextAttemptAt, del.LastError = nil, &msg
+}
+
+// post sends a delivery and returns the status code of the answer, if any, and
+// an error unless it is 2xx
+func (d *Dispatcher) post(ctx context.Context, w *model.Webhook, del *model.WebhookDelivery) (*int, error) {
+	ts := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "microservice-b-webhooks")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(del.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, ts, del.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// drain a bounded part of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	code := resp.StatusCode
+	if code < 200 || code > 299 {
+		return &code, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return &code, nil
+}
+
+// backoff returns the wait after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	b := firstRetry
+	for i := 1; i < attempts && b < maxBackoff; i++ {
+		b *= 2
+	}
+	if b > maxBackoff {
+		b = maxBackoff
+	}
+	return b
+}
+
+func newEvent(eventType string, data interface{}, at time.Time) (model.WebhookEvent, []byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return model.WebhookEvent{}, nil, err
+	}
+	event := model.WebhookEvent{ID: hex.EncodeToString(id), Type: eventType, CreatedAt: at.UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	return event, payload, err
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLen {
+		return s[:maxErrorLen]
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"microservice-b/internal/repository"
+	"microservice-b/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "whsec_0123456789abcdef"
+
+// receiver is a webhook endpoint that verifies signatures and answers with status
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	received []*http.Request
+	bodies   [][]byte
+	verified []bool
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	rc := &receiver{status: status}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.received = append(rc.received, r)
+		rc.bodies = append(rc.bodies, body)
+		rc.verified = append(rc.verified, Verify(secret, ts, body, r.Header.Get(HeaderSignature)))
+		w.WriteHeader(rc.status)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func newTestDispatcher(t *testing.T, maxAttempts int) (*Dispatcher, sqlmock.Sqlmock, time.Time) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := New(repository.NewWebhookRepository(sqlx.NewDb(db, "mysql")), time.Minute, maxAttempts)
+	now := time.Date(2025, 9, 8, 10, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	return d, mock, now
+}
+
+func webhookRows(url string, enabled bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+		AddRow(1, "on-call", url, "alert.firing,device.offline", secret, enabled)
+}
+
+func dueRows(attempts int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts"}).
+		AddRow(12, 1, "9f86d081884c7d659a2feaa0c55ad015", model.EventAlertFiring, []byte(`{"type":"alert.firing"}`), model.DeliveryPending, attempts)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"alert.firing"}`)
+	sig := Sign(secret, 1757325600, body)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", sig)
+	assert.True(t, Verify(secret, 1757325600, body, sig))
+	assert.False(t, Verify(secret, 1757325601, body, sig), "other timestamp")
+	assert.False(t, Verify("whsec_other_secret", 1757325600, body, sig), "other secret")
+	assert.False(t, Verify(secret, 1757325600, []byte(`{"type":"alert.resolved"}`), sig), "other body")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 32*time.Minute, backoff(7))
+	assert.Equal(t, time.Hour, backoff(8))
+	assert.Equal(t, time.Hour, backoff(100))
+}
+
+func TestDispatcher_PublishQueuesForSubscribedWebhooks(t *testing.T) {
+	d, mock, now := newTestDispatcher(t, 3)
+
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "enabled"}).
+			AddRow(1, "on-call", "http://a", "alert.firing", secret, true).
+			AddRow(2, "chat", "http://b", "device.offline", secret, true).
+			AddRow(3, "off", "http://c", "alert.firing", secret, false))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(uint64(1), sqlmock.AnyArg(), model.EventAlertFiring, sqlmock.AnyArg(), model.DeliveryPending, &now).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+
+	d.Publish(model.EventAlertFiring, model.Alert{ID: 5, RuleName: "hot boiler"})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	select {
+	case <-d.wake:
+	default:
+		t.Error("the worker was not woken up")
+	}
+}
+
+func TestDispatcher_DeliverDue_SignedDelivery(t *testing.T) {
+	rc := newReceiver(t, http.StatusNoContent)
+	d, mock, now := newTestDispatcher(t, 3)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries WHERE status = \\? AND next_attempt_at <= \\?").
+		WithArgs(model.DeliveryPending, now, batchSize).
+		WillReturnRows(dueRows(0))
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").WillReturnRows(webhookRows(rc.URL, true))
+	code := http.StatusNoContent
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryDelivered, 1, nil, &code, nil, &now, uint64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, d.DeliverDue(context.Background()))
+
+	require.Len(t, rc.received, 1)
+	r := rc.received[0]
+	assert.True(t, rc.verified[0], "signature does not verify")
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, model.EventAlertFiring, r.Header.Get(HeaderEvent))
+	assert.Equal(t, "12", r.Header.Get(HeaderDelivery))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), r.Header.Get(HeaderTimestamp))
+	assert.JSONEq(t, `{"type":"alert.firing"}`, string(rc.bodies[0]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_DeliverDue_RetriesWithBackoff(t *testing.T) {
+	rc := newReceiver(t, http.StatusServiceUnavailable)
+	d, mock, now := newTestDispatcher(t, 3)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries").WillReturnRows(dueRows(1))
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").WillReturnRows(webhookRows(rc.URL, true))
+	code, next, msg := http.StatusServiceUnavailable, now.Add(time.Minute), "receiver answered 503 Service Unavailable"
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryPending, 2, &next, &code, &msg, nil, uint64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, d.DeliverDue(context.Background()))
+	assert.Len(t, rc.received, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_DeliverDue_DeadAfterMaxAttempts(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	d, mock, _ := newTestDispatcher(t, 3)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries").WillReturnRows(dueRows(2))
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").WillReturnRows(webhookRows(rc.URL, true))
+	code, msg := http.StatusInternalServerError, "receiver answered 500 Internal Server Error"
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryDead, 3, nil, &code, &msg, nil, uint64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, d.DeliverDue(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_DeliverDue_DisabledWebhook(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	d, mock, _ := newTestDispatcher(t, 3)
+
+	mock.ExpectQuery("SELECT \\* FROM webhook_deliveries").WillReturnRows(dueRows(0))
+	mock.ExpectQuery("SELECT \\* FROM webhooks ORDER BY id").WillReturnRows(webhookRows(rc.URL, false))
+	msg := "webhook disabled"
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryDead, 0, nil, nil, &msg, nil, uint64(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, d.DeliverDue(context.Background()))
+	assert.Empty(t, rc.received)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcher_Test(t *testing.T) {
+	rc := newReceiver(t, http.StatusBadRequest)
+	d, mock, _ := newTestDispatcher(t, 3)
+
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(uint64(1), sqlmock.AnyArg(), model.EventWebhookTest, sqlmock.AnyArg(), model.DeliveryPending, nil).
+		WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET").
+		WithArgs(model.DeliveryDead, 1, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint64(13)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	del, err := d.Test(context.Background(), &model.Webhook{ID: 1, Name: "on-call", URL: rc.URL, Secret: secret})
+	require.NoError(t, err)
+
+	// a failed test is not retried
+	assert.Equal(t, model.DeliveryDead, del.Status)
+	require.NotNil(t, del.LastStatusCode)
+	assert.Equal(t, http.StatusBadRequest, *del.LastStatusCode)
+	require.Len(t, rc.received, 1)
+	assert.True(t, rc.verified[0])
+	var event model.WebhookEvent
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &event))
+	assert.Equal(t, model.EventWebhookTest, event.Type)
+	assert.Equal(t, del.EventID, event.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"microservice-b/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	auditAffectedKey = "audit_affected"
+	auditUserKey     = "audit_user"
+)
+
+// AuditRecorder stores audit events
+type AuditRecorder interface {
+	Insert(e *model.AuditEvent) error
+}
+
+// Audit records every request it wraps as an audit event: the caller from the
+// JWT claims, the route with its path and query parameters, the response status
+// and the rows the handler reported as affected. Must run after JWTMiddleware;
+// on public routes handlers name the caller with SetAuditUser. Failing to record
+// an event is logged and does not fail the request.
+func Audit(recorder AuditRecorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err != nil {
+				// let the error handler write the response so its status is recorded
+				c.Error(err)
+			}
+
+			e := &model.AuditEvent{
+				Action:   c.Request().Method + " " + c.Path(),
+				Path:     c.Request().URL.Path,
+				Params:   auditParams(c),
+				Status:   c.Response().Status,
+				RemoteIP: c.RealIP(),
+			}
+			u, ok := UserFromContext(c)
+			if !ok {
+				u, ok = c.Get(auditUserKey).(CurrentUser)
+			}
+			if ok {
+				if u.ID != 0 {
+					e.UserID = &u.ID
+				}
+				e.Email, e.Role = u.Email, u.Role
+			}
+			if n, ok := c.Get(auditAffectedKey).(int64); ok {
+				e.Affected = &n
+			}
+			if rerr := recorder.Insert(e); rerr != nil {
+				c.Logger().Errorf("audit: %v", rerr)
+			}
+			return err
+		}
+	}
+}
+
+// SetAffected reports the number of rows a request changed to the audit log
+func SetAffected(c echo.Context, n int64) {
+	c.Set(auditAffectedKey, n)
+}
+
+// SetAuditUser names the caller of a request without a JWT, such as a login
+func SetAuditUser(c echo.Context, u CurrentUser) {
+	c.Set(auditUserKey, u)
+}
+
+// auditParams encodes the path and query parameters of a request as JSON, or
+// returns nil when there are none
+func auditParams(c echo.Context) *string {
+	params := make(map[string]string)
+	for _, name := range c.ParamNames() {
+		params[name] = c.Param(name)
+	}
+	for name, values := range c.QueryParams() {
+		if len(values) > 0 {
+			params[name] = values[0]
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
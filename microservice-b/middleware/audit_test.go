@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"microservice-b/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	events []*model.AuditEvent
+}
+
+func (r *recorder) Insert(e *model.AuditEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestAudit_RecordsCallerAndAffectedRows(t *testing.T) {
+	rec := &recorder{}
+	e := echo.New()
+	e.DELETE("/api/sensors/:id", func(c echo.Context) error {
+		SetAffected(c, 3)
+		return c.NoContent(http.StatusOK)
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// stands in for JWTMiddleware
+			c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"user_id": float64(7), "email": "a@b.c", "role": "admin"}})
+			return next(c)
+		}
+	}, Audit(rec))
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/sensors/5?id1=A", nil))
+
+	require.Len(t, rec.events, 1)
+	ev := rec.events[0]
+	assert.Equal(t, "DELETE /api/sensors/:id", ev.Action)
+	assert.Equal(t, "/api/sensors/5", ev.Path)
+	require.NotNil(t, ev.Params)
+	assert.JSONEq(t, `{"id": "5", "id1": "A"}`, *ev.Params)
+	assert.Equal(t, http.StatusOK, ev.Status)
+	require.NotNil(t, ev.UserID)
+	assert.Equal(t, uint64(7), *ev.UserID)
+	assert.Equal(t, "a@b.c", ev.Email)
+	assert.Equal(t, "admin", ev.Role)
+	require.NotNil(t, ev.Affected)
+	assert.Equal(t, int64(3), *ev.Affected)
+}
+
+func TestAudit_RecordsErrorsAndPublicCallers(t *testing.T) {
+	rec := &recorder{}
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		SetAuditUser(c, CurrentUser{Email: "a@b.c"})
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}, Audit(rec))
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Len(t, rec.events, 1)
+	ev := rec.events[0]
+	assert.Equal(t, http.StatusUnauthorized, ev.Status)
+	assert.Nil(t, ev.UserID)
+	assert.Equal(t, "a@b.c", ev.Email)
+	assert.Nil(t, ev.Params)
+	assert.Nil(t, ev.Affected)
+}
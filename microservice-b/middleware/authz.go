@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"fmt"
+	"microservice-b/model"
+	"microservice-b/utils"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Permission is an action a role may be granted
+type Permission string
+
+const (
+	PermRead   Permission = "read"   // query readings, devices and history
+	PermWrite  Permission = "write"  // edit, restore and revert readings
+	PermDelete Permission = "delete" // delete readings
+	PermAdmin  Permission = "admin"  // device commands, retention, audit and users
+	PermSelf   Permission = "self"   // the caller's own account under /api/me
+)
+
+// RolePermissions lists the permissions of each role
+var RolePermissions = map[string][]Permission{
+	"admin":   {PermSelf, PermRead, PermWrite, PermDelete, PermAdmin},
+	"analyst": {PermSelf, PermRead},
+}
+
+// ScopePermissions maps each API key scope to the permission it grants. Keys
+// never hold PermSelf or PermAdmin.
+var ScopePermissions = map[string]Permission{
+	model.ScopeSensorsRead:   PermRead,
+	model.ScopeSensorsWrite:  PermWrite,
+	model.ScopeSensorsDelete: PermDelete,
+}
+
+// Allowed reports whether role has permission p
+func Allowed(role string, p Permission) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the caller has permission p. Callers with an API key are
+// limited to its scopes and, unless it is a service account key, to the
+// current role of its owner.
+func (u CurrentUser) Can(p Permission) bool {
+	if u.APIKeyID == 0 {
+		return Allowed(u.Role, p)
+	}
+	for _, scope := range u.Scopes {
+		if ScopePermissions[scope] == p {
+			return u.ID == 0 || Allowed(u.Role, p)
+		}
+	}
+	return false
+}
+
+// AllowsID1 reports whether the caller may read the sensors selected by an id1
+// filter. Callers whose API key has an id1 allowlist must filter by one of its
+// id1s; other callers may read everything.
+func (u CurrentUser) AllowsID1(id1 string) bool {
+	if len(u.ID1Allowlist) == 0 {
+		return true
+	}
+	for _, allowed := range u.ID1Allowlist {
+		if id1 == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutePermissions maps a route, as "METHOD path" with Echo path parameters
+// (e.g. "DELETE /api/sensors"), to the permission it requires
+type RoutePermissions map[string]Permission
+
+// Authorize rejects callers whose role lacks the permission the matched route
+// requires. Routes missing from routes require PermAdmin, so a route added
+// without a permission is closed rather than open. Callers who must change their
+// password are limited to PermSelf routes. Must run after JWTMiddleware.
+func Authorize(routes RoutePermissions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			u, ok := UserFromContext(c)
+			if !ok {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized", 3001, "")
+			}
+			p, ok := routes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				p = PermAdmin
+			}
+			if u.PasswordChangeRequired && p != PermSelf {
+				return utils.ErrorResponse(c, http.StatusForbidden, "password change required", 3003, "change the password via POST /api/me/password")
+			}
+			if !u.Can(p) {
+				if u.APIKeyID != 0 {
+					return utils.ErrorResponse(c, http.StatusForbidden, "forbidden", 3002, fmt.Sprintf("api key lacks %s permission", p))
+				}
+				return utils.ErrorResponse(c, http.StatusForbidden, "forbidden", 3002, fmt.Sprintf("role %q lacks %s permission", u.Role, p))
+			}
+			return next(c)
+		}
+	}
+}
+
+// ID1Routes lists the routes, keyed like RoutePermissions, whose readings are
+// selected by the id1 query parameter
+type ID1Routes map[string]bool
+
+// RestrictID1 limits callers whose API key has an id1 allowlist to routes in
+// routes, called with an id1 query parameter from the allowlist. Must run after
+// JWTMiddleware.
+func RestrictID1(routes ID1Routes) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			u, _ := UserFromContext(c)
+			if len(u.ID1Allowlist) == 0 {
+				return next(c)
+			}
+			if !routes[c.Request().Method+" "+c.Path()] {
+				return utils.ErrorResponse(c, http.StatusForbidden, "forbidden", 3004, "api key is restricted to id1 filtered sensor routes")
+			}
+			if u.AllowsID1(c.QueryParam("id1")) {
+				return next(c)
+			}
+			return utils.ErrorResponse(c, http.StatusForbidden, "forbidden", 3004, "api key requires id1 to be one of "+strings.Join(u.ID1Allowlist, ", "))
+		}
+	}
+}
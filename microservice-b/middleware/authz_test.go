@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"encoding/json"
+	"microservice-b/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	routes := RoutePermissions{
+		"GET /api/sensors":    PermRead,
+		"DELETE /api/sensors": PermDelete,
+	}
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+	for _, tc := range []struct {
+		role, method, path string
+		want               int
+	}{
+		{"analyst", http.MethodGet, "/api/sensors", http.StatusOK},
+		{"analyst", http.MethodDelete, "/api/sensors", http.StatusForbidden},
+		{"admin", http.MethodDelete, "/api/sensors", http.StatusOK},
+		// routes without a declared permission are admin only
+		{"analyst", http.MethodGet, "/api/unlisted", http.StatusForbidden},
+		{"admin", http.MethodGet, "/api/unlisted", http.StatusOK},
+		{"", http.MethodGet, "/api/sensors", http.StatusForbidden},
+	} {
+		e := echo.New()
+		api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"role": tc.role}})
+				return next(c)
+			}
+		}, Authorize(routes))
+		api.GET("/sensors", ok)
+		api.DELETE("/sensors", ok)
+		api.GET("/unlisted", ok)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.want, rec.Code, "%s %s %s", tc.role, tc.method, tc.path)
+
+		if tc.want == http.StatusForbidden {
+			var body model.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "forbidden", body.Error)
+			assert.Equal(t, 3002, body.Code)
+		}
+	}
+}
+
+func TestAuthorize_PasswordChangeRequired(t *testing.T) {
+	routes := RoutePermissions{
+		"GET /api/sensors":      PermRead,
+		"POST /api/me/password": PermSelf,
+	}
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e := echo.New()
+	api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"role": "admin", "pcr": true}})
+			return next(c)
+		}
+	}, Authorize(routes))
+	api.GET("/sensors", ok)
+	api.POST("/me/password", ok)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sensors", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body model.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 3003, body.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/me/password", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCurrentUser_Can(t *testing.T) {
+	analystKey := CurrentUser{ID: 7, Role: "analyst", APIKeyID: 1, Scopes: []string{"sensors:read", "sensors:write"}}
+	assert.True(t, analystKey.Can(PermRead))
+	// the owner's role still applies
+	assert.False(t, analystKey.Can(PermWrite))
+	// keys never manage accounts
+	assert.False(t, analystKey.Can(PermSelf))
+
+	serviceKey := CurrentUser{APIKeyID: 2, Scopes: []string{"sensors:write"}}
+	assert.True(t, serviceKey.Can(PermWrite))
+	assert.False(t, serviceKey.Can(PermRead))
+	assert.False(t, serviceKey.Can(PermAdmin))
+
+	assert.True(t, CurrentUser{Role: "admin"}.Can(PermAdmin))
+}
+
+func TestRestrictID1(t *testing.T) {
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	for _, tc := range []struct {
+		allowlist []string
+		target    string
+		want      int
+	}{
+		{nil, "/api/sensors/1/history", http.StatusOK},
+		{[]string{"A", "B"}, "/api/sensors?id1=B", http.StatusOK},
+		{[]string{"A", "B"}, "/api/sensors?id1=C", http.StatusForbidden},
+		{[]string{"A", "B"}, "/api/sensors", http.StatusForbidden},
+		// routes not filtered by id1 are closed to restricted keys
+		{[]string{"A"}, "/api/sensors/1/history?id1=A", http.StatusForbidden},
+	} {
+		e := echo.New()
+		api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set(apiKeyUserKey, CurrentUser{APIKeyID: 1, Scopes: []string{"sensors:read"}, ID1Allowlist: tc.allowlist})
+				return next(c)
+			}
+		}, RestrictID1(ID1Routes{"GET /api/sensors": true}))
+		api.GET("/sensors", ok)
+		api.GET("/sensors/:id/history", ok)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		assert.Equal(t, tc.want, rec.Code, tc.target)
+	}
+}
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -12,27 +16,204 @@ import (
 
 // GenerateJWT generates a signed JWT token
 func GenerateJWT(userID uint64, email, role, secret string, expiryHours int) (string, error) {
+	u := CurrentUser{ID: userID, Email: email, Role: role, ExpiresAt: time.Now().Add(time.Duration(expiryHours) * time.Hour)}
+	token, _, err := GenerateAccessToken(u, secret)
+	return token, err
+}
+
+// GenerateAccessToken generates a signed access token for u expiring at
+// u.ExpiresAt and returns it with its jti, under which it can be revoked
+func GenerateAccessToken(u CurrentUser, secret string) (string, string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	jti := hex.EncodeToString(b)
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"role":    role,
-		"exp":     time.Now().Add(time.Duration(expiryHours) * time.Hour).Unix(),
+		"user_id": u.ID,
+		"email":   u.Email,
+		"role":    u.Role,
+		"jti":     jti,
+		"exp":     u.ExpiresAt.Unix(),
+	}
+	if u.PasswordChangeRequired {
+		claims["pcr"] = true
 	}
-	fmt.Println("secret---", secret)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	signed, err := token.SignedString([]byte(secret))
+	return signed, jti, err
+}
+
+// RevocationList reports whether an access token has been revoked by its jti
+type RevocationList interface {
+	IsRevoked(jti string) (bool, error)
 }
 
-// JWTMiddleware returns Echo JWT middleware configured with the secret
-func JWTMiddleware(secret string) echo.MiddlewareFunc {
-	return echojwt.WithConfig(echojwt.Config{
-		SigningKey:  []byte(secret),
+// APIKeyAuthenticator resolves an API key to the caller it acts for
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key string) (CurrentUser, error)
+}
+
+// APIKeyPrefix starts every API key, so keys sent as Bearer tokens can be told
+// apart from JWTs
+const APIKeyPrefix = "smk_"
+
+const apiKeyUserKey = "api_key_user"
+
+// JWTMiddleware returns Echo JWT middleware configured with the secret. Tokens
+// without a jti or with a revoked one are rejected. When keys is not nil, an API
+// key in the X-API-Key header or as a Bearer token is accepted instead of a JWT.
+func JWTMiddleware(secret string, revoked RevocationList, keys APIKeyAuthenticator) echo.MiddlewareFunc {
+	unauthorized := func(c echo.Context, err error) error {
+		c.Logger().Errorf("JWT validation failed: %v", err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{
+			"error": "unauthorized",
+		})
+	}
+	validate := echojwt.WithConfig(echojwt.Config{
 		TokenLookup: "header:Authorization:Bearer ",
-		ErrorHandler: func(c echo.Context, err error) error {
-			c.Logger().Errorf("JWT validation failed: %v", err)
-			return c.JSON(http.StatusUnauthorized, echo.Map{
-				"error": "unauthorized",
-			})
+		// echo-jwt parses with golang-jwt/v5 by default; parse with v4 so the
+		// token stored under "user" is the type UserFromContext reads
+		ParseTokenFunc: func(c echo.Context, auth string) (interface{}, error) {
+			return parseToken(auth, secret)
 		},
+		ErrorHandler: unauthorized,
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := validate(func(c echo.Context) error {
+			u, _ := UserFromContext(c)
+			if err := checkRevoked(u, revoked); err != nil {
+				return unauthorized(c, err)
+			}
+			return next(c)
+		})
+		return func(c echo.Context) error {
+			key := apiKey(c.Request())
+			if key == "" || keys == nil {
+				return withJWT(c)
+			}
+			u, err := keys.AuthenticateAPIKey(key)
+			if err != nil {
+				return unauthorized(c, err)
+			}
+			c.Set(apiKeyUserKey, u)
+			return next(c)
+		}
+	}
+}
+
+// apiKey returns the API key of a request, if any
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer "); ok && strings.HasPrefix(token, APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// checkRevoked fails for tokens that cannot be revoked or have been
+func checkRevoked(u CurrentUser, revoked RevocationList) error {
+	if u.TokenID == "" {
+		return errors.New("token has no jti")
+	}
+	isRevoked, err := revoked.IsRevoked(u.TokenID)
+	if err != nil {
+		return err
+	}
+	if isRevoked {
+		return errors.New("token has been revoked")
+	}
+	return nil
+}
+
+// CurrentUser is the caller identified by the JWT claims or API key
+type CurrentUser struct {
+	ID        uint64 // zero for service account API keys
+	Email     string
+	Role      string
+	TokenID   string    // jti of the access token
+	ExpiresAt time.Time // expiry of the access token
+	// PasswordChangeRequired restricts the caller to PermSelf until the
+	// password has been changed
+	PasswordChangeRequired bool
+
+	APIKeyID     uint64   // set when authenticated with an API key
+	Scopes       []string // scopes of the API key
+	ID1Allowlist []string // id1s the API key is restricted to, if any
+}
+
+// UserFromContext returns the caller authenticated by JWTMiddleware
+func UserFromContext(c echo.Context) (CurrentUser, bool) {
+	if u, ok := c.Get(apiKeyUserKey).(CurrentUser); ok {
+		return u, true
+	}
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return CurrentUser{}, false
+	}
+	return userFromToken(token), true
+}
+
+// ParseJWT validates a signed token outside of Echo, e.g. for gRPC calls, and
+// returns its caller. Like JWTMiddleware it rejects revoked tokens.
+func ParseJWT(tokenString, secret string, revoked RevocationList) (CurrentUser, error) {
+	token, err := parseToken(tokenString, secret)
+	if err != nil {
+		return CurrentUser{}, err
+	}
+	u := userFromToken(token)
+	if err := checkRevoked(u, revoked); err != nil {
+		return CurrentUser{}, err
+	}
+	return u, nil
+}
+
+// parseToken validates the signature and expiry of an HS256 token
+func parseToken(tokenString, secret string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
 	})
 }
+
+func userFromToken(token *jwt.Token) CurrentUser {
+	claims, _ := token.Claims.(jwt.MapClaims)
+	var u CurrentUser
+	// numeric claims decode as float64
+	if id, ok := claims["user_id"].(float64); ok {
+		u.ID = uint64(id)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		u.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	u.Email, _ = claims["email"].(string)
+	u.Role, _ = claims["role"].(string)
+	u.TokenID, _ = claims["jti"].(string)
+	u.PasswordChangeRequired, _ = claims["pcr"].(bool)
+	return u
+}
+
+// QueryToken moves an access_token query parameter into the Authorization
+// header, for clients such as browser EventSource and WebSocket that cannot set
+// headers. Must run before JWTMiddleware; the parameter is removed from the
+// request so it is not logged or audited.
+func QueryToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		q := r.URL.Query()
+		if !q.Has("access_token") {
+			return next(c)
+		}
+		if r.Header.Get(echo.HeaderAuthorization) == "" {
+			r.Header.Set(echo.HeaderAuthorization, "Bearer "+q.Get("access_token"))
+		}
+		q.Del("access_token")
+		r.URL.RawQuery = q.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		return next(c)
+	}
+}
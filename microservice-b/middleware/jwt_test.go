@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type revocations map[string]bool
+
+func (r revocations) IsRevoked(jti string) (bool, error) { return r[jti], nil }
+
+type apiKeys map[string]CurrentUser
+
+func (k apiKeys) AuthenticateAPIKey(key string) (CurrentUser, error) {
+	u, ok := k[key]
+	if !ok {
+		return CurrentUser{}, errors.New("invalid api key")
+	}
+	return u, nil
+}
+
+func TestJWTMiddleware_Revocation(t *testing.T) {
+	const secret = "test-secret"
+	live, liveJTI, err := GenerateAccessToken(CurrentUser{ID: 1, Email: "a@b.c", Role: "admin", ExpiresAt: time.Now().Add(time.Minute)}, secret)
+	require.NoError(t, err)
+	revoked, revokedJTI, err := GenerateAccessToken(CurrentUser{ID: 1, Email: "a@b.c", Role: "admin", ExpiresAt: time.Now().Add(time.Minute)}, secret)
+	require.NoError(t, err)
+	// tokens issued before jti claims existed cannot be revoked and are refused
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	e := echo.New()
+	var caller CurrentUser
+	e.GET("/api", func(c echo.Context) error {
+		caller, _ = UserFromContext(c)
+		return c.NoContent(http.StatusOK)
+	}, JWTMiddleware(secret, revocations{revokedJTI: true}, nil))
+
+	for token, want := range map[string]int{live: http.StatusOK, revoked: http.StatusUnauthorized, legacy: http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code)
+	}
+	assert.Equal(t, liveJTI, caller.TokenID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), caller.ExpiresAt, 2*time.Second)
+}
+
+func TestJWTMiddleware_APIKey(t *testing.T) {
+	keys := apiKeys{"smk_1a2b3c4d_secret": {ID: 7, Role: "analyst", APIKeyID: 3, Scopes: []string{"sensors:read"}}}
+
+	for _, tc := range []struct {
+		name    string
+		keys    APIKeyAuthenticator
+		header  string
+		value   string
+		want    int
+		wantKey uint64
+	}{
+		{"header", keys, "X-API-Key", "smk_1a2b3c4d_secret", http.StatusOK, 3},
+		{"bearer", keys, "Authorization", "Bearer smk_1a2b3c4d_secret", http.StatusOK, 3},
+		{"unknown key", keys, "X-API-Key", "smk_1a2b3c4d_other", http.StatusUnauthorized, 0},
+		// routes without an authenticator only accept JWTs
+		{"keys disabled", nil, "X-API-Key", "smk_1a2b3c4d_secret", http.StatusUnauthorized, 0},
+	} {
+		e := echo.New()
+		var caller CurrentUser
+		e.GET("/api", func(c echo.Context) error {
+			caller, _ = UserFromContext(c)
+			return c.NoContent(http.StatusOK)
+		}, JWTMiddleware("test-secret", revocations{}, tc.keys))
+
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set(tc.header, tc.value)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+		assert.Equal(t, tc.wantKey, caller.APIKeyID, tc.name)
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	const secret = "test-secret"
+	token, _, err := GenerateAccessToken(CurrentUser{ID: 1, Email: "a@b.c", Role: "admin", ExpiresAt: time.Now().Add(time.Minute)}, secret)
+	require.NoError(t, err)
+
+	e := echo.New()
+	var query string
+	h := QueryToken(JWTMiddleware(secret, revocations{}, nil)(func(c echo.Context) error {
+		query = c.Request().RequestURI
+		return c.NoContent(http.StatusOK)
+	}))
+
+	for target, want := range map[string]int{
+		"/api/stream?id1=A&access_token=" + token: http.StatusOK,
+		"/api/stream?id1=A&access_token=invalid":  http.StatusUnauthorized,
+		"/api/stream?id1=A":                       http.StatusUnauthorized,
+	} {
+		query = ""
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		_ = h(e.NewContext(req, rec))
+		assert.Equal(t, want, rec.Code, target)
+		if want == http.StatusOK {
+			// the token does not end up in logs
+			assert.Equal(t, "/api/stream?id1=A", query)
+		}
+	}
+}
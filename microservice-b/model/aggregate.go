@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Gap fill modes of an aggregation
+const (
+	FillNone     = ""
+	FillNull     = "null"
+	FillPrevious = "previous"
+	FillLinear   = "linear"
+)
+
+// AggregateBuckets are the bucket sizes aggregations can be requested with
+var AggregateBuckets = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// AggregateQuery describes a time-bucketed aggregation over sensor readings
+type AggregateQuery struct {
+	Bucket    time.Duration
+	Functions []string // avg, min, max, sum, count, first, last, stddev or a percentile like p95
+	GroupBy   []string // any of id1, id2, sensor_type
+	Filters   map[string]interface{}
+	Fill      string // FillNone, FillNull, FillPrevious or FillLinear
+}
+
+// AggregatePoint is one bucket of one group; a nil value means the bucket had no data
+type AggregatePoint struct {
+	Bucket     time.Time           `json:"bucket"`
+	ID1        string              `json:"id1,omitempty"`
+	ID2        string              `json:"id2,omitempty"`
+	SensorType string              `json:"sensor_type,omitempty"`
+	Values     map[string]*float64 `json:"values"`
+}
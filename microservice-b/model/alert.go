@@ -0,0 +1,109 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Alert rule types
+const (
+	AlertThreshold = "threshold" // the value crosses Threshold in the direction of Operator
+	AlertRange     = "range"     // the value leaves [MinValue, MaxValue]
+	AlertRate      = "rate"      // the change per minute between consecutive readings crosses Threshold
+	AlertAbsence   = "absence"   // a sensor sends no reading for ForSeconds
+	AlertAnomaly   = "anomaly"   // the absolute anomaly score of a reading reaches Threshold
+)
+
+// Alert states
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertRule is evaluated against the readings of the sensors it covers. A rule
+// without SensorType, ID1 and ID2 covers every sensor. An alert fires once the
+// condition held for ForSeconds and resolves once the value is back within the
+// limit by Hysteresis.
+type AlertRule struct {
+	ID         uint64     `db:"id" json:"id"`
+	Name       string     `db:"name" json:"name"`
+	Type       string     `db:"type" json:"type"`
+	SensorType *string    `db:"sensor_type" json:"sensor_type,omitempty"`
+	ID1        *string    `db:"id1" json:"id1,omitempty"`
+	ID2        *int       `db:"id2" json:"id2,omitempty"`
+	Operator   *string    `db:"operator" json:"operator,omitempty"`   // >, >=, < or <= for threshold and rate rules
+	Threshold  *float64   `db:"threshold" json:"threshold,omitempty"` // for rate rules a change per minute, for anomaly rules a z-score
+	MinValue   *float64   `db:"min_value" json:"min_value,omitempty"`
+	MaxValue   *float64   `db:"max_value" json:"max_value,omitempty"`
+	Hysteresis float64    `db:"hysteresis" json:"hysteresis"`
+	ForSeconds int        `db:"for_seconds" json:"for_seconds"` // for absence rules the silence that fires the alert
+	Severity   string     `db:"severity" json:"severity"`
+	Enabled    bool       `db:"enabled" json:"enabled"`
+	CreatedBy  *uint64    `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Covers reports whether the rule applies to the sensor id1/id2 of sensorType
+func (r *AlertRule) Covers(sensorType, id1 string, id2 int) bool {
+	return (r.SensorType == nil || *r.SensorType == sensorType) &&
+		(r.ID1 == nil || *r.ID1 == id1) &&
+		(r.ID2 == nil || *r.ID2 == id2)
+}
+
+// Condition describes what the rule checks, leaving out its name, severity and
+// state, so changes to it can be told apart from renames
+func (r *AlertRule) Condition() string {
+	str := func(s *string) string {
+		if s == nil {
+			return "*"
+		}
+		return *s
+	}
+	num := func(f *float64) string {
+		if f == nil {
+			return "-"
+		}
+		return fmt.Sprint(*f)
+	}
+	id2 := "*"
+	if r.ID2 != nil {
+		id2 = fmt.Sprint(*r.ID2)
+	}
+	return fmt.Sprintf("%s %s %s/%s %s %s [%s,%s] h=%v for=%d",
+		r.Type, str(r.SensorType), str(r.ID1), id2, str(r.Operator), num(r.Threshold), num(r.MinValue), num(r.MaxValue), r.Hysteresis, r.ForSeconds)
+}
+
+// AlertRuleRequest is the payload for creating an alert rule; when updating
+// one, fields left out keep their value
+type AlertRuleRequest struct {
+	Name       *string  `json:"name,omitempty" example:"hot boiler"`
+	Type       *string  `json:"type,omitempty" example:"threshold" enums:"threshold,range,rate,absence,anomaly"`
+	SensorType *string  `json:"sensor_type,omitempty" example:"Temperature"`
+	ID1        *string  `json:"id1,omitempty" example:"A"`
+	ID2        *int     `json:"id2,omitempty" example:"1"`
+	Operator   *string  `json:"operator,omitempty" example:">" enums:">,>=,<,<="`
+	Threshold  *float64 `json:"threshold,omitempty" example:"80"`
+	MinValue   *float64 `json:"min_value,omitempty"`
+	MaxValue   *float64 `json:"max_value,omitempty"`
+	Hysteresis *float64 `json:"hysteresis,omitempty" example:"2"`
+	ForSeconds *int     `json:"for_seconds,omitempty" example:"60"`
+	Severity   *string  `json:"severity,omitempty" example:"critical" enums:"info,warning,critical"`
+	Enabled    *bool    `json:"enabled,omitempty"` // defaults to true
+}
+
+// Alert is an instance of a rule firing for one sensor
+type Alert struct {
+	ID         uint64     `db:"id" json:"id"`
+	RuleID     uint64     `db:"rule_id" json:"rule_id"`
+	RuleName   string     `db:"rule_name" json:"rule_name"`
+	Severity   string     `db:"severity" json:"severity"`
+	ID1        string     `db:"id1" json:"id1"`
+	ID2        int        `db:"id2" json:"id2"`
+	SensorType string     `db:"sensor_type" json:"sensor_type,omitempty"`
+	State      string     `db:"state" json:"state" example:"firing"`
+	Value      *float64   `db:"value" json:"value,omitempty"` // the value, rate or absolute anomaly score that fired the alert; none for absence rules
+	Message    string     `db:"message" json:"message" example:"value 85.2 > 80"`
+	FiredAt    time.Time  `db:"fired_at" json:"fired_at"`
+	ResolvedAt *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+}
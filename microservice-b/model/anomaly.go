@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Anomaly detection methods; each compares a reading with a baseline of its
+// sensor built from the readings before it
+const (
+	AnomalyRolling  = "rolling"  // mean and stddev of the last readings
+	AnomalyEWMA     = "ewma"     // exponentially weighted mean and variance
+	AnomalySeasonal = "seasonal" // exponentially weighted mean and variance of the readings of the same hour of day (UTC)
+)
+
+// AnomalyScore is how far a reading deviates from the baselines of its
+// sensor, as z-scores: the distance from the baseline mean in stddevs
+type AnomalyScore struct {
+	ID1        string    `db:"id1" json:"id1"`
+	ID2        int       `db:"id2" json:"id2"`
+	SensorType string    `db:"sensor_type" json:"sensor_type"`
+	Value      float64   `db:"value" json:"value"`
+	TS         time.Time `db:"ts" json:"ts"`
+	Method     string    `db:"method" json:"method" example:"ewma"` // the method the reading deviates most by
+	Score      float64   `db:"score" json:"score" example:"-5.2"`   // its z-score, negative below the baseline
+	Expected   float64   `db:"expected" json:"expected"`            // its baseline mean
+	// the z-score by method; nil while the baseline warms up or has no spread
+	ZRolling  *float64 `db:"z_rolling" json:"z_rolling,omitempty"`
+	ZEWMA     *float64 `db:"z_ewma" json:"z_ewma,omitempty"`
+	ZSeasonal *float64 `db:"z_seasonal" json:"z_seasonal,omitempty"`
+
+	Anomalous bool `db:"-" json:"-"` // the score reached the detector threshold
+}
+
+// Anomaly is a stored anomalous reading
+type Anomaly struct {
+	ID uint64 `db:"id" json:"id"`
+	AnomalyScore
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
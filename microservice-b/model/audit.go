@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AuditEvent records one authenticated API request: who made it, what it did
+// and how it ended
+type AuditEvent struct {
+	ID        uint64    `db:"id" json:"id"`
+	UserID    *uint64   `db:"user_id" json:"user_id,omitempty"`
+	Email     string    `db:"email" json:"email"`
+	Role      string    `db:"role" json:"role,omitempty"`
+	Action    string    `db:"action" json:"action" example:"DELETE /api/sensors"` // method and route
+	Path      string    `db:"path" json:"path" example:"/api/sensors"`
+	Params    *string   `db:"params" json:"params,omitempty" example:"{\"id1\":\"A\"}"` // path and query parameters as JSON
+	Status    int       `db:"status" json:"status" example:"200"`
+	Affected  *int64    `db:"affected" json:"affected,omitempty"` // rows changed, when the action changes data
+	RemoteIP  string    `db:"remote_ip" json:"remote_ip,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// SetFrequencyRequest changes how often a generator produces readings of a sensor
+type SetFrequencyRequest struct {
+	Frequency string `json:"frequency" example:"2s"` // Go duration
+}
+
+// ValueModelRequest selects the value model a generator uses for a sensor
+type ValueModelRequest struct {
+	Type   string            `json:"type" example:"sine"`
+	Seed   *int64            `json:"seed,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Device is a sensor known to the registry
+type Device struct {
+	ID         uint64    `db:"id" json:"id"`
+	ID1        string    `db:"id1" json:"id1"`
+	ID2        string    `db:"id2" json:"id2"`
+	SensorType string    `db:"sensor_type" json:"sensor_type"`
+	Unit       string    `db:"unit" json:"unit,omitempty"`
+	Firmware   string    `db:"firmware" json:"firmware,omitempty"`
+	ProducerID string    `db:"producer_id" json:"producer_id,omitempty"`
+	RemoteAddr string    `db:"remote_addr" json:"remote_addr,omitempty"`
+	Connected  bool      `db:"connected" json:"connected"` // control stream attached
+	FirstSeen  time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen   time.Time `db:"last_seen" json:"last_seen"`
+	Status     string    `db:"-" json:"status" example:"online"` // online or offline, derived from last_seen
+}
+
+// DeviceToken lets a generator stream readings for the sensors it is bound to.
+// Only the SHA-256 hash of the token is kept; Prefix identifies it in listings.
+type DeviceToken struct {
+	ID        uint64 `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Prefix    string `db:"prefix" json:"prefix" example:"smd_1a2b3c4d"`
+	TokenHash string `db:"token_hash" json:"-"`
+	// Sensors are the id1/id2 pairs the token may write; id1/* allows every id2 of id1
+	Sensors    StringList `db:"sensors" json:"sensors" swaggertype:"array,string" example:"A/1"`
+	CreatedBy  *uint64    `db:"created_by" json:"created_by"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Allows reports whether the token may write readings of id1/id2
+func (t *DeviceToken) Allows(id1, id2 string) bool {
+	for _, s := range t.Sensors {
+		if s == id1+"/"+id2 || s == id1+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDeviceTokenRequest is the payload for creating a device token
+type CreateDeviceTokenRequest struct {
+	Name    string   `json:"name" example:"humidity-generator"`
+	Sensors []string `json:"sensors" example:"A/1"`
+}
+
+// CreateDeviceTokenResponse carries the token, shown only once
+type CreateDeviceTokenResponse struct {
+	DeviceToken
+	Token string `json:"token" example:"smd_1a2b3c4d_..."`
+}
@@ -0,0 +1,18 @@
+package model
+
+// Live stream event types
+const (
+	LiveReading   = "reading"   // a stored reading
+	LiveDropped   = "dropped"   // readings were lost because the client was too slow
+	LiveTruncated = "truncated" // the resume replay hit its limit, older readings were skipped
+	LiveHeartbeat = "heartbeat" // keeps idle streams open
+	LiveError     = "error"     // the stream ends
+)
+
+// LiveEvent is one message of a live stream of readings
+type LiveEvent struct {
+	Type    string         `json:"type" example:"reading"`
+	Reading *SensorReading `json:"reading,omitempty"`
+	Dropped uint64         `json:"dropped,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
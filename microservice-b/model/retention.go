@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// RetentionPolicy archives the readings it covers once they are older than
+// ArchiveAfterDays and purges them once older than PurgeAfterDays. A policy
+// without SensorType, ID1 and ID2 covers every reading; when several policies
+// cover a reading the most specific one applies.
+type RetentionPolicy struct {
+	ID               uint64     `db:"id" json:"id"`
+	Name             string     `db:"name" json:"name"`
+	SensorType       *string    `db:"sensor_type" json:"sensor_type,omitempty"`
+	ID1              *string    `db:"id1" json:"id1,omitempty"`
+	ID2              *int       `db:"id2" json:"id2,omitempty"`
+	ArchiveAfterDays *int       `db:"archive_after_days" json:"archive_after_days,omitempty"`
+	PurgeAfterDays   *int       `db:"purge_after_days" json:"purge_after_days,omitempty"`
+	Enabled          bool       `db:"enabled" json:"enabled"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// RetentionPolicyRequest is the payload for creating or dry-running a retention policy
+type RetentionPolicyRequest struct {
+	Name             string  `json:"name" example:"raw temperature"`
+	SensorType       *string `json:"sensor_type,omitempty" example:"Temperature"`
+	ID1              *string `json:"id1,omitempty"`
+	ID2              *int    `json:"id2,omitempty"`
+	ArchiveAfterDays *int    `json:"archive_after_days,omitempty" example:"30"`
+	PurgeAfterDays   *int    `json:"purge_after_days,omitempty" example:"365"`
+	Enabled          *bool   `json:"enabled,omitempty"` // defaults to true
+}
+
+// RetentionResult reports what enforcing a policy did, or would do in a dry run
+type RetentionResult struct {
+	PolicyID      uint64     `json:"policy_id"`
+	Name          string     `json:"name"`
+	ArchiveBefore *time.Time `json:"archive_before,omitempty"`
+	PurgeBefore   *time.Time `json:"purge_before,omitempty"`
+	Archived      int64      `json:"archived"`
+	Purged        int64      `json:"purged"`
+}
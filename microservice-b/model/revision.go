@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Revision actions
+const (
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+)
+
+// Revision records one change to one sensor reading. All revisions written by a
+// single request share a change set; for deletes it is the delete batch id.
+type Revision struct {
+	ID        uint64    `db:"id" json:"id"`
+	ChangeSet string    `db:"change_set" json:"change_set"`
+	ReadingID uint64    `db:"reading_id" json:"reading_id"`
+	Action    string    `db:"action" json:"action" example:"update"`
+	OldValue  *float64  `db:"old_value" json:"old_value"`
+	NewValue  *float64  `db:"new_value" json:"new_value"`
+	UserID    *uint64   `db:"user_id" json:"user_id,omitempty"`
+	Reason    string    `db:"reason" json:"reason,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ChangeInfo identifies who changes sensor readings and why
+type ChangeInfo struct {
+	UserID uint64 // 0 when unknown
+	Reason string
+}
+
+// RevertRequest is the payload for reverting a change set
+type RevertRequest struct {
+	Reason string `json:"reason,omitempty" example:"wrong calibration offset"`
+}
+
+// RevertResult reports the outcome of reverting a change set
+type RevertResult struct {
+	ChangeSet string `json:"change_set"` // change set recording the revert itself
+	Reverted  int64  `json:"reverted"`
+	Conflicts int64  `json:"conflicts"` // readings changed again, purged or restored since
+}
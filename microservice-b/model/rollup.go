@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RollupRange is a span of readings whose rollups must be recomputed. Nil fields
+// are unbounded: a range without ID1 covers every sensor.
+type RollupRange struct {
+	ID   uint64     `db:"id"`
+	ID1  *string    `db:"id1"`
+	ID2  *string    `db:"id2"`
+	From *time.Time `db:"from_ts"`
+	To   *time.Time `db:"to_ts"`
+}
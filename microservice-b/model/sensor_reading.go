@@ -1,20 +1,51 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	pb "microservice-b/pb/shared-proto"
+)
 
 type SensorReading struct {
-	ID         uint64     `db:"id" json:"id"`
-	ID1        string     `db:"id1" json:"id1"`
-	ID2        int        `db:"id2" json:"id2"`
-	SensorType string     `db:"sensor_type" json:"sensor_type"`
-	Value      float64    `db:"value" json:"value"`
-	TS         time.Time  `db:"ts" json:"ts"`
-	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt  *time.Time `db:"updated_at" json:"updated_at,omitempty"`
-	ArchivedAt *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	ID          uint64     `db:"id" json:"id"`
+	ID1         string     `db:"id1" json:"id1"`
+	ID2         int        `db:"id2" json:"id2"`
+	SensorType  string     `db:"sensor_type" json:"sensor_type"`
+	Value       float64    `db:"value" json:"value"`
+	TS          time.Time  `db:"ts" json:"ts"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	ArchivedAt  *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	DeleteBatch *string    `db:"delete_batch" json:"delete_batch,omitempty"` // set while in the trash; ArchivedAt is the deletion time
 }
 
 // EditSensorsRequest represents the payload for updating sensor values
 type EditSensorsRequest struct {
-	Value float64 `json:"value"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason,omitempty" example:"sensor recalibrated"`
+}
+
+// RestoreSensorsRequest selects trashed readings to restore by delete batch;
+// without a batch id the query filters select them
+type RestoreSensorsRequest struct {
+	BatchID string `json:"batch_id,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// SensorSequence is the last accepted sequence number of a sensor for one
+// producer session
+type SensorSequence struct {
+	ID1        string `db:"id1"`
+	ID2        string `db:"id2"`
+	ProducerID string `db:"producer_id"`
+	LastSeq    uint64 `db:"last_seq"`
+}
+
+// IngestResult summarizes what happened to a batch of incoming readings
+type IngestResult struct {
+	Saved      int64 // readings inserted
+	Duplicates int64 // readings dropped because their sequence was already stored
+	Gaps       int64 // sequence numbers skipped between accepted readings
+
+	Stored []*pb.SensorData // the readings inserted, duplicates left out
 }
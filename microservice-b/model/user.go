@@ -3,16 +3,42 @@ package model
 import "time"
 
 type User struct {
-	ID         uint64     `db:"id" json:"id"`
-	FirstName  string     `db:"first_name" json:"first_name"`
-	LastName   string     `db:"last_name" json:"last_name"`
-	Email      string     `db:"email" json:"email"`
-	Password   string     `db:"password" json:"password"`
-	Role       string     `db:"role" json:"role"`
-	LastLogin  *time.Time `db:"last_login" json:"last_login"`
-	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt  *time.Time `db:"updated_at" json:"updated_at,omitempty"`
-	ArchivedAt *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	ID                 uint64     `db:"id" json:"id"`
+	FirstName          string     `db:"first_name" json:"first_name"`
+	LastName           string     `db:"last_name" json:"last_name"`
+	Email              string     `db:"email" json:"email"`
+	Password           string     `db:"password" json:"-"`
+	Role               string     `db:"role" json:"role"`
+	MustChangePassword bool       `db:"must_change_password" json:"must_change_password"` // set by an admin password reset
+	LastLogin          *time.Time `db:"last_login" json:"last_login"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	ArchivedAt         *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+}
+
+// UpdateUserRequest is the payload for an admin updating a user; omitted fields
+// are left unchanged
+type UpdateUserRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Role      *string `json:"role,omitempty" example:"analyst"`
+}
+
+// UpdateProfileRequest is the payload for users updating their own profile
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+}
+
+// ChangePasswordRequest is the payload for users changing their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PasswordResetResponse carries the temporary password set by an admin reset
+type PasswordResetResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
 }
 
 type SignupRequest struct {
@@ -21,6 +47,8 @@ type SignupRequest struct {
 	Email     string `db:"email" json:"email"`
 	Password  string `db:"password" json:"password"`
 	Role      string `db:"role" json:"role"`
+	// InviteToken is required when signup is invite-only; the invitation decides the role
+	InviteToken string `db:"-" json:"invite_token,omitempty"`
 }
 type Login struct {
 	Email    string `json:"email"`
@@ -28,7 +56,30 @@ type Login struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"` // access token
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"` // seconds until the access token expires
+	// PasswordChangeRequired is set after an admin password reset; until the
+	// password is changed via POST /api/me/password only /api/me is accessible
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+}
+
+// RefreshRequest carries a refresh token to exchange or revoke
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken is a stored login session. Only the SHA-256 hash of the token is
+// kept; AccessJTI is the access token issued with it, revoked with the session.
+type RefreshToken struct {
+	ID              uint64     `db:"id"`
+	UserID          uint64     `db:"user_id"`
+	TokenHash       string     `db:"token_hash"`
+	AccessJTI       string     `db:"access_jti"`
+	AccessExpiresAt time.Time  `db:"access_expires_at"`
+	ExpiresAt       time.Time  `db:"expires_at"`
+	RevokedAt       *time.Time `db:"revoked_at"`
+	CreatedAt       time.Time  `db:"created_at"`
 }
 
 type SignupResponse struct {
@@ -40,3 +91,58 @@ type ErrorResponse struct {
 	Code    int    `json:"code,omitempty"`
 	Details string `json:"details,omitempty"`
 }
+
+// Invitation lets the holder of its token sign up with Email and Role once
+// before ExpiresAt. Only the SHA-256 hash of the token is kept.
+type Invitation struct {
+	ID         uint64     `db:"id" json:"id"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Email      string     `db:"email" json:"email"`
+	Role       string     `db:"role" json:"role"`
+	CreatedBy  *uint64    `db:"created_by" json:"created_by"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+	AcceptedBy *uint64    `db:"accepted_by" json:"accepted_by,omitempty"` // id of the user created
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Status returns pending, accepted, revoked or expired
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// InvitationResponse is an invitation with its status
+type InvitationResponse struct {
+	Invitation
+	Status string `json:"status" example:"pending"`
+}
+
+// CreateInvitationRequest is the payload for an admin inviting a user
+type CreateInvitationRequest struct {
+	Email string `json:"email" example:"new.user@example.com"`
+	Role  string `json:"role,omitempty" example:"analyst"` // defaults to analyst
+}
+
+// CreateInvitationResponse carries the invitation token, shown only once
+type CreateInvitationResponse struct {
+	InvitationResponse
+	Token string `json:"token"`
+}
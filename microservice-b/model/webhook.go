@@ -0,0 +1,89 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	EventAlertFiring   = "alert.firing"
+	EventAlertResolved = "alert.resolved"
+	EventDeviceOffline = "device.offline"
+	EventDeviceOnline  = "device.online"
+	EventWebhookTest   = "webhook.test" // sent by the test endpoint only, whatever a webhook subscribes to
+)
+
+// WebhookEvents are the event types a webhook can subscribe to
+var WebhookEvents = []string{EventAlertFiring, EventAlertResolved, EventDeviceOffline, EventDeviceOnline}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"   // waiting for its first attempt or a retry
+	DeliveryDelivered = "delivered" // the receiver answered 2xx
+	DeliveryDead      = "dead"      // every attempt failed; kept for inspection and manual retry
+)
+
+// Webhook is a subscription of an outbound URL to event types. Payloads are
+// signed with Secret, which is never returned by the API after creation.
+type Webhook struct {
+	ID        uint64     `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	URL       string     `db:"url" json:"url"`
+	Events    StringList `db:"events" json:"events" swaggertype:"array,string" example:"alert.firing"`
+	Secret    string     `db:"secret" json:"-"`
+	Enabled   bool       `db:"enabled" json:"enabled"`
+	CreatedBy *uint64    `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Subscribed reports whether the webhook subscribes to the event type
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest is the payload for creating a webhook; when updating one,
+// fields left out keep their value. A secret is generated when none is given.
+type WebhookRequest struct {
+	Name    *string  `json:"name,omitempty" example:"on-call"`
+	URL     *string  `json:"url,omitempty" example:"https://oncall.example.com/hooks/sensors"`
+	Events  []string `json:"events,omitempty" example:"alert.firing,device.offline"`
+	Secret  *string  `json:"secret,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"` // defaults to true
+}
+
+// CreateWebhookResponse returns the secret of a new webhook, the only time it is shown
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret" example:"whsec_6f1c0e..."`
+}
+
+// WebhookEvent is the JSON body POSTed to webhooks
+type WebhookEvent struct {
+	ID        string      `json:"id"` // the same for every webhook the event is delivered to
+	Type      string      `json:"type" example:"alert.firing"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"` // an Alert for alert events, a Device for device events
+}
+
+// WebhookDelivery is an event queued for, or delivered to, one webhook
+type WebhookDelivery struct {
+	ID             uint64          `db:"id" json:"id"`
+	WebhookID      uint64          `db:"webhook_id" json:"webhook_id"`
+	EventID        string          `db:"event_id" json:"event_id"`
+	EventType      string          `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage `db:"payload" json:"payload" swaggertype:"object"`
+	Status         string          `db:"status" json:"status" example:"delivered"`
+	Attempts       int             `db:"attempts" json:"attempts"`
+	NextAttemptAt  *time.Time      `db:"next_attempt_at" json:"next_attempt_at,omitempty"` // set while pending
+	LastStatusCode *int            `db:"last_status_code" json:"last_status_code,omitempty"`
+	LastError      *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at" json:"delivered_at,omitempty"`
+}
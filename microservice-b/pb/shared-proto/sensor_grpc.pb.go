@@ -19,7 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	SensorService_SendSensorData_FullMethodName = "/sensor.SensorService/SendSensorData"
+	SensorService_SendSensorData_FullMethodName    = "/sensor.SensorService/SendSensorData"
+	SensorService_SendSensorBatch_FullMethodName   = "/sensor.SensorService/SendSensorBatch"
+	SensorService_GetHighWaterMarks_FullMethodName = "/sensor.SensorService/GetHighWaterMarks"
+	SensorService_Control_FullMethodName           = "/sensor.SensorService/Control"
+	SensorService_Register_FullMethodName          = "/sensor.SensorService/Register"
+	SensorService_QueryReadings_FullMethodName     = "/sensor.SensorService/QueryReadings"
+	SensorService_GetLatest_FullMethodName         = "/sensor.SensorService/GetLatest"
+	SensorService_Subscribe_FullMethodName         = "/sensor.SensorService/Subscribe"
 )
 
 // SensorServiceClient is the client API for SensorService service.
@@ -28,6 +35,22 @@ const (
 type SensorServiceClient interface {
 	// microservice-a streams events to microservice-b
 	SendSensorData(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SensorData, Ack], error)
+	// microservice-a streams coalesced batches, each stored in one transaction
+	SendSensorBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SensorBatch, Ack], error)
+	// last accepted sequence per sensor, so a reconnecting producer can skip what is already stored
+	GetHighWaterMarks(ctx context.Context, in *HighWaterRequest, opts ...grpc.CallOption) (*HighWaterReply, error)
+	// generators keep this stream open; microservice-b pushes commands, generators report results
+	Control(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ControlMessage, Command], error)
+	// announces the sensors of a generator to the device registry
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterReply, error)
+	// read calls for backends; they authenticate with an access token or API key,
+	// as "authorization: Bearer <token>" or "x-api-key" metadata
+	// stored readings, newest first, or aggregated into time buckets like GET /api/sensors/aggregate
+	QueryReadings(ctx context.Context, in *QueryReadingsRequest, opts ...grpc.CallOption) (*QueryReadingsReply, error)
+	// the latest reading of every matching sensor
+	GetLatest(ctx context.Context, in *GetLatestRequest, opts ...grpc.CallOption) (*GetLatestReply, error)
+	// streams readings as they are stored, like GET /api/stream
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeEvent], error)
 }
 
 type sensorServiceClient struct {
@@ -51,12 +74,113 @@ func (c *sensorServiceClient) SendSensorData(ctx context.Context, opts ...grpc.C
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type SensorService_SendSensorDataClient = grpc.ClientStreamingClient[SensorData, Ack]
 
+func (c *sensorServiceClient) SendSensorBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SensorBatch, Ack], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SensorService_ServiceDesc.Streams[1], SensorService_SendSensorBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SensorBatch, Ack]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_SendSensorBatchClient = grpc.ClientStreamingClient[SensorBatch, Ack]
+
+func (c *sensorServiceClient) GetHighWaterMarks(ctx context.Context, in *HighWaterRequest, opts ...grpc.CallOption) (*HighWaterReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HighWaterReply)
+	err := c.cc.Invoke(ctx, SensorService_GetHighWaterMarks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sensorServiceClient) Control(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ControlMessage, Command], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SensorService_ServiceDesc.Streams[2], SensorService_Control_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ControlMessage, Command]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_ControlClient = grpc.BidiStreamingClient[ControlMessage, Command]
+
+func (c *sensorServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterReply)
+	err := c.cc.Invoke(ctx, SensorService_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sensorServiceClient) QueryReadings(ctx context.Context, in *QueryReadingsRequest, opts ...grpc.CallOption) (*QueryReadingsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryReadingsReply)
+	err := c.cc.Invoke(ctx, SensorService_QueryReadings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sensorServiceClient) GetLatest(ctx context.Context, in *GetLatestRequest, opts ...grpc.CallOption) (*GetLatestReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLatestReply)
+	err := c.cc.Invoke(ctx, SensorService_GetLatest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sensorServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SensorService_ServiceDesc.Streams[3], SensorService_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, SubscribeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_SubscribeClient = grpc.ServerStreamingClient[SubscribeEvent]
+
 // SensorServiceServer is the server API for SensorService service.
 // All implementations must embed UnimplementedSensorServiceServer
 // for forward compatibility.
 type SensorServiceServer interface {
 	// microservice-a streams events to microservice-b
 	SendSensorData(grpc.ClientStreamingServer[SensorData, Ack]) error
+	// microservice-a streams coalesced batches, each stored in one transaction
+	SendSensorBatch(grpc.ClientStreamingServer[SensorBatch, Ack]) error
+	// last accepted sequence per sensor, so a reconnecting producer can skip what is already stored
+	GetHighWaterMarks(context.Context, *HighWaterRequest) (*HighWaterReply, error)
+	// generators keep this stream open; microservice-b pushes commands, generators report results
+	Control(grpc.BidiStreamingServer[ControlMessage, Command]) error
+	// announces the sensors of a generator to the device registry
+	Register(context.Context, *RegisterRequest) (*RegisterReply, error)
+	// read calls for backends; they authenticate with an access token or API key,
+	// as "authorization: Bearer <token>" or "x-api-key" metadata
+	// stored readings, newest first, or aggregated into time buckets like GET /api/sensors/aggregate
+	QueryReadings(context.Context, *QueryReadingsRequest) (*QueryReadingsReply, error)
+	// the latest reading of every matching sensor
+	GetLatest(context.Context, *GetLatestRequest) (*GetLatestReply, error)
+	// streams readings as they are stored, like GET /api/stream
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[SubscribeEvent]) error
 	mustEmbedUnimplementedSensorServiceServer()
 }
 
@@ -70,6 +194,27 @@ type UnimplementedSensorServiceServer struct{}
 func (UnimplementedSensorServiceServer) SendSensorData(grpc.ClientStreamingServer[SensorData, Ack]) error {
 	return status.Errorf(codes.Unimplemented, "method SendSensorData not implemented")
 }
+func (UnimplementedSensorServiceServer) SendSensorBatch(grpc.ClientStreamingServer[SensorBatch, Ack]) error {
+	return status.Errorf(codes.Unimplemented, "method SendSensorBatch not implemented")
+}
+func (UnimplementedSensorServiceServer) GetHighWaterMarks(context.Context, *HighWaterRequest) (*HighWaterReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHighWaterMarks not implemented")
+}
+func (UnimplementedSensorServiceServer) Control(grpc.BidiStreamingServer[ControlMessage, Command]) error {
+	return status.Errorf(codes.Unimplemented, "method Control not implemented")
+}
+func (UnimplementedSensorServiceServer) Register(context.Context, *RegisterRequest) (*RegisterReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedSensorServiceServer) QueryReadings(context.Context, *QueryReadingsRequest) (*QueryReadingsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryReadings not implemented")
+}
+func (UnimplementedSensorServiceServer) GetLatest(context.Context, *GetLatestRequest) (*GetLatestReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLatest not implemented")
+}
+func (UnimplementedSensorServiceServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[SubscribeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
 func (UnimplementedSensorServiceServer) mustEmbedUnimplementedSensorServiceServer() {}
 func (UnimplementedSensorServiceServer) testEmbeddedByValue()                       {}
 
@@ -98,19 +243,149 @@ func _SensorService_SendSensorData_Handler(srv interface{}, stream grpc.ServerSt
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type SensorService_SendSensorDataServer = grpc.ClientStreamingServer[SensorData, Ack]
 
+func _SensorService_SendSensorBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SensorServiceServer).SendSensorBatch(&grpc.GenericServerStream[SensorBatch, Ack]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_SendSensorBatchServer = grpc.ClientStreamingServer[SensorBatch, Ack]
+
+func _SensorService_GetHighWaterMarks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HighWaterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SensorServiceServer).GetHighWaterMarks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SensorService_GetHighWaterMarks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SensorServiceServer).GetHighWaterMarks(ctx, req.(*HighWaterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SensorService_Control_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SensorServiceServer).Control(&grpc.GenericServerStream[ControlMessage, Command]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_ControlServer = grpc.BidiStreamingServer[ControlMessage, Command]
+
+func _SensorService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SensorServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SensorService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SensorServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SensorService_QueryReadings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryReadingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SensorServiceServer).QueryReadings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SensorService_QueryReadings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SensorServiceServer).QueryReadings(ctx, req.(*QueryReadingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SensorService_GetLatest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLatestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SensorServiceServer).GetLatest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SensorService_GetLatest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SensorServiceServer).GetLatest(ctx, req.(*GetLatestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SensorService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SensorServiceServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, SubscribeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SensorService_SubscribeServer = grpc.ServerStreamingServer[SubscribeEvent]
+
 // SensorService_ServiceDesc is the grpc.ServiceDesc for SensorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var SensorService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "sensor.SensorService",
 	HandlerType: (*SensorServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetHighWaterMarks",
+			Handler:    _SensorService_GetHighWaterMarks_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _SensorService_Register_Handler,
+		},
+		{
+			MethodName: "QueryReadings",
+			Handler:    _SensorService_QueryReadings_Handler,
+		},
+		{
+			MethodName: "GetLatest",
+			Handler:    _SensorService_GetLatest_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "SendSensorData",
 			Handler:       _SensorService_SendSensorData_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "SendSensorBatch",
+			Handler:       _SensorService_SendSensorBatch_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Control",
+			Handler:       _SensorService_Control_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _SensorService_Subscribe_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "sensor.proto",
 }
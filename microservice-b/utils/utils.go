@@ -10,6 +10,26 @@ import (
 var (
 	ErrEmailNotFound    = errors.New("email is wrong")
 	ErrPasswordMismatch = errors.New("password is mismatch")
+	ErrUserNotFound     = errors.New("user not found")
+	// ErrInvalidRefreshToken covers unknown, expired, revoked and reused refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSignupDisabled      = errors.New("signup is disabled")
+	ErrInvitationRequired  = errors.New("signup requires an invitation")
+	// ErrInvalidInvitation covers unknown, expired, revoked and used invitations
+	// and invitations for another email
+	ErrInvalidInvitation  = errors.New("invalid invitation")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrRoleNotAllowed is returned for an open signup asking for the admin role
+	ErrRoleNotAllowed = errors.New("the admin role can only be granted by an invitation")
+	// ErrInvalidAPIKey covers unknown, expired and revoked API keys and keys of
+	// archived users
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrScopeNotAllowed is returned for an API key scope beyond the owner's role
+	ErrScopeNotAllowed = errors.New("scope exceeds the permissions of the key owner")
+	// ErrInvalidDeviceToken covers unknown and revoked device tokens
+	ErrInvalidDeviceToken  = errors.New("invalid device token")
+	ErrDeviceTokenNotFound = errors.New("device token not found")
 )
 
 // ErrorResponse sends a structured error response using the provided status code, message, optional code, and details.